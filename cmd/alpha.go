@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/urfave/cli/v3"
+)
+
+// alphaCommands are the constructors registered with RegisterAlpha.
+var alphaCommands []func() *cli.Command
+
+// RegisterAlpha adds an experimental command under `dctl compose alpha`, so
+// new subsystems can ship behind that namespace without changing the main
+// command set. The constructor is called for every NewApp; the command sees
+// the compose flags, so resolveComposeContext works as for any compose
+// command. Subsystems register from init, embedders before calling NewApp.
+func RegisterAlpha(newCommand func() *cli.Command) {
+	alphaCommands = append(alphaCommands, newCommand)
+}
+
+// alphaCommand returns the compose alpha command group.
+func alphaCommand() *cli.Command {
+	cmds := make([]*cli.Command, 0, len(alphaCommands))
+	for _, newCommand := range alphaCommands {
+		cmds = append(cmds, newCommand())
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return &cli.Command{
+		Name:     "alpha",
+		Usage:    "Experimental commands; their flags and output may change",
+		Commands: cmds,
+	}
+}
@@ -1,14 +1,46 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sonnes/dctl/pkg/config"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
 	"github.com/urfave/cli/v3"
 )
 
 // Version is set via ldflags at build time.
 var Version = "dev"
 
+// Option customizes the app created by NewApp.
+type Option func(*appOptions)
+
+type appOptions struct {
+	runner runner.Backend
+}
+
+// WithRunner sends every container CLI command through b instead of the
+// container binary, so embedders and tests can drive dctl against a
+// runner.Recorder or their own backend. Without it the app runs the
+// container binary, whatever an earlier app was given.
+func WithRunner(b runner.Backend) Option {
+	return func(o *appOptions) { o.runner = b }
+}
+
 // NewApp creates the root dctl CLI command.
-func NewApp() *cli.Command {
+// Defaults from ~/.dctl/config.yaml are applied to the environment first so
+// that flags and environment variables override them.
+func NewApp(opts ...Option) *cli.Command {
+	var o appOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	runner.SetBackend(o.runner)
+	configErr := config.ApplyDefaults()
+	cli.VersionPrinter = printVersion
+
 	return &cli.Command{
 		Name:    "dctl",
 		Usage:   "Docker Compose compatible CLI for Apple container",
@@ -19,7 +51,71 @@ func NewApp() *cli.Command {
 				Usage:   "Enable debug output",
 				Sources: cli.EnvVars("DCTL_DEBUG"),
 			},
+			&cli.StringFlag{
+				Name:  "context",
+				Usage: "Context to use (overrides DCTL_CONTEXT and the current context)",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Usage:   "Only print errors, not progress or warnings",
+				Sources: cli.EnvVars("DCTL_QUIET"),
+			},
+			&cli.StringFlag{
+				Name:    "ansi",
+				Usage:   "Control when to print ANSI control characters (never, always, auto)",
+				Value:   "auto",
+				Sources: cli.EnvVars("COMPOSE_ANSI"),
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			if configErr != nil {
+				return ctx, configErr
+			}
+			if err := applyANSI(cmd.String("ansi")); err != nil {
+				return ctx, err
+			}
+			report.SetQuiet(cmd.Bool("quiet"))
+			return applyContext(ctx, cmd)
 		},
-		Commands: composeCommands(),
+		// Warnings scroll past among progress output, so several are
+		// listed again at the end.
+		After: func(ctx context.Context, cmd *cli.Command) error {
+			report.Summary()
+			return nil
+		},
+		Commands: append(append(composeCommands(), contextCommand(), stackCommand(), systemCommand(), serveCommand(), selfUpdateCommand()), dockerCommands()...),
+		// Unknown commands are dispatched to dctl-<name> plugins.
+		Action: pluginAction,
+		// Errors, including a container CLI command's exit status, are
+		// returned to the caller rather than exiting; see ExitCode.
+		ExitErrHandler: func(context.Context, *cli.Command, error) {},
+	}
+}
+
+// ExitCode returns the status dctl exits with after the app returned err,
+// and whether err still needs reporting. A container CLI command that
+// exited non-zero has reported its own failure, and dctl exits with its
+// status; any other error, including one wrapping such a failure, is
+// reported and exits 1. An invocation a docker context handed to the
+// docker CLI returns an error even when the CLI succeeded, which exits 0.
+func ExitCode(err error) (int, bool) {
+	if exitErr, ok := err.(interface{ ExitCode() int }); ok {
+		return exitErr.ExitCode(), false
+	}
+	return 1, true
+}
+
+// applyANSI passes the ANSI mode on to the container CLI through the
+// conventional NO_COLOR and CLICOLOR_FORCE variables.
+func applyANSI(mode string) error {
+	switch mode {
+	case "auto":
+	case "never":
+		os.Setenv("NO_COLOR", "1")
+	case "always":
+		os.Setenv("CLICOLOR_FORCE", "1")
+	default:
+		return fmt.Errorf("invalid --ansi value %q (want never, always or auto)", mode)
 	}
+	return nil
 }
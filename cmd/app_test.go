@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// newProject makes a temporary directory that holds dctl's state and, when
+// yaml is not empty, a compose.yaml with it. It returns the directory and
+// the compose file's path.
+func newProject(t *testing.T, yaml string) (dir, file string) {
+	t.Helper()
+	dir = t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("DCTL_STATE_DIR", filepath.Join(dir, "state"))
+	file = filepath.Join(dir, "compose.yaml")
+	if yaml != "" {
+		writeFile(t, file, yaml)
+	}
+	return dir, file
+}
+
+// writeFile writes content to path, creating its directory.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// saveState records a project's state.
+func saveState(t *testing.T, state *compose.ProjectState) {
+	t.Helper()
+	if err := compose.SaveProject(state); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadState reads a project's recorded state.
+func loadState(t *testing.T, project string) *compose.ProjectState {
+	t.Helper()
+	state, err := compose.LoadProject(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// newRecorder returns a Recorder that lists no containers, networks or
+// volumes, as up finds on a fresh runtime.
+func newRecorder() *runner.Recorder {
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, "[]", nil)
+	rec.Respond([]string{"network", "list"}, "[]", nil)
+	rec.Respond([]string{"volume", "list"}, "[]", nil)
+	return rec
+}
+
+// runDctl runs dctl with args, sending container CLI commands to rec.
+func runDctl(t *testing.T, rec *runner.Recorder, args ...string) error {
+	t.Helper()
+	t.Cleanup(func() { runner.SetBackend(nil) })
+	return NewApp(WithRunner(rec)).Run(context.Background(), append([]string{"dctl"}, args...))
+}
+
+// runCompose runs dctl compose on file as project shop.
+func runCompose(t *testing.T, file string, rec *runner.Recorder, args ...string) error {
+	t.Helper()
+	return runDctl(t, rec, append([]string{"compose", "-f", file, "-p", "shop"}, args...)...)
+}
+
+// captureReport sends progress and warnings to the returned builder for
+// the rest of the test.
+func captureReport(t *testing.T) *strings.Builder {
+	t.Helper()
+	var b strings.Builder
+	prev := report.Default()
+	report.SetDefault(report.New(&b))
+	t.Cleanup(func() { report.SetDefault(prev) })
+	return &b
+}
+
+// captureStdout runs fn with os.Stdout going to a file and returns what fn
+// printed along with its error.
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = out
+	err = fn()
+	os.Stdout = stdout
+	out.Close()
+	data, rerr := os.ReadFile(out.Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return string(data), err
+}
+
+// calls returns the commands rec recorded whose first argument is one of
+// names.
+func calls(rec *runner.Recorder, names ...string) [][]string {
+	var matched [][]string
+	for _, call := range rec.Calls() {
+		for _, name := range names {
+			if call[0] == name {
+				matched = append(matched, call)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// envFileOf returns the contents of the env file a recorded run or exec
+// passes its variables through, or "" when it has none.
+func envFileOf(t *testing.T, args []string) string {
+	t.Helper()
+	i := slices.Index(args, "--env-file")
+	if i < 0 {
+		return ""
+	}
+	data, err := os.ReadFile(args[i+1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestQuietFlag(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+
+	stop := func(global ...string) string {
+		t.Helper()
+		saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+		b := captureReport(t)
+		args := append(global, "compose", "-f", file, "-p", "shop", "--parallel", "1", "stop", "web", "db")
+		if err := runDctl(t, &runner.Recorder{}, args...); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	if got, want := stop(), "Warning: no container found for service db\nStopping shop_web\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if got := stop("--quiet"); got != "" {
+		t.Errorf("output with --quiet = %q, want none", got)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	newProject(t, "")
+
+	// A failed command's status is returned rather than exiting dctl.
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"delete"}, "", &runner.ExitError{Code: 3})
+	err := runDctl(t, rec, "rm", "web")
+	if code, report := ExitCode(err); code != 3 || report {
+		t.Errorf("ExitCode(%v) = %d, %v; want 3, false", err, code, report)
+	}
+
+	// The next app runs the container binary again.
+	NewApp()
+	if !runner.UsesBinary() {
+		t.Error("NewApp() kept the previous app's runner")
+	}
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/logmerge"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// attachedServices returns the services whose output a foreground up
+// follows: those given with --attach, or else every service that does not
+// set attach: false, less those given with --no-attach.
+func attachedServices(cc *composeContext, cmd *cli.Command, order []string) ([]string, error) {
+	services := cc.composeFile.Services
+	for _, svcName := range append(cmd.StringSlice("attach"), cmd.StringSlice("no-attach")...) {
+		if _, ok := services[svcName]; !ok {
+			return nil, compose.UnknownServiceError(svcName, sortedKeys(services))
+		}
+	}
+	var attached []string
+	for _, svcName := range order {
+		if only := cmd.StringSlice("attach"); len(only) > 0 {
+			if !slices.Contains(only, svcName) {
+				continue
+			}
+		} else if a := services[svcName].Attach; a != nil && !*a {
+			continue
+		}
+		if !slices.Contains(cmd.StringSlice("no-attach"), svcName) {
+			attached = append(attached, svcName)
+		}
+	}
+	return attached, nil
+}
+
+// attachUp follows the output of the attached services after a foreground
+// up, merged as mergeLogs does, until their containers stop or the user
+// interrupts. New containers are followed from their first line,
+// others from now on. An interrupt stops the project's services as
+// compose stop does, and a second one kills them. The runtime gives
+// detached containers no stdin, so stdin_open services cannot be typed to
+// here; tty services' line endings are cleaned up by the prefixes.
+func attachUp(cmd *cli.Command, cc *composeContext, state *compose.ProjectState, order, attached []string, fresh map[string]bool) error {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	if len(attached) > 0 {
+		report.Infof("Attaching to %s", strings.Join(attached, ", "))
+	}
+	var streams []logStream
+	for _, svcName := range attached {
+		args := []string{"logs", "--follow"}
+		if !fresh[svcName] {
+			args = append(args, "-n", "0")
+		}
+		streams = append(streams, logStream{service: svcName, args: append(args, cc.containerName(svcName))})
+	}
+	exited := make(chan struct{})
+	go func() {
+		mergeLogs(streams, true)
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+		// The followed containers stopped on their own.
+		for _, svcName := range attached {
+			if containerStatus(cc.containerName(svcName)) != compose.StatusRunning {
+				recordStopped(state, svcName)
+			}
+		}
+		return compose.SaveProject(state)
+	case <-sigs:
+	}
+
+	report.Infof("Gracefully stopping... (press Ctrl+C again to force)")
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		levels, err := shutdownLevels(cc.composeFile.Services, order)
+		if err != nil {
+			levels = [][]string{order}
+		}
+		for _, level := range levels {
+			_ = forEachParallel(parallelLimit(cmd), level, func(svcName string) error {
+				cName := cc.containerName(svcName)
+				report.Infof("Stopping %s", cName)
+				if err := stopService(cmd, cc, svcName, cName); err != nil {
+					report.Warnf("failed to stop %s: %v", svcName, err)
+				}
+				return nil
+			})
+		}
+	}()
+	select {
+	case <-stopped:
+	case <-sigs:
+		for _, svcName := range order {
+			cName := cc.containerName(svcName)
+			report.Infof("Killing %s", cName)
+			_, _ = runner.Output("kill", cName)
+		}
+	}
+
+	for _, svcName := range order {
+		state.SetStatus(svcName, compose.StatusStopped)
+	}
+	return compose.SaveProject(state)
+}
+
+// mergeWindow is how long followed log lines are held so that a line
+// another service delivers a little later can still be shown before them.
+const mergeWindow = 200 * time.Millisecond
+
+// logStream is a container's log command, whose lines are shown under its
+// service; with match set, only the lines it matches.
+type logStream struct {
+	service string
+	args    []string
+	match   *regexp.Regexp
+}
+
+// mergeLogs shows the logs of several services as one stream, each line
+// prefixed with its service, ordered by the timestamps lines start with
+// rather than by arrival. Followed lines are held for mergeWindow; other
+// logs are ordered whole once read. It returns when every stream ends.
+func mergeLogs(streams []logStream, follow bool) {
+	width := 0
+	for _, s := range streams {
+		width = max(width, len(s.service))
+	}
+	m := logmerge.New(mergeWindow, func(l logmerge.Line) {
+		fmt.Printf("%-*s | %s\n", width, l.Service, l.Text)
+	})
+	stop := make(chan struct{})
+	if follow {
+		go func() {
+			ticker := time.NewTicker(mergeWindow / 4)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case now := <-ticker.C:
+					m.Release(now)
+				}
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := &prefixWriter{w: m.Writer(s.service), match: s.match}
+			if err := runner.Stream(w, s.args...); err != nil {
+				report.Warnf("failed to get logs for %s: %v", s.service, err)
+			}
+			w.Flush()
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	m.Flush()
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/launchd"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// autostartCommand returns the compose autostart command group.
+func autostartCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "autostart",
+		Usage: "Start the project automatically at login with launchd",
+		Commands: []*cli.Command{
+			{
+				Name:  "enable",
+				Usage: "Install a launchd agent running `compose up -d` at login",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "print", Usage: "Print the plist instead of installing it"},
+				},
+				Action: composeAutostartEnableAction,
+			},
+			{
+				Name:   "disable",
+				Usage:  "Unload and remove the project's launchd agent",
+				Action: composeAutostartDisableAction,
+			},
+		},
+	}
+}
+
+// autostartLabel returns the launchd label for a project's autostart agent.
+func autostartLabel(project string) string {
+	return launchd.LabelPrefix + project + ".autostart"
+}
+
+// projectInvocation returns the argv that re-runs dctl compose against this
+// project from any working directory: the absolute binary path followed by
+// the project name, directory and absolute compose file paths.
+func projectInvocation(cmd *cli.Command, cc *composeContext) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating dctl binary: %w", err)
+	}
+	args := []string{exe, "compose", "-p", cc.projectName, "--project-directory", cc.projectDir}
+
+	files, err := compose.ResolveFiles(cc.files, cc.projectDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		args = append(args, "-f", f)
+	}
+	for _, p := range cmd.StringSlice("profile") {
+		args = append(args, "--profile", p)
+	}
+	if envFile := cmd.String("env-file"); envFile != "" {
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(cc.projectDir, envFile)
+		}
+		args = append(args, "--env-file", envFile)
+	}
+	return args, nil
+}
+
+// agentEnvironment returns the environment launchd agents run dctl with,
+// so the container binary resolves the same way it does interactively.
+func agentEnvironment() map[string]string {
+	return map[string]string{
+		"PATH":               os.Getenv("PATH"),
+		"DCTL_CONTAINER_BIN": runner.ContainerBin,
+	}
+}
+
+// agentLogPath returns the log file for a launchd agent.
+func agentLogPath(label string) (string, error) {
+	dir, err := compose.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs", label+".log"), nil
+}
+
+func composeAutostartEnableAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	args, err := projectInvocation(cmd, cc)
+	if err != nil {
+		return err
+	}
+	label := autostartLabel(cc.projectName)
+	logPath, err := agentLogPath(label)
+	if err != nil {
+		return err
+	}
+
+	agent := &launchd.Agent{
+		Label:            label,
+		ProgramArguments: append(args, "up", "--detach"),
+		WorkingDirectory: cc.projectDir,
+		Environment:      agentEnvironment(),
+		RunAtLoad:        true,
+		LogPath:          logPath,
+	}
+
+	if cmd.Bool("print") {
+		fmt.Print(string(agent.Render()))
+		return nil
+	}
+
+	path, err := launchd.Install(agent)
+	if err != nil {
+		return err
+	}
+	report.Infof("Installed %s\nLogs: %s", path, logPath)
+	return nil
+}
+
+func composeAutostartDisableAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	label := autostartLabel(cc.projectName)
+	if err := launchd.Uninstall(label); err != nil {
+		return err
+	}
+	report.Infof("Removed %s", label)
+	return nil
+}
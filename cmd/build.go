@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/sonnes/dctl/pkg/buildctx"
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/format"
+	"github.com/sonnes/dctl/pkg/prune"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// largeContext is the context size above which a build warns that the
+// builder is sent more than it probably needs.
+const largeContext = 200 * 1000 * 1000
+
+// contextEntries is how many of a context's largest entries
+// build --check-context lists.
+const contextEntries = 10
+
+// ContextEntry is a top-level entry of a build context as
+// build --check-context lists it.
+type ContextEntry struct {
+	Path  string
+	Size  string
+	Files int
+}
+
+// contextTable is the build --check-context layout.
+const contextTable = `{{.Path}}\t{{.Size}}\t{{.Files}}`
+
+// warnLargeContext warns before a build whose context, after its ignore
+// file, is large enough to slow the build down. Remote contexts are
+// fetched by the builder and are not measured.
+func warnLargeContext(svcName string, bc *compose.BuildConfig) {
+	if compose.IsRemoteContext(bc.Context) {
+		return
+	}
+	s, err := buildctx.Measure(bc.Context, bc.Dockerfile)
+	if err != nil {
+		report.Warnf("service %s: %v", svcName, err)
+		return
+	}
+	if s.Size < largeContext {
+		return
+	}
+	hint := "add a .dockerignore to leave out what the build does not need"
+	if s.Ignore != "" {
+		hint = "extend " + filepath.Base(s.Ignore) + " to leave out what the build does not need"
+	}
+	report.Warnf("service %s: build context %s is %s in %d files; %s (see dctl compose build --check-context %s)",
+		svcName, bc.Context, prune.HumanSize(s.Size), s.Files, hint, svcName)
+}
+
+// checkContext lists the largest entries of a service's build context,
+// after its ignore file, instead of building it.
+func checkContext(svcName string, bc *compose.BuildConfig) error {
+	if compose.IsRemoteContext(bc.Context) {
+		report.Infof("Skipping %s: remote build context %s", svcName, bc.Context)
+		return nil
+	}
+	s, err := buildctx.Measure(bc.Context, bc.Dockerfile)
+	if err != nil {
+		return fmt.Errorf("service %s: %w", svcName, err)
+	}
+	ignore := "no ignore file"
+	if s.Ignore != "" {
+		ignore = "after " + filepath.Base(s.Ignore)
+	}
+	fmt.Printf("%s: %s in %d files (%s)\n", svcName, prune.HumanSize(s.Size), s.Files, ignore)
+	var rows []ContextEntry
+	for i, e := range s.Entries {
+		if i == contextEntries {
+			break
+		}
+		rows = append(rows, ContextEntry{Path: e.Path, Size: prune.HumanSize(e.Size), Files: e.Files})
+	}
+	return format.Write(os.Stdout, "table", contextTable, rows)
+}
+
+// Build progress modes, as --progress names them.
+const (
+	progressAuto  = "auto"
+	progressTTY   = "tty"
+	progressPlain = "plain"
+	progressQuiet = "quiet"
+)
+
+// buildJob is a service image to build and the container CLI arguments
+// that build it.
+type buildJob struct {
+	service string
+	args    []string
+}
+
+// buildProgress returns the progress mode builds run with. auto passes the
+// runtime's output through when builds go one at a time to a terminal, and
+// prefixes each line with its service otherwise; it is quiet with --quiet.
+func buildProgress(cmd *cli.Command, jobs int) (string, error) {
+	switch mode := cmd.String("progress"); mode {
+	case progressTTY, progressPlain, progressQuiet:
+		return mode, nil
+	case "", progressAuto:
+		if report.Quiet() || cmd.Bool("quiet") {
+			return progressQuiet, nil
+		}
+		if (jobs == 1 || parallelLimit(cmd) == 1) && runner.IsTerminal(os.Stdout) {
+			return progressTTY, nil
+		}
+		return progressPlain, nil
+	default:
+		return "", fmt.Errorf("invalid --progress value %q (want auto, tty, plain or quiet)", mode)
+	}
+}
+
+// runBuilds builds service images, as many at a time as --parallel allows.
+// Each build's output is kept, and a failed build's is saved to a file the
+// error names, since plain output is interleaved and quiet prints none.
+func runBuilds(cmd *cli.Command, project string, jobs []buildJob) error {
+	mode, err := buildProgress(cmd, len(jobs))
+	if err != nil {
+		return err
+	}
+	limit := parallelLimit(cmd)
+	if mode == progressTTY {
+		// Terminal progress redraws its lines, which builds side by side
+		// would garble.
+		limit = 1
+	}
+	return forEachParallel(limit, jobs, func(job buildJob) error {
+		report.Infof("Building %s", job.service)
+		var log bytes.Buffer
+		var out io.Writer = &log
+		var prefixed *prefixWriter
+		switch mode {
+		case progressTTY:
+			out = io.MultiWriter(os.Stdout, &log)
+		case progressPlain:
+			prefixed = &prefixWriter{w: os.Stdout, prefix: job.service + " | "}
+			out = io.MultiWriter(prefixed, &log)
+		}
+		err := runner.Stream(out, job.args...)
+		if prefixed != nil {
+			prefixed.Flush()
+		}
+		if err == nil {
+			return nil
+		}
+		path, saveErr := saveBuildLog(project, job.service, log.Bytes())
+		if saveErr != nil {
+			report.Warnf("%v", saveErr)
+			return fmt.Errorf("building service %s: %w", job.service, err)
+		}
+		return fmt.Errorf("building service %s: %w (output saved to %s)", job.service, err, path)
+	})
+}
+
+// saveBuildLog writes a failed build's output to
+// ~/.dctl/logs/<project>-<service>-build.log, replacing the last one.
+func saveBuildLog(project, service string, output []byte) (string, error) {
+	dir, err := compose.StateDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "logs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating log directory: %w", err)
+	}
+	path := filepath.Join(dir, project+"-"+service+"-build.log")
+	if err := os.WriteFile(path, output, 0o644); err != nil {
+		return "", fmt.Errorf("saving build output: %w", err)
+	}
+	return path, nil
+}
+
+// outputMu keeps lines of concurrent prefixWriters whole.
+var outputMu sync.Mutex
+
+// prefixWriter writes each line to w after a prefix, so the output of
+// builds or containers running side by side can be told apart. Carriage
+// returns ending lines, as tty output has, are dropped. With match set,
+// only the lines it matches are written.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	match  *regexp.Regexp
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a last line that did not end in a newline.
+func (p *prefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	line = bytes.TrimRight(line, "\r\n")
+	if p.match != nil && !p.match.Match(line) {
+		return nil
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	_, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, line)
+	return err
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeBuildContextWarning(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    build: .\n")
+	// A sparse file is large without taking the disk space.
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.Create(filepath.Join(dir, "data", "blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blob.Truncate(300 * 1000 * 1000); err != nil {
+		t.Fatal(err)
+	}
+	blob.Close()
+
+	build := func() string {
+		b := captureReport(t)
+		rec := &runner.Recorder{}
+		if err := runCompose(t, file, rec, "--project-directory", dir, "build"); err != nil {
+			t.Fatal(err)
+		}
+		if calls := rec.Calls(); len(calls) == 0 || calls[0][0] != "build" {
+			t.Errorf("calls = %v, want a build", calls)
+		}
+		return b.String()
+	}
+	if out := build(); !strings.Contains(out, "Warning: service web: build context "+dir+" is 300MB in 3 files; add a .dockerignore") {
+		t.Errorf("output = %q, want a context size warning", out)
+	}
+
+	writeFile(t, filepath.Join(dir, ".dockerignore"), "data\n")
+	if out := build(); strings.Contains(out, "Warning") {
+		t.Errorf("output = %q, want no warning once data is ignored", out)
+	}
+}
+
+func TestComposeBuildFailureLog(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    build: .\n  api:\n    build: .\n")
+	captureReport(t)
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"build", "--tag", "shop-api"}, "step 1/2\nerror: no such file\n", &runner.ExitError{Code: 1})
+	err := runCompose(t, file, rec, "--project-directory", dir, "--progress", "quiet", "build")
+	log := filepath.Join(dir, "state", "logs", "shop-api-build.log")
+	if err == nil || !strings.Contains(err.Error(), "building service api: exit status 1 (output saved to "+log+")") {
+		t.Fatalf("err = %v, want the api build to fail naming its log", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "step 1/2\nerror: no such file\n" {
+		t.Errorf("log = %q", data)
+	}
+	// The other service still builds.
+	if builds := calls(rec, "build"); len(builds) != 2 {
+		t.Errorf("%d builds, want 2", len(builds))
+	}
+}
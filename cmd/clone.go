@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/stack"
+	"github.com/urfave/cli/v3"
+)
+
+// composeCloneAction starts a copy of a project under the name given with
+// -p: the project named as the argument, or else the compose file's. The
+// copy runs the same services beside the original, with networks and
+// volumes of its own (see compose.Clone), which --copy-volumes fills with
+// the original's data before the copy starts.
+func composeCloneAction(ctx context.Context, cmd *cli.Command) error {
+	cloneName := cmd.String("project-name")
+	if cloneName == "" {
+		return fmt.Errorf("requires the name of the copy: compose clone -p NAME [PROJECT]")
+	}
+	if cmd.Args().Len() > 1 {
+		return fmt.Errorf("accepts at most 1 argument: PROJECT")
+	}
+	if err := compose.ValidateProjectName(cloneName); err != nil {
+		return err
+	}
+	src, err := loadComposeContext(cmd, cmd.Args().First())
+	if err != nil {
+		return err
+	}
+	if src.projectName == cloneName {
+		return fmt.Errorf("cannot clone project %s onto itself", cloneName)
+	}
+	state, err := compose.LoadProject(src.projectName)
+	if err != nil {
+		return err
+	}
+	if _, err := compose.LoadProject(cloneName); err == nil {
+		return fmt.Errorf("project %q already exists", cloneName)
+	}
+
+	clone := &compose.ProjectState{
+		Name:        cloneName,
+		ComposeFile: state.ComposeFile,
+		ProjectDir:  src.projectDir,
+		Containers:  map[string]string{},
+		Naming:      src.naming,
+		ClonePrefix: compose.ClonePrefix(cloneName),
+	}
+	if err := compose.SaveProject(clone); err != nil {
+		return err
+	}
+	report.Infof("Cloning project %s as %s", src.projectName, cloneName)
+
+	if cmd.Bool("copy-volumes") {
+		if err := copyCloneVolumes(cmd, src, state, clone); err != nil {
+			discardClone(clone)
+			return err
+		}
+	}
+
+	// The copy is brought up from the same files, given as absolute paths
+	// since it runs from the project directory.
+	p := stack.Project{Path: src.projectDir, Profiles: cmd.StringSlice("profile")}
+	for _, f := range cmd.StringSlice("file") {
+		if f != "-" && !strings.Contains(f, "://") {
+			abs, err := filepath.Abs(f)
+			if err != nil {
+				return fmt.Errorf("resolving %s: %w", f, err)
+			}
+			f = abs
+		}
+		p.Files = append(p.Files, f)
+	}
+	return stackCompose(ctx, cmd, cloneName, p, "up", "--detach")
+}
+
+// copyCloneVolumes creates the copy's volumes and fills them with the
+// original's data. They are recorded as they are created, so up keeps them
+// as created by the copy.
+func copyCloneVolumes(cmd *cli.Command, src *composeContext, state, clone *compose.ProjectState) error {
+	dst, err := loadComposeContext(cmd, clone.Name)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(dst.composeFile.Volumes) {
+		vol := dst.composeFile.Volumes[key]
+		if vol.External {
+			continue
+		}
+		from := volumeName(src.composeFile, state.ClonePrefix+strings.TrimPrefix(key, clone.ClonePrefix))
+		to := volumeName(dst.composeFile, key)
+		report.Infof("Creating volume %s", to)
+		if _, err := runner.Output(volumeCreateArgs(to, vol)...); err != nil {
+			return fmt.Errorf("creating volume %s: %w", to, err)
+		}
+		clone.Volumes = append(clone.Volumes, to)
+		if err := compose.SaveProject(clone); err != nil {
+			return err
+		}
+		report.Infof("Copying volume %s to %s", from, to)
+		if err := copyVolume(from, to, cmd.String("helper-image")); err != nil {
+			return fmt.Errorf("copying volume %s: %w", from, err)
+		}
+	}
+	return nil
+}
+
+// discardClone removes a copy that failed before it started: the volumes
+// created for it and its state.
+func discardClone(clone *compose.ProjectState) {
+	for _, vol := range clone.Volumes {
+		report.Infof("Removing volume %s", vol)
+		if _, err := runner.Output("volume", "delete", vol); err != nil {
+			report.Warnf("failed to remove volume %s: %v", vol, err)
+		}
+	}
+	if err := compose.DeleteProject(clone.Name); err != nil {
+		report.Warnf("deleting project state: %v", err)
+	}
+}
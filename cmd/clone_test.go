@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeClone(t *testing.T) {
+	dir, file := newProject(t, "services:\n  db:\n    image: postgres\n    ports: [\"5432:5432\"]\n    volumes: [data:/var/lib/postgresql/data]\n    networks: [back]\nnetworks:\n  back: {}\nvolumes:\n  data: {}\n")
+	saveState(t, &compose.ProjectState{Name: "shop", ProjectDir: dir, Containers: map[string]string{"db": "shop_db"}, Networks: []string{"back"}, Volumes: []string{"data"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"volume", "list"}, `[{"name":"data"},{"name":"copy_data"}]`, nil)
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/postgres:latest"}]`, nil)
+
+	captureReport(t)
+	if err := runDctl(t, rec, "compose", "-f", file, "--project-directory", dir, "clone", "-p", "copy", "--copy-volumes", "shop"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range calls(rec, "volume", "network", "run") {
+		if c[0] == "run" || c[1] == "create" {
+			got = append(got, strings.Join(c, " "))
+		}
+	}
+	if len(got) != 4 {
+		t.Fatalf("calls = %q, want a volume, its copy, a network and the copy's container", got)
+	}
+	if got[0] != "volume create copy_data" || got[2] != "network create copy_back" {
+		t.Errorf("calls = %q, want copy_data and copy_back created", got)
+	}
+	if want := "run --rm --volume data:/from:ro --volume copy_data:/to alpine:latest cp -a /from/. /to/"; got[1] != want {
+		t.Errorf("copy = %q, want %q", got[1], want)
+	}
+	run := got[3]
+	if !strings.Contains(run, "--name copy_db") || !strings.Contains(run, "--volume copy_data:/var/lib/postgresql/data") || !strings.Contains(run, "--network copy_back") {
+		t.Errorf("run = %q, want the copy's container on its own volume and network", run)
+	}
+	if strings.Contains(run, "--publish 5432:5432") {
+		t.Errorf("run = %q, want a free host port instead of the original's", run)
+	}
+
+	state := loadState(t, "copy")
+	if state.ClonePrefix != "copy_" || !slices.Equal(state.Volumes, []string{"copy_data"}) || !slices.Equal(state.Networks, []string{"copy_back"}) {
+		t.Errorf("state = %+v, want the copy's own networks and volumes", state)
+	}
+}
+
+func TestComposeCloneFailedCopy(t *testing.T) {
+	dir, file := newProject(t, "services:\n  db:\n    image: postgres\n    volumes: [data:/var/lib/postgresql/data]\nvolumes:\n  data: {}\n")
+	saveState(t, &compose.ProjectState{Name: "shop", ProjectDir: dir, Containers: map[string]string{"db": "shop_db"}, Volumes: []string{"data"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"run", "--rm"}, "", &runner.ExitError{Code: 1})
+
+	captureReport(t)
+	err := runDctl(t, rec, "compose", "-f", file, "--project-directory", dir, "clone", "-p", "copy", "--copy-volumes", "shop")
+	if err == nil || !strings.Contains(err.Error(), "copying volume data") {
+		t.Fatalf("err = %v, want the failed copy", err)
+	}
+	// The half-made copy is removed rather than left for up or down.
+	if !slices.ContainsFunc(rec.Calls(), func(c []string) bool { return slices.Equal(c, []string{"volume", "delete", "copy_data"}) }) {
+		t.Errorf("commands = %v, want copy_data deleted", rec.Calls())
+	}
+	if _, err := compose.LoadProject("copy"); err == nil {
+		t.Error("copy's state was left behind")
+	}
+}
@@ -1,15 +1,37 @@
 package cmd
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/netip"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/features"
+	"github.com/sonnes/dctl/pkg/format"
+	"github.com/sonnes/dctl/pkg/oci"
+	"github.com/sonnes/dctl/pkg/portcheck"
+	"github.com/sonnes/dctl/pkg/report"
 	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/secrets"
+	"github.com/sonnes/dctl/pkg/translate"
 	"github.com/urfave/cli/v3"
 	"gopkg.in/yaml.v3"
 )
@@ -17,11 +39,15 @@ import (
 // composeCommands returns the compose command group.
 func composeCommands() []*cli.Command {
 	composeGlobalFlags := []cli.Flag{
-		&cli.StringSliceFlag{Name: "file", Aliases: []string{"f"}, Usage: "Compose configuration files"},
+		&cli.StringSliceFlag{Name: "file", Aliases: []string{"f"}, Usage: "Compose configuration files (path, https:// URL, or - for stdin)"},
 		&cli.StringFlag{Name: "project-name", Aliases: []string{"p"}, Usage: "Project name"},
-		&cli.StringFlag{Name: "project-directory", Usage: "Specify an alternate working directory"},
-		&cli.StringSliceFlag{Name: "profile", Usage: "Specify a profile to enable"},
+		&cli.StringFlag{Name: "project-directory", Usage: "Specify an alternate working directory (default: the first compose file's directory)"},
+		&cli.StringSliceFlag{Name: "profile", Usage: "Specify a profile to enable; without -f, compose.PROFILE.yaml is layered over the default file", Sources: cli.EnvVars("COMPOSE_PROFILES")},
 		&cli.StringFlag{Name: "env-file", Usage: "Specify an alternate environment file"},
+		&cli.IntFlag{Name: "parallel", Usage: "Maximum number of concurrent operations, -1 for unlimited", Value: -1, Sources: cli.EnvVars("COMPOSE_PARALLEL_LIMIT")},
+		&cli.BoolFlag{Name: "strict", Usage: "Reject obsolete keys such as version, links and external_links"},
+		&cli.StringFlag{Name: "progress", Usage: "Build output: auto, tty (the runtime's own), plain (lines prefixed by service) or quiet", Value: progressAuto, Sources: cli.EnvVars("COMPOSE_PROGRESS")},
+		&cli.BoolFlag{Name: "compat-naming", Usage: "Name containers project-service-1 like docker compose v2", Sources: cli.EnvVars("DCTL_COMPAT_NAMING")},
 	}
 	_ = composeGlobalFlags
 
@@ -36,11 +62,18 @@ func composeCommands() []*cli.Command {
 					Usage: "Create and start containers",
 					Flags: []cli.Flag{
 						&cli.BoolFlag{Name: "detach", Aliases: []string{"d"}, Usage: "Detached mode: run containers in the background"},
+						&cli.StringSliceFlag{Name: "attach", Usage: "Follow only these services' output (default: those without attach: false)"},
+						&cli.StringSliceFlag{Name: "no-attach", Usage: "Don't follow these services' output"},
 						&cli.BoolFlag{Name: "build", Usage: "Build images before starting containers"},
+						&cli.BoolFlag{Name: "no-build", Usage: "Don't build images, even if they are missing"},
+						&cli.BoolFlag{Name: "no-start", Usage: "Create containers without starting them"},
 						&cli.BoolFlag{Name: "force-recreate", Usage: "Recreate containers even if unchanged"},
 						&cli.BoolFlag{Name: "remove-orphans", Usage: "Remove containers for undefined services"},
 						&cli.IntFlag{Name: "timeout", Aliases: []string{"t"}, Usage: "Shutdown timeout in seconds", Value: 10},
 						&cli.BoolFlag{Name: "wait", Usage: "Wait for services to be running/healthy"},
+						&cli.BoolFlag{Name: "dry-run", Usage: "Show the planned actions without executing them"},
+						&cli.BoolFlag{Name: "rollback-on-failure", Usage: "Restore the previous containers if a service fails to start or become ready"},
+						&cli.IntFlag{Name: "project-timeout", Usage: "Seconds to wait for the projects services name in x-dctl-depends-on-project to be running", Value: 60},
 					},
 					Action: composeUpAction,
 				},
@@ -51,6 +84,7 @@ func composeCommands() []*cli.Command {
 						&cli.BoolFlag{Name: "volumes", Aliases: []string{"v"}, Usage: "Remove named volumes"},
 						&cli.BoolFlag{Name: "remove-orphans", Usage: "Remove containers for undefined services"},
 						&cli.IntFlag{Name: "timeout", Aliases: []string{"t"}, Usage: "Shutdown timeout in seconds", Value: 10},
+						&cli.StringFlag{Name: "rmi", Usage: `Remove images used by services: "local" for images built without a custom tag, "all" for every image`},
 					},
 					Action: composeDownAction,
 				},
@@ -58,32 +92,107 @@ func composeCommands() []*cli.Command {
 					Name:  "ps",
 					Usage: "List containers",
 					Flags: []cli.Flag{
-						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Only display container IDs"},
-						&cli.StringFlag{Name: "format", Usage: "Output format (table|json)"},
+						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Only display container names; with --filter, exit 1 when none match"},
+						&cli.StringSliceFlag{Name: "filter", Usage: "Filter containers (health=healthy|unhealthy|none, status=STATUS)"},
+						&cli.StringFlag{Name: "format", Usage: "Output format: json, table, or a Go template such as '{{.Name}} {{.Ports}}' (default: the runtime's JSON)"},
+						&cli.BoolFlag{Name: "cached", Usage: "Show the statuses dctl last recorded instead of querying the runtime"},
+						&cli.BoolFlag{Name: "orphans", Usage: "List the containers of services no longer in the compose file, which up --remove-orphans deletes"},
 					},
 					Action: composePsAction,
 				},
+				{
+					Name:  "ls",
+					Usage: "List saved projects",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "Include projects with no running services"},
+						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Only display project names"},
+						&cli.StringFlag{Name: "format", Usage: "Output format: table, json, or a Go template such as '{{.Name}} {{.Status}}'"},
+					},
+					Action: composeLsAction,
+				},
+				{
+					Name:      "rename",
+					Usage:     "Rename a project, keeping its networks, volumes and images",
+					ArgsUsage: "OLD NEW",
+					Action:    composeRenameAction,
+				},
+				{
+					Name:      "clone",
+					Usage:     "Start a copy of a project, with its own networks and volumes, under the name given with -p",
+					ArgsUsage: "[PROJECT]",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "copy-volumes", Usage: "Copy the data of the project's volumes into the copy's"},
+						&cli.StringFlag{Name: "helper-image", Usage: "Image used for the volume copy helper container", Value: defaultHelperImage},
+					},
+					Action: composeCloneAction,
+				},
+				{
+					Name:      "images",
+					Usage:     "List images used by the project's containers",
+					ArgsUsage: "[SERVICE...]",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Only display image IDs"},
+						&cli.StringFlag{Name: "format", Usage: "Output format: table, json, or a Go template such as '{{.Repository}}:{{.Tag}}'"},
+					},
+					Action: composeImagesAction,
+					Commands: []*cli.Command{
+						{
+							Name:  "prune",
+							Usage: "Remove images dctl built or pulled for the project that the compose file no longer uses",
+							Flags: []cli.Flag{
+								&cli.BoolFlag{Name: "force", Aliases: []string{"f"}, Usage: "Do not prompt for confirmation"},
+								&cli.BoolFlag{Name: "dry-run", Usage: "List what would be removed without removing it"},
+							},
+							Action: composeImagesPruneAction,
+						},
+					},
+				},
+				{
+					Name:      "port",
+					Usage:     "Print the public address bound to a service's private port",
+					ArgsUsage: "SERVICE PRIVATE_PORT",
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "protocol", Usage: "tcp or udp", Value: "tcp"},
+						&cli.StringFlag{Name: "format", Usage: "Output format: json, or a Go template such as '{{.HostPort}}'"},
+					},
+					Action: composePortAction,
+				},
 				{
 					Name:      "logs",
 					Usage:     "View output from containers",
-					ArgsUsage: "[SERVICE...]",
+					ArgsUsage: "[SERVICE[=REGEX]...]",
 					Flags: []cli.Flag{
 						&cli.BoolFlag{Name: "follow", Aliases: []string{"f"}, Usage: "Follow log output"},
-						&cli.StringFlag{Name: "tail", Aliases: []string{"n"}, Usage: "Number of lines from end", Value: "all"},
+						&cli.StringFlag{Name: "tail", Aliases: []string{"n"}, Usage: "Number of lines from end", Value: "all", Sources: cli.EnvVars("DCTL_LOGS_TAIL")},
 						&cli.BoolFlag{Name: "timestamps", Aliases: []string{"t"}, Usage: "Show timestamps"},
+						&cli.StringFlag{Name: "grep", Usage: "Only show lines matching this regular expression (SERVICE=REGEX sets one service's)"},
 					},
 					Action: composeLogsAction,
 				},
+				{
+					Name:      "events",
+					Usage:     "Print the events of the project's containers as they happen",
+					ArgsUsage: "[SERVICE...]",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "json", Usage: "Print each event as a JSON object"},
+						&cli.StringSliceFlag{Name: "filter", Usage: "Only print matching events (service=NAME, type=create|start|stop|destroy)"},
+						&cli.StringFlag{Name: "exec", Usage: "Run this shell command for each event, with the event as JSON on stdin"},
+					},
+					Action: composeEventsAction,
+				},
 				{
 					Name:      "exec",
 					Usage:     "Execute a command in a running service container",
 					ArgsUsage: "SERVICE COMMAND [ARG...]",
 					Flags: []cli.Flag{
 						&cli.BoolFlag{Name: "detach", Aliases: []string{"d"}, Usage: "Run in background"},
-						&cli.StringSliceFlag{Name: "env", Aliases: []string{"e"}, Usage: "Set environment variables"},
+						&cli.StringSliceFlag{Name: "env", Aliases: []string{"e"}, Usage: "Set environment variables (override the service's)"},
+						&cli.StringSliceFlag{Name: "env-file", Usage: "Read environment variables from a file (after the service's)"},
 						&cli.BoolFlag{Name: "no-TTY", Aliases: []string{"T"}, Usage: "Disable pseudo-TTY allocation"},
-						&cli.StringFlag{Name: "user", Aliases: []string{"u"}, Usage: "Run as this user"},
-						&cli.StringFlag{Name: "workdir", Aliases: []string{"w"}, Usage: "Working directory"},
+						&cli.BoolFlag{Name: "privileged", Usage: "Give extended privileges to the process (ignored; not supported by the runtime)"},
+						&cli.StringFlag{Name: "user", Aliases: []string{"u"}, Usage: "Run as this user (default: the service's user)"},
+						&cli.StringFlag{Name: "workdir", Aliases: []string{"w"}, Usage: "Working directory (default: the service's working_dir)"},
+						detachKeysFlag,
 					},
 					Action: composeExecAction,
 				},
@@ -94,6 +203,7 @@ func composeCommands() []*cli.Command {
 					Flags: []cli.Flag{
 						&cli.BoolFlag{Name: "detach", Aliases: []string{"d"}, Usage: "Run in background"},
 						&cli.BoolFlag{Name: "rm", Usage: "Remove container when it exits"},
+						&cli.BoolFlag{Name: "no-TTY", Aliases: []string{"T"}, Usage: "Disable pseudo-TTY allocation"},
 						&cli.StringSliceFlag{Name: "env", Aliases: []string{"e"}, Usage: "Set environment variables"},
 						&cli.StringSliceFlag{Name: "publish", Aliases: []string{"p"}, Usage: "Publish port(s)"},
 						&cli.StringFlag{Name: "user", Aliases: []string{"u"}, Usage: "Run as this user"},
@@ -101,7 +211,8 @@ func composeCommands() []*cli.Command {
 						&cli.StringFlag{Name: "workdir", Aliases: []string{"w"}, Usage: "Working directory"},
 						&cli.BoolFlag{Name: "no-deps", Usage: "Don't start linked services"},
 						&cli.StringFlag{Name: "name", Usage: "Assign a name to the container"},
-						&cli.StringFlag{Name: "entrypoint", Usage: "Override the entrypoint"},
+						&cli.StringFlag{Name: "entrypoint", Usage: "Override the entrypoint (\"\" to clear the image's)"},
+						detachKeysFlag,
 					},
 					Action: composeRunAction,
 				},
@@ -113,12 +224,18 @@ func composeCommands() []*cli.Command {
 						&cli.BoolFlag{Name: "pull", Usage: "Always pull a newer version of the image"},
 						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Don't print anything to STDOUT"},
 						&cli.StringSliceFlag{Name: "build-arg", Usage: "Set build-time variables"},
+						&cli.BoolFlag{Name: "check-context", Usage: "List the largest entries of each build context, after .dockerignore, instead of building"},
 					},
 					Action: composeBuildAction,
 				},
 				{
-					Name:   "pull",
-					Usage:  "Pull service images",
+					Name:      "pull",
+					Usage:     "Pull service images",
+					ArgsUsage: "[SERVICE...]",
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "policy", Usage: "Pull policy: always or missing (default: each service's pull_policy)"},
+						&cli.BoolFlag{Name: "refresh", Usage: "Only pull images whose registry digest differs from the local one"},
+					},
 					Action: composePullAction,
 				},
 				{
@@ -136,14 +253,48 @@ func composeCommands() []*cli.Command {
 					ArgsUsage: "[SERVICE...]",
 					Flags: []cli.Flag{
 						&cli.IntFlag{Name: "timeout", Aliases: []string{"t"}, Usage: "Shutdown timeout in seconds", Value: 10},
+						&cli.BoolFlag{Name: "with-dependents", Usage: "Also restart every service that depends on the given services"},
+						&cli.BoolFlag{Name: "no-deps", Usage: "Don't restart dependents, even those whose depends_on sets restart: true"},
+						&cli.IntFlag{Name: "port-wait", Usage: "Seconds to wait for a service's host ports to be released before starting it, retrying a start that fails on them meanwhile", Value: 5},
 					},
 					Action: composeRestartAction,
 				},
+				{
+					Name:      "explain",
+					Usage:     "Print the container command that up would run for services",
+					ArgsUsage: "[SERVICE...]",
+					Action:    composeExplainAction,
+				},
+				watchCommand(),
+				{
+					Name:      "diff",
+					Usage:     "Compare running containers with the compose file",
+					ArgsUsage: "[SERVICE...]",
+					Action:    composeDiffAction,
+				},
+				{
+					Name:      "env",
+					Usage:     "Print the resolved environment of a service's container",
+					ArgsUsage: "SERVICE",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "diff", Usage: "Compare it with the environment of the service's running container"},
+					},
+					Action: composeEnvAction,
+				},
 				{
 					Name:  "config",
 					Usage: "Parse, resolve and render compose file",
 					Flags: []cli.Flag{
 						&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Only validate, don't print"},
+						&cli.BoolFlag{Name: "services", Usage: "Print the service names, one per line"},
+						&cli.BoolFlag{Name: "volumes", Usage: "Print the volume names, one per line"},
+						&cli.BoolFlag{Name: "images", Usage: "Print the image names, one per line"},
+						&cli.BoolFlag{Name: "variables", Usage: "List the variables the files interpolate, with their defaults and values"},
+						&cli.BoolFlag{Name: "check-support", Usage: "List keys dctl or the detected runtime will not honor"},
+						&cli.BoolFlag{Name: "image-defaults", Usage: "Fill in the command and entrypoint services inherit from their local images"},
+						&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Write the output to a file instead of stdout"},
+						&cli.StringFlag{Name: "hash", Usage: "Print the config hash of the given services (comma-separated, or \"*\" for all)"},
+						&cli.StringFlag{Name: "format", Usage: "Output format (yaml|json)", Value: "yaml"},
 					},
 					Action: composeConfigAction,
 				},
@@ -155,18 +306,49 @@ func composeCommands() []*cli.Command {
 						&cli.BoolFlag{Name: "force", Aliases: []string{"f"}, Usage: "Don't ask to confirm removal"},
 						&cli.BoolFlag{Name: "stop", Aliases: []string{"s"}, Usage: "Stop containers before removing"},
 						&cli.BoolFlag{Name: "volumes", Aliases: []string{"v"}, Usage: "Remove anonymous volumes"},
+						&cli.BoolFlag{Name: "all-run", Usage: "Remove leftover one-off run containers instead of service containers"},
 					},
 					Action: composeRmAction,
 				},
+				{
+					Name:      "pause",
+					Usage:     "Pause service containers (Docker backend only)",
+					ArgsUsage: "[SERVICE...]",
+					Action:    composePauseAction,
+				},
+				{
+					Name:      "unpause",
+					Usage:     "Unpause service containers (Docker backend only)",
+					ArgsUsage: "[SERVICE...]",
+					Action:    composePauseAction,
+				},
 				{
 					Name:      "kill",
 					Usage:     "Force stop service containers",
 					ArgsUsage: "[SERVICE...]",
 					Flags: []cli.Flag{
 						&cli.StringFlag{Name: "signal", Aliases: []string{"s"}, Usage: "Signal to send", Value: "SIGKILL"},
+						&cli.BoolFlag{Name: "remove", Usage: "Remove the killed containers"},
 					},
 					Action: composeKillAction,
 				},
+				publishCommand(),
+				convertCommand(),
+				{
+					Name:      "generate",
+					Usage:     "Generate a compose file from existing containers",
+					ArgsUsage: "[CONTAINER...]",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "Include stopped containers when none are named"},
+						&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Write to file instead of stdout"},
+					},
+					Action: composeGenerateAction,
+				},
+				volumesCommand(),
+				snapshotCommand(),
+				autostartCommand(),
+				scheduleCommand(),
+				alphaCommand(),
 			},
 		},
 	}
@@ -178,145 +360,451 @@ func composeCommands() []*cli.Command {
 // from the global compose flags.
 type composeContext struct {
 	projectDir  string
+	files       []string // as given to compose.Load; nil for the default file
 	composeFile *compose.ComposeFile
 	projectName string
+	naming      compose.NamingScheme
 }
 
 // resolveComposeContext loads compose files and resolves the project name.
 func resolveComposeContext(cmd *cli.Command) (*composeContext, error) {
+	return loadComposeContext(cmd, cmd.String("project-name"))
+}
+
+// loadComposeContext loads compose files for a project given by name, or
+// when name is empty the project the compose file or directory names.
+func loadComposeContext(cmd *cli.Command, name string) (*composeContext, error) {
+	// -f paths are relative to the working directory; the project
+	// directory defaults to the first file's.
+	files, err := compose.AbsFiles(cmd.StringSlice("file"))
+	if err != nil {
+		return nil, err
+	}
 	projectDir := cmd.String("project-directory")
 	if projectDir == "" {
-		wd, err := os.Getwd()
+		if projectDir, err = compose.DefaultProjectDir(files); err != nil {
+			return nil, err
+		}
+	} else if projectDir, err = filepath.Abs(projectDir); err != nil {
+		return nil, fmt.Errorf("resolving project directory: %w", err)
+	}
+
+	if profiles := cmd.StringSlice("profile"); len(files) == 0 && len(profiles) > 0 {
+		// The active profiles' compose.PROFILE.yaml files are layered over
+		// the default file.
+		base, err := compose.ResolveFiles(nil, projectDir)
 		if err != nil {
-			return nil, fmt.Errorf("getting working directory: %w", err)
+			return nil, err
+		}
+		if extra := compose.ProfileFiles(base[0], profiles); len(extra) > 0 {
+			files = append(base, extra...)
 		}
-		projectDir = wd
 	}
 
-	files := cmd.StringSlice("file")
+	// The merged model is cached between commands; see compose.LoadCached.
+	var cf *compose.ComposeFile
+	if dir, dirErr := compose.StateDir(); dirErr == nil {
+		cf, err = compose.LoadCached(files, projectDir, filepath.Join(dir, "cache"))
+	} else {
+		cf, err = compose.Load(files, projectDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Legacy keys load silently; --strict asks for them to be cleaned up.
+	if obsolete := compose.ObsoleteKeys(cf); cmd.Bool("strict") && len(obsolete) > 0 {
+		var b strings.Builder
+		b.WriteString("obsolete keys (--strict):")
+		for _, o := range obsolete {
+			b.WriteString("\n  " + o.String())
+		}
+		return nil, errors.New(b.String())
+	}
 
-	cf, err := compose.Load(files, projectDir)
+	projectName, err := compose.ResolveProjectName(name, cf, projectDir)
 	if err != nil {
 		return nil, err
 	}
 
-	projectName := compose.ResolveProjectName(cmd.String("project-name"), cf, projectDir)
+	// --compat-naming opts in; otherwise keep the scheme the project was
+	// started with so existing containers are still found.
+	naming := compose.NamingDefault
+	state, stateErr := compose.LoadProject(projectName)
+	if cmd.Bool("compat-naming") {
+		naming = compose.NamingCompat
+	} else if stateErr == nil {
+		naming = state.Naming
+	}
+	// A copy made by compose clone keeps to its own networks and volumes.
+	if stateErr == nil && state.ClonePrefix != "" {
+		compose.Clone(cf, state.ClonePrefix)
+	}
 
 	return &composeContext{
 		projectDir:  projectDir,
+		files:       files,
 		composeFile: cf,
 		projectName: projectName,
+		naming:      naming,
 	}, nil
 }
 
-// containerName returns the container name for a service in a project.
-func containerName(project, service string) string {
-	return project + "_" + service
+// containerName returns the container name for a service in the project:
+// its container_name, or else the name the naming scheme gives it.
+func (cc *composeContext) containerName(service string) string {
+	if name := cc.composeFile.Services[service].ContainerName; name != "" {
+		return name
+	}
+	return cc.naming.ContainerName(cc.projectName, service)
 }
 
-// buildRunArgs constructs container run arguments from a compose.Service definition.
-func buildRunArgs(svc compose.Service, project, svcName string) []string {
-	name := containerName(project, svcName)
-	args := []string{"run", "--detach", "--name", name}
-
-	// ports
-	for _, p := range svc.Ports {
-		args = append(args, "--publish", p)
+// serviceImage returns the image reference for a service, falling back to
+// the project-scoped tag used for services that only define a build.
+func serviceImage(project, svcName string, svc compose.Service) string {
+	if svc.Image != "" {
+		return svc.Image
 	}
+	return project + "-" + svcName
+}
 
-	// volumes
-	for _, v := range svc.Volumes {
-		args = append(args, "--volume", v)
+// servicePlatform returns the service's platform, defaulting to
+// DOCKER_DEFAULT_PLATFORM.
+func servicePlatform(svc compose.Service) string {
+	if svc.Platform != "" {
+		return svc.Platform
 	}
+	return os.Getenv("DOCKER_DEFAULT_PLATFORM")
+}
 
-	// environment
-	if env, ok := svc.Environment.(map[string]string); ok {
-		for k, v := range env {
-			args = append(args, "--env", k+"="+v)
+// assignPublishedPorts replaces --publish values that leave the host port
+// open ("80", "0:80") with free host ports. It runs just before a container
+// is started; run arguments recorded in state keep the open form so config
+// hashes stay stable.
+func assignPublishedPorts(args []string) ([]string, error) {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--publish" || i+1 == len(args) {
+			out = append(out, args[i])
+			continue
+		}
+		i++
+		specs, err := portcheck.Assign([]string{args[i]})
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range specs {
+			out = append(out, "--publish", spec)
 		}
 	}
+	return out, nil
+}
 
-	// working_dir
-	if svc.WorkingDir != "" {
-		args = append(args, "--workdir", svc.WorkingDir)
+// startContainer runs a container from run arguments after assigning
+// free host ports, with its variables in a private env file.
+func startContainer(args []string) error {
+	args, err := assignPublishedPorts(args)
+	if err != nil {
+		return err
 	}
-
-	// user
-	if svc.User != "" {
-		args = append(args, "--user", svc.User)
+	if args, err = withEnvFile(args); err != nil {
+		return err
 	}
+	_, err = runner.Output(args...)
+	return err
+}
 
-	// tty
-	if svc.Tty {
-		args = append(args, "--tty")
+// createContainer creates a container from run arguments without starting
+// it, after assigning free host ports.
+func createContainer(args []string) error {
+	create := append([]string{"create"}, args[1:]...)
+	if len(create) > 1 && create[1] == "--detach" {
+		create = slices.Delete(create, 1, 2)
 	}
+	return startContainer(create)
+}
 
-	// stdin_open
-	if svc.StdinOpen {
-		args = append(args, "--interactive")
+// servicesToBuild returns the services up builds: with --build every service
+// with a build config, otherwise those whose image is missing. --no-build
+// turns a missing image into an error.
+func servicesToBuild(cc *composeContext, build, noBuild bool) ([]string, error) {
+	var images []runner.ImageInfo
+	listed := false
+	var services []string
+	for _, svcName := range sortedKeys(cc.composeFile.Services) {
+		svc := cc.composeFile.Services[svcName]
+		if bc, ok := svc.Build.(*compose.BuildConfig); !ok || bc == nil {
+			continue
+		}
+		if build {
+			services = append(services, svcName)
+			continue
+		}
+		if !listed {
+			var err error
+			if images, err = runner.ListImages(); err != nil {
+				return nil, err
+			}
+			listed = true
+		}
+		image := serviceImage(cc.projectName, svcName, svc)
+		if slices.ContainsFunc(images, func(i runner.ImageInfo) bool { return i.HasReference(image) }) {
+			continue
+		}
+		if noBuild {
+			return nil, fmt.Errorf("service %s: image %s is missing and --no-build forbids building it; run compose build first", svcName, image)
+		}
+		services = append(services, svcName)
 	}
+	return services, nil
+}
 
-	// read_only
-	if svc.ReadOnly {
-		args = append(args, "--read-only")
-	}
+// serviceNetworks returns the networks a service attaches to, sorted.
+func serviceNetworks(svc compose.Service) []string {
+	nets, _ := svc.Networks.(map[string]interface{})
+	return sortedKeys(nets)
+}
 
-	// cpus
-	if svc.CPUs != nil {
-		args = append(args, "--cpus", fmt.Sprintf("%v", svc.CPUs))
-	}
+// dockerBackend reports whether commands go to the Docker CLI rather than
+// the container runtime.
+func dockerBackend() bool {
+	return filepath.Base(runner.ContainerBin) == "docker"
+}
 
-	// mem_limit
-	if svc.MemLimit != "" {
-		args = append(args, "--memory", svc.MemLimit)
+// runtimeVersion returns the container runtime version, detected once per
+// invocation and cached in the state directory. It is unknown (and nothing
+// is gated) for the Docker backend, a runner backend installed by an
+// embedder, or when detection fails.
+var runtimeVersion = sync.OnceValue(func() features.Version {
+	if dockerBackend() || !runner.UsesBinary() {
+		return features.Version{}
+	}
+	dir, err := compose.StateDir()
+	if err != nil {
+		return features.Version{}
+	}
+	v, err := features.Detect(runner.ContainerBin, dir)
+	if err != nil {
+		return features.Version{}
 	}
+	return v
+})
 
-	// dns
-	if dns, ok := svc.DNS.([]string); ok {
-		for _, d := range dns {
-			args = append(args, "--dns", d)
+// checkServiceSupport fails when a service uses a feature the detected
+// runtime version does not have, and warns about settings the backend
+// drops.
+func checkServiceSupport(cf *compose.ComposeFile, services []string) error {
+	v := runtimeVersion()
+	for _, svcName := range services {
+		svc := cf.Services[svcName]
+		if keys := features.DockerOnly(svc); len(keys) > 0 && !dockerBackend() {
+			report.Warnf("service %s: %s not supported by the container runtime, ignoring", svcName, strings.Join(keys, ", "))
+		}
+		if err := features.NetworkMode(svc.NetworkMode); err != nil && !dockerBackend() {
+			return fmt.Errorf("service %s: %w", svcName, err)
+		}
+		if err := features.Devices(compose.DeviceRequests(svc)); err != nil {
+			return fmt.Errorf("service %s: %w", svcName, err)
+		}
+		if len(serviceNetworks(svc)) > 1 {
+			if err := features.Require(v, features.MultiNetwork); err != nil {
+				return fmt.Errorf("service %s: %w", svcName, err)
+			}
+		}
+		gates := features.ServiceGates(svc)
+		for _, key := range sortedKeys(gates) {
+			if err := features.Require(v, gates[key]); err != nil {
+				return fmt.Errorf("service %s: %s: %w", svcName, key, err)
+			}
 		}
 	}
+	return nil
+}
 
-	// labels
-	for k, v := range svc.Labels {
-		args = append(args, "--label", k+"="+v)
+// checkNetworkSupport fails when a network up would create uses a feature
+// the detected runtime version does not have.
+func checkNetworkSupport(cf *compose.ComposeFile) error {
+	v := runtimeVersion()
+	for _, name := range sortedKeys(cf.Networks) {
+		n := cf.Networks[name]
+		if n.External || n.IPAM == nil {
+			continue
+		}
+		for _, c := range n.IPAM.Config {
+			prefix, err := netip.ParsePrefix(c.Subnet)
+			if err != nil {
+				continue
+			}
+			f := features.Subnet
+			if prefix.Addr().Is6() {
+				f = features.SubnetV6
+			}
+			if err := features.Require(v, f); err != nil {
+				return fmt.Errorf("network %s: %w", name, err)
+			}
+		}
 	}
+	return nil
+}
 
-	// tmpfs
-	if tmpfs, ok := svc.Tmpfs.([]string); ok {
-		for _, t := range tmpfs {
-			args = append(args, "--tmpfs", t)
+// networkCreateArgs returns the container CLI arguments creating a compose
+// network. IPAM subnets are passed with --subnet, or --subnet-v6 for IPv6
+// ones; subnets are validated when the file is loaded.
+func networkCreateArgs(name string, n compose.Network) []string {
+	args := []string{"network", "create"}
+	hasV6 := false
+	if n.IPAM != nil {
+		for _, c := range n.IPAM.Config {
+			prefix, err := netip.ParsePrefix(c.Subnet)
+			if err != nil {
+				continue
+			}
+			if prefix.Addr().Is6() {
+				hasV6 = true
+				args = append(args, "--subnet-v6", c.Subnet)
+			} else {
+				args = append(args, "--subnet", c.Subnet)
+			}
 		}
 	}
+	if n.EnableIPv6 && !hasV6 {
+		report.Warnf("network %s has enable_ipv6 but no IPv6 subnet in ipam.config; creating it without one", name)
+	}
+	return append(args, name)
+}
 
-	// entrypoint
-	if ep, ok := svc.Entrypoint.([]string); ok && len(ep) > 0 {
-		args = append(args, "--entrypoint", ep[0])
+// volumeCreateArgs returns the container CLI arguments creating a compose
+// volume. Labels and driver_opts are passed as --label and --opt; the
+// runtime only has its local driver, so other drivers fall back to it.
+func volumeCreateArgs(name string, v compose.VolumeConfig) []string {
+	args := []string{"volume", "create"}
+	for _, k := range sortedKeys(v.Labels) {
+		args = append(args, "--label", k+"="+v.Labels[k])
+	}
+	for _, k := range sortedKeys(v.DriverOpts) {
+		args = append(args, "--opt", k+"="+v.DriverOpts[k])
 	}
+	if v.Driver != "" && v.Driver != "local" {
+		report.Warnf("volume %s uses driver %s, which the runtime does not have; creating it with the local driver", name, v.Driver)
+	}
+	return append(args, name)
+}
 
-	// platform
-	if svc.Platform != "" {
-		args = append(args, "--platform", svc.Platform)
+// buildRunArgs returns the container run arguments for a service, with
+// DOCKER_DEFAULT_PLATFORM applied as its default platform.
+func buildRunArgs(svc compose.Service, opts translate.Options) ([]string, error) {
+	opts.DefaultPlatform = os.Getenv("DOCKER_DEFAULT_PLATFORM")
+	spec, err := translate.FromService(svc, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Settings only Docker has are dropped; checkServiceSupport warns. The
+	// runtime runs no healthchecks, so theirs are dctl's to run.
+	if !dockerBackend() {
+		spec.Privileged, spec.GroupAdd, spec.Userns, spec.AddHosts = false, nil, "", nil
+		spec.Pid, spec.Ipc, spec.Uts = "", "", ""
+		spec.Health = nil
 	}
+	// A runtime that takes one --network attaches the first network;
+	// up and run refuse more before getting here.
+	if len(spec.Networks) > 1 && features.Require(runtimeVersion(), features.MultiNetwork) != nil {
+		spec.Networks = spec.Networks[:1]
+	}
+	return spec.Args(), nil
+}
 
-	// network (first network key)
-	if nets, ok := svc.Networks.(map[string]interface{}); ok {
-		for netName := range nets {
-			args = append(args, "--network", netName)
-			break
+// withEnvFile moves the --env values of run arguments, as RunSpec.Args
+// renders them, into a private env file for the container, so their
+// values stay out of process listings. It is applied only when a container
+// is launched: the arguments explain prints and up hashes and records keep
+// the values. Multi-line values, which env files cannot hold, stay inline.
+func withEnvFile(args []string) ([]string, error) {
+	var name string
+	var lines []string
+	out := []string{args[0]}
+	i := 1
+flags:
+	for ; i < len(args); i++ {
+		switch args[i] {
+		case "--detach", "--rm":
+			out = append(out, args[i])
+		case "--name", "--publish", "--volume", "--env":
+			if i+1 == len(args) {
+				break flags
+			}
+			i++
+			switch {
+			case args[i-1] == "--name":
+				name = args[i]
+			case args[i-1] == "--env" && !strings.ContainsAny(args[i], "\r\n"):
+				lines = append(lines, args[i])
+				continue
+			}
+			out = append(out, args[i-1], args[i])
+		default:
+			break flags
 		}
 	}
+	if len(lines) == 0 || name == "" {
+		return args, nil
+	}
+	path, err := compose.WriteRunEnv(name, lines)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, "--env-file", path)
+	return append(out, args[i:]...), nil
+}
+
+// runService returns a service as it is run, by up and compose run alike:
+// services that only define a build run the project-scoped image it tags.
+func (cc *composeContext) runService(svcName string) (compose.Service, error) {
+	svc, ok := cc.composeFile.Services[svcName]
+	if !ok {
+		return compose.Service{}, compose.UnknownServiceError(svcName, sortedKeys(cc.composeFile.Services))
+	}
+	if svc.Image == "" {
+		if bc, ok := svc.Build.(*compose.BuildConfig); !ok || bc == nil {
+			return compose.Service{}, fmt.Errorf("service %s has no image and no build config", svcName)
+		}
+		svc.Image = serviceImage(cc.projectName, svcName, svc)
+	}
+	// A namespace shared with a service is its container's.
+	for _, ns := range []*string{&svc.NetworkMode, &svc.Pid, &svc.Ipc} {
+		if name, ok := strings.CutPrefix(*ns, "service:"); ok {
+			*ns = "container:" + cc.containerName(name)
+		}
+	}
+	return svc, nil
+}
 
-	// image (required positional arg)
-	args = append(args, svc.Image)
+// runArgs returns the container run arguments `up` uses for a service.
+func (cc *composeContext) runArgs(svcName string) ([]string, error) {
+	svc, err := cc.runService(svcName)
+	if err != nil {
+		return nil, err
+	}
+	return buildRunArgs(svc, translate.Options{Name: cc.containerName(svcName), Detach: true})
+}
 
-	// command
-	if cmdSlice, ok := svc.Command.([]string); ok {
-		args = append(args, cmdSlice...)
+// writeSecrets materializes a service's secrets where its run arguments
+// expect them.
+func writeSecrets(cc *composeContext, svcName string) error {
+	secs, ok := cc.composeFile.Services[svcName].Secrets.([]compose.ServiceSecret)
+	if !ok {
+		return nil
 	}
+	return secrets.Write(cc.containerName(svcName), secs, cc.composeFile.Secrets)
+}
 
-	return args
+// removeContainerFiles deletes the env files and secrets written for a
+// removed container.
+func removeContainerFiles(cName string) {
+	if err := secrets.Remove(cName); err != nil {
+		report.Warnf("%v", err)
+	}
+	if err := compose.RemoveRunEnv(cName); err != nil {
+		report.Warnf("%v", err)
+	}
 }
 
 // filterServices returns the list of services to operate on.
@@ -332,167 +820,579 @@ func filterServices(state *compose.ProjectState, args []string) []string {
 	return services
 }
 
-// --- Compose actions ---
+// attachArgs returns the stdio flags for an exec or run and whether it
+// gets a TTY. Attached processes always receive stdin, so input can be
+// piped in; a TTY is allocated only when stdout is a terminal and -T was
+// not given, keeping piped output free of terminal control sequences.
+func attachArgs(detach, noTTY bool) ([]string, bool) {
+	if detach {
+		return nil, false
+	}
+	if noTTY || !runner.IsTerminal(os.Stdout) {
+		return []string{"--interactive"}, false
+	}
+	return []string{"--interactive", "--tty"}, true
+}
 
-func composeUpAction(ctx context.Context, cmd *cli.Command) error {
-	cc, err := resolveComposeContext(cmd)
-	if err != nil {
-		return err
+// runAttached runs an exec or run, handing the terminal over to it when it
+// has a TTY.
+func runAttached(args []string, tty bool) error {
+	if tty {
+		return runner.RunTerminal(args...)
 	}
+	return runner.Run(args...)
+}
 
-	cf := cc.composeFile
-	project := cc.projectName
+var detachKeysFlag = &cli.StringFlag{
+	Name:    "detach-keys",
+	Usage:   "Key sequence for detaching from an attached session, e.g. ctrl-p,ctrl-q",
+	Sources: cli.EnvVars("DCTL_DETACH_KEYS"),
+}
 
-	// Create networks
-	var createdNetworks []string
-	for name, net := range cf.Networks {
-		if net.External {
-			continue
-		}
-		netName := name
-		if net.Name != "" {
-			netName = net.Name
-		}
-		fmt.Fprintf(os.Stderr, "Creating network %s\n", netName)
-		createArgs := []string{"network", "create", netName}
-		if err := runner.Run(createArgs...); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to create network %s: %v\n", netName, err)
-		} else {
-			createdNetworks = append(createdNetworks, netName)
-		}
+// detachKeys parses the --detach-keys sequence of an attached exec or run.
+// Docker reads the sequence itself, so for its backend no keys are returned
+// and the flag arguments that pass it on are instead.
+func detachKeys(cmd *cli.Command) ([]string, []byte, error) {
+	value := cmd.String("detach-keys")
+	if value == "" || cmd.Bool("detach") {
+		return nil, nil, nil
 	}
-
-	// Create volumes
-	var createdVolumes []string
-	for name, vol := range cf.Volumes {
-		if vol.External {
-			continue
-		}
-		volName := name
-		if vol.Name != "" {
-			volName = vol.Name
-		}
-		fmt.Fprintf(os.Stderr, "Creating volume %s\n", volName)
-		createArgs := []string{"volume", "create", volName}
-		if err := runner.Run(createArgs...); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to create volume %s: %v\n", volName, err)
-		} else {
-			createdVolumes = append(createdVolumes, volName)
-		}
+	if dockerBackend() {
+		return []string{"--detach-keys", value}, nil, nil
 	}
-
-	// Build images if --build flag is set
-	if cmd.Bool("build") {
-		for svcName, svc := range cf.Services {
-			bc, ok := svc.Build.(*compose.BuildConfig)
-			if !ok || bc == nil {
-				continue
-			}
-			fmt.Fprintf(os.Stderr, "Building %s\n", svcName)
-			buildArgs := composeBuildCLIArgs(bc, svc.Image, cc.projectDir)
-			if err := runner.Run(buildArgs...); err != nil {
-				return fmt.Errorf("building service %s: %w", svcName, err)
-			}
-		}
+	keys, err := runner.ParseDetachKeys(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("--detach-keys: %w", err)
 	}
+	return nil, keys, nil
+}
 
-	// Resolve startup order
-	order, err := compose.ResolveOrder(cf.Services)
+// runDetachable runs an attached exec or run of container name that the
+// user can detach from with keys, and reports whether they did. A detached
+// session keeps running with its output going to a log in the state
+// directory.
+func runDetachable(args []string, tty bool, keys []byte, name string) (bool, error) {
+	dir, err := compose.StateDir()
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	// Start containers in order
-	containers := make(map[string]string)
-	var startedServices []string
-	for _, svcName := range order {
-		svc := cf.Services[svcName]
-		if svc.Image == "" {
-			if bc, ok := svc.Build.(*compose.BuildConfig); ok && bc != nil {
-				svc.Image = project + "-" + svcName
-			} else {
-				return fmt.Errorf("service %s has no image and no build config", svcName)
-			}
-		}
-
-		cName := containerName(project, svcName)
-		fmt.Fprintf(os.Stderr, "Starting %s\n", cName)
-
-		runArgs := buildRunArgs(svc, project, svcName)
-		if err := runner.Run(runArgs...); err != nil {
-			// Rollback: stop already-started services
-			fmt.Fprintf(os.Stderr, "Failed to start %s, stopping started services\n", cName)
-			for i := len(startedServices) - 1; i >= 0; i-- {
-				stopName := containerName(project, startedServices[i])
-				_ = runner.Run("stop", stopName)
-			}
-			return fmt.Errorf("starting service %s: %w", svcName, err)
-		}
-		startedServices = append(startedServices, svcName)
-		containers[svcName] = cName
+	logPath := filepath.Join(dir, "sessions", fmt.Sprintf("%s-%d.log", name, time.Now().UnixNano()))
+	detached, err := runner.AttachedDetachable(tty, keys, logPath, args...)
+	if detached {
+		report.Infof("\nDetached from %s; its output goes to %s", name, logPath)
 	}
+	return detached, err
+}
 
-	// Determine compose file path for state
-	composeFilePath := ""
-	files := cmd.StringSlice("file")
-	if len(files) > 0 {
-		composeFilePath = files[0]
+// checkServiceNames fails on the first name that is neither defined in the
+// compose file nor recorded in state, suggesting the closest known names.
+func checkServiceNames(cc *composeContext, state *compose.ProjectState, names []string) error {
+	known := make(map[string]bool)
+	for name := range cc.composeFile.Services {
+		known[name] = true
 	}
-
-	// Save project state
-	state := &compose.ProjectState{
-		Name:        project,
-		ComposeFile: composeFilePath,
-		ProjectDir:  cc.projectDir,
-		Containers:  containers,
-		Networks:    createdNetworks,
-		Volumes:     createdVolumes,
+	for name := range state.Containers {
+		known[name] = true
 	}
-	if err := compose.SaveProject(state); err != nil {
-		return fmt.Errorf("saving project state: %w", err)
+	for _, name := range names {
+		if !known[name] {
+			return compose.UnknownServiceError(name, sortedKeys(known))
+		}
 	}
-
 	return nil
 }
 
-func composeDownAction(ctx context.Context, cmd *cli.Command) error {
+// --- Compose actions ---
+
+func composeUpAction(ctx context.Context, cmd *cli.Command) error {
 	cc, err := resolveComposeContext(cmd)
 	if err != nil {
 		return err
 	}
 
-	state, err := compose.LoadProject(cc.projectName)
+	cf := cc.composeFile
+	project := cc.projectName
+	dryRun := cmd.Bool("dry-run")
+	noStart := cmd.Bool("no-start")
+	if cmd.Bool("build") && cmd.Bool("no-build") {
+		return fmt.Errorf("--build and --no-build cannot be used together")
+	}
+	if noStart && cmd.Bool("wait") {
+		return fmt.Errorf("--no-start and --wait cannot be used together")
+	}
+	prev, _ := compose.LoadProject(project)
+	begin := time.Now()
+
+	// Resolve startup order
+	order, err := compose.ResolveOrder(cf.Services)
 	if err != nil {
 		return err
 	}
 
-	// Stop and remove all containers
-	for svcName, cName := range state.Containers {
-		fmt.Fprintf(os.Stderr, "Stopping %s\n", cName)
-		if err := runner.Run("stop", cName); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to stop %s: %v\n", svcName, err)
-		}
-		fmt.Fprintf(os.Stderr, "Removing %s\n", cName)
-		if err := runner.Run("delete", cName); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", svcName, err)
-		}
+	// Without --detach, up stays in the foreground following the output
+	// of the attached services. --wait returns once they are ready, as
+	// docker compose's does.
+	foreground := !cmd.Bool("detach") && !cmd.Bool("wait") && !noStart && !dryRun
+	attached, err := attachedServices(cc, cmd, order)
+	if err != nil {
+		return err
 	}
 
-	// Remove volumes if --volumes flag
-	if cmd.Bool("volumes") {
-		for _, vol := range state.Volumes {
-			fmt.Fprintf(os.Stderr, "Removing volume %s\n", vol)
-			if err := runner.Run("volume", "delete", vol); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to remove volume %s: %v\n", vol, err)
-			}
-		}
+	// Services to recreate regardless of their configuration hash
+	force := make(map[string]bool)
+	for svcName, svc := range cf.Services {
+		bc, ok := svc.Build.(*compose.BuildConfig)
+		force[svcName] = cmd.Bool("force-recreate") || (cmd.Bool("build") && ok && bc != nil)
 	}
 
-	// Remove networks
-	for _, net := range state.Networks {
-		fmt.Fprintf(os.Stderr, "Removing network %s\n", net)
-		if err := runner.Run("network", "delete", net); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove network %s: %v\n", net, err)
+	removeOrphanContainers := cmd.Bool("remove-orphans")
+	plan, err := planUp(cc, order, prev, force, removeOrphanContainers)
+	if err != nil {
+		return err
+	}
+
+	// Containers from a previous up whose services are gone
+	orphans := plan.orphans
+	if len(orphans) > 0 && !removeOrphanContainers && !ignoreOrphans() {
+		report.Warnf("found orphan containers (%s) for this project. If you removed or renamed this service in your compose file, you can run this command with the --remove-orphans flag to clean it up.", strings.Join(sortedValues(orphans), ", "))
+	}
+	var removed map[string]string
+	if removeOrphanContainers {
+		removed = orphans
+	}
+	if dryRun {
+		printPlan(project, plan.steps)
+		return nil
+	}
+	if err := checkServiceSupport(cf, order); err != nil {
+		return err
+	}
+	if err := checkNetworkSupport(cf); err != nil {
+		return err
+	}
+	if err := checkPorts(cc, plan, prev); err != nil {
+		return err
+	}
+	if err := checkContainerNames(cc, plan.containers, prev); err != nil {
+		return err
+	}
+	builds, err := servicesToBuild(cc, cmd.Bool("build"), cmd.Bool("no-build"))
+	if err != nil {
+		return err
+	}
+	if err := checkImagePlatforms(cc, plan, builds); err != nil {
+		return err
+	}
+
+	// Networks and volumes: create missing ones and keep ownership of
+	// those an earlier up created.
+	var createdNetworks, createdVolumes []string
+	var owned []string
+	if prev != nil {
+		owned = slices.Concat(prev.Networks, prev.Volumes)
+	}
+	networkConfigs := make(map[string]compose.Network)
+	for name, n := range cf.Networks {
+		if n.Name != "" {
+			name = n.Name
+		}
+		networkConfigs[name] = n
+	}
+	volumeConfigs := make(map[string]compose.VolumeConfig)
+	for name, v := range cf.Volumes {
+		if v.Name != "" {
+			name = v.Name
+		}
+		volumeConfigs[name] = v
+	}
+	for _, step := range plan.steps {
+		if step.Kind != "network" && step.Kind != "volume" {
+			continue
+		}
+		created := slices.Contains(owned, step.Name)
+		if step.Action == compose.ActionCreate {
+			report.Infof("Creating %s %s", step.Kind, step.Name)
+			createArgs := volumeCreateArgs(step.Name, volumeConfigs[step.Name])
+			if step.Kind == "network" {
+				createArgs = networkCreateArgs(step.Name, networkConfigs[step.Name])
+			}
+			if err := runner.Run(createArgs...); err != nil {
+				report.Warnf("failed to create %s %s: %v", step.Kind, step.Name, err)
+			} else {
+				created = true
+			}
+		}
+		if !created {
+			continue
+		}
+		if step.Kind == "network" {
+			createdNetworks = append(createdNetworks, step.Name)
+		} else {
+			createdVolumes = append(createdVolumes, step.Name)
+		}
+	}
+
+	var jobs []buildJob
+	for _, svcName := range builds {
+		svc := cf.Services[svcName]
+		bc := svc.Build.(*compose.BuildConfig)
+		warnLargeContext(svcName, bc)
+		jobs = append(jobs, buildJob{service: svcName, args: composeBuildCLIArgs(bc, serviceImage(project, svcName, svc), servicePlatform(svc))})
+	}
+	// With --rollback-on-failure, the images of containers about to be
+	// recreated are kept before builds move their tags.
+	rollbackOnFailure := cmd.Bool("rollback-on-failure")
+	rb := &upRollback{cmd: cmd, cc: cc, prev: prev}
+	if rollbackOnFailure {
+		var recreated []string
+		for _, svcName := range order {
+			if plan.services[svcName].Action == compose.ActionRecreate {
+				recreated = append(recreated, svcName)
+			}
+		}
+		rb.pin(recreated)
+	}
+	if err := runBuilds(cmd, project, jobs); err != nil {
+		rb.unpin()
+		return err
+	}
+
+	if len(removed) > 0 {
+		removeOrphans(cmd, cc, removed)
+		orphans = nil
+	}
+
+	// Only images this project built, or that starting it pulls, are its
+	// to prune; images already there may be another project's.
+	fetched := missingImages(cc, order)
+	for _, svcName := range builds {
+		if image := serviceImage(project, svcName, cf.Services[svcName]); !slices.Contains(fetched, image) {
+			fetched = append(fetched, image)
+		}
+	}
+
+	// Start containers in order
+	containers := make(map[string]string)
+	for svcName, cName := range orphans {
+		containers[svcName] = cName
+	}
+
+	// Determine compose file path for state
+	composeFilePath := ""
+	files := cmd.StringSlice("file")
+	if len(files) > 0 {
+		composeFilePath = files[0]
+	}
+	state := &compose.ProjectState{
+		Name:         project,
+		ComposeFile:  composeFilePath,
+		ProjectDir:   cc.projectDir,
+		Containers:   containers,
+		Networks:     createdNetworks,
+		Volumes:      createdVolumes,
+		Naming:       cc.naming,
+		ConfigHashes: plan.hashes,
+		RunArgs:      plan.runArgs,
+		Profiles:     cmd.StringSlice("profile"),
+	}
+	// Files read from stdin or a URL cannot be loaded again; commands that
+	// rerun the project then fall back to ComposeFile.
+	if files, err := compose.ResolveFiles(cc.files, cc.projectDir); err == nil {
+		state.ComposeFiles = files
+	}
+	if envFile := cmd.String("env-file"); envFile != "" {
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(cc.projectDir, envFile)
+		}
+		state.EnvFile = envFile
+	}
+	if prev != nil {
+		state.ClonePrefix = prev.ClonePrefix
+		for svcName := range orphans {
+			state.SetStatus(svcName, prev.StatusOf(svcName))
+		}
+	}
+
+	// fail undoes this up: with --rollback-on-failure the previous
+	// containers are restored, otherwise started services are stopped.
+	fail := func(err error) error {
+		if !rollbackOnFailure {
+			report.Infof("Stopping started services")
+			rb.stop(state)
+			if saveErr := compose.SaveProject(state); saveErr != nil {
+				report.Warnf("saving project state: %v", saveErr)
+			}
+			return err
+		}
+		report.Infof("Rolling back to the previous containers")
+		state.Containers = rb.restore()
+		if prev != nil {
+			state.ConfigHashes = prev.ConfigHashes
+			state.RunArgs = prev.RunArgs
+			state.Status = maps.Clone(prev.Status)
+		} else {
+			state.ConfigHashes = nil
+			state.RunArgs = nil
+			state.Status = nil
+		}
+		maps.DeleteFunc(state.Status, func(svcName string, _ compose.ServiceStatus) bool {
+			_, ok := state.Containers[svcName]
+			return !ok
+		})
+		if saveErr := compose.SaveProject(state); saveErr != nil {
+			report.Warnf("saving project state: %v", saveErr)
+		}
+		return err
+	}
+
+	done := make(map[string]UpSummary)
+	for _, svcName := range order {
+		svc := cf.Services[svcName]
+		cName := cc.containerName(svcName)
+		step := plan.services[svcName]
+		stepBegin := time.Now()
+
+		// Refresh secret files, including for running containers
+		if err := writeSecrets(cc, svcName); err != nil {
+			return fail(err)
+		}
+
+		if step.Action == compose.ActionUpToDate {
+			report.Infof("Container %s is up-to-date", cName)
+			containers[svcName] = cName
+			state.SetStatus(svcName, compose.StatusRunning)
+			done[svcName] = UpSummary{Service: svcName, Action: "up-to-date"}
+			continue
+		}
+
+		// Gate on service_healthy dependencies that define a readiness probe
+		// and on the other projects the service depends on
+		if !noStart {
+			if err := waitForDependencies(ctx, cc, svc); err != nil {
+				report.Infof("Dependencies of %s not ready", cName)
+				return fail(err)
+			}
+			if err := waitForProjects(ctx, svcName, svc, time.Duration(cmd.Int("project-timeout"))*time.Second); err != nil {
+				return fail(err)
+			}
+		}
+
+		// With --no-start, containers are created from the same arguments
+		// and a later up starts them.
+		launch, verb := startContainer, "Starting"
+		if noStart {
+			launch, verb = createContainer, "Creating"
+		}
+		switch step.Action {
+		case compose.ActionStart:
+			if noStart {
+				report.Infof("Container %s is created", cName)
+				containers[svcName] = cName
+				state.SetStatus(svcName, step.Reason)
+				done[svcName] = UpSummary{Service: svcName, Action: "up-to-date"}
+				continue
+			}
+			report.Infof("Starting %s", cName)
+			_, err = runner.Output("start", cName)
+		case compose.ActionRecreate:
+			report.Infof("Recreating %s", cName)
+			if err := stopService(cmd, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+			}
+			if _, err := runner.Output("delete", cName); err != nil {
+				report.Warnf("failed to remove %s: %v", cName, err)
+			}
+			err = launch(plan.runArgs[svcName])
+		default:
+			report.Infof("%s %s", verb, cName)
+			err = launch(plan.runArgs[svcName])
+		}
+		// A recreated service has lost its old container even if the new
+		// one failed, so it is recorded before checking the error.
+		rb.record(step)
+		if err != nil {
+			report.Infof("Failed to start %s", cName)
+			return fail(fmt.Errorf("starting service %s: %w", svcName, err))
+		}
+		containers[svcName] = cName
+		if noStart {
+			state.SetStatus(svcName, compose.StatusCreated)
+		} else {
+			state.SetStatus(svcName, compose.StatusRunning)
+		}
+		done[svcName] = UpSummary{Service: svcName, Action: upActions[step.Action], Time: formatDuration(time.Since(stepBegin))}
+	}
+
+	// Save project state
+	if err := compose.SaveProject(state); err != nil {
+		return fmt.Errorf("saving project state: %w", err)
+	}
+	recordImages(project, fetched...)
+
+	// Wait for readiness probes if --wait flag is set. With
+	// --rollback-on-failure, services this up touched must become ready.
+	if noStart {
+		rb.unpin()
+		printUpSummary(ctx, cc, order, done, false, time.Since(begin))
+		return nil
+	}
+	for _, svcName := range order {
+		touched := plan.services[svcName].Action != compose.ActionUpToDate
+		if !cmd.Bool("wait") && !(rollbackOnFailure && touched) {
+			continue
+		}
+		// Without --rollback-on-failure, services that are slow to become
+		// ready are left running, as they were started.
+		if err := waitForService(ctx, cc, svcName); err != nil {
+			if rollbackOnFailure && touched {
+				return fail(err)
+			}
+			return err
+		}
+	}
+
+	rb.unpin()
+	printUpSummary(ctx, cc, order, done, cmd.Bool("wait"), time.Since(begin))
+	if !foreground {
+		return nil
+	}
+	fresh := make(map[string]bool)
+	for svcName, step := range plan.services {
+		fresh[svcName] = step.Action == compose.ActionCreate || step.Action == compose.ActionRecreate
+	}
+	return attachUp(cmd, cc, state, order, attached, fresh)
+}
+
+// upActions names what up did to a service's container in its report.
+var upActions = map[compose.Action]string{
+	compose.ActionCreate:   "created",
+	compose.ActionRecreate: "recreated",
+	compose.ActionStart:    "started",
+}
+
+// printUpSummary reports what up did to each service in order, with the
+// ports its container ended up with and, after --wait, its health,
+// followed by totals. Health is not probed otherwise, since services that
+// just started are rarely ready yet.
+func printUpSummary(ctx context.Context, cc *composeContext, order []string, done map[string]UpSummary, checkHealth bool, took time.Duration) {
+	if report.Quiet() {
+		return
+	}
+	names := make([]string, len(order))
+	for i, svcName := range order {
+		names[i] = cc.containerName(svcName)
+	}
+	infos, _ := runner.Inspect(names...)
+	byName := make(map[string]runner.ContainerInfo, len(infos))
+	for _, info := range infos {
+		byName[info.Configuration.ID] = info
+	}
+
+	rows := make([]UpSummary, len(order))
+	var wg sync.WaitGroup
+	for i, svcName := range order {
+		rows[i] = done[svcName]
+		rows[i].Ports = publishedPorts(byName[names[i]])
+		if !checkHealth || byName[names[i]].Status != "running" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if health := serviceHealth(ctx, cc, svcName, names[i]); health != healthNone {
+				rows[i].Health = health
+			}
+		}()
+	}
+	wg.Wait()
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.Action]++
+	}
+	var totals []string
+	for _, action := range []string{"created", "recreated", "started", "up-to-date"} {
+		if counts[action] > 0 {
+			totals = append(totals, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	noun := "services"
+	if len(rows) == 1 {
+		noun = "service"
+	}
+	var b strings.Builder
+	_ = format.Write(&b, "table", upTable, rows)
+	report.Infof("%s%d %s in %s: %s", b.String(), len(rows), noun, formatDuration(took), strings.Join(totals, ", "))
+}
+
+// formatDuration rounds a duration for reports, e.g. 1.2s.
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func composeDownAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	images, err := downImages(cc, cmd.String("rmi"))
+	if err != nil {
+		return err
+	}
+
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return err
+	}
+	discoverUnrecorded(state, cc.composeFile)
+
+	// Stop and remove all containers, dependents first and up to
+	// --parallel at a time
+	levels, err := shutdownLevels(cc.composeFile.Services, sortedKeys(state.Containers))
+	if err != nil {
+		return err
+	}
+	for _, level := range levels {
+		_ = forEachParallel(parallelLimit(cmd), level, func(svcName string) error {
+			cName := state.Containers[svcName]
+			report.Infof("Stopping %s", cName)
+			if err := stopService(cmd, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+			}
+			report.Infof("Removing %s", cName)
+			if err := runner.Run("delete", cName); err != nil {
+				report.Warnf("failed to remove %s: %v", svcName, err)
+			}
+			removeContainerFiles(cName)
+			return nil
+		})
+	}
+
+	// Remove volumes if --volumes flag
+	if cmd.Bool("volumes") {
+		for _, vol := range state.Volumes {
+			report.Infof("Removing volume %s", vol)
+			if err := runner.Run("volume", "delete", vol); err != nil {
+				report.Warnf("failed to remove volume %s: %v", vol, err)
+			}
+		}
+	}
+
+	// Remove images if --rmi flag
+	for _, image := range images {
+		report.Infof("Removing image %s", image)
+		// An image still used by another container only warns.
+		if _, err := runner.Output("image", "delete", image); err != nil {
+			report.Warnf("failed to remove image %s: %v", image, err)
+		}
+	}
+
+	// Remove networks
+	for _, net := range state.Networks {
+		report.Infof("Removing network %s", net)
+		if err := runner.Run("network", "delete", net); err != nil {
+			report.Warnf("failed to remove network %s: %v", net, err)
 		}
 	}
 
@@ -504,6 +1404,26 @@ func composeDownAction(ctx context.Context, cmd *cli.Command) error {
 	return nil
 }
 
+// downImages returns the images `down --rmi` removes: with "local" those
+// built under the project's own tag because the service sets no image, with
+// "all" every service's image.
+func downImages(cc *composeContext, rmi string) ([]string, error) {
+	if rmi != "" && rmi != "local" && rmi != "all" {
+		return nil, fmt.Errorf("invalid --rmi value %q (want local or all)", rmi)
+	}
+	var images []string
+	for _, svcName := range sortedKeys(cc.composeFile.Services) {
+		svc := cc.composeFile.Services[svcName]
+		if rmi == "all" || rmi == "local" && svc.Image == "" {
+			image := serviceImage(cc.projectName, svcName, svc)
+			if !slices.Contains(images, image) {
+				images = append(images, image)
+			}
+		}
+	}
+	return images, nil
+}
+
 func composePsAction(ctx context.Context, cmd *cli.Command) error {
 	cc, err := resolveComposeContext(cmd)
 	if err != nil {
@@ -514,21 +1434,49 @@ func composePsAction(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return err
 	}
+	filters, err := parsePsFilters(cmd.StringSlice("filter"))
+	if err != nil {
+		return err
+	}
+	if cmd.Bool("cached") {
+		if cmd.Bool("orphans") {
+			return fmt.Errorf("--cached cannot be combined with --orphans")
+		}
+		if _, ok := filters["health"]; ok {
+			return fmt.Errorf("--cached cannot be combined with --filter health")
+		}
+		return printCachedPs(state, filters, cmd.Bool("quiet"), cmd.String("format"))
+	}
 
-	// Get all containers in JSON format
-	out, err := runner.Output("list", "--format", "json")
+	// With --orphans, the containers up --remove-orphans would delete are
+	// listed instead, stopped or not.
+	orphans := cmd.Bool("orphans")
+	listArgs := []string{"list", "--format", "json"}
+	if orphans {
+		listArgs = append(listArgs, "--all")
+	}
+	out, err := runner.Output(listArgs...)
 	if err != nil {
 		return fmt.Errorf("listing containers: %w", err)
 	}
 
-	if out == "" {
-		return nil
+	infos, _ := runner.ParseContainers(out)
+	byName := make(map[string]runner.ContainerInfo, len(infos))
+	for _, info := range infos {
+		byName[info.Configuration.ID] = info
 	}
 
-	// Build set of our container names
-	projectContainers := make(map[string]bool)
-	for _, cName := range state.Containers {
-		projectContainers[cName] = true
+	// Map our container names to their services, including those named
+	// under the other naming scheme
+	projectContainers := make(map[string]string)
+	shown := state.Containers
+	if orphans {
+		shown = orphanContainers(cc.projectName, cc.composeFile, infos)
+	} else {
+		discoverContainers(state, cc.composeFile, infos)
+	}
+	for svcName, cName := range shown {
+		projectContainers[cName] = svcName
 	}
 
 	// Parse and filter JSON output
@@ -549,21 +1497,254 @@ func composePsAction(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
-	// Filter to project containers and print
+	// Keep project containers and check the health of each concurrently
+	type listed struct {
+		name, service, health string
+		fields                map[string]interface{}
+	}
+	var rows []*listed
+	var wg sync.WaitGroup
 	for _, c := range allContainers {
-		name, _ := c["Name"].(string)
-		if name == "" {
-			name, _ = c["name"].(string)
+		name := listedContainerName(c)
+		svcName, ok := projectContainers[name]
+		if !ok {
+			continue
+		}
+		row := &listed{name: name, service: svcName, fields: c}
+		rows = append(rows, row)
+		if status, _ := c["status"].(string); orphans || status != "running" {
+			// An orphan's service, and so its health check, is gone, and a
+			// container that is not running has no health.
+			row.health = healthNone
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			row.health = serviceHealth(ctx, cc, svcName, name)
+		}()
+	}
+	wg.Wait()
+	if !orphans {
+		listedStatus := make(map[string]string, len(rows))
+		for _, row := range rows {
+			listedStatus[row.service], _ = row.fields["status"].(string)
+		}
+		refreshStatus(state, listedStatus)
+	}
+
+	// --format renders ContainerSummary rows; without it the runtime's
+	// own fields are printed.
+	psFormat := cmd.String("format")
+	templated := psFormat != ""
+	var summaries []ContainerSummary
+	matched := 0
+	for _, row := range rows {
+		c := row.fields
+		if row.health != healthNone {
+			c["Health"] = row.health
+		}
+		status, _ := c["status"].(string)
+		if want, ok := filters["status"]; ok && want != status {
+			continue
+		}
+		if want, ok := filters["health"]; ok && want != row.health {
+			continue
+		}
+		matched++
+		if cmd.Bool("quiet") {
+			fmt.Println(row.name)
+			continue
+		}
+		if templated {
+			summaries = append(summaries, containerSummary(cc.projectName, row.service, row.health, byName[row.name], cc.composeFile.Services[row.service]))
+			continue
+		}
+
+		// Ports reachable only from project networks are listed apart
+		// from the published ones the runtime reports.
+		if exposed := compose.ExposedOnly(cc.composeFile.Services[row.service]); len(exposed) > 0 {
+			specs := make([]string, len(exposed))
+			for i, p := range exposed {
+				specs[i] = p.String()
+			}
+			c["ExposedPorts"] = specs
+		}
+		data, _ := json.Marshal(c)
+		fmt.Println(string(data))
+	}
+
+	// Scripts gate on `ps --quiet --filter ...` like grep: no match fails.
+	if cmd.Bool("quiet") && len(filters) > 0 && matched == 0 {
+		return &runner.ExitError{Code: 1}
+	}
+	if templated && !cmd.Bool("quiet") {
+		return format.Write(os.Stdout, psFormat, psTable, summaries)
+	}
+	return nil
+}
+
+// refreshStatus records the statuses the runtime listed, keyed by service,
+// in the project state. The list holds only running containers, so a
+// service recorded as running or paused but not listed has stopped.
+func refreshStatus(state *compose.ProjectState, listed map[string]string) {
+	changed := false
+	for svcName := range state.Containers {
+		status, ok := listed[svcName]
+		if !ok {
+			if prev := state.StatusOf(svcName); prev == compose.StatusRunning || prev == compose.StatusPaused {
+				recordStopped(state, svcName)
+				changed = true
+			}
+			continue
 		}
-		if projectContainers[name] {
-			data, _ := json.Marshal(c)
-			fmt.Println(string(data))
+		if status != "" && status != state.StatusOf(svcName) {
+			state.SetStatus(svcName, status)
+			changed = true
 		}
 	}
+	if !changed {
+		return
+	}
+	if err := compose.SaveProject(state); err != nil {
+		report.Warnf("saving project state: %v", err)
+	}
+}
 
+// printCachedPs prints the project's containers with the statuses dctl
+// last recorded, without asking the runtime. Formatted rows have only the
+// name, project, service and status.
+func printCachedPs(state *compose.ProjectState, filters map[string]string, quiet bool, psFormat string) error {
+	templated := psFormat != ""
+	var summaries []ContainerSummary
+	matched := 0
+	for _, svcName := range sortedKeys(state.Containers) {
+		cName := state.Containers[svcName]
+		status := state.Status[svcName]
+		if want, ok := filters["status"]; ok && want != status.State {
+			continue
+		}
+		matched++
+		if quiet {
+			fmt.Println(cName)
+			continue
+		}
+		if templated {
+			summaries = append(summaries, ContainerSummary{ID: cName, Name: cName, Project: state.Name, Service: svcName, State: status.State, Status: status.String()})
+			continue
+		}
+		row := map[string]interface{}{"Name": cName, "Service": svcName, "status": status.State}
+		if status.State == compose.StatusExited {
+			row["ExitCode"] = status.ExitCode
+		}
+		if !status.Updated.IsZero() {
+			row["StatusUpdated"] = status.Updated
+		}
+		data, _ := json.Marshal(row)
+		fmt.Println(string(data))
+	}
+	if quiet && len(filters) > 0 && matched == 0 {
+		return &runner.ExitError{Code: 1}
+	}
+	if templated && !quiet {
+		return format.Write(os.Stdout, psFormat, psTable, summaries)
+	}
 	return nil
 }
 
+// parsePsFilters parses ps --filter values: health=healthy|unhealthy|none
+// and status=STATUS.
+func parsePsFilters(specs []string) (map[string]string, error) {
+	filters := make(map[string]string)
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		switch {
+		case !ok:
+		case key == "health" && slices.Contains([]string{healthHealthy, healthUnhealthy, healthNone}, value):
+			filters[key] = value
+			continue
+		case key == "status":
+			filters[key] = value
+			continue
+		}
+		return nil, fmt.Errorf("unsupported filter %q (supported: health=healthy|unhealthy|none, status=STATUS)", spec)
+	}
+	return filters, nil
+}
+
+// listedContainerName returns the name of a container in list output, which
+// newer runtimes only give as the configuration id.
+func listedContainerName(c map[string]interface{}) string {
+	for _, key := range []string{"Name", "name"} {
+		if name, _ := c[key].(string); name != "" {
+			return name
+		}
+	}
+	config, _ := c["configuration"].(map[string]interface{})
+	id, _ := config["id"].(string)
+	return id
+}
+
+func composePortAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 2 {
+		return fmt.Errorf("requires exactly 2 arguments: SERVICE PRIVATE_PORT")
+	}
+	svcName := cmd.Args().Get(0)
+	port, err := strconv.Atoi(cmd.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("invalid port %q", cmd.Args().Get(1))
+	}
+	protocol := strings.ToLower(cmd.String("protocol"))
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return err
+	}
+	if err := checkServiceNames(cc, state, []string{svcName}); err != nil {
+		return err
+	}
+	cName, ok := state.Containers[svcName]
+	if !ok {
+		return fmt.Errorf("no container found for service %s", svcName)
+	}
+
+	infos, err := runner.Inspect(cName)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		for _, p := range info.Configuration.PublishedPorts {
+			proto := p.Proto
+			if proto == "" {
+				proto = "tcp"
+			}
+			if p.ContainerPort != port || proto != protocol {
+				continue
+			}
+			host := p.HostAddress
+			if host == "" {
+				host = "0.0.0.0"
+			}
+			if f := cmd.String("format"); f != "" {
+				row := PortSummary{Service: svcName, PrivatePort: port, Protocol: protocol, HostIP: host, HostPort: p.HostPort}
+				return format.Write(os.Stdout, f, "", []PortSummary{row})
+			}
+			fmt.Println(net.JoinHostPort(host, strconv.Itoa(p.HostPort)))
+			return nil
+		}
+	}
+	for _, p := range compose.ExposedOnly(cc.composeFile.Services[svcName]) {
+		if p.Target == port && p.Protocol == protocol {
+			return fmt.Errorf("port %d/%s of service %s is exposed to the project networks only, not published", port, protocol, svcName)
+		}
+	}
+	return fmt.Errorf("no port %d/%s published for service %s", port, protocol, svcName)
+}
+
 func composeLogsAction(ctx context.Context, cmd *cli.Command) error {
 	cc, err := resolveComposeContext(cmd)
 	if err != nil {
@@ -575,12 +1756,22 @@ func composeLogsAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
-	services := filterServices(state, cmd.Args().Slice())
+	names, filters, err := logFilters(cmd)
+	if err != nil {
+		return err
+	}
+	discoverUnrecorded(state, cc.composeFile)
+	if err := checkServiceNames(cc, state, names); err != nil {
+		return err
+	}
+	services := filterServices(state, names)
+	sort.Strings(services)
 
+	var streams []logStream
 	for _, svcName := range services {
 		cName, ok := state.Containers[svcName]
 		if !ok {
-			fmt.Fprintf(os.Stderr, "Warning: no container found for service %s\n", svcName)
+			report.Warnf("no container found for service %s", svcName)
 			continue
 		}
 
@@ -592,15 +1783,57 @@ func composeLogsAction(ctx context.Context, cmd *cli.Command) error {
 			args = append(args, "-n", n)
 		}
 		args = append(args, cName)
+		streams = append(streams, logStream{service: svcName, args: args, match: cmp.Or(filters[svcName], filters[""])})
+	}
 
-		if err := runner.Run(args...); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to get logs for %s: %v\n", svcName, err)
+	// Several services are shown as one stream, in timestamp order.
+	if len(streams) > 1 {
+		mergeLogs(streams, cmd.Bool("follow"))
+		return nil
+	}
+	for _, s := range streams {
+		if s.match != nil {
+			w := &prefixWriter{w: os.Stdout, match: s.match}
+			err = runner.Stream(w, s.args...)
+			w.Flush()
+		} else {
+			err = runner.Run(s.args...)
+		}
+		if err != nil {
+			report.Warnf("failed to get logs for %s: %v", s.service, err)
 		}
 	}
-
 	return nil
 }
 
+// logFilters splits the compose logs arguments into service names and the
+// patterns their lines must match: SERVICE=REGEX sets a service's own, and
+// --grep, keyed "", the one for the others.
+func logFilters(cmd *cli.Command) ([]string, map[string]*regexp.Regexp, error) {
+	filters := make(map[string]*regexp.Regexp)
+	if grep := cmd.String("grep"); grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+		filters[""] = re
+	}
+	var names []string
+	for _, arg := range cmd.Args().Slice() {
+		svcName, pattern, ok := strings.Cut(arg, "=")
+		names = append(names, svcName)
+		if !ok {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern for %s: %w", svcName, err)
+		}
+		filters[svcName] = re
+	}
+	return names, filters, nil
+}
+
 func composeExecAction(ctx context.Context, cmd *cli.Command) error {
 	if cmd.Args().Len() < 2 {
 		return fmt.Errorf("requires at least 2 arguments: SERVICE COMMAND [ARG...]")
@@ -619,31 +1852,99 @@ func composeExecAction(ctx context.Context, cmd *cli.Command) error {
 	svcName := cmd.Args().First()
 	execArgs := cmd.Args().Tail()
 
+	if err := checkServiceNames(cc, state, []string{svcName}); err != nil {
+		return err
+	}
 	cName, ok := state.Containers[svcName]
 	if !ok {
 		return fmt.Errorf("no container found for service %s", svcName)
 	}
 
+	if cmd.Bool("privileged") {
+		report.Warnf("exec --privileged is not supported by the container runtime, ignoring")
+	}
+
+	// Flags combine with the service's defaults: -u and -w replace them,
+	// while env files and -e entries override the service's variables, -e
+	// last.
+	svc := cc.composeFile.Services[svcName]
+	var overrides []string
+	for _, f := range cmd.StringSlice("env-file") {
+		vars, err := compose.ReadEnvFile(f)
+		if err != nil {
+			return err
+		}
+		for _, k := range sortedKeys(vars) {
+			overrides = append(overrides, k+"="+vars[k])
+		}
+	}
+	env, err := compose.ServiceEnvironment(svc, append(overrides, cmd.StringSlice("env")...))
+	if err != nil {
+		return err
+	}
+
+	keyArgs, keys, err := detachKeys(cmd)
+	if err != nil {
+		return err
+	}
 	args := []string{"exec"}
 	if cmd.Bool("detach") {
 		args = append(args, "--detach")
 	}
-	if !cmd.Bool("no-TTY") {
-		args = append(args, "--tty")
-	}
-	if u := cmd.String("user"); u != "" {
+	args = append(args, keyArgs...)
+	stdio, tty := attachArgs(cmd.Bool("detach"), cmd.Bool("no-TTY"))
+	args = append(args, stdio...)
+	if u := cmp.Or(cmd.String("user"), svc.User); u != "" {
 		args = append(args, "--user", u)
 	}
-	if w := cmd.String("workdir"); w != "" {
+	if w := cmp.Or(cmd.String("workdir"), svc.WorkingDir); w != "" {
 		args = append(args, "--workdir", w)
 	}
-	for _, e := range cmd.StringSlice("env") {
-		args = append(args, "--env", e)
+	// Variables go through a private env file that lives as long as the
+	// exec, so their values stay out of process listings; as for runs,
+	// multi-line values stay inline.
+	var lines []string
+	for _, k := range sortedKeys(env) {
+		if kv := k + "=" + env[k]; strings.ContainsAny(kv, "\r\n") {
+			args = append(args, "--env", kv)
+		} else {
+			lines = append(lines, kv)
+		}
+	}
+	if len(lines) > 0 {
+		path, err := writeExecEnv(lines)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+		args = append(args, "--env-file", path)
 	}
 	args = append(args, cName)
 	args = append(args, execArgs...)
 
-	return runner.Run(args...)
+	if keys != nil {
+		_, err := runDetachable(args, tty, keys, cName)
+		return err
+	}
+	return runAttached(args, tty)
+}
+
+// writeExecEnv writes an exec's KEY=VALUE entries to a temporary env file
+// readable only by the current user and returns its path.
+func writeExecEnv(lines []string) (string, error) {
+	f, err := os.CreateTemp("", "dctl-exec-env-")
+	if err != nil {
+		return "", fmt.Errorf("writing exec environment: %w", err)
+	}
+	_, err = f.WriteString(strings.Join(lines, "\n") + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing exec environment: %w", err)
+	}
+	return f.Name(), nil
 }
 
 func composeRunAction(ctx context.Context, cmd *cli.Command) error {
@@ -661,118 +1962,147 @@ func composeRunAction(ctx context.Context, cmd *cli.Command) error {
 	svcName := cmd.Args().First()
 	cmdArgs := cmd.Args().Tail()
 
-	svc, ok := cf.Services[svcName]
-	if !ok {
-		return fmt.Errorf("no such service: %s", svcName)
+	// The service runs as up would run it, with the flags applied on top.
+	svc, err := cc.runService(svcName)
+	if err != nil {
+		return err
 	}
-
-	if svc.Image == "" {
-		if bc, ok := svc.Build.(*compose.BuildConfig); ok && bc != nil {
-			svc.Image = project + "-" + svcName
-		} else {
-			return fmt.Errorf("service %s has no image and no build config", svcName)
-		}
+	if err := checkServiceSupport(cf, []string{svcName}); err != nil {
+		return err
 	}
-
-	// Override command if provided
-	if len(cmdArgs) > 0 {
-		svc.Command = cmdArgs
+	keyArgs, keys, err := detachKeys(cmd)
+	if err != nil {
+		return err
 	}
 
-	// Build run args from service config
-	name := containerName(project, svcName) + "_run"
-	if n := cmd.String("name"); n != "" {
-		name = n
-	}
-	args := []string{"run"}
-	if cmd.Bool("detach") {
-		args = append(args, "--detach")
-	}
-	if cmd.Bool("rm") {
-		args = append(args, "--rm")
+	name := cmd.String("name")
+	if name == "" {
+		id, err := compose.NewRunID()
+		if err != nil {
+			return err
+		}
+		name = cc.naming.RunContainerName(project, svcName, id)
 	}
-	args = append(args, "--name", name)
 
 	// Ports from service, overridden by flag
 	ports := svc.Ports
 	if flagPorts := cmd.StringSlice("publish"); len(flagPorts) > 0 {
 		ports = flagPorts
 	}
-	for _, p := range ports {
-		args = append(args, "--publish", p)
-	}
-
-	// Volumes from service, plus flag overrides
-	for _, v := range svc.Volumes {
-		args = append(args, "--volume", v)
-	}
-	for _, v := range cmd.StringSlice("volume") {
-		args = append(args, "--volume", v)
+	ports, err = portcheck.Assign(ports)
+	if err != nil {
+		return err
 	}
 
-	// Environment from service, plus flag overrides
-	if env, ok := svc.Environment.(map[string]string); ok {
-		for k, v := range env {
-			args = append(args, "--env", k+"="+v)
+	if secs, ok := svc.Secrets.([]compose.ServiceSecret); ok {
+		if err := secrets.Write(name, secs, cf.Secrets); err != nil {
+			return err
 		}
 	}
-	for _, e := range cmd.StringSlice("env") {
-		args = append(args, "--env", e)
-	}
 
-	// User
-	user := svc.User
-	if u := cmd.String("user"); u != "" {
-		user = u
-	}
-	if user != "" {
-		args = append(args, "--user", user)
-	}
-
-	// Workdir
-	workdir := svc.WorkingDir
-	if w := cmd.String("workdir"); w != "" {
-		workdir = w
+	var stdio *translate.Stdio
+	_, tty := attachArgs(cmd.Bool("detach"), cmd.Bool("no-TTY"))
+	if !cmd.Bool("detach") {
+		stdio = &translate.Stdio{Interactive: true, TTY: tty}
+	}
+	args, err := buildRunArgs(svc, translate.Options{
+		Name:       name,
+		Detach:     cmd.Bool("detach"),
+		Remove:     cmd.Bool("rm"),
+		Ports:      ports,
+		Volumes:    cmd.StringSlice("volume"),
+		Env:        cmd.StringSlice("env"),
+		User:       cmd.String("user"),
+		Workdir:    cmd.String("workdir"),
+		Entrypoint: cmd.String("entrypoint"),
+		// --entrypoint "" runs the command without the image's entrypoint.
+		ClearEntrypoint: cmd.IsSet("entrypoint") && cmd.String("entrypoint") == "",
+		Command:         cmdArgs,
+		Stdio:           stdio,
+	})
+	if err != nil {
+		return err
 	}
-	if workdir != "" {
-		args = append(args, "--workdir", workdir)
+	if args, err = withEnvFile(args); err != nil {
+		return err
 	}
 
-	// Entrypoint
-	if ep := cmd.String("entrypoint"); ep != "" {
-		args = append(args, "--entrypoint", ep)
-	} else if ep, ok := svc.Entrypoint.([]string); ok && len(ep) > 0 {
-		args = append(args, "--entrypoint", ep[0])
-	}
+	args = slices.Insert(args, 1, keyArgs...)
 
-	if svc.Tty {
-		args = append(args, "--tty")
-	}
-	if svc.StdinOpen {
-		args = append(args, "--interactive")
+	trackRunContainer(project, name, true)
+	if cmd.Bool("detach") {
+		return runner.Run(args...)
 	}
+	return runForeground(project, args, tty, name, cmd.Bool("rm"), keys)
+}
 
-	// Network
-	if nets, ok := svc.Networks.(map[string]interface{}); ok {
-		for netName := range nets {
-			args = append(args, "--network", netName)
-			break
+// trackRunContainer records or forgets a one-off run container in the
+// project state so `compose rm --all-run` can find leftovers. Projects that
+// have not been brought up have no state; their run containers are still
+// found by name.
+func trackRunContainer(project, name string, add bool) {
+	if _, err := compose.LoadProject(project); err != nil {
+		return
+	}
+	// Concurrent runs of the project each update the list.
+	err := compose.UpdateProject(project, func(state *compose.ProjectState) error {
+		state.RunContainers = slices.DeleteFunc(state.RunContainers, func(n string) bool { return n == name })
+		if add {
+			state.RunContainers = append(state.RunContainers, name)
 		}
+		return nil
+	})
+	if err != nil {
+		report.Warnf("saving project state: %v", err)
 	}
+}
 
-	// Platform
-	if svc.Platform != "" {
-		args = append(args, "--platform", svc.Platform)
-	}
-
-	args = append(args, svc.Image)
+// foregroundSignals names the signals a foreground run passes on to its
+// container.
+var foregroundSignals = map[os.Signal]string{
+	os.Interrupt:    "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+}
 
-	// Command args
-	if cmdSlice, ok := svc.Command.([]string); ok {
-		args = append(args, cmdSlice...)
+// runForeground runs an attached `compose run`. SIGINT and SIGTERM sent to
+// dctl are forwarded to the container rather than ending dctl, so it stays
+// around to remove the container afterwards when rm is set, even if the
+// runtime's own --rm was skipped because the CLI was interrupted. With
+// detach keys, the user can leave the container running instead.
+func runForeground(project string, args []string, tty bool, name string, rm bool, keys []byte) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range sigs {
+			_, _ = runner.Output("kill", "--signal", foregroundSignals[sig], name)
+		}
+	}()
+	var detached bool
+	var err error
+	if keys != nil {
+		detached, err = runDetachable(args, tty, keys, name)
+	} else {
+		err = runner.Attached(tty, args...)
+	}
+	signal.Stop(sigs)
+	close(sigs)
+	if detached {
+		return nil
 	}
 
-	return runner.Run(args...)
+	if rm {
+		if infos, _ := runner.Inspect(name); len(infos) > 0 {
+			if _, rmErr := runner.Output("delete", "--force", name); rmErr != nil {
+				report.Warnf("failed to remove %s: %v", name, rmErr)
+				return err
+			}
+		}
+		if rmErr := compose.RemoveRunEnv(name); rmErr != nil {
+			report.Warnf("%v", rmErr)
+		}
+		trackRunContainer(project, name, false)
+	}
+	return err
 }
 
 func composeBuildAction(ctx context.Context, cmd *cli.Command) error {
@@ -791,15 +2121,24 @@ func composeBuildAction(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	var jobs []buildJob
+	var tags []string
 	for _, svcName := range services {
 		svc, ok := cf.Services[svcName]
 		if !ok {
-			return fmt.Errorf("no such service: %s", svcName)
+			return compose.UnknownServiceError(svcName, sortedKeys(cf.Services))
 		}
 
 		bc, ok := svc.Build.(*compose.BuildConfig)
 		if !ok || bc == nil {
-			fmt.Fprintf(os.Stderr, "Skipping %s: no build config\n", svcName)
+			report.Infof("Skipping %s: no build config", svcName)
+			continue
+		}
+
+		if cmd.Bool("check-context") {
+			if err := checkContext(svcName, bc); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -808,8 +2147,8 @@ func composeBuildAction(ctx context.Context, cmd *cli.Command) error {
 			tag = project + "-" + svcName
 		}
 
-		fmt.Fprintf(os.Stderr, "Building %s\n", svcName)
-		buildArgs := composeBuildCLIArgs(bc, tag, cc.projectDir)
+		warnLargeContext(svcName, bc)
+		buildArgs := composeBuildCLIArgs(bc, tag, servicePlatform(svc))
 
 		// Add CLI flag overrides
 		if cmd.Bool("no-cache") {
@@ -818,22 +2157,28 @@ func composeBuildAction(ctx context.Context, cmd *cli.Command) error {
 		for _, arg := range cmd.StringSlice("build-arg") {
 			buildArgs = append(buildArgs, "--build-arg", arg)
 		}
-
-		if err := runner.Run(buildArgs...); err != nil {
-			return fmt.Errorf("building service %s: %w", svcName, err)
-		}
+		jobs = append(jobs, buildJob{service: svcName, args: buildArgs})
+		tags = append(tags, tag)
 	}
 
-	return nil
+	err = runBuilds(cmd, project, jobs)
+	// Record the tags even when a build failed, as the others may have
+	// produced theirs.
+	recordImages(project, tags...)
+	return err
 }
 
 // composeBuildCLIArgs builds container build CLI arguments from a BuildConfig.
-func composeBuildCLIArgs(bc *compose.BuildConfig, tag, projectDir string) []string {
+// The build context is already resolved against the project directory.
+func composeBuildCLIArgs(bc *compose.BuildConfig, tag, platform string) []string {
 	args := []string{"build"}
 
 	if tag != "" {
 		args = append(args, "--tag", tag)
 	}
+	if platform != "" {
+		args = append(args, "--platform", platform)
+	}
 	if bc.Dockerfile != "" {
 		args = append(args, "--file", bc.Dockerfile)
 	}
@@ -847,49 +2192,151 @@ func composeBuildCLIArgs(bc *compose.BuildConfig, tag, projectDir string) []stri
 		args = append(args, "--label", k+"="+v)
 	}
 
-	buildContext := bc.Context
-	if buildContext == "" {
-		buildContext = "."
-	}
-	if !filepath.IsAbs(buildContext) {
-		buildContext = filepath.Join(projectDir, buildContext)
-	}
-	args = append(args, buildContext)
+	args = append(args, bc.Context)
 
 	return args
 }
 
+// Pull policies, as compose pull --policy and pull_policy name them.
+const (
+	pullAlways  = "always"
+	pullMissing = "missing"
+	pullNever   = "never"
+	pullBuild   = "build"
+)
+
+// pullPolicy returns the policy pull applies to a service: --policy when
+// given, else the service's pull_policy. Services that are never pulled or
+// always built are skipped either way, and scheduled policies such as daily
+// pull every time.
+func pullPolicy(flag string, svc compose.Service) string {
+	if svc.PullPolicy == pullNever || svc.PullPolicy == pullBuild {
+		return svc.PullPolicy
+	}
+	switch p := cmp.Or(flag, svc.PullPolicy); p {
+	case "if_not_present":
+		return pullMissing
+	case pullMissing:
+		return p
+	default:
+		return pullAlways
+	}
+}
+
 func composePullAction(ctx context.Context, cmd *cli.Command) error {
 	cc, err := resolveComposeContext(cmd)
 	if err != nil {
 		return err
 	}
+	policy := cmd.String("policy")
+	if policy != "" && policy != pullAlways && policy != pullMissing {
+		return fmt.Errorf("invalid --policy %q (want always or missing)", policy)
+	}
 
 	cf := cc.composeFile
 
 	services := cmd.Args().Slice()
 	if len(services) == 0 {
-		for name := range cf.Services {
-			services = append(services, name)
+		services = sortedKeys(cf.Services)
+	}
+
+	// Local images are listed once, when a policy or --refresh needs them.
+	var local []runner.ImageInfo
+	listed := false
+	localImage := func(ref string) (runner.ImageInfo, bool, error) {
+		if !listed {
+			if local, err = runner.ListImages(); err != nil {
+				return runner.ImageInfo{}, false, err
+			}
+			listed = true
+		}
+		for _, img := range local {
+			if img.HasReference(ref) {
+				return img, true, nil
+			}
 		}
+		return runner.ImageInfo{}, false, nil
 	}
 
+	var pulls []string
+	seen := make(map[string]bool)
 	for _, svcName := range services {
 		svc, ok := cf.Services[svcName]
 		if !ok {
-			return fmt.Errorf("no such service: %s", svcName)
+			return compose.UnknownServiceError(svcName, sortedKeys(cf.Services))
 		}
 		if svc.Image == "" {
-			fmt.Fprintf(os.Stderr, "Skipping %s: no image defined\n", svcName)
+			report.Infof("Skipping %s: no image defined", svcName)
 			continue
 		}
-		fmt.Fprintf(os.Stderr, "Pulling %s\n", svc.Image)
-		if err := runner.Run("image", "pull", svc.Image); err != nil {
-			return fmt.Errorf("pulling image for %s: %w", svcName, err)
+		switch p := pullPolicy(policy, svc); p {
+		case pullNever, pullBuild:
+			report.Infof("Skipping %s: pull_policy is %s", svcName, p)
+			continue
+		case pullMissing:
+			_, found, err := localImage(svc.Image)
+			if err != nil {
+				return err
+			}
+			if found {
+				report.Infof("Skipping %s: %s is present", svcName, svc.Image)
+				continue
+			}
+		}
+		if !seen[svc.Image] {
+			seen[svc.Image] = true
+			pulls = append(pulls, svc.Image)
 		}
 	}
 
-	return nil
+	refresh := cmd.Bool("refresh")
+	if refresh && !listed {
+		if local, err = runner.ListImages(); err != nil {
+			return err
+		}
+	}
+	var mu sync.Mutex
+	var pulled []string
+	err = forEachParallel(parallelLimit(cmd), pulls, func(image string) error {
+		if refresh && imageCurrent(image, local) {
+			report.Infof("%s is up to date", image)
+			return nil
+		}
+		report.Infof("Pulling %s", image)
+		if err := runner.Run("image", "pull", image); err != nil {
+			return fmt.Errorf("pulling %s: %w", image, err)
+		}
+		mu.Lock()
+		pulled = append(pulled, image)
+		mu.Unlock()
+		return nil
+	})
+	recordImages(cc.projectName, pulled...)
+	return err
+}
+
+// imageCurrent reports whether the local copy of image has the digest its
+// tag has in the registry; a local image pinned by digest always is.
+// Images that are missing or cannot be checked are not current, so they
+// are pulled.
+func imageCurrent(image string, local []runner.ImageInfo) bool {
+	i := slices.IndexFunc(local, func(img runner.ImageInfo) bool { return img.HasReference(image) })
+	if i < 0 || local[i].Descriptor.Digest == "" {
+		return false
+	}
+	ref, err := oci.ParseReference(runner.QualifyReference(image))
+	if err != nil {
+		return false
+	}
+	if ref.Digest != "" {
+		return true
+	}
+	remote, err := oci.ManifestDigest(ref)
+	if err != nil {
+		report.Warnf("checking %s for updates: %v; pulling it", image, err)
+		return false
+	}
+	return remote == local[i].Descriptor.Digest
 }
 
 func composeStopAction(ctx context.Context, cmd *cli.Command) error {
@@ -903,21 +2350,38 @@ func composeStopAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
 	services := filterServices(state, cmd.Args().Slice())
 
-	for _, svcName := range services {
-		cName, ok := state.Containers[svcName]
-		if !ok {
-			fmt.Fprintf(os.Stderr, "Warning: no container found for service %s\n", svcName)
-			continue
-		}
-		fmt.Fprintf(os.Stderr, "Stopping %s\n", cName)
-		if err := runner.Run("stop", cName); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to stop %s: %v\n", svcName, err)
-		}
+	// Dependents stop first; independent services up to --parallel at a
+	// time.
+	levels, err := shutdownLevels(cc.composeFile.Services, services)
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	for _, level := range levels {
+		_ = forEachParallel(parallelLimit(cmd), level, func(svcName string) error {
+			cName, ok := state.Containers[svcName]
+			if !ok {
+				report.Warnf("no container found for service %s", svcName)
+				return nil
+			}
+			report.Infof("Stopping %s", cName)
+			if err := stopService(cmd, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+				return nil
+			}
+			mu.Lock()
+			state.SetStatus(svcName, compose.StatusStopped)
+			mu.Unlock()
+			return nil
+		})
 	}
 
-	return nil
+	return compose.SaveProject(state)
 }
 
 func composeRestartAction(ctx context.Context, cmd *cli.Command) error {
@@ -931,33 +2395,66 @@ func composeRestartAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
+	if cmd.Bool("with-dependents") && cmd.Bool("no-deps") {
+		return fmt.Errorf("--with-dependents and --no-deps cannot be used together")
+	}
 	services := filterServices(state, cmd.Args().Slice())
+	if cmd.Args().Len() > 0 && !cmd.Bool("no-deps") {
+		// Dependents marked restart: true, and services sharing a restarted
+		// service's namespaces, always restart with it; --with-dependents
+		// takes every dependent.
+		for _, svcName := range compose.Dependents(cc.composeFile.Services, services, !cmd.Bool("with-dependents")) {
+			if _, ok := state.Containers[svcName]; ok {
+				services = append(services, svcName)
+			}
+		}
+	}
+
+	// Dependents stop first and start last.
+	levels, err := shutdownLevels(cc.composeFile.Services, services)
+	if err != nil {
+		return err
+	}
+	var order []string
+	for _, level := range levels {
+		order = append(order, level...)
+	}
 
 	// Stop services
-	for _, svcName := range services {
+	for _, svcName := range order {
 		cName, ok := state.Containers[svcName]
 		if !ok {
 			continue
 		}
-		fmt.Fprintf(os.Stderr, "Stopping %s\n", cName)
-		if err := runner.Run("stop", cName); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to stop %s: %v\n", svcName, err)
+		report.Infof("Stopping %s", cName)
+		if err := stopService(cmd, cc, svcName, cName); err != nil {
+			report.Warnf("failed to stop %s: %v", svcName, err)
+			continue
 		}
+		state.SetStatus(svcName, compose.StatusStopped)
 	}
 
 	// Start services
-	for _, svcName := range services {
+	slices.Reverse(order)
+	for _, svcName := range order {
 		cName, ok := state.Containers[svcName]
 		if !ok {
 			continue
 		}
-		fmt.Fprintf(os.Stderr, "Starting %s\n", cName)
-		if err := runner.Run("start", cName); err != nil {
+		report.Infof("Starting %s", cName)
+		if err := startReleased(cName, time.Duration(cmd.Int("port-wait"))*time.Second); err != nil {
+			if saveErr := compose.SaveProject(state); saveErr != nil {
+				report.Warnf("saving project state: %v", saveErr)
+			}
 			return fmt.Errorf("starting %s: %w", svcName, err)
 		}
+		state.SetStatus(svcName, compose.StatusRunning)
 	}
 
-	return nil
+	return compose.SaveProject(state)
 }
 
 func composeConfigAction(ctx context.Context, cmd *cli.Command) error {
@@ -966,16 +2463,204 @@ func composeConfigAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	// Container names other projects or containers hold are invalid too;
+	// without a runtime, only other projects' are checked.
+	containers, _ := runner.List(true)
+	prev, _ := compose.LoadProject(cc.projectName)
+	if err := checkContainerNames(cc, containers, prev); err != nil {
+		return err
+	}
+
 	if cmd.Bool("quiet") {
 		// Just validate, don't print
 		return nil
 	}
 
-	out, err := yaml.Marshal(cc.composeFile)
+	asJSON := false
+	switch f := cmd.String("format"); f {
+	case "yaml":
+	case format.JSON:
+		asJSON = true
+	default:
+		return fmt.Errorf("invalid --format %q (want yaml or json)", f)
+	}
+
+	// lines prints one value per line, or a JSON array of them.
+	var out bytes.Buffer
+	lines := func(values []string) {
+		if asJSON {
+			writeJSONList(&out, values)
+			return
+		}
+		for _, v := range values {
+			fmt.Fprintln(&out, v)
+		}
+	}
+	cf := cc.composeFile
+	switch {
+	case cmd.Bool("services"):
+		lines(sortedKeys(cf.Services))
+	case cmd.Bool("volumes"):
+		lines(sortedKeys(cf.Volumes))
+	case cmd.Bool("images"):
+		var images []string
+		for _, name := range sortedKeys(cf.Services) {
+			img := serviceImage(cc.projectName, name, cf.Services[name])
+			if !slices.Contains(images, img) {
+				images = append(images, img)
+			}
+		}
+		lines(images)
+	case cmd.Bool("variables"):
+		vars, err := compose.Variables(cc.files, cc.projectDir)
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			writeJSONList(&out, vars)
+			break
+		}
+		if err := format.Write(&out, "table", variablesTable, vars); err != nil {
+			return err
+		}
+	case cmd.IsSet("hash"):
+		hashes, err := configHashes(cc, cmd.String("hash"))
+		if err != nil {
+			return err
+		}
+		if asJSON {
+			writeJSONList(&out, hashes)
+			break
+		}
+		for _, h := range hashes {
+			fmt.Fprintf(&out, "%s %s\n", h.Service, h.Hash)
+		}
+	case cmd.Bool("check-support"):
+		v := runtimeVersion()
+		if v.Known() {
+			report.Infof("Checking against container %s", v)
+		}
+		issues := features.CheckSupport(cf, v)
+		if len(issues) == 0 {
+			report.Infof("All keys are supported")
+		}
+		if asJSON {
+			writeJSONList(&out, issues)
+			break
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(&out, issue)
+		}
+	default:
+		if cmd.Bool("image-defaults") {
+			applyImageDefaults(cc)
+		}
+		data, err := yaml.Marshal(cf)
+		if err != nil {
+			return fmt.Errorf("marshaling compose file: %w", err)
+		}
+		if asJSON {
+			// Going through YAML keeps the compose key names.
+			var model interface{}
+			if err := yaml.Unmarshal(data, &model); err != nil {
+				return fmt.Errorf("converting compose file: %w", err)
+			}
+			writeJSON(&out, model)
+			break
+		}
+		out.Write(data)
+	}
+
+	if path := cmd.String("output"); path != "" {
+		if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		return nil
+	}
+	fmt.Print(out.String())
+	return nil
+}
+
+// variablesTable is the config --variables layout.
+const variablesTable = `{{.Name}}\t{{.Default}}\t{{.Set}}\t{{.Value}}`
+
+// ConfigHash is a service's configuration hash as config --hash prints it.
+type ConfigHash struct {
+	Service string
+	Hash    string
+}
+
+// configHashes returns the hashes of the selected services ("*" for all).
+// The hashes are the ones up records to detect changes.
+func configHashes(cc *composeContext, selection string) ([]ConfigHash, error) {
+	services := sortedKeys(cc.composeFile.Services)
+	if selection != "*" {
+		services = strings.Split(selection, ",")
+	}
+	var hashes []ConfigHash
+	for _, name := range services {
+		name = strings.TrimSpace(name)
+		args, err := cc.runArgs(name)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, ConfigHash{Service: name, Hash: compose.ConfigHash(args)})
+	}
+	return hashes, nil
+}
+
+// writeJSONList writes values as an indented JSON array, [] when empty.
+func writeJSONList[T any](w io.Writer, values []T) {
+	if values == nil {
+		values = []T{}
+	}
+	writeJSON(w, values)
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v interface{}) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func composeExplainAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
 	if err != nil {
-		return fmt.Errorf("marshaling compose file: %w", err)
+		return err
+	}
+
+	services := cmd.Args().Slice()
+	if len(services) == 0 {
+		if services, err = compose.ResolveOrder(cc.composeFile.Services); err != nil {
+			return err
+		}
+	}
+
+	// The commands are printed, so values of sensitive variables and of
+	// the project's secrets are masked.
+	secrets.RememberLocal(cc.composeFile.Secrets)
+	for i, svcName := range services {
+		args, err := cc.runArgs(svcName)
+		if err != nil {
+			return err
+		}
+		args = secrets.Redact(args)
+		if len(services) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s\n", svcName)
+		}
+		fmt.Println(runner.CommandLine(args...))
+		// Without a command or entrypoint the image decides what runs.
+		if inheritsCommand(cc.composeFile.Services[svcName]) {
+			if c, ok := imageConfig(cc, svcName); ok && len(c.Command()) > 0 {
+				fmt.Printf("# command from image: %s\n", runner.CommandLine(c.Command()...))
+			}
+		}
 	}
-	fmt.Print(string(out))
 	return nil
 }
 
@@ -990,6 +2675,12 @@ func composeRmAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
+	if cmd.Bool("all-run") {
+		return removeRunContainers(cc, state, cmd.Args().Slice(), cmd.Bool("stop"), cmd.Bool("force"))
+	}
 	services := filterServices(state, cmd.Args().Slice())
 
 	// Optionally stop first
@@ -999,29 +2690,106 @@ func composeRmAction(ctx context.Context, cmd *cli.Command) error {
 			if !ok {
 				continue
 			}
-			fmt.Fprintf(os.Stderr, "Stopping %s\n", cName)
-			_ = runner.Run("stop", cName)
+			report.Infof("Stopping %s", cName)
+			if err := stopService(cmd, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+				continue
+			}
+			state.SetStatus(svcName, compose.StatusStopped)
 		}
 	}
 
 	for _, svcName := range services {
 		cName, ok := state.Containers[svcName]
 		if !ok {
-			fmt.Fprintf(os.Stderr, "Warning: no container found for service %s\n", svcName)
+			report.Warnf("no container found for service %s", svcName)
 			continue
 		}
-		fmt.Fprintf(os.Stderr, "Removing %s\n", cName)
+		report.Infof("Removing %s", cName)
 		deleteArgs := []string{"delete"}
 		if cmd.Bool("force") {
 			deleteArgs = append(deleteArgs, "--force")
 		}
 		deleteArgs = append(deleteArgs, cName)
 		if err := runner.Run(deleteArgs...); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", svcName, err)
+			report.Warnf("failed to remove %s: %v", svcName, err)
+			continue
 		}
+		delete(state.Containers, svcName)
+		state.SetStatus(svcName, "")
 	}
 
-	return nil
+	return compose.SaveProject(state)
+}
+
+// removeRunContainers removes the project's one-off run containers, limited
+// to services when given: those recorded in state and any others whose
+// names mark them as run containers of one of the project's services. A
+// name alone is not enough, since project "shop" would otherwise claim the
+// run containers of a project named "shop_web".
+func removeRunContainers(cc *composeContext, state *compose.ProjectState, services []string, stop, force bool) error {
+	project := cc.projectName
+	existing := make(map[string]bool)
+	containers, err := runner.List(true)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		existing[c.Configuration.ID] = true
+	}
+
+	names := make(map[string]bool)
+	for _, name := range state.RunContainers {
+		names[name] = true
+	}
+	for name := range existing {
+		if svc, ok := compose.RunContainerService(project, name); ok {
+			if _, defined := cc.composeFile.Services[svc]; defined {
+				names[name] = true
+			}
+		}
+	}
+
+	// Recorded names are forgotten once their container is gone.
+	forgotten := make(map[string]bool)
+	for _, name := range sortedKeys(names) {
+		if len(services) > 0 {
+			svc, ok := compose.RunContainerService(project, name)
+			if !ok || !slices.Contains(services, svc) {
+				continue
+			}
+		}
+		if !existing[name] {
+			forgotten[name] = true
+			continue
+		}
+		if stop {
+			report.Infof("Stopping %s", name)
+			svcName, _ := compose.RunContainerService(project, name)
+			if err := stopService(nil, cc, svcName, name); err != nil {
+				report.Warnf("failed to stop %s: %v", name, err)
+			}
+		}
+		report.Infof("Removing %s", name)
+		deleteArgs := []string{"delete"}
+		if force {
+			deleteArgs = append(deleteArgs, "--force")
+		}
+		if _, err := runner.Output(append(deleteArgs, name)...); err != nil {
+			report.Warnf("failed to remove %s: %v", name, err)
+			continue
+		}
+		forgotten[name] = true
+		if err := compose.RemoveRunEnv(name); err != nil {
+			report.Warnf("%v", err)
+		}
+	}
+
+	// Runs started meanwhile stay recorded.
+	return compose.UpdateProject(project, func(state *compose.ProjectState) error {
+		state.RunContainers = slices.DeleteFunc(state.RunContainers, func(n string) bool { return forgotten[n] })
+		return nil
+	})
 }
 
 func composeKillAction(ctx context.Context, cmd *cli.Command) error {
@@ -1035,25 +2803,360 @@ func composeKillAction(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
 	services := filterServices(state, cmd.Args().Slice())
 	signal := cmd.String("signal")
+	remove := cmd.Bool("remove")
 
 	for _, svcName := range services {
 		cName, ok := state.Containers[svcName]
 		if !ok {
-			fmt.Fprintf(os.Stderr, "Warning: no container found for service %s\n", svcName)
+			report.Warnf("no container found for service %s", svcName)
 			continue
 		}
-		fmt.Fprintf(os.Stderr, "Killing %s\n", cName)
+		report.Infof("Killing %s", cName)
 		killArgs := []string{"kill"}
 		if signal != "" && signal != "SIGKILL" {
 			killArgs = append(killArgs, "--signal", signal)
 		}
 		killArgs = append(killArgs, cName)
 		if err := runner.Run(killArgs...); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to kill %s: %v\n", svcName, err)
+			report.Warnf("failed to kill %s: %v", svcName, err)
+			continue
+		}
+
+		// Other signals need not end the container, so only a kill is
+		// checked.
+		if killSignal(signal) {
+			status := waitStopped(cName, killTimeout)
+			if status == "running" {
+				report.Warnf("%s still running %s after kill, stopping it", cName, killTimeout)
+				_, _ = runner.Output("stop", cName)
+				status = containerStatus(cName)
+			}
+			state.SetStatus(svcName, status)
+		}
+
+		if remove {
+			report.Infof("Removing %s", cName)
+			if _, err := runner.Output("delete", "--force", cName); err != nil {
+				report.Warnf("failed to remove %s: %v", svcName, err)
+				continue
+			}
+			delete(state.Containers, svcName)
+			state.SetStatus(svcName, "")
+		}
+	}
+
+	return compose.SaveProject(state)
+}
+
+// composePauseAction runs pause or unpause, after the command's name, on the
+// service containers. The container runtime cannot pause a container.
+func composePauseAction(ctx context.Context, cmd *cli.Command) error {
+	if !dockerBackend() {
+		return fmt.Errorf("%s is not supported by the container runtime", cmd.Name)
+	}
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return err
+	}
+
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
+	status, verb := compose.StatusPaused, "Pausing"
+	if cmd.Name == "unpause" {
+		status, verb = compose.StatusRunning, "Unpausing"
+	}
+	for _, svcName := range filterServices(state, cmd.Args().Slice()) {
+		cName, ok := state.Containers[svcName]
+		if !ok {
+			report.Warnf("no container found for service %s", svcName)
+			continue
 		}
+		report.Infof("%s %s", verb, cName)
+		if err := runner.Run(cmd.Name, cName); err != nil {
+			report.Warnf("failed to %s %s: %v", cmd.Name, svcName, err)
+			continue
+		}
+		state.SetStatus(svcName, status)
+	}
+
+	return compose.SaveProject(state)
+}
+
+// defaultStopTimeout is how long a container gets to stop after its stop
+// signal when neither --timeout nor stop_grace_period is set.
+const defaultStopTimeout = 10 * time.Second
+
+// stopTimeout returns how long a service gets to stop: --timeout when given,
+// else its stop_grace_period.
+func stopTimeout(cmd *cli.Command, svc compose.Service) time.Duration {
+	if cmd != nil && cmd.IsSet("timeout") {
+		return time.Duration(cmd.Int("timeout")) * time.Second
+	}
+	if d, err := time.ParseDuration(svc.StopGracePeriod); err == nil {
+		return d
+	}
+	return defaultStopTimeout
+}
+
+// stopService stops a service's container: it sends the service's
+// stop_signal (SIGTERM by default), waits up to the stop timeout and then
+// kills the container, warning that it had to. cmd gives --timeout, and
+// may be nil for commands without one.
+func stopService(cmd *cli.Command, cc *composeContext, svcName, cName string) error {
+	svc := cc.composeFile.Services[svcName]
+	signal := cmp.Or(svc.StopSignal, "SIGTERM")
+	timeout := stopTimeout(cmd, svc)
+	if _, err := runner.Output("kill", "--signal", signal, cName); err != nil {
+		// Only a running container can be signalled.
+		if containerStatus(cName) != "running" {
+			return nil
+		}
+		return err
+	}
+	if waitStopped(cName, timeout) != "running" {
+		return nil
 	}
 
+	report.Warnf("%s did not stop within %s of %s, killing it", cName, timeout, signal)
+	if _, err := runner.Output("kill", cName); err != nil {
+		return err
+	}
+	if waitStopped(cName, killTimeout) == "running" {
+		_, err := runner.Output("stop", cName)
+		return err
+	}
 	return nil
 }
+
+// startReleased starts a stopped container once the host ports it
+// publishes are free again, waiting up to wait for them. The runtime may
+// hold a port a little longer than the host shows, so a failed start is
+// retried until wait passes.
+func startReleased(cName string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	var ports []runner.PublishedPort
+	if infos, err := runner.Inspect(cName); err == nil && len(infos) > 0 {
+		ports = infos[0].Configuration.PublishedPorts
+	}
+	for {
+		if taken := portcheck.WaitFree(ports, time.Until(deadline)); len(taken) > 0 {
+			report.Warnf("host port %d of %s is still in use after %s", taken[0].HostPort, cName, wait)
+		}
+		err := runner.RunInput(nil, "start", cName)
+		if err == nil || len(ports) == 0 || !time.Now().Before(deadline) {
+			return err
+		}
+		report.Infof("Retrying start of %s", cName)
+		time.Sleep(startRetryInterval)
+	}
+}
+
+// startRetryInterval is how long startReleased waits between starts.
+var startRetryInterval = 500 * time.Millisecond
+
+// killTimeout is how long kill waits for a killed container to stop before
+// falling back to stop. killPollInterval is how often it checks.
+var (
+	killTimeout      = 5 * time.Second
+	killPollInterval = 200 * time.Millisecond
+)
+
+// killSignal reports whether a kill --signal value is SIGKILL, in any of
+// the forms the runtime accepts.
+func killSignal(signal string) bool {
+	switch strings.TrimPrefix(strings.ToUpper(signal), "SIG") {
+	case "", "KILL", "9":
+		return true
+	}
+	return false
+}
+
+// waitStopped polls a container until it is no longer running or timeout
+// passes, and returns its last status; "" once it is gone.
+func waitStopped(cName string, timeout time.Duration) string {
+	deadline := time.Now().Add(timeout)
+	for {
+		status := containerStatus(cName)
+		if status != "running" || time.Now().After(deadline) {
+			return status
+		}
+		time.Sleep(killPollInterval)
+	}
+}
+
+// containerStatus returns a container's status, or "" when it does not
+// exist.
+func containerStatus(cName string) string {
+	infos, err := runner.Inspect(cName)
+	if err != nil || len(infos) == 0 {
+		return ""
+	}
+	return infos[0].Status
+}
+
+// recordStopped records that a service's container stopped on its own,
+// with its exit code when the backend reports one.
+func recordStopped(state *compose.ProjectState, svcName string) {
+	if code, ok := containerExitCode(state.Containers[svcName]); ok {
+		state.SetExited(svcName, code)
+		return
+	}
+	state.SetStatus(svcName, compose.StatusStopped)
+}
+
+// containerExitCode returns the exit code of a stopped container. Only the
+// Docker backend reports one.
+func containerExitCode(cName string) (int, bool) {
+	if !dockerBackend() {
+		return 0, false
+	}
+	out, err := runner.Output("inspect", "--format", "{{.State.ExitCode}}", cName)
+	if err != nil {
+		return 0, false
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(out))
+	return code, err == nil
+}
+
+// orphanContainers returns the project's containers whose services are no
+// longer defined, keyed by service name: those recorded in its state and
+// those the runtime listed under either naming scheme.
+func orphanContainers(project string, cf *compose.ComposeFile, infos []runner.ContainerInfo) map[string]string {
+	state, err := compose.LoadProject(project)
+	if err != nil {
+		state = &compose.ProjectState{Name: project}
+	}
+	orphans := make(map[string]string)
+	for svcName, cName := range state.Containers {
+		if _, ok := cf.Services[svcName]; !ok {
+			orphans[svcName] = cName
+		}
+	}
+	for svcName, cName := range listedContainers(state, infos) {
+		if _, ok := cf.Services[svcName]; !ok {
+			orphans[svcName] = cName
+		}
+	}
+	return orphans
+}
+
+// discoverContainers adds to the project state the containers of the
+// compose file's services that the runtime listed but the state does not
+// record, such as those created under the other naming scheme.
+func discoverContainers(state *compose.ProjectState, cf *compose.ComposeFile, infos []runner.ContainerInfo) {
+	for svcName, cName := range listedContainers(state, infos) {
+		if _, ok := cf.Services[svcName]; !ok {
+			continue
+		}
+		if state.Containers == nil {
+			state.Containers = make(map[string]string)
+		}
+		state.Containers[svcName] = cName
+	}
+}
+
+// discoverUnrecorded is discoverContainers with the runtime's listing,
+// which is only asked for when a service has no recorded container.
+func discoverUnrecorded(state *compose.ProjectState, cf *compose.ComposeFile) {
+	for svcName := range cf.Services {
+		if _, ok := state.Containers[svcName]; ok {
+			continue
+		}
+		if infos, err := runner.List(true); err == nil {
+			discoverContainers(state, cf, infos)
+		}
+		return
+	}
+}
+
+// listedContainers returns, keyed by service, the listed containers named
+// for the project under either naming scheme whose services have no
+// recorded container. One-off run containers are left out, as are
+// containers another project records, whose names may share the prefix.
+func listedContainers(state *compose.ProjectState, infos []runner.ContainerInfo) map[string]string {
+	claimed := make(map[string]bool)
+	for _, cName := range state.Containers {
+		claimed[cName] = true
+	}
+	names, _ := compose.ListProjects()
+	for _, name := range names {
+		if name == state.Name {
+			continue
+		}
+		if other, err := compose.LoadProject(name); err == nil {
+			for _, cName := range other.Containers {
+				claimed[cName] = true
+			}
+		}
+	}
+	found := make(map[string]string)
+	for _, info := range infos {
+		cName := info.Configuration.ID
+		if claimed[cName] {
+			continue
+		}
+		if _, ok := compose.RunContainerService(state.Name, cName); ok {
+			continue
+		}
+		svcName, ok := compose.ServiceFromContainerName(state.Name, cName)
+		if _, recorded := state.Containers[svcName]; !ok || recorded {
+			continue
+		}
+		if prev, ok := found[svcName]; !ok || cName < prev {
+			found[svcName] = cName
+		}
+	}
+	return found
+}
+
+// removeOrphans stops and deletes orphan containers. Their services are
+// gone, so they get the default stop signal and timeout.
+func removeOrphans(cmd *cli.Command, cc *composeContext, orphans map[string]string) {
+	for _, svcName := range sortedKeys(orphans) {
+		cName := orphans[svcName]
+		report.Infof("Removing orphan container %s", cName)
+		if err := stopService(cmd, cc, svcName, cName); err != nil {
+			report.Warnf("failed to stop %s: %v", cName, err)
+		}
+		if _, err := runner.Output("delete", cName); err != nil {
+			report.Warnf("failed to remove %s: %v", cName, err)
+		}
+	}
+}
+
+// ignoreOrphans reports whether COMPOSE_IGNORE_ORPHANS silences orphan warnings.
+func ignoreOrphans() bool {
+	ignore, _ := strconv.ParseBool(os.Getenv("COMPOSE_IGNORE_ORPHANS"))
+	return ignore
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedValues returns the values of m in sorted order.
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeConfigJSON(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n    ports: [\"8080:80\"]\n  db:\n    image: postgres\n")
+
+	config := func(args ...string) string {
+		t.Helper()
+		output := filepath.Join(dir, "out.json")
+		if err := runCompose(t, file, &runner.Recorder{}, append([]string{"config", "--format", "json", "--output", output}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got, want := config("--services"), "[\n  \"db\",\n  \"web\"\n]\n"; got != want {
+		t.Errorf("config --services = %q, want %q", got, want)
+	}
+	var model struct {
+		Services map[string]struct {
+			Image string   `json:"image"`
+			Ports []string `json:"ports"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal([]byte(config()), &model); err != nil {
+		t.Fatal(err)
+	}
+	if web := model.Services["web"]; web.Image != "nginx" || len(web.Ports) != 1 {
+		t.Errorf("config model = %+v", model)
+	}
+}
+
+func TestComposeProfileFiles(t *testing.T) {
+	dir, _ := newProject(t, "services:\n  web:\n    image: nginx\n")
+	writeFile(t, filepath.Join(dir, "compose.ci.yaml"), "services:\n  web:\n    image: nginx:ci\n  tests:\n    image: runner\n")
+
+	images := func(args ...string) string {
+		t.Helper()
+		output := filepath.Join(dir, "images.txt")
+		args = append([]string{"compose", "--project-directory", dir}, args...)
+		if err := runDctl(t, &runner.Recorder{}, append(args, "config", "--images", "--output", output)...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got, want := images(), "nginx\n"; got != want {
+		t.Errorf("images without a profile = %q, want %q", got, want)
+	}
+	if got, want := images("--profile", "ci"), "runner\nnginx:ci\n"; got != want {
+		t.Errorf("images with --profile ci = %q, want %q", got, want)
+	}
+	t.Setenv("COMPOSE_PROFILES", "dev,ci")
+	if got, want := images(), "runner\nnginx:ci\n"; got != want {
+		t.Errorf("images with COMPOSE_PROFILES = %q, want %q", got, want)
+	}
+	if got, want := images("-f", filepath.Join(dir, "compose.yaml")), "nginx\n"; got != want {
+		t.Errorf("images with -f = %q, want %q", got, want)
+	}
+}
+
+func TestComposeConfigImageDefaults(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n  worker:\n    image: nginx\n    command: [sleep, infinity]\n")
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"image", "inspect"}, `[{"Config":{"Entrypoint":["/docker-entrypoint.sh"],"Cmd":["nginx","-g","daemon off;"]}}]`, nil)
+	out := filepath.Join(dir, "config.json")
+	if err := runCompose(t, file, rec, "config", "--image-defaults", "--format", "json", "--output", out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var model struct {
+		Services map[string]struct {
+			Entrypoint []string `json:"entrypoint"`
+			Command    []string `json:"command"`
+		} `json:"services"`
+	}
+	if err := json.Unmarshal(data, &model); err != nil {
+		t.Fatal(err)
+	}
+	if web := model.Services["web"]; !slices.Equal(web.Entrypoint, []string{"/docker-entrypoint.sh"}) || !slices.Equal(web.Command, []string{"nginx", "-g", "daemon off;"}) {
+		t.Errorf("web = %+v, want the image's entrypoint and command", web)
+	}
+	if worker := model.Services["worker"]; worker.Entrypoint != nil || !slices.Equal(worker.Command, []string{"sleep", "infinity"}) {
+		t.Errorf("worker = %+v, want its own command only", worker)
+	}
+}
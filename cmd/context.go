@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/contexts"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// contextCommand returns the context command group.
+func contextCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "context",
+		Usage: "Manage backend contexts",
+		Commands: []*cli.Command{
+			{
+				Name:    "ls",
+				Aliases: []string{"list"},
+				Usage:   "List contexts",
+				Action:  contextListAction,
+			},
+			{
+				Name:      "use",
+				Usage:     "Set the current context",
+				ArgsUsage: "NAME",
+				Action:    contextUseAction,
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a context",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "backend", Usage: "Backend type: container or docker", Value: contexts.BackendContainer},
+					&cli.StringFlag{Name: "bin", Usage: "Path to the backend binary"},
+					&cli.StringFlag{Name: "state-dir", Usage: "Directory for dctl project state"},
+					&cli.StringSliceFlag{Name: "env", Aliases: []string{"e"}, Usage: "Environment variable for backend commands (KEY=VALUE)"},
+					&cli.StringFlag{Name: "description", Usage: "Context description"},
+				},
+				Action: contextCreateAction,
+			},
+			{
+				Name:      "rm",
+				Usage:     "Remove a context",
+				ArgsUsage: "NAME",
+				Action:    contextRemoveAction,
+			},
+		},
+	}
+}
+
+// applyContext activates the selected context before any command runs: its
+// variables go to the container CLI and its state directory to dctl. Docker
+// contexts hand invocations of compose and the docker-compatible commands
+// to the docker CLI unchanged; dctl's own commands, such as context, stack
+// and serve, run as usual.
+func applyContext(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	store, err := contexts.Load()
+	if err != nil {
+		return ctx, err
+	}
+	c, err := store.Active(cmd.String("context"))
+	if err != nil {
+		return ctx, err
+	}
+
+	runner.Env = nil
+	for _, k := range sortedKeys(c.Env) {
+		runner.Env = append(runner.Env, k+"="+c.Env[k])
+	}
+	compose.SetStateDir(c.StateDir)
+	switch {
+	case c.Bin != "":
+		runner.ContainerBin = c.Bin
+	case c.Backend == contexts.BackendDocker:
+		runner.ContainerBin = "docker"
+	}
+
+	if c.Backend == contexts.BackendDocker && forwardsToDocker(cmd.Args().First()) {
+		return ctx, handOff(runner.Run(cmd.Args().Slice()...))
+	}
+	return ctx, nil
+}
+
+// forwardsToDocker reports whether a docker context hands a top-level
+// command to the docker CLI: compose and the commands docker has too.
+func forwardsToDocker(name string) bool {
+	_, ok := dockerUsage[name]
+	return ok || name == "compose"
+}
+
+// handedOff ends an invocation the docker CLI ran in full. It exits with
+// the CLI's status, zero when it succeeded; see ExitCode.
+type handedOff struct {
+	err error
+}
+
+func (h handedOff) Error() string {
+	if h.err == nil {
+		return "handed off to docker"
+	}
+	return h.err.Error()
+}
+
+func (h handedOff) ExitCode() int {
+	if exitErr, ok := h.err.(interface{ ExitCode() int }); ok {
+		return exitErr.ExitCode()
+	}
+	return 0
+}
+
+func (h handedOff) Unwrap() error {
+	return h.err
+}
+
+// handOff returns the error that ends an invocation handed to docker. An
+// error other than the CLI's exit status, such as a missing binary, is
+// returned as is to be reported.
+func handOff(err error) error {
+	if _, ok := err.(interface{ ExitCode() int }); err != nil && !ok {
+		return err
+	}
+	return handedOff{err: err}
+}
+
+func contextListAction(ctx context.Context, cmd *cli.Command) error {
+	store, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	active, err := store.Active(cmd.Root().String("context"))
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tBACKEND\tBIN\tSTATE DIR\tDESCRIPTION")
+	for _, c := range store.List() {
+		name := c.Name
+		if name == active.Name {
+			name += " *"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, c.Backend, c.Bin, c.StateDir, c.Description)
+	}
+	return w.Flush()
+}
+
+func contextUseAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+	name := cmd.Args().First()
+
+	store, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	if err := store.Use(name); err != nil {
+		return err
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	report.Infof("Current context is now %q", name)
+	return nil
+}
+
+func contextCreateAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+
+	c := contexts.Context{
+		Name:        cmd.Args().First(),
+		Description: cmd.String("description"),
+		Backend:     cmd.String("backend"),
+		Bin:         cmd.String("bin"),
+		StateDir:    cmd.String("state-dir"),
+	}
+	for _, kv := range cmd.StringSlice("env") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("invalid --env %q: expected KEY=VALUE", kv)
+		}
+		if c.Env == nil {
+			c.Env = make(map[string]string)
+		}
+		c.Env[k] = v
+	}
+
+	store, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	if err := store.Create(c); err != nil {
+		return err
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	report.Infof("Created context %q", c.Name)
+	return nil
+}
+
+func contextRemoveAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+
+	store, err := contexts.Load()
+	if err != nil {
+		return err
+	}
+	if err := store.Remove(cmd.Args().First()); err != nil {
+		return err
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	report.Infof("Removed context %q", cmd.Args().First())
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestDockerContext(t *testing.T) {
+	newProject(t, "")
+	t.Setenv("DOCKER_HOST", "")
+	bin := runner.ContainerBin
+	stateDir := t.TempDir()
+	t.Cleanup(func() {
+		runner.ContainerBin, runner.Env = bin, nil
+		compose.SetStateDir("")
+	})
+	captureReport(t)
+	if err := runDctl(t, &runner.Recorder{}, "context", "create", "--backend", "docker", "--state-dir", stateDir, "-e", "DOCKER_HOST=ssh://box", "box"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &runner.Recorder{}
+	err := runDctl(t, rec, "--context", "box", "ps", "-a")
+	if code, report := ExitCode(err); code != 0 || report {
+		t.Errorf("ps under a docker context = %v, want a successful hand-off", err)
+	}
+	if want := [][]string{{"ps", "-a"}}; !reflect.DeepEqual(rec.Calls(), want) {
+		t.Errorf("calls = %q, want ps handed to docker unchanged", rec.Calls())
+	}
+	// The context's variables go to the docker CLI, not to dctl.
+	if !reflect.DeepEqual(runner.Env, []string{"DOCKER_HOST=ssh://box"}) || os.Getenv("DOCKER_HOST") != "" {
+		t.Errorf("runner env = %q, DOCKER_HOST = %q; want the variable passed to the CLI only", runner.Env, os.Getenv("DOCKER_HOST"))
+	}
+	if dir, _ := compose.StateDir(); dir != stateDir {
+		t.Errorf("state dir = %s, want the context's %s", dir, stateDir)
+	}
+
+	// dctl's own commands run as usual.
+	rec = &runner.Recorder{}
+	if err := runDctl(t, rec, "--context", "box", "context", "ls"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Calls()) > 0 {
+		t.Errorf("calls = %q, want context ls run by dctl", rec.Calls())
+	}
+}
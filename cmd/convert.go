@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sonnes/dctl/pkg/kube"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/urfave/cli/v3"
+)
+
+func init() { RegisterAlpha(convertCommand) }
+
+// convertCommand returns the compose convert command.
+func convertCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "convert",
+		Usage: "Convert the compose model to another format",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "Output format (k8s)", Value: "k8s"},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Write to file instead of stdout"},
+		},
+		Action: composeConvertAction,
+	}
+}
+
+func composeConvertAction(ctx context.Context, cmd *cli.Command) error {
+	if format := cmd.String("format"); format != "k8s" {
+		return fmt.Errorf("unsupported format %q (supported: k8s)", format)
+	}
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := kube.Convert(cc.composeFile, cc.projectName, cc.projectDir)
+	if err != nil {
+		return err
+	}
+	out, err := kube.Marshal(manifests)
+	if err != nil {
+		return err
+	}
+
+	if path := cmd.String("output"); path != "" {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		report.Infof("Wrote %d objects to %s", len(manifests), path)
+		return nil
+	}
+	fmt.Print(string(out))
+	return nil
+}
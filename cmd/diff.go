@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/drift"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+func composeDiffAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	cf := cc.composeFile
+
+	services := cmd.Args().Slice()
+	if len(services) == 0 {
+		if services, err = compose.ResolveOrder(cf.Services); err != nil {
+			return err
+		}
+	}
+
+	containers, err := runner.List(true)
+	if err != nil {
+		return err
+	}
+	live := make(map[string]runner.ContainerInfo)
+	observed := make(map[string]string)
+	for _, c := range containers {
+		live[c.Configuration.ID] = c
+		observed[c.Configuration.ID] = c.Status
+	}
+
+	targets := make([]compose.ServiceTarget, 0, len(services))
+	for _, svcName := range services {
+		args, err := cc.runArgs(svcName)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, compose.ServiceTarget{
+			Service:   svcName,
+			Container: cc.containerName(svcName),
+			Hash:      compose.ConfigHash(args),
+		})
+	}
+	prev, _ := compose.LoadProject(cc.projectName)
+	steps := compose.PlanServices(targets, prev, observed)
+
+	for i, t := range targets {
+		c, ok := live[t.Container]
+		if !ok {
+			fmt.Printf("%s: not created\n", t.Service)
+			continue
+		}
+
+		svc := cf.Services[t.Service]
+		svc.Image = serviceImage(cc.projectName, t.Service, svc)
+		env, err := compose.ServiceEnvironment(svc, nil)
+		if err != nil {
+			return err
+		}
+		changes := drift.Compare(svc, env, c)
+
+		step := steps[i]
+		status := "up-to-date"
+		if len(changes) > 0 {
+			status = "drifted"
+		}
+		if step.Action != compose.ActionUpToDate {
+			status += ", up will " + string(step.Action)
+			if step.Reason != "" {
+				status += " (" + step.Reason + ")"
+			}
+		}
+		fmt.Printf("%s: %s\n", t.Service, status)
+		for _, ch := range changes {
+			fmt.Printf("    %s\n", ch)
+		}
+	}
+	return nil
+}
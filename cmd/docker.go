@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sonnes/dctl/pkg/dockercli"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/secrets"
+	"github.com/urfave/cli/v3"
+)
+
+// dockerUsage describes each docker-compatible top-level command.
+var dockerUsage = map[string]string{
+	"build":   "Build an image (docker build)",
+	"exec":    "Execute a command in a running container (docker exec)",
+	"images":  "List images (docker images)",
+	"inspect": "Display container information (docker inspect)",
+	"kill":    "Kill running containers (docker kill)",
+	"load":    "Load an image from a tar archive (docker load)",
+	"logs":    "Fetch container logs (docker logs)",
+	"network": "Manage networks (docker network)",
+	"ps":      "List containers (docker ps)",
+	"pull":    "Pull an image (docker pull)",
+	"push":    "Push an image (docker push)",
+	"rm":      "Remove containers (docker rm)",
+	"rmi":     "Remove images (docker rmi)",
+	"run":     "Run a container (docker run)",
+	"save":    "Save an image to a tar archive (docker save)",
+	"start":   "Start stopped containers (docker start)",
+	"stop":    "Stop running containers (docker stop)",
+	"tag":     "Tag an image (docker tag)",
+	"volume":  "Manage volumes (docker volume)",
+}
+
+// dockerCommands returns top-level commands that accept docker CLI syntax
+// and translate it to the container CLI.
+func dockerCommands() []*cli.Command {
+	var cmds []*cli.Command
+	for _, name := range dockercli.Commands() {
+		cmds = append(cmds, &cli.Command{
+			Name:            name,
+			Usage:           dockerUsage[name],
+			ArgsUsage:       "[DOCKER ARGS...]",
+			SkipFlagParsing: true,
+			Action:          dockerPassthroughAction,
+		})
+	}
+	return cmds
+}
+
+func dockerPassthroughAction(ctx context.Context, cmd *cli.Command) error {
+	args, warnings, err := dockercli.Translate(cmd.Name, cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		report.Warnf("%s", w)
+	}
+	if cmd.Root().Bool("debug") {
+		fmt.Fprintf(os.Stderr, "+ %s\n", runner.CommandLine(secrets.Redact(args)...))
+	}
+	return runner.Run(args...)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/drift"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// composeEnvAction prints the environment a service's container gets, or
+// with --diff how the live container's differs from it.
+func composeEnvAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("service name required")
+	}
+	svcName := cmd.Args().First()
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	svc, ok := cc.composeFile.Services[svcName]
+	if !ok {
+		return compose.UnknownServiceError(svcName, sortedKeys(cc.composeFile.Services))
+	}
+	env, err := compose.ServiceEnvironment(svc, nil)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Bool("diff") {
+		for _, k := range sortedKeys(env) {
+			fmt.Printf("%s=%s\n", k, env[k])
+		}
+		return nil
+	}
+
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return err
+	}
+	cName, ok := state.Containers[svcName]
+	if !ok {
+		return fmt.Errorf("no container found for service %s", svcName)
+	}
+	infos, err := runner.Inspect(cName)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		return fmt.Errorf("container %s not found", cName)
+	}
+	changes := drift.CompareEnv(env, infos[0].Configuration.InitProcess.Environment)
+	if len(changes) == 0 {
+		report.Infof("%s has the environment of service %s", cName, svcName)
+		return nil
+	}
+	for _, ch := range changes {
+		fmt.Println(ch)
+	}
+	return fmt.Errorf("environment of %s differs from service %s", cName, svcName)
+}
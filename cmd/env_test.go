@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeEnvDiff(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n    env_file: web.env\n    environment:\n      TZ: ${TZ:-UTC}\n")
+	writeFile(t, filepath.Join(dir, "web.env"), "DEBUG=1\n")
+	b := captureReport(t)
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+
+	diff := func(environment string) error {
+		rec := &runner.Recorder{}
+		rec.Respond([]string{"inspect"}, `[{"status":"running","configuration":{"initProcess":{"environment":`+environment+`}}}]`, nil)
+		return runCompose(t, file, rec, "--project-directory", dir, "env", "--diff", "web")
+	}
+	if err := diff(`["PATH=/usr/bin","DEBUG=1","TZ=UTC"]`); err != nil {
+		t.Errorf("env --diff on a matching container: %v", err)
+	}
+	if !strings.Contains(b.String(), "shop_web has the environment of service web") {
+		t.Errorf("output = %q", b.String())
+	}
+	if err := diff(`["DEBUG=0","TZ=UTC"]`); err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Errorf("env --diff on a drifted container = %v, want a difference", err)
+	}
+}
+
+func TestComposeExecEnvFile(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n    env_file: web.env\n")
+	writeFile(t, filepath.Join(dir, "web.env"), "API_KEY=hunter2\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+
+	rec := &runner.Recorder{}
+	if err := runDctl(t, rec, "compose", "-f", file, "--project-directory", dir, "-p", "shop", "exec", "-T", "-e", "DEBUG=1", "web", "env"); err != nil {
+		t.Fatal(err)
+	}
+	execs := calls(rec, "exec")
+	if len(execs) != 1 {
+		t.Fatalf("execs = %q, want one", execs)
+	}
+	line := strings.Join(execs[0], " ")
+	if strings.Contains(line, "hunter2") || strings.Contains(line, "DEBUG=1") || !strings.Contains(line, "--env-file") {
+		t.Errorf("exec = %q, want the variables passed through an env file", line)
+	}
+	// The file lives only as long as the exec.
+	if _, err := os.Stat(execs[0][slices.Index(execs[0], "--env-file")+1]); err == nil {
+		t.Error("the exec's env file was left behind")
+	}
+}
+
+func TestComposeReadOnlyCommandsWriteNoEnvFiles(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    environment:\n      MODE: test\n      DB_PASSWORD: hunter2\n")
+	captureReport(t)
+
+	out, err := captureStdout(t, func() error { return runCompose(t, file, newRecorder(), "explain", "web") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// explain shows what the container gets, sensitive values masked.
+	if !strings.Contains(out, "MODE=test") || strings.Contains(out, "hunter2") || strings.Contains(out, "--env-file") {
+		t.Errorf("explain printed %q, want the environment inline and masked", out)
+	}
+	if err := runCompose(t, file, newRecorder(), "up", "--dry-run"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := captureStdout(t, func() error { return runCompose(t, file, newRecorder(), "config", "--hash", "*") }); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := compose.RunEnvDir("shop_web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("env files written by explain, up --dry-run or config --hash: %v", err)
+	}
+}
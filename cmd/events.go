@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/events"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// eventsInterval is how often compose events lists the project's
+// containers to find what changed.
+var eventsInterval = time.Second
+
+// composeEventsAction prints the events of the project's containers as
+// they happen, until interrupted. The runtime has no event stream, so the
+// containers are listed every eventsInterval and compared with the last
+// listing; changes that undo each other in between go unseen.
+func composeEventsAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	filter, err := events.ParseFilter(cmd.StringSlice("filter"))
+	if err != nil {
+		return err
+	}
+	services := cc.composeFile.Services
+	for _, svcName := range append(cmd.Args().Slice(), filter.Services...) {
+		if _, ok := services[svcName]; !ok {
+			return compose.UnknownServiceError(svcName, sortedKeys(services))
+		}
+	}
+	// Services given as arguments are service filters too.
+	filter.Services = append(filter.Services, cmd.Args().Slice()...)
+
+	names := make(map[string]string)
+	for svcName := range services {
+		names[cc.containerName(svcName)] = svcName
+	}
+	snapshot := func() (map[string]events.Container, error) {
+		infos, err := runner.List(true)
+		if err != nil {
+			return nil, err
+		}
+		containers := make(map[string]events.Container)
+		for _, info := range infos {
+			if svcName, ok := names[info.Configuration.ID]; ok {
+				containers[info.Configuration.ID] = events.Container{Service: svcName, Image: info.Configuration.Image.Reference, Status: info.Status}
+			}
+		}
+		return containers, nil
+	}
+
+	prev, err := snapshot()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(eventsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			cur, err := snapshot()
+			if err != nil {
+				report.Warnf("%v", err)
+				continue
+			}
+			for _, e := range events.Diff(prev, cur, now) {
+				if !filter.Match(e) {
+					continue
+				}
+				if err := printEvent(ctx, cmd, e); err != nil {
+					return err
+				}
+			}
+			prev = cur
+		}
+	}
+}
+
+// printEvent prints an event, as JSON with --json, and runs the --exec
+// command for it with the event's JSON on stdin and its action, service
+// and container in DCTL_EVENT_ACTION, DCTL_EVENT_SERVICE and
+// DCTL_EVENT_CONTAINER. A failed command is reported, not fatal.
+func printEvent(ctx context.Context, cmd *cli.Command, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	if cmd.Bool("json") {
+		fmt.Println(string(data))
+	} else {
+		fmt.Println(e.String())
+	}
+
+	hook := cmd.String("exec")
+	if hook == "" {
+		return nil
+	}
+	c := exec.CommandContext(ctx, "sh", "-c", hook)
+	c.Stdin = bytes.NewReader(append(data, '\n'))
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(),
+		"DCTL_EVENT_ACTION="+e.Action,
+		"DCTL_EVENT_SERVICE="+e.Service,
+		"DCTL_EVENT_CONTAINER="+e.ID,
+	)
+	if err := c.Run(); err != nil && ctx.Err() == nil {
+		report.Warnf("--exec for %s %s: %v", e.Action, e.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeEventsExec(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	defer func(d time.Duration) { eventsInterval = d }(eventsInterval)
+	eventsInterval = 10 * time.Millisecond
+	defer runner.SetBackend(nil)
+
+	list := func(web, db string) string {
+		return `[{"status":"` + web + `","configuration":{"id":"shop_web","image":{"reference":"nginx"}}},` +
+			`{"status":"` + db + `","configuration":{"id":"shop_db","image":{"reference":"postgres"}}},` +
+			`{"status":"running","configuration":{"id":"other_web","image":{"reference":"nginx"}}}]`
+	}
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, list("running", "running"), nil)
+
+	// events runs until cancelled, so the app runs in the background.
+	hooked := filepath.Join(dir, "hooked")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- NewApp(WithRunner(rec)).Run(ctx, []string{"dctl", "compose", "-f", file, "-p", "shop", "events",
+			"--filter", "type=stop", "--exec", `echo "$DCTL_EVENT_SERVICE $(cat)" >> ` + hooked})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	rec.Respond([]string{"list"}, list("stopped", "stopped"), nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(hooked)
+		if strings.Count(string(data), "\n") >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(hooked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `db {"time":`) || !strings.Contains(lines[0], `"action":"stop","id":"shop_db","service":"db"`) ||
+		!strings.HasPrefix(lines[1], `web {"time":`) {
+		t.Errorf("hook ran with %q", lines)
+	}
+}
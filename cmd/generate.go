@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// runtimeEnv lists variables the runtime injects into every container,
+// which are dropped from generated services.
+var runtimeEnv = map[string]bool{
+	"PATH":     true,
+	"HOSTNAME": true,
+	"HOME":     true,
+	"TERM":     true,
+}
+
+func composeGenerateAction(ctx context.Context, cmd *cli.Command) error {
+	var containers []runner.ContainerInfo
+	var err error
+	if cmd.Args().Len() > 0 {
+		containers, err = runner.Inspect(cmd.Args().Slice()...)
+	} else {
+		containers, err = runner.List(cmd.Bool("all"))
+	}
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("no containers found")
+	}
+
+	cf := &compose.ComposeFile{
+		Name:     cmd.String("project-name"),
+		Services: make(map[string]compose.Service),
+	}
+	for _, c := range containers {
+		name := generatedServiceName(c.Configuration.ID)
+		// Containers of the named project keep their service names in
+		// either naming scheme (project_service or project-service-1).
+		if svcName, ok := compose.ServiceFromContainerName(cf.Name, c.Configuration.ID); cf.Name != "" && ok {
+			name = generatedServiceName(svcName)
+		}
+		if _, exists := cf.Services[name]; exists {
+			return fmt.Errorf("containers map to duplicate service name %q", name)
+		}
+		cf.Services[name] = serviceFromContainer(c, cf)
+	}
+
+	out, err := yaml.Marshal(cf)
+	if err != nil {
+		return fmt.Errorf("marshaling compose file: %w", err)
+	}
+
+	if path := cmd.String("output"); path != "" {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		report.Infof("Wrote %d services to %s", len(cf.Services), path)
+		return nil
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+// serviceFromContainer reconstructs a service definition from a container.
+// Named volumes and non-default networks are registered on cf as external
+// resources so the generated project reuses them.
+func serviceFromContainer(c runner.ContainerInfo, cf *compose.ComposeFile) compose.Service {
+	cfg := c.Configuration
+	svc := compose.Service{
+		Image:      cfg.Image.Reference,
+		WorkingDir: cfg.InitProcess.WorkingDirectory,
+		Tty:        cfg.InitProcess.Terminal,
+		Labels:     cfg.Labels,
+	}
+	if svc.WorkingDir == "/" {
+		svc.WorkingDir = ""
+	}
+	if cfg.Hostname != "" && cfg.Hostname != cfg.ID {
+		svc.Hostname = cfg.Hostname
+	}
+
+	if cfg.InitProcess.Executable != "" {
+		svc.Entrypoint = []string{cfg.InitProcess.Executable}
+		if len(cfg.InitProcess.Arguments) > 0 {
+			svc.Command = cfg.InitProcess.Arguments
+		}
+	}
+
+	env := make(map[string]string)
+	for _, kv := range cfg.InitProcess.Environment {
+		k, v, _ := strings.Cut(kv, "=")
+		if !runtimeEnv[k] {
+			env[k] = v
+		}
+	}
+	if len(env) > 0 {
+		svc.Environment = env
+	}
+
+	for _, p := range cfg.PublishedPorts {
+		spec := strconv.Itoa(p.HostPort) + ":" + strconv.Itoa(p.ContainerPort)
+		if p.HostAddress != "" && p.HostAddress != "0.0.0.0" {
+			spec = p.HostAddress + ":" + spec
+		}
+		if p.Proto != "" && p.Proto != "tcp" {
+			spec += "/" + p.Proto
+		}
+		svc.Ports = append(svc.Ports, spec)
+	}
+
+	var tmpfs []string
+	for _, m := range cfg.Mounts {
+		switch m.Kind() {
+		case "tmpfs":
+			tmpfs = append(tmpfs, m.Destination)
+		case "volume":
+			vol := m.VolumeName()
+			if cf.Volumes == nil {
+				cf.Volumes = make(map[string]compose.VolumeConfig)
+			}
+			cf.Volumes[vol] = compose.VolumeConfig{External: true}
+			svc.Volumes = append(svc.Volumes, mountSpec(vol, m))
+		default:
+			svc.Volumes = append(svc.Volumes, mountSpec(m.Source, m))
+		}
+	}
+	if len(tmpfs) > 0 {
+		svc.Tmpfs = tmpfs
+	}
+
+	var nets []string
+	for _, n := range cfg.NetworkNames() {
+		if n == "" || n == "default" {
+			continue
+		}
+		if cf.Networks == nil {
+			cf.Networks = make(map[string]compose.Network)
+		}
+		cf.Networks[n] = compose.Network{External: true}
+		nets = append(nets, n)
+	}
+	if len(nets) > 0 {
+		sort.Strings(nets)
+		svc.Networks = nets
+	}
+
+	return svc
+}
+
+// mountSpec renders a short-syntax volume entry, keeping the read-only flag.
+func mountSpec(source string, m runner.Mount) string {
+	spec := source + ":" + m.Destination
+	for _, o := range m.Options {
+		if o == "ro" || o == "readonly" {
+			return spec + ":ro"
+		}
+	}
+	return spec
+}
+
+// generatedServiceName derives a compose service name from a container name.
+func generatedServiceName(id string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(id) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
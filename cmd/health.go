@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/probe"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// Health states reported by compose ps. Services without a check have
+// none.
+const (
+	healthHealthy   = "healthy"
+	healthUnhealthy = "unhealthy"
+	healthNone      = "none"
+)
+
+// healthTimeout bounds one health check when the service sets no timeout.
+const healthTimeout = 5 * time.Second
+
+// serviceHealth runs a running service's check once: its x-dctl-wait probe,
+// or else its healthcheck test, or its image's, inside the container. The runtime does not
+// run healthchecks itself, so the state is as of the call. A test that does
+// not finish within the healthcheck's timeout, or healthTimeout, fails.
+// Callers skip containers that are not running, which have no health.
+func serviceHealth(ctx context.Context, cc *composeContext, svcName, cName string) string {
+	wc, target, err := waitTarget(cc, svcName)
+	if err != nil {
+		return healthUnhealthy
+	}
+	if wc != nil {
+		timeout, _ := time.ParseDuration(wc.Timeout)
+		ctx, cancel := context.WithTimeout(ctx, cmp.Or(min(timeout, healthTimeout), healthTimeout))
+		defer cancel()
+		if probe.Check(ctx, target) != nil {
+			return healthUnhealthy
+		}
+		return healthHealthy
+	}
+
+	check := serviceHealthcheck(cc, svcName)
+	test := check.Command()
+	if test == nil {
+		return healthNone
+	}
+	timeout, _ := time.ParseDuration(check.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(timeout, healthTimeout))
+	defer cancel()
+	if _, err := runner.OutputContext(ctx, append([]string{"exec", cName}, test...)...); err != nil {
+		return healthUnhealthy
+	}
+	return healthHealthy
+}
+
+// serviceHealthcheck returns the healthcheck a service's container runs:
+// the service's own, with the image's test when it only sets timings, or
+// the image's when it sets none. It is nil when disable: true or test:
+// ["NONE"] turns checks off, the image's included.
+func serviceHealthcheck(cc *composeContext, svcName string) *compose.Healthcheck {
+	check := cc.composeFile.Services[svcName].Healthcheck
+	if check.Disabled() {
+		return nil
+	}
+	if check != nil && check.Test != nil {
+		return check
+	}
+	image := imageHealthcheck(cc, svcName)
+	if check == nil || image == nil {
+		return cmp.Or(image, check)
+	}
+	merged := *check
+	merged.Test = image.Test
+	return &merged
+}
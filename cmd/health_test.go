@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposePsHealthcheckInheritance(t *testing.T) {
+	_, file := newProject(t, "services:\n  cache:\n    image: redis\n    healthcheck:\n      start_interval: 1s\n  queue:\n    image: redis\n    healthcheck:\n      disable: true\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"cache": "shop_cache", "queue": "shop_queue"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_cache"}},{"status":"running","configuration":{"id":"shop_queue"}}]`, nil)
+	rec.Respond([]string{"image", "inspect"}, `[{"Id":"sha256:abc","Config":{"Healthcheck":{"Test":["CMD-SHELL","redis-cli ping"]}}}]`, nil)
+
+	out, err := captureStdout(t, func() error {
+		return runCompose(t, file, rec, "ps", "--format", "{{.Service}} {{.Health}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cache keeps the image's test with its own timings; disable: true
+	// turns off queue's image healthcheck.
+	if want := "cache healthy\nqueue \n"; out != want {
+		t.Errorf("ps printed %q, want %q", out, want)
+	}
+	if want := [][]string{{"exec", "shop_cache", "sh", "-c", "redis-cli ping"}}; !reflect.DeepEqual(calls(rec, "exec"), want) {
+		t.Errorf("execs = %q, want %q", calls(rec, "exec"), want)
+	}
+}
+
+// hangingExec is a Recorder whose exec commands never finish.
+type hangingExec struct {
+	*runner.Recorder
+	release chan struct{}
+}
+
+func (h hangingExec) Run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) > 0 && args[0] == "exec" {
+		<-h.release
+	}
+	return h.Recorder.Run(args, stdin, stdout, stderr)
+}
+
+func TestComposePsHealthcheckTimeoutAndStopped(t *testing.T) {
+	_, file := newProject(t, "services:\n  cache:\n    image: redis\n    healthcheck:\n      test: [CMD, redis-cli, ping]\n      timeout: 50ms\n  queue:\n    image: redis\n    healthcheck:\n      test: [CMD, true]\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"cache": "shop_cache", "queue": "shop_queue"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_cache"}},{"status":"stopped","configuration":{"id":"shop_queue"}}]`, nil)
+	backend := hangingExec{Recorder: rec, release: make(chan struct{})}
+	t.Cleanup(func() { close(backend.release) })
+	t.Cleanup(func() { runner.SetBackend(nil) })
+
+	start := time.Now()
+	out, err := captureStdout(t, func() error {
+		return NewApp(WithRunner(backend)).Run(context.Background(), []string{"dctl", "compose", "-f", file, "-p", "shop", "ps", "--format", "{{.Service}} {{.Health}}"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("ps took %v, want the hanging check cut off by its timeout", took)
+	}
+	// The hanging check times out; the stopped queue, whose check would
+	// pass, is not checked.
+	if want := "cache unhealthy\nqueue \n"; out != want {
+		t.Errorf("ps printed %q, want %q", out, want)
+	}
+}
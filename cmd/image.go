@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/prune"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// inheritsCommand reports whether a service leaves its command to its
+// image, setting neither command nor entrypoint.
+func inheritsCommand(svc compose.Service) bool {
+	return svc.Command == nil && svc.Entrypoint == nil
+}
+
+// imageConfig returns the configuration of a service's image. ok is false
+// when the image is not local, e.g. before it is pulled or built.
+func imageConfig(cc *composeContext, svcName string) (runner.ImageConfig, bool) {
+	img := serviceImage(cc.projectName, svcName, cc.composeFile.Services[svcName])
+	c, err := runner.InspectImage(img)
+	return c, err == nil
+}
+
+// applyImageDefaults writes the command and entrypoint services inherit
+// from their images into the model, for config --image-defaults. Services
+// whose image is not local are left as they are.
+func applyImageDefaults(cc *composeContext) {
+	for _, name := range sortedKeys(cc.composeFile.Services) {
+		svc := cc.composeFile.Services[name]
+		if !inheritsCommand(svc) {
+			continue
+		}
+		c, ok := imageConfig(cc, name)
+		if !ok {
+			continue
+		}
+		if len(c.Entrypoint) > 0 {
+			svc.Entrypoint = c.Entrypoint
+		}
+		if len(c.Cmd) > 0 {
+			svc.Command = c.Cmd
+		}
+		cc.composeFile.Services[name] = svc
+	}
+}
+
+// imageHealthcheck returns the HEALTHCHECK of a service's image, which
+// applies when the service configures no healthcheck of its own.
+func imageHealthcheck(cc *composeContext, svcName string) *compose.Healthcheck {
+	c, ok := imageConfig(cc, svcName)
+	if !ok || c.Healthcheck == nil {
+		return nil
+	}
+	test := make([]interface{}, len(c.Healthcheck.Test))
+	for i, arg := range c.Healthcheck.Test {
+		test[i] = arg
+	}
+	return &compose.Healthcheck{Test: test}
+}
+
+// recordImages notes images built or pulled for a project, for compose
+// images prune.
+func recordImages(project string, refs ...string) {
+	if len(refs) == 0 {
+		return
+	}
+	if err := compose.RecordImages(project, refs...); err != nil {
+		report.Warnf("recording project images: %v", err)
+	}
+}
+
+// missingImages returns the images of services that are not present
+// locally, which starting their containers pulls. It is empty when the
+// images cannot be listed.
+func missingImages(cc *composeContext, services []string) []string {
+	local, err := runner.ListImages()
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for _, svcName := range services {
+		image := serviceImage(cc.projectName, svcName, cc.composeFile.Services[svcName])
+		if !slices.ContainsFunc(local, func(i runner.ImageInfo) bool { return i.HasReference(image) }) && !slices.Contains(missing, image) {
+			missing = append(missing, image)
+		}
+	}
+	return missing
+}
+
+// sharedImages returns the qualified references of images other projects
+// recorded building or pulling, or whose recorded run arguments use them.
+func sharedImages(project string) (map[string]bool, error) {
+	shared := make(map[string]bool)
+	others, err := compose.ImageProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range others {
+		if other == project {
+			continue
+		}
+		refs, err := compose.ProjectImages(other)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			shared[runner.QualifyReference(ref)] = true
+		}
+	}
+	states, err := compose.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range states {
+		if other == project {
+			continue
+		}
+		state, err := compose.LoadProject(other)
+		if err != nil {
+			continue
+		}
+		// The image is the one argument of the run arguments that is a
+		// recorded reference, so every argument is a candidate.
+		for _, args := range state.RunArgs {
+			for _, arg := range args {
+				shared[runner.QualifyReference(arg)] = true
+			}
+		}
+	}
+	return shared, nil
+}
+
+// composeImagesPruneAction removes the images recorded for the project that
+// no service of the compose file uses any more, e.g. the old tag after a
+// version bump. Images a container still uses, of this project or another,
+// and images another project recorded or runs are kept.
+func composeImagesPruneAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	recorded, err := compose.ProjectImages(cc.projectName)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	for _, svcName := range sortedKeys(cc.composeFile.Services) {
+		current[runner.QualifyReference(serviceImage(cc.projectName, svcName, cc.composeFile.Services[svcName]))] = true
+	}
+	containers, err := runner.List(true)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, c := range containers {
+		used[runner.QualifyReference(c.Configuration.Image.Reference)] = true
+	}
+	shared, err := sharedImages(cc.projectName)
+	if err != nil {
+		return err
+	}
+	local, err := runner.ListImages()
+	if err != nil {
+		return err
+	}
+
+	var stale []runner.ImageInfo
+	var gone []string // recorded images removed some other way
+	for _, ref := range recorded {
+		q := runner.QualifyReference(ref)
+		if current[q] {
+			continue
+		}
+		i := slices.IndexFunc(local, func(img runner.ImageInfo) bool { return img.HasReference(ref) })
+		switch {
+		case i < 0:
+			gone = append(gone, ref)
+		case used[q]:
+			report.Infof("Keeping %s: a container uses it", ref)
+		case shared[q]:
+			report.Infof("Keeping %s: another project uses it", ref)
+		default:
+			img := local[i]
+			img.Reference = ref
+			stale = append(stale, img)
+		}
+	}
+	forget := func(refs []string) {
+		if err := compose.ForgetImages(cc.projectName, refs...); err != nil {
+			report.Warnf("recording project images: %v", err)
+		}
+	}
+	if len(stale) == 0 {
+		report.Infof("Nothing to prune")
+		forget(gone)
+		return nil
+	}
+
+	printPrunePlan(prune.Plan{Images: stale})
+	if cmd.Bool("dry-run") {
+		return nil
+	}
+	if !cmd.Bool("force") && !confirm("Are you sure you want to continue?") {
+		return nil
+	}
+	var reclaimed int64
+	for _, img := range stale {
+		if _, err := runner.Output("image", "delete", img.Reference); err != nil {
+			report.Warnf("failed to remove image %s: %v", img.Reference, err)
+			continue
+		}
+		reclaimed += img.Descriptor.Size
+		gone = append(gone, img.Reference)
+		fmt.Println(img.Reference)
+	}
+	forget(gone)
+	report.Infof("Total reclaimed space: %s", prune.HumanSize(reclaimed))
+	return nil
+}
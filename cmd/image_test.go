@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/oci"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposePullPolicy(t *testing.T) {
+	_, file := newProject(t, "")
+	captureReport(t)
+
+	reg := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		digests := map[string]string{"/v2/acme/app/manifests/1.0": "sha256:same", "/v2/acme/api/manifests/1.0": "sha256:new"}
+		if d, ok := digests[r.URL.Path]; ok {
+			w.Header().Set("Docker-Content-Digest", d)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer reg.Close()
+	defer func(c *http.Client) { oci.HTTPClient = c }(oci.HTTPClient)
+	oci.HTTPClient = reg.Client()
+	host := strings.TrimPrefix(reg.URL, "https://")
+
+	writeFile(t, file, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n  tool:\n    image: busybox\n    pull_policy: never\n"+
+		"  app:\n    image: "+host+"/acme/app:1.0\n  api:\n    image: "+host+"/acme/api:1.0\n")
+	pull := func(args ...string) []string {
+		rec := &runner.Recorder{}
+		rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/nginx:latest"},`+
+			`{"reference":"`+host+`/acme/app:1.0","descriptor":{"digest":"sha256:same"}},`+
+			`{"reference":"`+host+`/acme/api:1.0","descriptor":{"digest":"sha256:old"}}]`, nil)
+		if err := runCompose(t, file, rec, append([]string{"pull"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+		var pulled []string
+		for _, call := range calls(rec, "image") {
+			if len(call) == 3 && call[1] == "pull" {
+				pulled = append(pulled, call[2])
+			}
+		}
+		slices.Sort(pulled)
+		return pulled
+	}
+
+	app, api := host+"/acme/app:1.0", host+"/acme/api:1.0"
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{api, app, "nginx", "postgres"}},
+		{[]string{"--policy", "missing"}, []string{"postgres"}},
+		{[]string{"--refresh"}, []string{api, "nginx", "postgres"}},
+	}
+	for _, tt := range tests {
+		want := slices.Clone(tt.want)
+		slices.Sort(want)
+		if got := pull(tt.args...); !slices.Equal(got, want) {
+			t.Errorf("pull %v pulled %v, want %v", tt.args, got, want)
+		}
+	}
+}
+
+func TestComposeImagesPrune(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx:1.1\n")
+	if err := compose.RecordImages("shop", "nginx:1.0", "nginx:1.1", "redis", "postgres"); err != nil {
+		t.Fatal(err)
+	}
+	b := captureReport(t)
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"stopped","configuration":{"id":"cache","image":{"reference":"docker.io/library/redis:latest"}}}]`, nil)
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/nginx:1.0","descriptor":{"size":2048}},`+
+		`{"reference":"docker.io/library/nginx:1.1"},{"reference":"docker.io/library/redis:latest"}]`, nil)
+	if err := runCompose(t, file, rec, "images", "prune", "--force"); err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted [][]string
+	for _, call := range calls(rec, "image") {
+		if call[1] == "delete" {
+			deleted = append(deleted, call)
+		}
+	}
+	if want := [][]string{{"image", "delete", "nginx:1.0"}}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+	if !strings.Contains(b.String(), "Total reclaimed space: 2.05kB") {
+		t.Errorf("output = %q, want the reclaimed space", b.String())
+	}
+	refs, err := compose.ProjectImages("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nginx:1.1", "redis"}; !slices.Equal(refs, want) {
+		t.Errorf("recorded images = %v, want %v", refs, want)
+	}
+}
+
+func TestComposeImagesPruneKeepsSharedImages(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx:1.1\n")
+	if err := compose.RecordImages("shop", "nginx:1.0", "redis", "postgres"); err != nil {
+		t.Fatal(err)
+	}
+	// Another project recorded redis, and a third runs postgres.
+	if err := compose.RecordImages("blog", "redis"); err != nil {
+		t.Fatal(err)
+	}
+	saveState(t, &compose.ProjectState{Name: "wiki", RunArgs: map[string][]string{"db": {"run", "--name", "wiki_db", "postgres"}}})
+	captureReport(t)
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[]`, nil)
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/nginx:1.0"},`+
+		`{"reference":"docker.io/library/redis:latest"},{"reference":"docker.io/library/postgres:latest"}]`, nil)
+	if err := runCompose(t, file, rec, "images", "prune", "--force"); err != nil {
+		t.Fatal(err)
+	}
+
+	var deleted [][]string
+	for _, call := range calls(rec, "image") {
+		if call[1] == "delete" {
+			deleted = append(deleted, call)
+		}
+	}
+	if want := [][]string{{"image", "delete", "nginx:1.0"}}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+}
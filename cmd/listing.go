@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/format"
+	"github.com/sonnes/dctl/pkg/prune"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// ContainerSummary is a project container as ps --format templates see it.
+type ContainerSummary struct {
+	ID           string
+	Name         string
+	Image        string
+	Command      string
+	Project      string
+	Service      string
+	State        string // runtime status, e.g. running or stopped
+	Status       string // State with the health, e.g. "running (healthy)"
+	Health       string
+	Ports        string // published ports, e.g. "0.0.0.0:8080->80/tcp"
+	ExposedPorts string // ports reachable only from project networks
+	Networks     string
+}
+
+// psTable is the ps --format table layout.
+const psTable = `{{.Name}}\t{{.Image}}\t{{.Service}}\t{{.Status}}\t{{.Ports}}`
+
+// UpSummary is a service's row in the report up prints when it is done.
+type UpSummary struct {
+	Service string
+	Action  string // created, recreated, started or up-to-date
+	Time    string
+	Ports   string
+	Health  string
+}
+
+// upTable is the layout of the up report.
+const upTable = `{{.Service}}\t{{.Action}}\t{{.Time}}\t{{.Ports}}\t{{.Health}}`
+
+// ProjectSummary is a saved project as ls --format templates see it.
+type ProjectSummary struct {
+	Name        string
+	Status      string // service counts by recorded status, e.g. "running(2), stopped(1)"
+	ConfigFiles string
+	Dir         string
+}
+
+// lsTable is the ls output layout.
+const lsTable = `{{.Name}}\t{{.Status}}\t{{.ConfigFiles}}`
+
+// ImageSummary is the image of a project container as images --format
+// templates see it.
+type ImageSummary struct {
+	Container  string
+	Service    string
+	Repository string
+	Tag        string
+	ID         string // short digest
+	Size       string
+}
+
+// imagesTable is the images output layout.
+const imagesTable = `{{.Container}}\t{{.Repository}}\t{{.Tag}}\t{{.ID}}\t{{.Size}}`
+
+// containerSummary describes a listed project container.
+func containerSummary(project, service, health string, info runner.ContainerInfo, svc compose.Service) ContainerSummary {
+	s := ContainerSummary{
+		ID:       info.Configuration.ID,
+		Name:     info.Configuration.ID,
+		Image:    info.Configuration.Image.Reference,
+		Command:  strings.Join(append([]string{info.Configuration.InitProcess.Executable}, info.Configuration.InitProcess.Arguments...), " "),
+		Project:  project,
+		Service:  service,
+		State:    info.Status,
+		Status:   info.Status,
+		Networks: strings.Join(info.Configuration.NetworkNames(), ","),
+	}
+	if health != healthNone {
+		s.Health = health
+		s.Status += " (" + health + ")"
+	}
+	s.Ports = publishedPorts(info)
+	var exposed []string
+	for _, p := range compose.ExposedOnly(svc) {
+		exposed = append(exposed, p.String())
+	}
+	s.ExposedPorts = strings.Join(exposed, ", ")
+	return s
+}
+
+// publishedPorts lists a container's published ports the way docker does,
+// e.g. "0.0.0.0:8080->80/tcp, 0.0.0.0:8443->443/tcp".
+func publishedPorts(info runner.ContainerInfo) string {
+	var ports []string
+	for _, p := range info.Configuration.PublishedPorts {
+		host, proto := p.HostAddress, p.Proto
+		if host == "" {
+			host = "0.0.0.0"
+		}
+		if proto == "" {
+			proto = "tcp"
+		}
+		ports = append(ports, fmt.Sprintf("%s:%d->%d/%s", host, p.HostPort, p.ContainerPort, proto))
+	}
+	return strings.Join(ports, ", ")
+}
+
+// projectStatus summarizes the recorded statuses of a project's services,
+// e.g. "running(2), stopped(1)".
+func projectStatus(state *compose.ProjectState) string {
+	counts := make(map[string]int)
+	for svcName := range state.Containers {
+		status := state.StatusOf(svcName)
+		if status == "" {
+			status = "unknown"
+		}
+		counts[status]++
+	}
+	var parts []string
+	for _, status := range sortedKeys(counts) {
+		parts = append(parts, fmt.Sprintf("%s(%d)", status, counts[status]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// composeLsAction lists saved projects with their containers' statuses,
+// taken from one listing of the runtime's containers. When the runtime
+// cannot be reached, the statuses dctl last recorded are shown instead.
+func composeLsAction(ctx context.Context, cmd *cli.Command) error {
+	names, err := compose.ListProjects()
+	if err != nil {
+		return err
+	}
+	var live map[string]string // container name → status
+	if containers, err := runner.List(true); err != nil {
+		report.Warnf("%v; showing recorded statuses", err)
+	} else {
+		live = make(map[string]string, len(containers))
+		for _, c := range containers {
+			live[c.Configuration.ID] = c.Status
+		}
+	}
+
+	var projects []ProjectSummary
+	for _, name := range names {
+		state, err := compose.LoadProject(name)
+		if err != nil {
+			return err
+		}
+		if live != nil {
+			// Containers removed outside dctl are left out.
+			for svcName, cName := range state.Containers {
+				if status, ok := live[cName]; ok {
+					state.SetStatus(svcName, status)
+				} else {
+					delete(state.Containers, svcName)
+				}
+			}
+		}
+		running := false
+		for svcName := range state.Containers {
+			running = running || state.StatusOf(svcName) == compose.StatusRunning
+		}
+		if !running && !cmd.Bool("all") {
+			continue
+		}
+		if cmd.Bool("quiet") {
+			fmt.Println(state.Name)
+			continue
+		}
+		projects = append(projects, ProjectSummary{
+			Name:        state.Name,
+			Status:      projectStatus(state),
+			ConfigFiles: state.ComposeFile,
+			Dir:         state.ProjectDir,
+		})
+	}
+	if cmd.Bool("quiet") {
+		return nil
+	}
+	return format.Write(os.Stdout, cmp.Or(cmd.String("format"), "table"), lsTable, projects)
+}
+
+// composeImagesAction lists the images of the project's containers.
+func composeImagesAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return err
+	}
+	if err := checkServiceNames(cc, state, cmd.Args().Slice()); err != nil {
+		return err
+	}
+
+	var names []string
+	services := make(map[string]string)
+	for _, svcName := range filterServices(state, cmd.Args().Slice()) {
+		if cName, ok := state.Containers[svcName]; ok {
+			names = append(names, cName)
+			services[cName] = svcName
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	infos, err := runner.Inspect(names...)
+	if err != nil {
+		return err
+	}
+	images, err := runner.ListImages()
+	if err != nil {
+		return err
+	}
+
+	var rows []ImageSummary
+	for _, info := range infos {
+		ref := info.Configuration.Image.Reference
+		row := ImageSummary{Container: info.Configuration.ID, Service: services[info.Configuration.ID]}
+		row.Repository, row.Tag = splitReference(ref)
+		for _, image := range images {
+			if image.HasReference(ref) {
+				row.ID = shortDigest(image.Descriptor.Digest)
+				row.Size = prune.HumanSize(image.Descriptor.Size)
+				break
+			}
+		}
+		if cmd.Bool("quiet") {
+			fmt.Println(row.ID)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if cmd.Bool("quiet") {
+		return nil
+	}
+	return format.Write(os.Stdout, cmp.Or(cmd.String("format"), "table"), imagesTable, rows)
+}
+
+// splitReference splits an image reference into repository and tag; a
+// digest reference has no tag.
+func splitReference(ref string) (string, string) {
+	if name, _, ok := strings.Cut(ref, "@"); ok {
+		return name, ""
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+// shortDigest returns the first 12 hex digits of a digest, as image IDs
+// are shown.
+func shortDigest(digest string) string {
+	_, hex, _ := strings.Cut(digest, ":")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return hex
+}
+
+// PortSummary is a published port as port --format templates see it.
+type PortSummary struct {
+	Service     string
+	PrivatePort int
+	Protocol    string
+	HostIP      string
+	HostPort    int
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeLogsGrep(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}})
+
+	logs := func(args ...string) ([][]string, error) {
+		rec := &runner.Recorder{}
+		err := runCompose(t, file, rec, append([]string{"logs"}, args...)...)
+		return rec.Calls(), err
+	}
+	got, err := logs("--grep", "ERROR", "web=5\\d\\d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"logs", "shop_web"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if _, err := logs("--grep", "("); err == nil || !strings.Contains(err.Error(), "invalid --grep pattern") {
+		t.Errorf("err = %v, want an invalid --grep pattern", err)
+	}
+	if _, err := logs("wbe=x"); err == nil || !strings.Contains(err.Error(), "wbe") {
+		t.Errorf("err = %v, want the unknown service", err)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/urfave/cli/v3"
+)
+
+// parallelLimit returns the maximum number of concurrent container CLI
+// operations, from --parallel or COMPOSE_PARALLEL_LIMIT. Values below 1
+// mean unlimited.
+func parallelLimit(cmd *cli.Command) int {
+	return int(cmd.Int("parallel"))
+}
+
+// forEachParallel runs fn for every item, at most limit at a time, and
+// returns the errors joined in item order.
+func forEachParallel[T any](limit int, items []T, fn func(T) error) error {
+	if limit < 1 || limit > len(items) {
+		limit = len(items)
+	}
+
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(item)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// shutdownLevels groups the named services for stopping a group at a time:
+// dependents come before the services they depend on, and the services of
+// a group do not depend on each other. Services the compose file no longer
+// defines are stopped first.
+func shutdownLevels(services map[string]compose.Service, names []string) ([][]string, error) {
+	levels, err := compose.ResolveLevels(services)
+	if err != nil {
+		return nil, err
+	}
+	var undefined []string
+	for _, name := range names {
+		if _, ok := services[name]; !ok {
+			undefined = append(undefined, name)
+		}
+	}
+	sort.Strings(undefined)
+
+	var groups [][]string
+	if len(undefined) > 0 {
+		groups = append(groups, undefined)
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		var group []string
+		for _, name := range levels[i] {
+			if slices.Contains(names, name) {
+				group = append(group, name)
+			}
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/portcheck"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// upPlan is what `up` will do, computed before anything runs.
+type upPlan struct {
+	steps    []compose.Step
+	services map[string]compose.Step
+	runArgs  map[string][]string
+	hashes   map[string]string
+
+	containers []runner.ContainerInfo // all containers, as listed while planning
+	orphans    map[string]string      // containers of undefined services, by service
+}
+
+// planUp compares the compose model with saved state and the runtime.
+// force lists services to recreate regardless of their config hash; with
+// removeOrphans, the orphan containers are planned for removal.
+func planUp(cc *composeContext, order []string, prev *compose.ProjectState, force map[string]bool, removeOrphans bool) (*upPlan, error) {
+	cf := cc.composeFile
+	plan := &upPlan{
+		services: make(map[string]compose.Step),
+		runArgs:  make(map[string][]string),
+		hashes:   make(map[string]string),
+	}
+
+	networks, err := runner.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool)
+	for _, n := range networks {
+		existing[n.ID] = true
+	}
+	for _, name := range sortedKeys(cf.Networks) {
+		net := cf.Networks[name]
+		if net.External {
+			continue
+		}
+		if net.Name != "" {
+			name = net.Name
+		}
+		plan.steps = append(plan.steps, resourceStep("network", name, existing[name]))
+	}
+
+	volumes, err := runner.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+	existing = make(map[string]bool)
+	for _, v := range volumes {
+		existing[v.Name] = true
+	}
+	for _, name := range sortedKeys(cf.Volumes) {
+		vol := cf.Volumes[name]
+		if vol.External {
+			continue
+		}
+		if vol.Name != "" {
+			name = vol.Name
+		}
+		plan.steps = append(plan.steps, resourceStep("volume", name, existing[name]))
+	}
+
+	containers, err := runner.List(true)
+	if err != nil {
+		return nil, err
+	}
+	plan.containers = containers
+	plan.orphans = orphanContainers(cc.projectName, cf, containers)
+	observed := make(map[string]string)
+	for _, c := range containers {
+		observed[c.Configuration.ID] = c.Status
+	}
+
+	targets := make([]compose.ServiceTarget, 0, len(order))
+	for _, svcName := range order {
+		args, err := cc.runArgs(svcName)
+		if err != nil {
+			return nil, err
+		}
+		hash := compose.ConfigHash(args)
+		plan.runArgs[svcName] = args
+		plan.hashes[svcName] = hash
+		targets = append(targets, compose.ServiceTarget{
+			Service:   svcName,
+			Container: cc.containerName(svcName),
+			Hash:      hash,
+			Force:     force[svcName],
+		})
+	}
+	for _, step := range compose.PlanServices(targets, prev, observed) {
+		plan.services[step.Name] = step
+		plan.steps = append(plan.steps, step)
+	}
+
+	if removeOrphans {
+		for _, cName := range sortedValues(plan.orphans) {
+			plan.steps = append(plan.steps, compose.Step{Kind: "container", Name: cName, Action: compose.ActionRemoveOrphan})
+		}
+	}
+	return plan, nil
+}
+
+// checkPorts fails when a service that up will create, recreate or start
+// asks for a host port that is already taken. The project's own
+// containers do not count, as recreating them releases their ports.
+func checkPorts(cc *composeContext, plan *upPlan, prev *compose.ProjectState) error {
+	own := make(map[string]bool)
+	if prev != nil {
+		for _, cName := range prev.Containers {
+			own[cName] = true
+		}
+	}
+
+	var reqs []portcheck.Request
+	for _, svcName := range sortedKeys(plan.services) {
+		own[cc.containerName(svcName)] = true
+		if plan.services[svcName].Action == compose.ActionUpToDate {
+			continue
+		}
+		ports, err := compose.ParsePorts(cc.composeFile.Services[svcName].Ports)
+		if err != nil {
+			return fmt.Errorf("service %s: %w", svcName, err)
+		}
+		for _, p := range ports {
+			reqs = append(reqs, portcheck.Request{Service: svcName, Port: p})
+		}
+	}
+
+	conflicts := portcheck.Check(reqs, plan.containers, own)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	lines := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		lines[i] = c.String()
+	}
+	return fmt.Errorf("port conflicts:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// checkContainerNames fails when a container name a service wants is held
+// by another owner: a service of another project, as its state records, or
+// for a service that sets container_name, a container the project did not
+// create. A listed container with a name the naming scheme gives is taken to
+// be the project's own, as up does.
+func checkContainerNames(cc *composeContext, containers []runner.ContainerInfo, prev *compose.ProjectState) error {
+	owners := make(map[string]string)
+	projects, err := compose.ListProjects()
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		if project == cc.projectName {
+			continue
+		}
+		state, err := compose.LoadProject(project)
+		if err != nil {
+			continue
+		}
+		for svcName, cName := range state.Containers {
+			owners[cName] = fmt.Sprintf("service %s of project %s", svcName, project)
+		}
+	}
+	own := make(map[string]bool)
+	if prev != nil {
+		for _, cName := range prev.Containers {
+			own[cName] = true
+		}
+	}
+	listed := make(map[string]bool)
+	for _, c := range containers {
+		listed[c.Configuration.ID] = true
+	}
+
+	var conflicts []string
+	for _, svcName := range sortedKeys(cc.composeFile.Services) {
+		cName := cc.containerName(svcName)
+		owner, taken := owners[cName]
+		if !taken && cc.composeFile.Services[svcName].ContainerName != "" && listed[cName] && !own[cName] {
+			owner, taken = "a container not created by dctl", true
+		}
+		if taken {
+			conflicts = append(conflicts, fmt.Sprintf("%s: wanted by service %s of project %s, held by %s", cName, svcName, cc.projectName, owner))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("container name conflicts:\n  %s\nchange container_name, or remove the other container", strings.Join(conflicts, "\n  "))
+}
+
+// checkImagePlatforms fails when a local image a service is about to be
+// created from has no variant for the platform it runs on, which would
+// otherwise surface as an exec format error inside the container. Images up
+// builds are built for that platform and missing ones are pulled for it, so
+// both are skipped.
+func checkImagePlatforms(cc *composeContext, plan *upPlan, builds []string) error {
+	var mismatches []string
+	for _, svcName := range sortedKeys(plan.services) {
+		action := plan.services[svcName].Action
+		if action != compose.ActionCreate && action != compose.ActionRecreate || slices.Contains(builds, svcName) {
+			continue
+		}
+		svc := cc.composeFile.Services[svcName]
+		img := serviceImage(cc.projectName, svcName, svc)
+		platforms, err := runner.InspectImagePlatforms(img)
+		if err != nil || len(platforms) == 0 {
+			continue
+		}
+		want := cmp.Or(servicePlatform(svc), "linux/"+runtime.GOARCH)
+		if !slices.Contains(platforms, trimVariant(want)) {
+			mismatches = append(mismatches, fmt.Sprintf("service %s: image %s is %s, not %s", svcName, img, strings.Join(platforms, ", "), want))
+		}
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	return fmt.Errorf("images for the wrong platform:\n  %s\npull them for the platform (dctl compose pull), or set the service's platform to one the image has to run it under emulation", strings.Join(mismatches, "\n  "))
+}
+
+// trimVariant trims a platform to os/arch: linux/arm64/v8 is linux/arm64.
+func trimVariant(platform string) string {
+	if parts := strings.SplitN(platform, "/", 3); len(parts) == 3 {
+		return parts[0] + "/" + parts[1]
+	}
+	return platform
+}
+
+func resourceStep(kind, name string, exists bool) compose.Step {
+	if exists {
+		return compose.Step{Kind: kind, Name: name, Action: compose.ActionUpToDate}
+	}
+	return compose.Step{Kind: kind, Name: name, Action: compose.ActionCreate}
+}
+
+// actionSymbols prefix plan lines, in the style of terraform plan.
+var actionSymbols = map[compose.Action]string{
+	compose.ActionCreate:       "+",
+	compose.ActionRecreate:     "~",
+	compose.ActionStart:        ">",
+	compose.ActionUpToDate:     "=",
+	compose.ActionRemoveOrphan: "-",
+}
+
+// printPlan writes the plan and a one-line summary to stdout.
+func printPlan(project string, steps []compose.Step) {
+	fmt.Printf("Planned actions for project %s:\n", project)
+	counts := make(map[compose.Action]int)
+	for _, s := range steps {
+		counts[s.Action]++
+		line := fmt.Sprintf("  %s %-9s %-24s %s", actionSymbols[s.Action], s.Kind, s.Name, s.Action)
+		if s.Reason != "" {
+			line += " (" + s.Reason + ")"
+		}
+		fmt.Println(line)
+	}
+
+	var summary []string
+	for _, a := range []compose.Action{compose.ActionCreate, compose.ActionRecreate, compose.ActionStart, compose.ActionRemoveOrphan, compose.ActionUpToDate} {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[a], a))
+	}
+	fmt.Printf("\nPlan: %s.\n", strings.Join(summary, ", "))
+}
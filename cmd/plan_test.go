@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeContainerNameConflicts(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    container_name: front\n  db:\n    image: postgres\n    container_name: db-main\n  cache:\n    image: redis\n")
+	saveState(t, &compose.ProjectState{Name: "store", Containers: map[string]string{"proxy": "front"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"db-main"}},{"status":"running","configuration":{"id":"shop_cache"}}]`, nil)
+
+	captureReport(t)
+	want := "container name conflicts:\n" +
+		"  db-main: wanted by service db of project shop, held by a container not created by dctl\n" +
+		"  front: wanted by service web of project shop, held by service proxy of project store\n"
+	for _, args := range [][]string{{"config", "-q"}, {"up", "-d"}} {
+		err := runCompose(t, file, rec, args...)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", args[0], err, want)
+		}
+	}
+	if runs := calls(rec, "run"); len(runs) > 0 {
+		t.Errorf("up ran %v despite the conflicts", runs)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// pluginPrefix names external subcommands: `dctl foo` runs dctl-foo.
+const pluginPrefix = "dctl-"
+
+// pluginAction runs when no built-in command matches. It replaces dctl
+// with the dctl-<name> binary on PATH, passing the remaining arguments and
+// describing the project in the environment.
+func pluginAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() == 0 {
+		return cli.ShowAppHelp(cmd)
+	}
+	name := cmd.Args().First()
+	bin, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return fmt.Errorf("unknown command %q (no %s%s on PATH); see 'dctl --help'", name, pluginPrefix, name)
+	}
+
+	argv := append([]string{filepath.Base(bin)}, cmd.Args().Tail()...)
+	env := append(os.Environ(), pluginEnv()...)
+	return syscall.Exec(bin, argv, env)
+}
+
+// PluginArgs prepares os.Args for app.Run so that flags after an unknown
+// command reach its plugin instead of failing root flag parsing. Root flags
+// before the command still apply; a "--" is inserted before the command.
+func PluginArgs(app *cli.Command, args []string) []string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if app.Command(arg) != nil || arg == "help" || arg == "h" {
+				return args
+			}
+			return slices.Concat(args[:i], []string{"--"}, args[i:])
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		fl := rootFlag(app, name)
+		if fl == nil {
+			return args
+		}
+		if tv, ok := fl.(interface{ TakesValue() bool }); ok && tv.TakesValue() && !hasValue {
+			i++
+		}
+	}
+	return args
+}
+
+// rootFlag returns the root flag with the given name or alias.
+func rootFlag(app *cli.Command, name string) cli.Flag {
+	for _, fl := range app.Flags {
+		if slices.Contains(fl.Names(), name) {
+			return fl
+		}
+	}
+	return nil
+}
+
+// pluginEnv describes dctl and, when the working directory holds a
+// compose project, that project.
+func pluginEnv() []string {
+	env := []string{
+		"DCTL_VERSION=" + Version,
+		"DCTL_CONTAINER_BIN=" + runner.ContainerBin,
+	}
+	// The context's variables reach the plugin's container CLI commands too.
+	env = append(env, runner.Env...)
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "DCTL_BIN="+exe)
+	}
+	if dir, err := compose.StateDir(); err == nil {
+		env = append(env, "DCTL_STATE_DIR="+dir)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return env
+	}
+	files, err := compose.ResolveFiles(nil, wd)
+	if err != nil {
+		return env
+	}
+	env = append(env,
+		"DCTL_PROJECT_DIR="+wd,
+		"DCTL_COMPOSE_FILES="+strings.Join(files, string(os.PathListSeparator)),
+	)
+	if cf, err := compose.Load(files, wd); err == nil {
+		if name, err := compose.ResolveProjectName("", cf, wd); err == nil {
+			env = append(env, "DCTL_PROJECT_NAME="+name)
+		}
+	}
+	return env
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeStatusTracking(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	state := &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}}
+	state.SetStatus("web", compose.StatusRunning)
+	state.SetStatus("db", compose.StatusRunning)
+	saveState(t, state)
+	run := func(rec *runner.Recorder, args ...string) *compose.ProjectState {
+		t.Helper()
+		if err := runCompose(t, file, rec, args...); err != nil {
+			t.Fatal(err)
+		}
+		return loadState(t, "shop")
+	}
+
+	state = run(&runner.Recorder{}, "stop", "db")
+	if state.StatusOf("db") != compose.StatusStopped || state.StatusOf("web") != compose.StatusRunning {
+		t.Errorf("after stop, status = %v", state.Status)
+	}
+
+	// ps records what the runtime lists; a missing running service has stopped.
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"configuration":{"id":"shop_db"},"status":"running"}]`, nil)
+	state = run(rec, "ps")
+	if state.StatusOf("db") != compose.StatusRunning || state.StatusOf("web") != compose.StatusStopped {
+		t.Errorf("after ps, status = %v", state.Status)
+	}
+
+	// ps --cached does not ask the runtime.
+	rec = &runner.Recorder{}
+	run(rec, "ps", "--cached")
+	if calls := rec.Calls(); len(calls) != 0 {
+		t.Errorf("ps --cached ran %v", calls)
+	}
+
+	state = run(&runner.Recorder{}, "rm", "web")
+	if _, ok := state.Containers["web"]; ok || state.StatusOf("web") != "" {
+		t.Errorf("after rm, containers = %v, status = %v", state.Containers, state.Status)
+	}
+}
+
+func TestComposePauseAndExitStatus(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	state := &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}}
+	state.SetStatus("web", compose.StatusRunning)
+	state.SetStatus("db", compose.StatusRunning)
+	saveState(t, state)
+	captureReport(t)
+	bin := runner.ContainerBin
+	t.Cleanup(func() { runner.ContainerBin = bin })
+
+	// The container runtime cannot pause.
+	if err := runCompose(t, file, &runner.Recorder{}, "pause"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("pause on the runtime = %v, want an unsupported error", err)
+	}
+
+	runner.ContainerBin = "docker"
+	rec := &runner.Recorder{}
+	if err := runCompose(t, file, rec, "pause", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"pause", "shop_web"}}; !reflect.DeepEqual(rec.Calls(), want) {
+		t.Errorf("commands = %v, want %v", rec.Calls(), want)
+	}
+	if got := loadState(t, "shop").StatusOf("web"); got != compose.StatusPaused {
+		t.Errorf("after pause, web = %q, want %q", got, compose.StatusPaused)
+	}
+	if err := runCompose(t, file, &runner.Recorder{}, "unpause", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadState(t, "shop").StatusOf("web"); got != compose.StatusRunning {
+		t.Errorf("after unpause, web = %q, want %q", got, compose.StatusRunning)
+	}
+
+	// A service that is no longer listed exited, with the code Docker reports.
+	rec = &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"configuration":{"id":"shop_web"},"status":"running"}]`, nil)
+	rec.Respond([]string{"inspect", "--format"}, "3\n", nil)
+	if err := runCompose(t, file, rec, "ps"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadState(t, "shop").Status["db"]; got.String() != "exited (3)" {
+		t.Errorf("after ps, db = %+v, want exited (3)", got)
+	}
+	out, err := captureStdout(t, func() error {
+		return runCompose(t, file, &runner.Recorder{}, "ps", "--cached", "--format", "{{.Service}} {{.Status}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "db exited (3)") {
+		t.Errorf("ps --cached = %q, want db exited (3)", out)
+	}
+}
+
+func TestComposePsOrphans(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "worker": "shop_worker"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web","image":{"reference":"nginx"}}},`+
+		`{"status":"stopped","configuration":{"id":"shop_worker","image":{"reference":"app"}}}]`, nil)
+
+	out, err := captureStdout(t, func() error {
+		return runCompose(t, file, rec, "ps", "--orphans", "--format", "{{.Name}} {{.Service}} {{.State}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "shop_worker worker stopped\n"; out != want {
+		t.Errorf("ps --orphans printed %q, want %q", out, want)
+	}
+	if calls := rec.Calls(); !reflect.DeepEqual(calls[0], []string{"list", "--format", "json", "--all"}) {
+		t.Errorf("first call = %v, want a list of all containers", calls[0])
+	}
+	// The orphan's status is not recorded as its service's.
+	if state := loadState(t, "shop"); state.StatusOf("worker") != "" {
+		t.Errorf("worker status = %q, want none recorded", state.StatusOf("worker"))
+	}
+}
+
+func TestComposeDiscoversEitherNamingScheme(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	saveState(t, &compose.ProjectState{Name: "shop_admin", Containers: map[string]string{"api": "shop_admin_api"}})
+	listing := `[{"status":"running","configuration":{"id":"shop_web"}},` +
+		`{"status":"running","configuration":{"id":"shop-db-1"}},` +
+		`{"status":"running","configuration":{"id":"shop-worker-1"}},` +
+		`{"status":"running","configuration":{"id":"shop-db-run-ab12"}},` +
+		`{"status":"running","configuration":{"id":"shop_admin_api"}}]`
+	newRec := func() *runner.Recorder {
+		rec := &runner.Recorder{}
+		rec.Respond([]string{"list"}, listing, nil)
+		return rec
+	}
+	reset := func() {
+		saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+	}
+	captureReport(t)
+
+	// Containers named under the other scheme belong to their services;
+	// run containers and other projects' containers do not.
+	reset()
+	out, err := captureStdout(t, func() error {
+		return runCompose(t, file, newRec(), "ps", "--format", "{{.Name}} {{.Service}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "shop_web web\nshop-db-1 db\n"; out != want {
+		t.Errorf("ps printed %q, want %q", out, want)
+	}
+	out, err = captureStdout(t, func() error {
+		return runCompose(t, file, newRec(), "ps", "--orphans", "--format", "{{.Name}} {{.Service}}")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "shop-worker-1 worker\n"; out != want {
+		t.Errorf("ps --orphans printed %q, want %q", out, want)
+	}
+
+	reset()
+	rec := newRec()
+	if err := runCompose(t, file, rec, "logs", "db"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := calls(rec, "logs"), [][]string{{"logs", "shop-db-1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logs calls = %v, want %v", got, want)
+	}
+
+	reset()
+	rec = newRec()
+	if err := runCompose(t, file, rec, "down"); err != nil {
+		t.Fatal(err)
+	}
+	// Services at the same level are taken down concurrently.
+	got := calls(rec, "delete")
+	slices.SortFunc(got, func(a, b []string) int { return strings.Compare(a[1], b[1]) })
+	if want := [][]string{{"delete", "shop-db-1"}, {"delete", "shop_web"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("down deleted %v, want %v", got, want)
+	}
+}
+
+func TestComposeLsReconcilesStatus(t *testing.T) {
+	newProject(t, "")
+	shop := &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}}
+	shop.SetStatus("web", compose.StatusRunning)
+	shop.SetStatus("db", compose.StatusRunning)
+	saveState(t, shop)
+	blog := &compose.ProjectState{Name: "blog", Containers: map[string]string{"web": "blog_web"}}
+	blog.SetStatus("web", compose.StatusStopped)
+	saveState(t, blog)
+	captureReport(t)
+
+	ls := func(rec *runner.Recorder, args ...string) string {
+		t.Helper()
+		out, err := captureStdout(t, func() error {
+			return runDctl(t, rec, append([]string{"compose", "ls", "--format", "{{.Name}} {{.Status}}"}, args...)...)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// shop's web stopped and its db was removed behind dctl's back; blog
+	// was started.
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"list"}, `[{"status":"stopped","configuration":{"id":"shop_web"}},{"status":"running","configuration":{"id":"blog_web"}}]`, nil)
+	if got, want := ls(rec), "blog running(1)\n"; got != want {
+		t.Errorf("ls printed %q, want %q", got, want)
+	}
+	if got, want := ls(rec, "--all"), "blog running(1)\nshop stopped(1)\n"; got != want {
+		t.Errorf("ls --all printed %q, want %q", got, want)
+	}
+	if want := [][]string{{"list", "--format", "json", "--all"}, {"list", "--format", "json", "--all"}}; !reflect.DeepEqual(rec.Calls(), want) {
+		t.Errorf("calls = %v, want one listing per ls", rec.Calls())
+	}
+
+	// Without the runtime, the recorded statuses are shown.
+	rec = &runner.Recorder{}
+	rec.Respond([]string{"list"}, "", &runner.ExitError{Code: 1})
+	if got, want := ls(rec), "shop running(2)\n"; got != want {
+		t.Errorf("ls without the runtime printed %q, want %q", got, want)
+	}
+}
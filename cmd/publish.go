@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/oci"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/urfave/cli/v3"
+)
+
+func init() { RegisterAlpha(publishCommand) }
+
+// publishCommand returns the compose publish command.
+func publishCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "publish",
+		Usage:     "Publish the compose files as an OCI artifact",
+		ArgsUsage: "REPOSITORY[:TAG]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "with-env", Usage: "Include the environment file as variable defaults"},
+		},
+		Action: composePublishAction,
+	}
+}
+
+func composePublishAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: REPOSITORY[:TAG]")
+	}
+
+	ref, err := oci.ParseReference(cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	// Validate the project before publishing it
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	for svcName, svc := range cc.composeFile.Services {
+		if svc.Image == "" {
+			return fmt.Errorf("service %s has no image; published projects cannot build images", svcName)
+		}
+	}
+
+	paths, err := compose.ResolveFiles(cc.files, cc.projectDir)
+	if err != nil {
+		return err
+	}
+
+	var layers []oci.Layer
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		layers = append(layers, oci.Layer{MediaType: oci.ComposeMediaType, Name: filepath.Base(path), Data: data})
+	}
+
+	if cmd.Bool("with-env") {
+		envPath := cmd.String("env-file")
+		if envPath == "" {
+			envPath = ".env"
+		}
+		if !filepath.IsAbs(envPath) {
+			envPath = filepath.Join(cc.projectDir, envPath)
+		}
+		data, err := os.ReadFile(envPath)
+		if err != nil {
+			return fmt.Errorf("reading env file: %w", err)
+		}
+		layers = append(layers, oci.Layer{MediaType: oci.EnvFileMediaType, Name: filepath.Base(envPath), Data: data})
+	}
+
+	report.Infof("Publishing %s", ref)
+	digest, err := oci.Push(ref, layers)
+	if err != nil {
+		return fmt.Errorf("publishing %s: %w", ref, err)
+	}
+	fmt.Println(ref.Registry + "/" + ref.Repository + "@" + digest)
+	return nil
+}
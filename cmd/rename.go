@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/stack"
+	"github.com/urfave/cli/v3"
+)
+
+// composeRenameAction renames a project: its state and image record move to
+// the new name, its containers are replaced by ones named for it, and the
+// networks and volumes it created stay its own. Those are named by their
+// compose keys rather than by project, so volume data survives. Images of
+// services that only define a build are retagged rather than rebuilt, and
+// services that were not running are stopped again afterwards.
+func composeRenameAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 2 {
+		return fmt.Errorf("requires exactly 2 arguments: OLD NEW")
+	}
+	oldName, newName := cmd.Args().Get(0), cmd.Args().Get(1)
+	if err := compose.ValidateProjectName(newName); err != nil {
+		return err
+	}
+	state, err := compose.LoadProject(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err := compose.LoadProject(newName); err == nil {
+		return fmt.Errorf("project %q already exists", newName)
+	}
+
+	// The project is brought up again from where up last ran it, with the
+	// same files, profiles and environment file. It must still load before
+	// any of the old containers are removed.
+	p := stack.Project{Path: state.ProjectDir, Files: state.Files(), Profiles: state.Profiles, EnvFile: state.EnvFile}
+	cf, err := compose.Load(p.Files, p.Path)
+	if err != nil {
+		return fmt.Errorf("loading project %s: %w", oldName, err)
+	}
+
+	// Services whose containers exist but are not running are stopped
+	// again once renamed.
+	infos, err := runner.List(true)
+	if err != nil {
+		return err
+	}
+	running := make(map[string]bool)
+	for _, info := range infos {
+		running[info.Configuration.ID] = info.Status == "running"
+	}
+	var stopped []string
+	for _, svcName := range sortedKeys(state.Containers) {
+		isRunning, listed := running[state.Containers[svcName]]
+		if _, ok := cf.Services[svcName]; ok && listed && !isRunning {
+			stopped = append(stopped, svcName)
+		}
+	}
+
+	oldCC := &composeContext{projectDir: p.Path, files: p.Files, composeFile: cf, projectName: oldName, naming: state.Naming}
+	levels, err := shutdownLevels(cf.Services, sortedKeys(state.Containers))
+	if err != nil {
+		return err
+	}
+	for _, level := range levels {
+		if err := forEachParallel(parallelLimit(cmd), level, func(svcName string) error {
+			cName := state.Containers[svcName]
+			// A rename that failed to bring the new project up leaves the
+			// old state recording containers it already removed.
+			if _, ok := running[cName]; !ok {
+				return nil
+			}
+			report.Infof("Removing %s", cName)
+			if err := stopService(cmd, oldCC, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+			}
+			if _, err := runner.Output("delete", cName); err != nil {
+				return fmt.Errorf("removing %s: %w", cName, err)
+			}
+			removeContainerFiles(cName)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := renameImages(cf, state, oldName, newName); err != nil {
+		return err
+	}
+
+	// The networks and volumes move with the state, so the new project's
+	// up finds them existing and still owns them.
+	renamed := &compose.ProjectState{
+		Name:         newName,
+		ComposeFile:  state.ComposeFile,
+		ComposeFiles: state.ComposeFiles,
+		Profiles:     state.Profiles,
+		EnvFile:      state.EnvFile,
+		ProjectDir:   state.ProjectDir,
+		Containers:   map[string]string{},
+		Networks:     state.Networks,
+		Volumes:      state.Volumes,
+		Naming:       state.Naming,
+		ClonePrefix:  state.ClonePrefix,
+	}
+	if err := compose.SaveProject(renamed); err != nil {
+		return err
+	}
+
+	// The old state is kept until the new project is up, so a failed up
+	// leaves the project under its old name to be brought up again.
+	if err := stackCompose(ctx, cmd, newName, p, "up", "--detach"); err != nil {
+		undoRename(cf, state, oldName, newName)
+		return fmt.Errorf("bringing up %s: %w; project %s is kept, run up to start it again", newName, err, oldName)
+	}
+	if err := compose.DeleteProject(oldName); err != nil {
+		return err
+	}
+	report.Infof("Renamed project %s to %s", oldName, newName)
+	if len(stopped) > 0 {
+		if err := stackCompose(ctx, cmd, newName, p, append([]string{"stop"}, stopped...)...); err != nil {
+			return err
+		}
+	}
+
+	if name, err := compose.ResolveProjectName("", cf, p.Path); err == nil && name != newName {
+		report.Warnf("commands run in %s still resolve to project %s; pass -p %s or set name: %s in the compose file", p.Path, name, newName, newName)
+	}
+	if snaps, err := compose.ListSnapshots(oldName); err == nil && len(snaps) > 0 {
+		report.Warnf("snapshots of %s are kept under that name", oldName)
+	}
+	if runs, err := loadSchedules(oldName); err == nil && len(runs) > 0 {
+		report.Warnf("scheduled runs of %s still use that name; schedule them again as %s", oldName, newName)
+	}
+	return nil
+}
+
+// undoRename removes what a failed up started for the new project, moves
+// the images back and deletes the new project's state.
+func undoRename(cf *compose.ComposeFile, state *compose.ProjectState, oldName, newName string) {
+	if renamed, err := compose.LoadProject(newName); err == nil {
+		cc := &composeContext{projectDir: state.ProjectDir, files: state.Files(), composeFile: cf, projectName: newName, naming: state.Naming}
+		for _, svcName := range sortedKeys(renamed.Containers) {
+			cName := renamed.Containers[svcName]
+			if err := stopService(nil, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+			}
+			if _, err := runner.Output("delete", cName); err != nil {
+				report.Warnf("failed to remove %s: %v", cName, err)
+			}
+			removeContainerFiles(cName)
+		}
+	}
+	if err := renameImages(cf, state, newName, oldName); err != nil {
+		report.Warnf("%v", err)
+	}
+	if err := compose.DeleteProject(newName); err != nil {
+		report.Warnf("%v", err)
+	}
+}
+
+// renameImages retags the images built for services that only define a
+// build, which are named for their project, and moves the project's image
+// record to the new name.
+func renameImages(cf *compose.ComposeFile, state *compose.ProjectState, oldName, newName string) error {
+	original, err := compose.ProjectImages(oldName)
+	if err != nil {
+		return err
+	}
+	recorded := slices.Clone(original)
+	for _, svcName := range sortedKeys(state.Containers) {
+		svc, ok := cf.Services[svcName]
+		if !ok || svc.Image != "" {
+			continue
+		}
+		from, to := serviceImage(oldName, svcName, svc), serviceImage(newName, svcName, svc)
+		if _, err := runner.Output("image", "tag", from, to); err != nil {
+			report.Warnf("failed to tag %s as %s, it will be rebuilt: %v", from, to, err)
+			continue
+		}
+		if _, err := runner.Output("image", "delete", from); err != nil {
+			report.Warnf("failed to remove %s: %v", from, err)
+		}
+		if i := slices.Index(recorded, from); i >= 0 {
+			recorded[i] = to
+		}
+	}
+	if err := compose.RecordImages(newName, recorded...); err != nil {
+		return err
+	}
+	return compose.ForgetImages(oldName, original...)
+}
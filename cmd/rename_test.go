@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeRename(t *testing.T) {
+	dir, _ := newProject(t, "services:\n  web:\n    build: .\n    depends_on: [db]\n  db:\n    image: postgres\n    volumes: [data:/var/lib/postgresql/data]\nvolumes:\n  data: {}\n")
+	saveState(t, &compose.ProjectState{Name: "shop", ProjectDir: dir, Containers: map[string]string{"web": "shop_web", "db": "shop_db"}, Volumes: []string{"data"}})
+	if err := compose.RecordImages("shop", "shop-web", "postgres"); err != nil {
+		t.Fatal(err)
+	}
+	rec := newRecorder()
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web"}},{"status":"stopped","configuration":{"id":"shop_db"}}]`, nil)
+	rec.Respond([]string{"volume", "list"}, `[{"name":"data"}]`, nil)
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/store-web:latest"},{"reference":"docker.io/library/postgres:latest"}]`, nil)
+
+	if _, err := compose.WriteRunEnv("shop_web", []string{"MODE=dev"}); err != nil {
+		t.Fatal(err)
+	}
+
+	captureReport(t)
+	if err := runDctl(t, rec, "compose", "rename", "shop", "store"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range rec.Calls() {
+		switch c[0] {
+		case "run":
+			got = append(got, "run "+c[slices.Index(c, "--name")+1])
+		case "kill":
+			got = append(got, "kill "+c[len(c)-1])
+		case "stop", "delete", "image", "volume":
+			got = append(got, strings.Join(c, " "))
+		}
+	}
+	want := []string{
+		"kill shop_web", "delete shop_web", "kill shop_db", "delete shop_db",
+		"image tag shop-web store-web", "image delete shop-web",
+		"volume list --format json", "image list --format json",
+		"image inspect postgres", "image inspect store-web", "image list --format json",
+		"run store_db", "run store_web",
+	}
+	if !slices.Equal(got[:len(want)], want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+	// db was stopped before the rename and is stopped again.
+	if !slices.Contains(got, "kill store_db") || slices.Contains(got, "kill store_web") {
+		t.Errorf("calls = %q, want only store_db stopped after up", got)
+	}
+
+	if _, err := compose.LoadProject("shop"); err == nil {
+		t.Error("old project state was kept")
+	}
+	envDir, err := compose.RunEnvDir("shop_web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(envDir); !os.IsNotExist(err) {
+		t.Errorf("env files of shop_web were kept in %s", envDir)
+	}
+	if state := loadState(t, "store"); !slices.Equal(state.Volumes, []string{"data"}) {
+		t.Errorf("volumes = %v, want data still owned", state.Volumes)
+	}
+	if images, _ := compose.ProjectImages("store"); !slices.Equal(images, []string{"postgres", "store-web"}) {
+		t.Errorf("images = %v, want postgres and store-web", images)
+	}
+	if images, _ := compose.ProjectImages("shop"); len(images) != 0 {
+		t.Errorf("old project images = %v, want none", images)
+	}
+}
+
+func TestComposeRenameFailedUpKeepsOldProject(t *testing.T) {
+	dir, _ := newProject(t, "services:\n  web:\n    image: nginx\n")
+	saveState(t, &compose.ProjectState{Name: "shop", ProjectDir: dir, Containers: map[string]string{"web": "shop_web"}})
+	rec := newRecorder()
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web"}}]`, nil)
+	rec.Respond([]string{"run"}, "", &runner.ExitError{Code: 1})
+	captureReport(t)
+
+	if err := runDctl(t, rec, "compose", "rename", "shop", "store"); err == nil {
+		t.Fatal("rename succeeded, want the failed up")
+	}
+	if _, err := compose.LoadProject("store"); err == nil {
+		t.Error("new project state was kept")
+	}
+	if state := loadState(t, "shop"); state.Containers["web"] != "shop_web" {
+		t.Errorf("state = %+v, want the old project kept", state)
+	}
+
+	// The old containers are gone, so a second rename only brings the
+	// project up again.
+	rec = newRecorder()
+	if err := runDctl(t, rec, "compose", "rename", "shop", "store"); err != nil {
+		t.Fatal(err)
+	}
+	if deletes := calls(rec, "kill", "delete"); len(deletes) > 0 {
+		t.Errorf("calls = %q, want no containers removed", deletes)
+	}
+	if _, err := compose.LoadProject("shop"); err == nil {
+		t.Error("old project state was kept")
+	}
+}
+
+func TestComposeRenameUsesRecordedFiles(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: nginx\n")
+	override := filepath.Join(dir, "compose.prod.yaml")
+	writeFile(t, override, "services:\n  web:\n    environment:\n      MODE: prod\n")
+	saveState(t, &compose.ProjectState{Name: "shop", ProjectDir: dir, ComposeFile: file, ComposeFiles: []string{file, override}, Profiles: []string{"web"}, Containers: map[string]string{"web": "shop_web"}})
+	rec := newRecorder()
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web"}}]`, nil)
+	captureReport(t)
+
+	if err := runDctl(t, rec, "compose", "rename", "shop", "store"); err != nil {
+		t.Fatal(err)
+	}
+	runs := calls(rec, "run")
+	if len(runs) != 1 || envFileOf(t, runs[0]) != "MODE=prod\n" {
+		t.Errorf("runs = %q, want store_web started with the override's environment", runs)
+	}
+	if state := loadState(t, "store"); !slices.Equal(state.Files(), []string{file, override}) || !slices.Equal(state.Profiles, []string{"web"}) {
+		t.Errorf("state = %+v, want the recorded files and profiles kept", state)
+	}
+
+	// A project that no longer loads keeps its containers.
+	if err := os.Remove(override); err != nil {
+		t.Fatal(err)
+	}
+	rec = newRecorder()
+	if err := runDctl(t, rec, "compose", "rename", "store", "depot"); err == nil {
+		t.Error("rename succeeded with a missing compose file")
+	}
+	if deletes := calls(rec, "stop", "delete"); len(deletes) > 0 {
+		t.Errorf("calls = %q, want no containers removed", deletes)
+	}
+}
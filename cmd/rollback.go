@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// upRollback records the containers an `up` touched so a failed up can be
+// undone.
+type upRollback struct {
+	cmd     *cli.Command
+	cc      *composeContext
+	prev    *compose.ProjectState
+	started []compose.Step // service steps that ran, in order
+	pinned  map[string]pinnedImage
+}
+
+// pinnedImage is the image a recreated service's previous container ran,
+// kept under a rollback tag while up moves its own tag.
+type pinnedImage struct {
+	ref    string // the tag the container was created from
+	digest string
+	pin    string // the rollback tag
+}
+
+// pin tags the images the containers up recreates run, before builds or
+// pulls can move their tags, so a restore brings back the previous image
+// rather than the new one. A tag that already names another image than
+// its container's cannot be pinned, and is warned about.
+func (r *upRollback) pin(services []string) {
+	images, _ := runner.ListImages()
+	digests := make(map[string]string, len(images))
+	for _, img := range images {
+		digests[img.Reference] = img.Descriptor.Digest
+	}
+	for _, svcName := range services {
+		cName := r.cc.containerName(svcName)
+		infos, err := runner.Inspect(cName)
+		if err != nil || len(infos) == 0 {
+			continue
+		}
+		img := infos[0].Configuration.Image
+		if img.Reference == "" {
+			continue
+		}
+		if d, ok := digests[img.Reference]; ok && img.Descriptor.Digest != "" && d != img.Descriptor.Digest {
+			report.Warnf("%s no longer names the image %s runs; a rollback would restart it from the new image", img.Reference, cName)
+			continue
+		}
+		p := pinnedImage{ref: img.Reference, digest: img.Descriptor.Digest, pin: rollbackTag(img.Reference, r.cc.projectName)}
+		if _, err := runner.Output("image", "tag", p.ref, p.pin); err != nil {
+			report.Warnf("failed to keep the previous image of %s: %v", svcName, err)
+			continue
+		}
+		if r.pinned == nil {
+			r.pinned = make(map[string]pinnedImage)
+		}
+		r.pinned[svcName] = p
+	}
+}
+
+// unpin deletes the rollback tags.
+func (r *upRollback) unpin() {
+	for _, p := range r.pinned {
+		if _, err := runner.Output("image", "delete", p.pin); err != nil {
+			report.Warnf("failed to remove image %s: %v", p.pin, err)
+		}
+	}
+	r.pinned = nil
+}
+
+// rollbackTag returns the tag an image is kept under for a project's
+// rollback: its repository, tagged dctl-rollback-PROJECT.
+func rollbackTag(ref, project string) string {
+	repo, _, _ := strings.Cut(ref, "@")
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo + ":dctl-rollback-" + project
+}
+
+// record notes that a service step has been applied.
+func (r *upRollback) record(step compose.Step) {
+	r.started = append(r.started, step)
+}
+
+// stop stops the containers started so far, newest first, as compose stop
+// does, and records them in state as stopped.
+func (r *upRollback) stop(state *compose.ProjectState) {
+	for i := len(r.started) - 1; i >= 0; i-- {
+		svcName := r.started[i].Name
+		cName := r.cc.containerName(svcName)
+		if err := stopService(r.cmd, r.cc, svcName, cName); err != nil {
+			report.Warnf("failed to stop %s: %v", svcName, err)
+			continue
+		}
+		if _, ok := state.Containers[svcName]; ok {
+			state.SetStatus(svcName, compose.StatusStopped)
+		}
+	}
+}
+
+// restore returns the project to its state before up: new containers are
+// removed, recreated ones are replaced by containers run with their
+// previous arguments, from their previous images, and started ones are
+// stopped again. It returns the containers that are left, keyed by
+// service.
+func (r *upRollback) restore() map[string]string {
+	var prevArgs map[string][]string
+	containers := make(map[string]string)
+	if r.prev != nil {
+		prevArgs = r.prev.RunArgs
+		for svc, cName := range r.prev.Containers {
+			containers[svc] = cName
+		}
+	}
+
+	for i := len(r.started) - 1; i >= 0; i-- {
+		step := r.started[i]
+		cName := r.cc.containerName(step.Name)
+		if err := stopService(r.cmd, r.cc, step.Name, cName); err != nil {
+			report.Warnf("failed to stop %s: %v", step.Name, err)
+		}
+		if step.Action == compose.ActionStart {
+			continue
+		}
+
+		report.Infof("Removing %s", cName)
+		if _, err := runner.Output("delete", cName); err != nil {
+			report.Warnf("failed to remove %s: %v", cName, err)
+		}
+		if step.Action != compose.ActionRecreate {
+			delete(containers, step.Name)
+			continue
+		}
+
+		args, ok := prevArgs[step.Name]
+		if !ok {
+			report.Warnf("no previous configuration recorded for %s, leaving it removed", step.Name)
+			delete(containers, step.Name)
+			continue
+		}
+		// The image's tag is moved back, as the arguments name it.
+		if p, ok := r.pinned[step.Name]; ok {
+			if _, err := runner.Output("image", "tag", p.pin, p.ref); err != nil {
+				report.Warnf("failed to restore image %s of %s: %v", p.ref, cName, err)
+			}
+		}
+		report.Infof("Restoring previous %s", cName)
+		if err := startContainer(args); err != nil {
+			report.Warnf("failed to restore %s: %v", cName, err)
+			delete(containers, step.Name)
+		}
+	}
+	r.unpin()
+	return containers
+}
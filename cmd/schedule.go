@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/launchd"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/urfave/cli/v3"
+)
+
+// scheduleCommand returns the compose schedule command group.
+func scheduleCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "schedule",
+		Usage: "Run one-off service commands periodically with launchd",
+		Commands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Install a launchd timer running `compose run --rm SERVICE`",
+				ArgsUsage: "SERVICE [COMMAND...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "cron", Usage: "Cron expression (minute hour day month weekday) or @daily/@hourly/...", Required: true},
+					&cli.BoolFlag{Name: "print", Usage: "Print the plist instead of installing it"},
+				},
+				Action: composeScheduleAddAction,
+			},
+			{
+				Name:    "list",
+				Aliases: []string{"ls"},
+				Usage:   "List the project's scheduled runs",
+				Action:  composeScheduleListAction,
+			},
+			{
+				Name:      "remove",
+				Aliases:   []string{"rm"},
+				Usage:     "Unload and remove a scheduled run",
+				ArgsUsage: "SERVICE",
+				Action:    composeScheduleRemoveAction,
+			},
+		},
+	}
+}
+
+// scheduledRun is a recorded schedule, kept so `list` can show the cron
+// expression and command behind each launchd agent.
+type scheduledRun struct {
+	Service string   `json:"service"`
+	Cron    string   `json:"cron"`
+	Command []string `json:"command,omitempty"`
+	Label   string   `json:"label"`
+}
+
+// scheduleLabel returns the launchd label for a service's scheduled run.
+func scheduleLabel(project, service string) string {
+	return launchd.LabelPrefix + project + ".schedule." + service
+}
+
+func schedulesPath(project string) (string, error) {
+	dir, err := compose.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "schedules", project+".json"), nil
+}
+
+func loadSchedules(project string) (map[string]scheduledRun, error) {
+	path, err := schedulesPath(project)
+	if err != nil {
+		return nil, err
+	}
+	runs := make(map[string]scheduledRun)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return runs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading schedules: %w", err)
+	}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return runs, nil
+}
+
+func saveSchedules(project string, runs map[string]scheduledRun) error {
+	path, err := schedulesPath(project)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing schedules: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating schedules directory: %w", err)
+	}
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling schedules: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing schedules: %w", err)
+	}
+	return nil
+}
+
+func composeScheduleAddAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() < 1 {
+		return fmt.Errorf("service name required")
+	}
+	service := cmd.Args().First()
+	command := cmd.Args().Tail()
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	if _, ok := cc.composeFile.Services[service]; !ok {
+		return compose.UnknownServiceError(service, sortedKeys(cc.composeFile.Services))
+	}
+
+	cron := cmd.String("cron")
+	calendar, err := launchd.ParseCron(cron)
+	if err != nil {
+		return err
+	}
+
+	args, err := projectInvocation(cmd, cc)
+	if err != nil {
+		return err
+	}
+	args = append(args, "run", "--rm", service)
+	args = append(args, command...)
+
+	label := scheduleLabel(cc.projectName, service)
+	logPath, err := agentLogPath(label)
+	if err != nil {
+		return err
+	}
+	agent := &launchd.Agent{
+		Label:            label,
+		ProgramArguments: args,
+		WorkingDirectory: cc.projectDir,
+		Environment:      agentEnvironment(),
+		Calendar:         calendar,
+		LogPath:          logPath,
+	}
+
+	if cmd.Bool("print") {
+		fmt.Print(string(agent.Render()))
+		return nil
+	}
+
+	path, err := launchd.Install(agent)
+	if err != nil {
+		return err
+	}
+	runs, err := loadSchedules(cc.projectName)
+	if err != nil {
+		return err
+	}
+	runs[service] = scheduledRun{Service: service, Cron: cron, Command: command, Label: label}
+	if err := saveSchedules(cc.projectName, runs); err != nil {
+		return err
+	}
+	report.Infof("Installed %s\nLogs: %s", path, logPath)
+	return nil
+}
+
+func composeScheduleListAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	runs, err := loadSchedules(cc.projectName)
+	if err != nil {
+		return err
+	}
+	installed, err := launchd.Installed(launchd.LabelPrefix + cc.projectName + ".schedule.")
+	if err != nil {
+		return err
+	}
+	loaded := make(map[string]bool)
+	for _, label := range installed {
+		loaded[label] = true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tSCHEDULE\tCOMMAND\tSTATUS")
+	for _, service := range sortedKeys(runs) {
+		run := runs[service]
+		status := "installed"
+		if !loaded[run.Label] {
+			status = "missing"
+		}
+		command := "(default)"
+		if len(run.Command) > 0 {
+			command = fmt.Sprint(run.Command)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", service, run.Cron, command, status)
+	}
+	return w.Flush()
+}
+
+func composeScheduleRemoveAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("service name required")
+	}
+	service := cmd.Args().First()
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	runs, err := loadSchedules(cc.projectName)
+	if err != nil {
+		return err
+	}
+
+	label := scheduleLabel(cc.projectName, service)
+	uninstallErr := launchd.Uninstall(label)
+	if _, ok := runs[service]; !ok && uninstallErr != nil {
+		return uninstallErr
+	}
+	delete(runs, service)
+	if err := saveSchedules(cc.projectName, runs); err != nil {
+		return err
+	}
+	report.Infof("Removed %s", label)
+	return nil
+}
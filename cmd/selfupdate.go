@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/selfupdate"
+	"github.com/urfave/cli/v3"
+)
+
+// selfUpdateCommand returns the self-update command.
+func selfUpdateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "self-update",
+		Usage: "Update dctl to the latest GitHub release",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "check", Usage: "Only report whether a newer version is available"},
+			&cli.BoolFlag{Name: "force", Usage: "Install the latest release even if it is not newer"},
+			&cli.BoolFlag{Name: "insecure", Usage: "Install a release that has no checksums, without verifying it"},
+		},
+		Action: selfUpdateAction,
+	}
+}
+
+func selfUpdateAction(ctx context.Context, cmd *cli.Command) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	rel, err := selfupdate.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if dir, err := compose.StateDir(); err == nil {
+		_ = selfupdate.SaveCheck(dir, selfupdate.Check{Latest: rel.Tag, CheckedAt: time.Now()})
+	}
+
+	newer := selfupdate.Newer(Version, rel.Tag)
+	if cmd.Bool("check") {
+		if newer {
+			fmt.Printf("dctl %s is available (current %s); run 'dctl self-update'\n", rel.Tag, Version)
+		} else {
+			fmt.Printf("dctl %s is up to date (latest release %s)\n", Version, rel.Tag)
+		}
+		return nil
+	}
+	if !newer && !cmd.Bool("force") {
+		fmt.Printf("dctl %s is up to date (latest release %s)\n", Version, rel.Tag)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating dctl binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("locating dctl binary: %w", err)
+	}
+	if strings.Contains(exe, "/Cellar/") {
+		return fmt.Errorf("dctl was installed with Homebrew; run 'brew upgrade dctl' instead")
+	}
+
+	report.Infof("Downloading dctl %s", rel.Tag)
+	if err := selfupdate.Install(ctx, rel, runtime.GOOS, runtime.GOARCH, exe, cmd.Bool("insecure")); err != nil {
+		return err
+	}
+	fmt.Printf("Updated dctl %s -> %s\n", Version, rel.Tag)
+	return nil
+}
+
+// printVersion prints the version and, for release builds unless
+// DCTL_NO_UPDATE_CHECK is set, a notice when the last check found a newer
+// release. A stale check is refreshed by a background `self-update --check`
+// so --version never waits on the network.
+func printVersion(cmd *cli.Command) {
+	fmt.Printf("%s version %s\n", cmd.Root().Name, cmd.Root().Version)
+	if Version == "dev" || os.Getenv("DCTL_NO_UPDATE_CHECK") != "" {
+		return
+	}
+	dir, err := compose.StateDir()
+	if err != nil {
+		return
+	}
+
+	check := selfupdate.LoadCheck(dir)
+	if selfupdate.Newer(Version, check.Latest) {
+		report.Infof("A new version of dctl is available: %s (run 'dctl self-update')", check.Latest)
+	}
+	if !check.Stale(time.Now()) {
+		return
+	}
+	// Record the attempt first so a failing check is not retried on every call.
+	check.CheckedAt = time.Now()
+	if err := selfupdate.SaveCheck(dir, check); err != nil {
+		return
+	}
+	if exe, err := os.Executable(); err == nil {
+		if c := exec.Command(exe, "self-update", "--check"); c.Start() == nil {
+			c.Process.Release()
+		}
+	}
+}
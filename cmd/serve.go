@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/api"
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// serveCommand returns the serve command.
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve project operations over an HTTP API",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "listen",
+				Usage:   "Address to listen on (unix:///path, tcp://host:port or host:port)",
+				Value:   "unix:///tmp/dctl.sock",
+				Sources: cli.EnvVars("DCTL_LISTEN"),
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "Bearer token clients must present (generated for TCP addresses when not given)",
+				Sources: cli.EnvVars("DCTL_API_TOKEN"),
+			},
+		},
+		Action: serveAction,
+	}
+}
+
+func serveAction(ctx context.Context, cmd *cli.Command) error {
+	l, err := api.Listen(cmd.String("listen"))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Any local process can reach a TCP port, so TCP always needs a token;
+	// the unix socket is already private to the owner.
+	token := cmd.String("token")
+	if token == "" && l.Addr().Network() == "tcp" {
+		if token, err = writeAPIToken(); err != nil {
+			l.Close()
+			return err
+		}
+	}
+
+	srv := &http.Server{Handler: api.NewHandler(&projectBackend{context: cmd.Root().String("context")}, token)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	report.Infof("Listening on %s", cmd.String("listen"))
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+	return nil
+}
+
+// writeAPIToken generates a bearer token for the API and saves it, readable
+// only by the owner, where clients can read it.
+func writeAPIToken() (string, error) {
+	dir, err := compose.StateDir()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating API token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating state directory: %w", err)
+	}
+	path := filepath.Join(dir, "api-token")
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("writing API token: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file.
+	if err := os.Chmod(path, 0o600); err != nil {
+		return "", fmt.Errorf("writing API token: %w", err)
+	}
+	report.Infof("API token written to %s", path)
+	return token, nil
+}
+
+// projectBackend serves saved projects. Operations that change or stream a
+// project run dctl compose against it, so they behave exactly like the CLI.
+type projectBackend struct {
+	context string // --context of the serve invocation, passed on to dctl
+}
+
+func (b *projectBackend) Projects() ([]api.Project, error) {
+	names, err := compose.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	projects := make([]api.Project, 0, len(names))
+	for _, name := range names {
+		state, err := compose.LoadProject(name)
+		if err != nil {
+			return nil, err
+		}
+		project := api.Project{
+			Name:     state.Name,
+			Dir:      state.ProjectDir,
+			Services: sortedKeys(state.Containers),
+		}
+		for _, svc := range project.Services {
+			if status := state.StatusOf(svc); status != "" {
+				if project.Status == nil {
+					project.Status = make(map[string]string)
+				}
+				project.Status[svc] = status
+			}
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+func (b *projectBackend) Ps(project string) ([]api.Service, error) {
+	state, err := loadServedProject(project)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := runner.List(true)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]runner.ContainerInfo, len(containers))
+	for _, c := range containers {
+		byName[c.Configuration.ID] = c
+	}
+
+	services := make([]api.Service, 0, len(state.Containers))
+	for _, svc := range sortedKeys(state.Containers) {
+		s := api.Service{Service: svc, Container: state.Containers[svc], Status: "missing"}
+		if c, ok := byName[s.Container]; ok {
+			s.Image = c.Configuration.Image.Reference
+			s.Status = c.Status
+		}
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+func (b *projectBackend) Up(ctx context.Context, project string, services []string, w io.Writer) error {
+	return b.compose(ctx, project, w, append([]string{"up", "--detach"}, services...)...)
+}
+
+func (b *projectBackend) Down(ctx context.Context, project string, w io.Writer) error {
+	return b.compose(ctx, project, w, "down")
+}
+
+func (b *projectBackend) Logs(ctx context.Context, project string, opts api.LogOptions, w io.Writer) error {
+	args := []string{"logs"}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	return b.compose(ctx, project, w, append(args, opts.Services...)...)
+}
+
+// compose runs dctl compose against a saved project, writing its output
+// to w. The command is killed when ctx ends, e.g. when the client leaves.
+func (b *projectBackend) compose(ctx context.Context, project string, w io.Writer, args ...string) error {
+	state, err := loadServedProject(project)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating dctl binary: %w", err)
+	}
+
+	// The project runs with every file, profile and environment file up
+	// last used.
+	argv := []string{"compose", "-p", state.Name, "--project-directory", state.ProjectDir}
+	for _, file := range state.Files() {
+		argv = append(argv, "-f", file)
+	}
+	for _, profile := range state.Profiles {
+		argv = append(argv, "--profile", profile)
+	}
+	if state.EnvFile != "" {
+		argv = append(argv, "--env-file", state.EnvFile)
+	}
+	c := exec.CommandContext(ctx, exe, append(argv, args...)...)
+	c.Dir = state.ProjectDir
+	c.Stdout = w
+	c.Stderr = w
+	c.Env = append(os.Environ(), "DCTL_CONTAINER_BIN="+runner.ContainerBin)
+	if b.context != "" {
+		c.Env = append(c.Env, "DCTL_CONTEXT="+b.context)
+	}
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("dctl compose %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// loadServedProject loads a saved project, reporting unknown names as
+// api.ErrNotFound.
+func loadServedProject(name string) (*compose.ProjectState, error) {
+	names, err := compose.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(names, name) {
+		return nil, fmt.Errorf("project %q: %w", name, api.ErrNotFound)
+	}
+	return compose.LoadProject(name)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteAPIToken(t *testing.T) {
+	newProject(t, "")
+	captureReport(t)
+	token, err := writeAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(os.Getenv("DCTL_STATE_DIR"), "api-token")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 64 || strings.TrimSpace(string(data)) != token {
+		t.Errorf("token file = %q, want the 64-character token %q", data, token)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", fi.Mode().Perm())
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/secrets"
+	"github.com/sonnes/dctl/pkg/translate"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// snapshotCommand returns the compose snapshot command group.
+func snapshotCommand() *cli.Command {
+	helperFlag := &cli.StringFlag{Name: "helper-image", Usage: "Image used for the volume helper container", Value: defaultHelperImage}
+
+	return &cli.Command{
+		Name:  "snapshot",
+		Usage: "Save and restore the project's images, volumes and state",
+		Commands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Snapshot the running project",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "no-images", Usage: "Record the services' images instead of committing and saving the containers"},
+					helperFlag,
+				},
+				Action: composeSnapshotCreateAction,
+			},
+			{
+				Name:      "restore",
+				Usage:     "Replace the project with a snapshot",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					helperFlag,
+				},
+				Action: composeSnapshotRestoreAction,
+			},
+			{
+				Name:   "ls",
+				Usage:  "List project snapshots",
+				Action: composeSnapshotListAction,
+			},
+			{
+				Name:      "rm",
+				Usage:     "Delete a snapshot",
+				ArgsUsage: "NAME",
+				Action:    composeSnapshotRemoveAction,
+			},
+		},
+	}
+}
+
+// snapshotImage returns the image a service's container is committed to
+// for a snapshot.
+func snapshotImage(project, svcName, snapshot string) string {
+	return project + "-" + svcName + "-snapshot:" + snapshot
+}
+
+func composeSnapshotCreateAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+	name := cmd.Args().First()
+	if err := compose.ValidateSnapshotName(name); err != nil {
+		return err
+	}
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	project := cc.projectName
+
+	state, err := compose.LoadProject(project)
+	if err != nil {
+		return err
+	}
+
+	dir, err := compose.CreateSnapshotDir(project, name)
+	if err != nil {
+		return err
+	}
+
+	snap := &compose.Snapshot{
+		Name:      name,
+		Project:   project,
+		CreatedAt: time.Now(),
+		State:     state,
+		Images:    make(map[string]string),
+		Volumes:   state.Volumes,
+	}
+
+	// Save the resolved compose model so restore recreates the same containers
+	out, err := yaml.Marshal(cc.composeFile)
+	if err != nil {
+		return fmt.Errorf("marshaling compose file: %w", err)
+	}
+	if err := os.WriteFile(compose.SnapshotComposeFile(dir), out, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot compose file: %w", err)
+	}
+
+	// Commit each container, so changes made inside it are kept, and save
+	// the committed image
+	for _, svcName := range sortedKeys(state.Containers) {
+		if cmd.Bool("no-images") {
+			snap.Images[svcName] = serviceImage(project, svcName, cc.composeFile.Services[svcName])
+			continue
+		}
+		cName := state.Containers[svcName]
+		ref := snapshotImage(project, svcName, name)
+		report.Infof("Committing %s as %s", cName, ref)
+		if _, err := runner.Output("commit", cName, ref); err != nil {
+			return fmt.Errorf("committing %s: %w", cName, err)
+		}
+		snap.Images[svcName] = ref
+		if err := runner.Run("image", "save", "--output", compose.SnapshotImageArchive(dir, svcName), ref); err != nil {
+			return fmt.Errorf("saving image for %s: %w", svcName, err)
+		}
+	}
+
+	// Save volume contents
+	for _, vol := range state.Volumes {
+		report.Infof("Saving volume %s", vol)
+		if err := exportVolume(vol, compose.SnapshotVolumeArchive(dir, vol), cmd.String("helper-image")); err != nil {
+			return fmt.Errorf("saving volume %s: %w", vol, err)
+		}
+	}
+
+	if err := compose.SaveSnapshot(snap); err != nil {
+		return err
+	}
+	report.Infof("Created snapshot %s", name)
+	return nil
+}
+
+func composeSnapshotRestoreAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+	name := cmd.Args().First()
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+	project := cc.projectName
+
+	snap, err := compose.LoadSnapshot(project, name)
+	if err != nil {
+		return err
+	}
+	dir, err := compose.SnapshotDir(project, name)
+	if err != nil {
+		return err
+	}
+
+	cf, err := compose.Load([]string{compose.SnapshotComposeFile(dir)}, snap.State.ProjectDir)
+	if err != nil {
+		return fmt.Errorf("loading snapshot compose file: %w", err)
+	}
+
+	// Remove current containers, or recreate networks and volumes if the
+	// project has been taken down since the snapshot
+	if current, err := compose.LoadProject(project); err == nil {
+		for svcName, cName := range current.Containers {
+			report.Infof("Removing %s", cName)
+			if err := stopService(cmd, cc, svcName, cName); err != nil {
+				report.Warnf("failed to stop %s: %v", svcName, err)
+			}
+			_ = runner.Run("delete", cName)
+		}
+	} else {
+		for _, net := range snap.State.Networks {
+			report.Infof("Creating network %s", net)
+			if err := runner.Run("network", "create", net); err != nil {
+				report.Warnf("failed to create network %s: %v", net, err)
+			}
+		}
+		for _, vol := range snap.State.Volumes {
+			report.Infof("Creating volume %s", vol)
+			if err := runner.Run("volume", "create", vol); err != nil {
+				report.Warnf("failed to create volume %s: %v", vol, err)
+			}
+		}
+	}
+
+	// Load images
+	for svcName, ref := range snap.Images {
+		archive := compose.SnapshotImageArchive(dir, svcName)
+		if _, err := os.Stat(archive); err != nil {
+			continue
+		}
+		report.Infof("Loading image %s", ref)
+		if err := runner.Run("image", "load", "--input", archive); err != nil {
+			return fmt.Errorf("loading image for %s: %w", svcName, err)
+		}
+	}
+
+	// Restore volume contents
+	for _, vol := range snap.Volumes {
+		report.Infof("Restoring volume %s", vol)
+		if err := clearVolume(vol, cmd.String("helper-image")); err != nil {
+			return fmt.Errorf("clearing volume %s: %w", vol, err)
+		}
+		if err := importVolume(vol, compose.SnapshotVolumeArchive(dir, vol), cmd.String("helper-image")); err != nil {
+			return fmt.Errorf("restoring volume %s: %w", vol, err)
+		}
+	}
+
+	// Start containers in order
+	order, err := compose.ResolveOrder(cf.Services)
+	if err != nil {
+		return err
+	}
+	for _, svcName := range order {
+		cName, ok := snap.State.Containers[svcName]
+		if !ok {
+			continue
+		}
+		svc := cf.Services[svcName]
+		svc.Image = snap.Images[svcName]
+		report.Infof("Starting %s", cName)
+		args, err := buildRunArgs(svc, translate.Options{Name: cName, Detach: true})
+		if err != nil {
+			return err
+		}
+		args, err = assignPublishedPorts(args)
+		if err != nil {
+			return err
+		}
+		if args, err = withEnvFile(args); err != nil {
+			return err
+		}
+		if secs, ok := svc.Secrets.([]compose.ServiceSecret); ok {
+			if err := secrets.Write(cName, secs, cf.Secrets); err != nil {
+				return err
+			}
+		}
+		if err := runner.Run(args...); err != nil {
+			return fmt.Errorf("starting service %s: %w", svcName, err)
+		}
+	}
+
+	if err := compose.SaveProject(snap.State); err != nil {
+		return fmt.Errorf("saving project state: %w", err)
+	}
+	report.Infof("Restored snapshot %s", name)
+	return nil
+}
+
+func composeSnapshotListAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	snaps, err := compose.ListSnapshots(cc.projectName)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tSERVICES\tVOLUMES")
+	for _, s := range snaps {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", s.Name, s.CreatedAt.Format(time.RFC3339), len(s.Images), len(s.Volumes))
+	}
+	return w.Flush()
+}
+
+func composeSnapshotRemoveAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: NAME")
+	}
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := compose.LoadSnapshot(cc.projectName, cmd.Args().First()); err != nil {
+		return err
+	}
+	return compose.DeleteSnapshot(cc.projectName, cmd.Args().First())
+}
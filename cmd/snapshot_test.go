@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/secrets"
+)
+
+func TestComposeSnapshotCommits(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+	captureReport(t)
+
+	rec := &runner.Recorder{}
+	if err := runCompose(t, file, rec, "snapshot", "create", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := compose.SnapshotDir("shop", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"commit", "shop_web", "shop-web-snapshot:v1"},
+		{"image", "save", "--output", compose.SnapshotImageArchive(dir, "web"), "shop-web-snapshot:v1"},
+	}
+	if got := rec.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	snap, err := compose.LoadSnapshot("shop", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Images["web"] != "shop-web-snapshot:v1" {
+		t.Errorf("images = %v, want the committed image", snap.Images)
+	}
+
+	if err := runCompose(t, file, rec, "snapshot", "rm", "../../state"); err == nil || !strings.Contains(err.Error(), "invalid snapshot name") {
+		t.Errorf("snapshot rm ../../state = %v, want an invalid name", err)
+	}
+}
+
+func TestComposeSnapshotRestoreWritesSecrets(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    secrets:\n      - source: token\n        x-dctl-env-var: API_TOKEN\nsecrets:\n  token:\n    environment: SHOP_TOKEN\n")
+	t.Setenv("SHOP_TOKEN", "s3cret")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+	captureReport(t)
+
+	if err := runCompose(t, file, &runner.Recorder{}, "snapshot", "create", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.Remove("shop_web"); err != nil {
+		t.Fatal(err)
+	}
+	rec := &runner.Recorder{}
+	if err := runCompose(t, file, rec, "snapshot", "restore", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	runs := calls(rec, "run")
+	if len(runs) != 1 {
+		t.Fatalf("commands = %v, want shop_web started", rec.Calls())
+	}
+	i := slices.Index(runs[0], "--env-file")
+	if i < 0 {
+		t.Fatalf("run = %v, want the secret env file", runs[0])
+	}
+	data, err := os.ReadFile(runs[0][i+1])
+	if err != nil {
+		t.Fatalf("secret env file not written before start: %v", err)
+	}
+	if string(data) != "API_TOKEN=s3cret\n" {
+		t.Errorf("secret env file = %q", data)
+	}
+}
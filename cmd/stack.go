@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/format"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/stack"
+	"github.com/urfave/cli/v3"
+)
+
+// stackCommand returns the stack command group, which runs compose commands
+// across the projects of a stack manifest.
+func stackCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stack",
+		Usage: "Run up, down and ps across the compose projects of a stack manifest",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Usage: "Stack manifest (default: dctl-stack.yaml in the working directory)"},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "up",
+				Usage:     "Start projects in the background, after the projects they depend on",
+				ArgsUsage: "[PROJECT...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "build", Usage: "Build images before starting containers"},
+					&cli.BoolFlag{Name: "wait", Usage: "Wait for each project's services to be running/healthy before starting the next"},
+					&cli.BoolFlag{Name: "no-deps", Usage: "Don't start the projects the named projects depend on"},
+				},
+				Action: stackUpAction,
+			},
+			{
+				Name:      "down",
+				Usage:     "Stop and remove projects and the projects depending on them, dependents first",
+				ArgsUsage: "[PROJECT...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "volumes", Aliases: []string{"v"}, Usage: "Remove named volumes"},
+				},
+				Action: stackDownAction,
+			},
+			{
+				Name:      "ps",
+				Usage:     "List the containers of each project",
+				ArgsUsage: "[PROJECT...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "format", Usage: "Output format: json, table, or a Go template", Value: "table"},
+				},
+				Action: stackPsAction,
+			},
+		},
+	}
+}
+
+// loadStack reads the manifest given with --file, or the one in the working
+// directory.
+func loadStack(cmd *cli.Command) (*stack.Manifest, error) {
+	path := cmd.String("file")
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("getting working directory: %w", err)
+		}
+		if path, err = stack.Find(wd); err != nil {
+			return nil, err
+		}
+	}
+	return stack.Load(path)
+}
+
+// stackCompose runs dctl compose against a project of the stack, in this
+// process, so it shares the active context and backend.
+func stackCompose(ctx context.Context, cmd *cli.Command, name string, p stack.Project, args ...string) error {
+	argv := []string{"dctl"}
+	if report.Quiet() {
+		argv = append(argv, "--quiet")
+	}
+	if c := cmd.String("context"); c != "" {
+		argv = append(argv, "--context", c)
+	}
+	argv = append(argv, "compose", "--project-directory", p.Path, "-p", name)
+	for _, f := range p.Files {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(p.Path, f)
+		}
+		argv = append(argv, "-f", f)
+	}
+	for _, profile := range p.Profiles {
+		argv = append(argv, "--profile", profile)
+	}
+	if f := p.EnvFile; f != "" {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(p.Path, f)
+		}
+		argv = append(argv, "--env-file", f)
+	}
+	err := NewApp(WithRunner(runner.CurrentBackend())).Run(ctx, append(argv, args...))
+	// Under a docker context the docker CLI ran the command.
+	var h handedOff
+	if errors.As(err, &h) {
+		err = h.err
+	}
+	if err != nil {
+		return fmt.Errorf("project %s: %w", name, err)
+	}
+	return nil
+}
+
+// stackUpAction starts the projects in dependency order. Each is detached,
+// since the next cannot start until up returns.
+func stackUpAction(ctx context.Context, cmd *cli.Command) error {
+	m, err := loadStack(cmd)
+	if err != nil {
+		return err
+	}
+	order, err := m.Order(cmd.Args().Slice(), !cmd.Bool("no-deps"))
+	if err != nil {
+		return err
+	}
+	args := []string{"up", "--detach"}
+	if cmd.Bool("build") {
+		args = append(args, "--build")
+	}
+	if cmd.Bool("wait") {
+		args = append(args, "--wait")
+	}
+	for _, name := range order {
+		report.Infof("Starting project %s", name)
+		if err := stackCompose(ctx, cmd, name, m.Projects[name], args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stackDownAction removes the projects in reverse dependency order, so no
+// project loses a project it depends on while it is still running.
+func stackDownAction(ctx context.Context, cmd *cli.Command) error {
+	m, err := loadStack(cmd)
+	if err != nil {
+		return err
+	}
+	// Projects depending on the named ones go too, and first, rather than
+	// being left running without them.
+	names := cmd.Args().Slice()
+	if len(names) > 0 {
+		all := m.Dependents(names)
+		for _, name := range all[len(names):] {
+			report.Infof("Including project %s, which depends on %s", name, strings.Join(names, ", "))
+		}
+		names = all
+	}
+	order, err := m.Order(names, false)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(order)
+	args := []string{"down"}
+	if cmd.Bool("volumes") {
+		args = append(args, "--volumes")
+	}
+	for _, name := range order {
+		report.Infof("Removing project %s", name)
+		if err := stackCompose(ctx, cmd, name, m.Projects[name], args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stackPsAction lists each project's containers under a line naming it.
+func stackPsAction(ctx context.Context, cmd *cli.Command) error {
+	m, err := loadStack(cmd)
+	if err != nil {
+		return err
+	}
+	order, err := m.Order(cmd.Args().Slice(), false)
+	if err != nil {
+		return err
+	}
+	for i, name := range order {
+		// JSON rows carry their project, so need no heading.
+		if cmd.String("format") != format.JSON {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", name)
+		}
+		if err := stackCompose(ctx, cmd, name, m.Projects[name], "ps", "--format", cmd.String("format")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestStackOrder(t *testing.T) {
+	dir, _ := newProject(t, "")
+	writeFile(t, filepath.Join(dir, "dctl-stack.yaml"), "projects:\n  shop:\n    path: apps/shop\n    depends_on: [infra]\n  infra: {}\n")
+	writeFile(t, filepath.Join(dir, "infra", "compose.yaml"), "services:\n  db:\n    image: postgres\n")
+	writeFile(t, filepath.Join(dir, "apps", "shop", "compose.yaml"), "services:\n  web:\n    image: nginx\n")
+	captureReport(t)
+
+	// order returns the containers the calls named cmd acted on, in order.
+	order := func(rec *runner.Recorder, cmd string) []string {
+		var names []string
+		for _, call := range calls(rec, cmd) {
+			for _, name := range []string{"infra_db", "shop_web"} {
+				if slices.Contains(call, name) {
+					names = append(names, name)
+				}
+			}
+		}
+		return names
+	}
+	stack := func(args ...string) *runner.Recorder {
+		rec := newRecorder()
+		if err := runDctl(t, rec, append([]string{"stack", "-f", filepath.Join(dir, "dctl-stack.yaml")}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	if got, want := order(stack("up", "shop"), "run"), []string{"infra_db", "shop_web"}; !slices.Equal(got, want) {
+		t.Errorf("up started %v, want %v", got, want)
+	}
+	if got, want := order(stack("down"), "kill"), []string{"shop_web", "infra_db"}; !slices.Equal(got, want) {
+		t.Errorf("down stopped %v, want %v", got, want)
+	}
+
+	// Taking infra down takes shop, which depends on it, down first.
+	stack("up")
+	if got, want := order(stack("down", "infra"), "kill"), []string{"shop_web", "infra_db"}; !slices.Equal(got, want) {
+		t.Errorf("down infra stopped %v, want %v", got, want)
+	}
+}
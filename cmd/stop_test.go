@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestComposeKill(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	defer func(timeout time.Duration) { killTimeout = timeout }(killTimeout)
+	killTimeout = 0
+
+	kill := func(rec *runner.Recorder, args ...string) *compose.ProjectState {
+		t.Helper()
+		saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}})
+		if err := runCompose(t, file, rec, append([]string{"kill"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+		return loadState(t, "shop")
+	}
+
+	// A container that survives the kill is stopped.
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"inspect"}, `[{"status":"running"}]`, nil)
+	state := kill(rec, "web")
+	if !slices.ContainsFunc(rec.Calls(), func(c []string) bool { return slices.Equal(c, []string{"stop", "shop_web"}) }) {
+		t.Errorf("commands = %v, want a fallback stop", rec.Calls())
+	}
+	if state.StatusOf("web") != "running" || state.StatusOf("db") != "" {
+		t.Errorf("status = %v", state.Status)
+	}
+
+	rec = &runner.Recorder{}
+	rec.Respond([]string{"inspect"}, `[{"status":"stopped"}]`, nil)
+	state = kill(rec, "--remove", "db")
+	want := [][]string{{"kill", "shop_db"}, {"inspect", "shop_db"}, {"delete", "--force", "shop_db"}}
+	if got := rec.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	if _, ok := state.Containers["db"]; ok || state.Containers["web"] != "shop_web" {
+		t.Errorf("containers = %v, want only web", state.Containers)
+	}
+}
+
+func TestComposeDownOrder(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    depends_on: [api]\n  api:\n    image: api\n    depends_on: [db, cache]\n  db:\n    image: postgres\n  cache:\n    image: redis\n")
+	state := &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "api": "shop_api", "db": "shop_db", "cache": "shop_cache", "old": "shop_old"}}
+	saveState(t, state)
+
+	rec := &runner.Recorder{}
+	if err := runCompose(t, file, rec, "down"); err != nil {
+		t.Fatal(err)
+	}
+	position := make(map[string]int)
+	for i, call := range rec.Calls() {
+		if call[0] == "kill" {
+			position[call[len(call)-1]] = i
+		}
+	}
+	if len(position) != len(state.Containers) {
+		t.Fatalf("commands = %v, want every container signalled", rec.Calls())
+	}
+	for _, pair := range [][2]string{{"shop_old", "shop_web"}, {"shop_web", "shop_api"}, {"shop_api", "shop_db"}, {"shop_api", "shop_cache"}} {
+		if position[pair[0]] > position[pair[1]] {
+			t.Errorf("%s stopped after %s: %v", pair[0], pair[1], rec.Calls())
+		}
+	}
+}
+
+func TestComposeStopEscalation(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    stop_signal: SIGQUIT\n    stop_grace_period: 0s\n")
+	defer func(timeout time.Duration) { killTimeout = timeout }(killTimeout)
+	killTimeout = 0
+	b := captureReport(t)
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"inspect"}, `[{"status":"running"}]`, nil)
+	if err := runCompose(t, file, rec, "stop"); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"kill", "--signal", "SIGQUIT", "shop_web"},
+		{"inspect", "shop_web"},
+		{"kill", "shop_web"},
+		{"inspect", "shop_web"},
+		{"stop", "shop_web"},
+	}
+	if got := rec.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	if !strings.Contains(b.String(), "Warning: shop_web did not stop within 0s of SIGQUIT, killing it") {
+		t.Errorf("output = %q, want a kill warning", b.String())
+	}
+}
+
+func TestComposeRemoveUsesStopSignal(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    stop_signal: SIGQUIT\n")
+	captureReport(t)
+
+	tests := []struct {
+		args   []string
+		delete []string
+	}{
+		{[]string{"up", "--detach", "--force-recreate"}, []string{"delete", "shop_web"}},
+		{[]string{"rm", "--stop", "--force"}, []string{"delete", "--force", "shop_web"}},
+	}
+	for _, tt := range tests {
+		saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+		rec := newRecorder()
+		rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web"}}]`, nil)
+		if err := runCompose(t, file, rec, tt.args...); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		got := calls(rec, "kill", "stop", "delete")
+		want := [][]string{{"kill", "--signal", "SIGQUIT", "shop_web"}, tt.delete}
+		if len(got) < 2 || !reflect.DeepEqual(got[:2], want) {
+			t.Errorf("%v: commands = %v, want %v first", tt.args, got, want)
+		}
+	}
+}
+
+func TestComposeRestartRetriesStart(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n")
+	defer func(interval time.Duration) { startRetryInterval = interval }(startRetryInterval)
+	startRetryInterval = 10 * time.Millisecond
+	b := captureReport(t)
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}})
+
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"inspect"}, `[{"status":"stopped","configuration":{"publishedPorts":[{"hostPort":0,"containerPort":80}]}}]`, nil)
+	rec.Respond([]string{"start"}, "", errors.New("address already in use"))
+	err := runCompose(t, file, rec, "restart", "--port-wait", "1")
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("restart error = %v, want the start failure", err)
+	}
+	if starts := calls(rec, "start"); len(starts) < 2 {
+		t.Errorf("started %d times, want retries", len(starts))
+	}
+	if !strings.Contains(b.String(), "Retrying start of shop_web") {
+		t.Errorf("output = %q, want a retry notice", b.String())
+	}
+	if got := loadState(t, "shop").StatusOf("web"); got != compose.StatusStopped {
+		t.Errorf("status = %q, want %q", got, compose.StatusStopped)
+	}
+}
+
+func TestComposeRestartDependents(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    depends_on: [api]\n  api:\n    image: api\n    depends_on:\n      db:\n        condition: service_started\n        restart: true\n  db:\n    image: postgres\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "api": "shop_api", "db": "shop_db"}})
+
+	tests := []struct {
+		flags []string
+		want  []string
+	}{
+		{nil, []string{"kill shop_api", "kill shop_db", "start shop_db", "start shop_api"}},
+		{[]string{"--with-dependents"}, []string{"kill shop_web", "kill shop_api", "kill shop_db", "start shop_db", "start shop_api", "start shop_web"}},
+		{[]string{"--no-deps"}, []string{"kill shop_db", "start shop_db"}},
+	}
+	for _, tt := range tests {
+		rec := &runner.Recorder{}
+		args := append(append([]string{"restart"}, tt.flags...), "db")
+		if err := runCompose(t, file, rec, args...); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, call := range calls(rec, "kill", "start") {
+			got = append(got, call[0]+" "+call[len(call)-1])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("restart %v db: commands = %v, want %v", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestComposeRmAllRun(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web"}, RunContainers: []string{"shop_web_run_gone", "custom"}})
+	rec := &runner.Recorder{}
+	// shop_web_db_run_1 is a run container of project shop_web's db service.
+	rec.Respond([]string{"list"}, `[{"status":"stopped","configuration":{"id":"shop_web_run_1a2b"}},{"status":"stopped","configuration":{"id":"shop_web_db_run_1"}},{"status":"stopped","configuration":{"id":"custom"}}]`, nil)
+	captureReport(t)
+
+	if err := runCompose(t, file, rec, "rm", "--all-run"); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"delete", "custom"}, {"delete", "shop_web_run_1a2b"}}
+	if got := calls(rec, "delete"); !reflect.DeepEqual(got, want) {
+		t.Errorf("deletes = %q, want %q", got, want)
+	}
+	if state := loadState(t, "shop"); len(state.RunContainers) != 0 {
+		t.Errorf("run containers = %v, want none left recorded", state.RunContainers)
+	}
+}
+
+func TestComposeDownRmi(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    build: .\n  db:\n    image: postgres\n")
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}})
+	rec := &runner.Recorder{}
+	rec.Respond([]string{"image", "delete", "postgres"}, "", errors.New("image is in use"))
+	b := captureReport(t)
+
+	if err := runCompose(t, file, rec, "down", "--rmi", "all"); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"image", "delete", "postgres"}, {"image", "delete", "shop-web"}}
+	if got := calls(rec, "image"); !reflect.DeepEqual(got, want) {
+		t.Errorf("image calls = %q, want %q", got, want)
+	}
+	if !strings.Contains(b.String(), "failed to remove image postgres: image is in use") {
+		t.Errorf("report = %q, want a warning for the image in use", b.String())
+	}
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/prune"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// systemCommand returns the system command group.
+func systemCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "system",
+		Usage: "Manage container runtime resources",
+		Commands: []*cli.Command{
+			{
+				Name:  "prune",
+				Usage: "Remove stopped containers, dangling images and unused project networks",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "project", Aliases: []string{"p"}, Usage: "Only prune resources belonging to this compose project"},
+					&cli.BoolFlag{Name: "all", Aliases: []string{"a"}, Usage: "Remove all unused images, not just dangling ones"},
+					&cli.BoolFlag{Name: "volumes", Usage: "Also remove unused project volumes"},
+					&cli.StringSliceFlag{Name: "filter", Usage: "Only remove containers matching a filter (label=KEY[=VALUE], label!=KEY[=VALUE]); networks and volumes are not filtered"},
+					&cli.BoolFlag{Name: "force", Aliases: []string{"f"}, Usage: "Do not prompt for confirmation"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "Show what would be removed"},
+				},
+				Action: systemPruneAction,
+			},
+		},
+	}
+}
+
+func systemPruneAction(ctx context.Context, cmd *cli.Command) error {
+	filters, err := prune.ParseFilters(cmd.StringSlice("filter"))
+	if err != nil {
+		return err
+	}
+	opts := prune.Options{
+		AllImages: cmd.Bool("all"),
+		Volumes:   cmd.Bool("volumes"),
+		Filters:   filters,
+	}
+
+	var scope *prune.Scope
+	if project := cmd.String("project"); project != "" {
+		if err := compose.ValidateProjectName(project); err != nil {
+			return err
+		}
+		state, err := compose.LoadProject(project)
+		if err != nil {
+			return err
+		}
+		scope = projectScope(state)
+	} else {
+		owned, err := projectsScope()
+		if err != nil {
+			return err
+		}
+		opts.Owned = owned
+	}
+
+	res, err := loadPruneResources()
+	if err != nil {
+		return err
+	}
+	plan := prune.Compute(res, scope, opts)
+	if plan.Empty() {
+		report.Infof("Nothing to prune")
+		return nil
+	}
+
+	printPrunePlan(plan)
+	if cmd.Bool("dry-run") {
+		return nil
+	}
+	if !cmd.Bool("force") && !confirm("Are you sure you want to continue?") {
+		return nil
+	}
+
+	removed := make(map[string]bool)
+	for _, c := range plan.Containers {
+		if _, err := runner.Output("delete", c); err != nil {
+			report.Warnf("failed to remove container %s: %v", c, err)
+			continue
+		}
+		removed[c] = true
+		fmt.Println(c)
+	}
+	var reclaimed int64
+	for _, img := range plan.Images {
+		if _, err := runner.Output("image", "delete", img.Reference); err != nil {
+			report.Warnf("failed to remove image %s: %v", img.Reference, err)
+			continue
+		}
+		reclaimed += img.Descriptor.Size
+		fmt.Println(img.Reference)
+	}
+	for _, n := range plan.Networks {
+		if _, err := runner.Output("network", "delete", n); err != nil {
+			report.Warnf("failed to remove network %s: %v", n, err)
+			continue
+		}
+		removed[n] = true
+		fmt.Println(n)
+	}
+	for _, v := range plan.Volumes {
+		if _, err := runner.Output("volume", "delete", v); err != nil {
+			report.Warnf("failed to remove volume %s: %v", v, err)
+			continue
+		}
+		removed[v] = true
+		fmt.Println(v)
+	}
+
+	report.Infof("Total reclaimed space: %s", prune.HumanSize(reclaimed))
+	return forgetPruned(removed)
+}
+
+// projectScope limits pruning to the resources recorded in project state.
+func projectScope(state *compose.ProjectState) *prune.Scope {
+	scope := &prune.Scope{
+		Containers: make(map[string]bool),
+		Networks:   make(map[string]bool),
+		Volumes:    make(map[string]bool),
+	}
+	for _, c := range state.Containers {
+		scope.Containers[c] = true
+	}
+	for _, n := range state.Networks {
+		scope.Networks[n] = true
+	}
+	for _, v := range state.Volumes {
+		scope.Volumes[v] = true
+	}
+	return scope
+}
+
+// projectsScope holds the networks and volumes recorded in every project's
+// state.
+func projectsScope() (*prune.Scope, error) {
+	names, err := compose.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	scope := &prune.Scope{Networks: make(map[string]bool), Volumes: make(map[string]bool)}
+	for _, name := range names {
+		state, err := compose.LoadProject(name)
+		if err != nil {
+			return nil, err
+		}
+		project := projectScope(state)
+		maps.Copy(scope.Networks, project.Networks)
+		maps.Copy(scope.Volumes, project.Volumes)
+	}
+	return scope, nil
+}
+
+func loadPruneResources() (prune.Resources, error) {
+	var res prune.Resources
+	var err error
+	if res.Containers, err = runner.List(true); err != nil {
+		return res, err
+	}
+	if res.Images, err = runner.ListImages(); err != nil {
+		return res, err
+	}
+	if res.Networks, err = runner.ListNetworks(); err != nil {
+		return res, err
+	}
+	if res.Volumes, err = runner.ListVolumes(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func printPrunePlan(plan prune.Plan) {
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s:\n", title)
+		for _, item := range items {
+			fmt.Fprintf(os.Stderr, "  - %s\n", item)
+		}
+	}
+	section("Containers", plan.Containers)
+	var images []string
+	for _, img := range plan.Images {
+		images = append(images, fmt.Sprintf("%s (%s)", img.Reference, prune.HumanSize(img.Descriptor.Size)))
+	}
+	section("Images", images)
+	section("Networks", plan.Networks)
+	section("Volumes", plan.Volumes)
+	report.Infof("Reclaimable image space: %s", prune.HumanSize(plan.ImageBytes()))
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// forgetPruned drops removed containers, networks and volumes from every
+// saved project so later commands do not act on them.
+func forgetPruned(removed map[string]bool) error {
+	projects, err := compose.ListProjects()
+	if err != nil {
+		return err
+	}
+	for _, name := range projects {
+		state, err := compose.LoadProject(name)
+		if err != nil {
+			return err
+		}
+		changed := false
+		for svc, c := range state.Containers {
+			if removed[c] {
+				delete(state.Containers, svc)
+				changed = true
+			}
+		}
+		keep := func(items []string) []string {
+			var out []string
+			for _, item := range items {
+				if removed[item] {
+					changed = true
+					continue
+				}
+				out = append(out, item)
+			}
+			return out
+		}
+		state.Networks = keep(state.Networks)
+		state.Volumes = keep(state.Volumes)
+		if changed {
+			if err := compose.SaveProject(state); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/features"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/translate"
+)
+
+func TestComposeUpWithRecorder(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    environment:\n      MODE: test\n")
+
+	rec := newRecorder()
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := calls(rec, "run")
+	if len(runs) != 1 {
+		t.Fatalf("run commands = %v, want one", runs)
+	}
+	// The values stay out of the arguments, in a file only the user can read.
+	dir, err := compose.RunEnvDir("shop_web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := runs[0]
+	i := slices.Index(run, "--env-file")
+	if i < 0 || filepath.Dir(run[i+1]) != dir || slices.Contains(run, "MODE=test") {
+		t.Fatalf("run command = %v, want the environment in an env file under %s", run, dir)
+	}
+	if env := envFileOf(t, run); env != "MODE=test\n" {
+		t.Errorf("env file = %q, want MODE=test", env)
+	}
+	if fi, err := os.Stat(run[i+1]); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Errorf("env file mode = %v, want 0600", fi.Mode().Perm())
+	}
+	if state := loadState(t, "shop"); !slices.Equal(state.ComposeFiles, []string{file}) {
+		t.Errorf("compose files = %v, want %v recorded", state.ComposeFiles, file)
+	}
+}
+
+func TestComposeUpNoStartNoBuild(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    build: .\n")
+
+	rec := newRecorder()
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/shop-web:latest"}]`, nil)
+	if err := runCompose(t, file, rec, "up", "--no-start", "--no-build"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"create", "--name", "shop_web", "shop-web"}}
+	if launches := calls(rec, "run", "create", "build"); !reflect.DeepEqual(launches, want) {
+		t.Errorf("commands = %v, want %v", launches, want)
+	}
+}
+
+func TestComposeUpSummary(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    ports: [\"8080:80\"]\n  db:\n    image: postgres\n")
+	b := captureReport(t)
+
+	rec := newRecorder()
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_db"}}]`, nil)
+	rec.Respond([]string{"inspect"}, `[{"configuration":{"id":"shop_web","publishedPorts":[{"hostPort":8080,"containerPort":80}]}},{"configuration":{"id":"shop_db"}}]`, nil)
+	saveState(t, &compose.ProjectState{Name: "shop", Containers: map[string]string{"db": "shop_db"}})
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.String()
+	for _, want := range []string{"SERVICE   ACTION", "web       created", "0.0.0.0:8080->80/tcp", "db        recreated", "2 services in ", ": 1 created, 1 recreated"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestComposeUpDependsOnProject(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    x-dctl-depends-on-project: infra\n")
+	captureReport(t)
+
+	up := func() (*runner.Recorder, error) {
+		rec := newRecorder()
+		rec.Respond([]string{"inspect"}, `[{"status":"running","configuration":{"id":"infra_db"}}]`, nil)
+		return rec, runCompose(t, file, rec, "up", "--detach", "--project-timeout", "0")
+	}
+
+	rec, err := up()
+	if err == nil || !strings.Contains(err.Error(), "depends on project infra, which is not running: not started") {
+		t.Errorf("up before infra = %v, want a not running error", err)
+	}
+	if len(calls(rec, "run")) > 0 {
+		t.Errorf("commands = %v, want web not started", rec.Calls())
+	}
+
+	saveState(t, &compose.ProjectState{Name: "infra", Containers: map[string]string{"db": "infra_db"}})
+	rec, err = up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls(rec, "run")) == 0 {
+		t.Errorf("commands = %v, want web started", rec.Calls())
+	}
+}
+
+func TestComposeRunMatchesUp(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    build: .\n    labels:\n      tier: web\n    dns: 1.1.1.1\n    tmpfs: /tmp\n    extra_hosts: [\"db:10.0.0.5\"]\n")
+	captureReport(t)
+
+	launch := func(args ...string) []string {
+		rec := newRecorder()
+		rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/shop-web:latest"}]`, nil)
+		if err := runCompose(t, file, rec, args...); err != nil {
+			t.Fatal(err)
+		}
+		runs := calls(rec, "run")
+		if len(runs) == 0 {
+			t.Fatalf("commands = %v, want a run", rec.Calls())
+		}
+		return runs[0]
+	}
+
+	up := launch("up", "--detach")
+	for _, want := range []string{"tier=web", "1.1.1.1", "/tmp", "shop-web"} {
+		if !slices.Contains(up, want) {
+			t.Errorf("up ran %v, want it to include %q", up, want)
+		}
+	}
+	if slices.Contains(up, "--add-host") {
+		t.Errorf("up ran %v, want extra_hosts dropped for the container runtime", up)
+	}
+	if run := launch("run", "--detach", "--name", "shop_web", "web"); !slices.Equal(run, up) {
+		t.Errorf("run ran %v, want the run of up %v", run, up)
+	}
+}
+
+func TestComposeUpImagePlatform(t *testing.T) {
+	_, file := newProject(t, "")
+	t.Setenv("DOCKER_DEFAULT_PLATFORM", "")
+	captureReport(t)
+
+	up := func(yaml string) (*runner.Recorder, error) {
+		writeFile(t, file, yaml)
+		rec := newRecorder()
+		rec.Respond([]string{"image", "inspect"}, `[{"Os":"linux","Architecture":"s390x","Config":{}}]`, nil)
+		return rec, runCompose(t, file, rec, "up", "--detach")
+	}
+
+	rec, err := up("services:\n  web:\n    image: nginx\n")
+	if err == nil || !strings.Contains(err.Error(), "service web: image nginx is linux/s390x, not linux/") {
+		t.Errorf("up = %v, want a platform mismatch", err)
+	}
+	if runs := calls(rec, "run"); len(runs) > 0 {
+		t.Errorf("commands = %v, want nothing started", rec.Calls())
+	}
+	if _, err := up("services:\n  web:\n    image: nginx\n    platform: linux/s390x\n"); err != nil {
+		t.Errorf("up with the image's platform = %v", err)
+	}
+}
+
+func TestComposeUpAttach(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n  api:\n    image: nginx\n  metrics:\n    image: prom\n    attach: false\n")
+
+	follows := func(args ...string) [][]string {
+		t.Helper()
+		rec := &runner.Recorder{}
+		if err := runCompose(t, file, rec, append([]string{"up"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+		logs := calls(rec, "logs")
+		// Services are followed concurrently.
+		slices.SortFunc(logs, func(a, b []string) int { return strings.Compare(a[len(a)-1], b[len(b)-1]) })
+		return logs
+	}
+
+	want := [][]string{{"logs", "--follow", "shop_api"}, {"logs", "--follow", "shop_web"}}
+	if got := follows(); !reflect.DeepEqual(got, want) {
+		t.Errorf("up followed %v, want %v", got, want)
+	}
+	want = [][]string{{"logs", "--follow", "shop_metrics"}}
+	if got := follows("--attach", "metrics"); !reflect.DeepEqual(got, want) {
+		t.Errorf("up --attach metrics followed %v, want %v", got, want)
+	}
+	want = [][]string{{"logs", "--follow", "shop_web"}}
+	if got := follows("--no-attach", "api"); !reflect.DeepEqual(got, want) {
+		t.Errorf("up --no-attach api followed %v, want %v", got, want)
+	}
+	if got := follows("--detach"); len(got) != 0 {
+		t.Errorf("up --detach followed %v", got)
+	}
+}
+
+func TestComposeUpWaitFailureKeepsServices(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    x-dctl-wait:\n      wait_for: tcp://127.0.0.1:1\n      timeout: 100ms\n      interval: 20ms\n")
+	captureReport(t)
+
+	rec := newRecorder()
+	err := runCompose(t, file, rec, "up", "--detach", "--wait")
+	if err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Fatalf("up = %v, want web not ready", err)
+	}
+	// Without --rollback-on-failure the started service is left running.
+	if stops := calls(rec, "stop", "delete"); len(stops) > 0 {
+		t.Errorf("calls = %q, want web left running", stops)
+	}
+}
+
+func TestComposeUpRecordsFetchedImages(t *testing.T) {
+	_, file := newProject(t, "services:\n  web:\n    build: .\n  db:\n    image: postgres\n  cache:\n    image: redis\n")
+
+	rec := newRecorder()
+	// redis was already there, perhaps another project's; postgres is
+	// pulled by run and web is built.
+	rec.Respond([]string{"image", "list"}, `[{"reference":"docker.io/library/redis:latest"}]`, nil)
+	captureReport(t)
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	images, err := compose.ProjectImages("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"postgres", "shop-web"}; !slices.Equal(images, want) {
+		t.Errorf("recorded images = %v, want %v", images, want)
+	}
+}
+
+func TestComposeUpNamespaces(t *testing.T) {
+	_, file := newProject(t, "services:\n  db:\n    image: postgres\n  app:\n    image: nginx\n    pid: service:db\n    ipc: host\n    depends_on: [db]\n")
+	bin := runner.ContainerBin
+	t.Cleanup(func() { runner.ContainerBin = bin })
+
+	runArgs := func(rec *runner.Recorder) []string {
+		for _, c := range calls(rec, "run") {
+			if slices.Contains(c, "shop_app") {
+				return c
+			}
+		}
+		t.Fatalf("no run of shop_app in %v", rec.Calls())
+		return nil
+	}
+
+	// The Docker backend shares the namespaces, with the service's
+	// container standing in for the service.
+	runner.ContainerBin = "docker"
+	rec := newRecorder()
+	captureReport(t)
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(runArgs(rec), " ")
+	if !strings.Contains(args, "--pid container:shop_db --ipc host") {
+		t.Errorf("run = %s, want the pid and ipc namespaces passed", args)
+	}
+
+	// The container runtime cannot share them, and warns.
+	runner.ContainerBin = bin
+	compose.DeleteProject("shop")
+	rec = newRecorder()
+	b := captureReport(t)
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	if args := runArgs(rec); slices.Contains(args, "--pid") || slices.Contains(args, "--ipc") {
+		t.Errorf("run = %v, want no namespace flags", args)
+	}
+	if !strings.Contains(b.String(), "service app: pid, ipc not supported by the container runtime") {
+		t.Errorf("output = %q, want a warning", b.String())
+	}
+}
+
+func TestComposeUpNetworkMode(t *testing.T) {
+	_, file := newProject(t, "services:\n  db:\n    image: postgres\n    network_mode: host\n  app:\n    image: nginx\n    network_mode: service:db\n    depends_on: [db]\n")
+	bin := runner.ContainerBin
+	t.Cleanup(func() { runner.ContainerBin = bin })
+
+	// The Docker backend is given the modes, with the service's container
+	// standing in for the service.
+	runner.ContainerBin = "docker"
+	rec := newRecorder()
+	captureReport(t)
+	if err := runCompose(t, file, rec, "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	var networks []string
+	for _, c := range calls(rec, "run") {
+		if i := slices.Index(c, "--network"); i >= 0 {
+			networks = append(networks, c[i+1])
+		}
+	}
+	if want := []string{"host", "container:shop_db"}; !slices.Equal(networks, want) {
+		t.Errorf("run networks = %v, want %v", networks, want)
+	}
+
+	// The container runtime rejects them before anything starts.
+	runner.ContainerBin = bin
+	compose.DeleteProject("shop")
+	rec = newRecorder()
+	err := runCompose(t, file, rec, "up", "--detach")
+	if err == nil || !strings.Contains(err.Error(), `network_mode "host" is not supported`) {
+		t.Errorf("up = %v, want network_mode rejected", err)
+	}
+	if runs := calls(rec, "run"); len(runs) > 0 {
+		t.Errorf("runs = %v, want none", runs)
+	}
+}
+
+func TestComposeUpRuntimeGates(t *testing.T) {
+	saved := runtimeVersion
+	t.Cleanup(func() { runtimeVersion = saved })
+	runtimeVersion = func() features.Version { return features.Version{Minor: 3} }
+
+	// up refuses what the runtime does not have, before anything starts.
+	_, file := newProject(t, "services:\n  web:\n    image: nginx\n    networks: [front, back]\nnetworks:\n  front: {}\n  back: {}\n")
+	rec := newRecorder()
+	err := runCompose(t, file, rec, "up", "--detach")
+	if err == nil || !strings.Contains(err.Error(), "multiple networks requires container >= 0.4.0 (found 0.3.0)") {
+		t.Errorf("up = %v, want a runtime version error", err)
+	}
+	runtimeVersion = func() features.Version { return features.Version{Minor: 1} }
+	_, file = newProject(t, "services:\n  web:\n    image: nginx\n    healthcheck:\n      test: [CMD, true]\n")
+	err = runCompose(t, file, rec, "up", "--detach")
+	if err == nil || !strings.Contains(err.Error(), "service web: healthcheck: a healthcheck requires container >= 0.2.0") {
+		t.Errorf("up = %v, want a runtime version error", err)
+	}
+
+	// Other runs of a service attach the first network only on a runtime
+	// that takes one, and every network otherwise.
+	svc := compose.Service{Image: "nginx", Networks: map[string]interface{}{"front": nil, "back": nil}}
+	for _, tt := range []struct {
+		v    features.Version
+		want []string
+	}{
+		{features.Version{Minor: 3}, []string{"back"}},
+		{features.Version{Minor: 4}, []string{"back", "front"}},
+		{features.Version{}, []string{"back", "front"}},
+	} {
+		runtimeVersion = func() features.Version { return tt.v }
+		args, err := buildRunArgs(svc, translate.Options{Name: "shop_web"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var networks []string
+		for i, a := range args {
+			if a == "--network" {
+				networks = append(networks, args[i+1])
+			}
+		}
+		if !slices.Equal(networks, tt.want) {
+			t.Errorf("networks on %s = %v, want %v", tt.v, networks, tt.want)
+		}
+	}
+}
+
+func TestComposeUpRollbackRestoresPreviousImage(t *testing.T) {
+	dir, file := newProject(t, "services:\n  web:\n    image: app:dev\n    build: .\n")
+	writeFile(t, filepath.Join(dir, "Dockerfile"), "FROM nginx\n")
+	captureReport(t)
+	if err := runCompose(t, file, newRecorder(), "up", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rebuild moves app:dev to a new image whose container fails.
+	container := `[{"status":"running","configuration":{"id":"shop_web","image":{"reference":"app:dev","descriptor":{"digest":"sha256:old"}}}}]`
+	rec := newRecorder()
+	rec.Respond([]string{"list"}, container, nil)
+	rec.Respond([]string{"inspect"}, container, nil)
+	rec.Respond([]string{"image", "list"}, `[{"reference":"app:dev","descriptor":{"digest":"sha256:old"}}]`, nil)
+	rec.Respond([]string{"run"}, "", &runner.ExitError{Code: 1})
+	if err := runCompose(t, file, rec, "up", "--detach", "--build", "--rollback-on-failure"); err == nil {
+		t.Fatal("up succeeded, want the new container's failure")
+	}
+
+	got := rec.Calls()
+	index := func(call ...string) int {
+		return slices.IndexFunc(got, func(c []string) bool { return slices.Equal(c, call) })
+	}
+	pin, build := index("image", "tag", "app:dev", "app:dctl-rollback-shop"), slices.IndexFunc(got, func(c []string) bool { return c[0] == "build" })
+	if pin < 0 || build < 0 || pin > build {
+		t.Fatalf("calls = %q, want the previous image tagged before the build", got)
+	}
+	// The tag is moved back before the previous container is run again.
+	restore := index("image", "tag", "app:dctl-rollback-shop", "app:dev")
+	var runs []int
+	for i, c := range got {
+		if c[0] == "run" {
+			runs = append(runs, i)
+		}
+	}
+	if len(runs) != 2 || restore < runs[0] || restore > runs[1] {
+		t.Errorf("calls = %q, want app:dev retagged between the failed run and the restore", got)
+	}
+	if index("image", "delete", "app:dctl-rollback-shop") < restore {
+		t.Errorf("calls = %q, want the rollback tag removed afterwards", got)
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultHelperImage is the image used for helper containers that read or
+// write volume contents.
+const defaultHelperImage = "alpine:latest"
+
+// volumesCommand returns the compose volumes command group.
+func volumesCommand() *cli.Command {
+	helperFlag := &cli.StringFlag{Name: "helper-image", Usage: "Image used for the helper container", Value: defaultHelperImage}
+
+	return &cli.Command{
+		Name:  "volumes",
+		Usage: "Manage project volume data",
+		Commands: []*cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export a volume's contents to a tar.gz archive",
+				ArgsUsage: "SERVICE_OR_VOLUME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Archive path (defaults to VOLUME.tar.gz)"},
+					helperFlag,
+				},
+				Action: composeVolumesExportAction,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import a tar.gz archive into a volume",
+				ArgsUsage: "SERVICE_OR_VOLUME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "input", Aliases: []string{"i"}, Usage: "Archive path", Required: true},
+					helperFlag,
+				},
+				Action: composeVolumesImportAction,
+			},
+		},
+	}
+}
+
+// volumeName returns the runtime name of a top-level volume.
+func volumeName(cf *compose.ComposeFile, key string) string {
+	if vol, ok := cf.Volumes[key]; ok && vol.Name != "" {
+		return vol.Name
+	}
+	return key
+}
+
+// serviceNamedVolumes returns the top-level volume keys mounted by a service.
+func serviceNamedVolumes(cf *compose.ComposeFile, svc compose.Service) []string {
+	var keys []string
+	for _, v := range svc.Volumes {
+		source, _, ok := strings.Cut(v, ":")
+		if !ok {
+			continue
+		}
+		if _, defined := cf.Volumes[source]; defined {
+			keys = append(keys, source)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolveVolumeTarget resolves a SERVICE_OR_VOLUME argument to a runtime volume name.
+// Top-level volume keys take precedence; a service resolves to its only named volume.
+func resolveVolumeTarget(cf *compose.ComposeFile, target string) (string, error) {
+	if _, ok := cf.Volumes[target]; ok {
+		return volumeName(cf, target), nil
+	}
+
+	svc, ok := cf.Services[target]
+	if !ok {
+		return "", fmt.Errorf("no such service or volume: %s", target)
+	}
+
+	keys := serviceNamedVolumes(cf, svc)
+	switch len(keys) {
+	case 0:
+		return "", fmt.Errorf("service %s has no named volumes", target)
+	case 1:
+		return volumeName(cf, keys[0]), nil
+	default:
+		return "", fmt.Errorf("service %s mounts multiple named volumes (%s), specify one", target, strings.Join(keys, ", "))
+	}
+}
+
+func composeVolumesExportAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: SERVICE_OR_VOLUME")
+	}
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	vol, err := resolveVolumeTarget(cc.composeFile, cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	output := cmd.String("output")
+	if output == "" {
+		output = vol + ".tar.gz"
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		return fmt.Errorf("resolving output path: %w", err)
+	}
+
+	report.Infof("Exporting volume %s to %s", vol, output)
+	return exportVolume(vol, output, cmd.String("helper-image"))
+}
+
+func composeVolumesImportAction(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("requires exactly 1 argument: SERVICE_OR_VOLUME")
+	}
+
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	vol, err := resolveVolumeTarget(cc.composeFile, cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	input, err := filepath.Abs(cmd.String("input"))
+	if err != nil {
+		return fmt.Errorf("resolving input path: %w", err)
+	}
+	if _, err := os.Stat(input); err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+
+	report.Infof("Importing %s into volume %s", input, vol)
+	return importVolume(vol, input, cmd.String("helper-image"))
+}
+
+// exportVolume tars a volume's contents into the archive at output (an absolute path).
+func exportVolume(vol, output, helperImage string) error {
+	args := []string{
+		"run", "--rm",
+		"--volume", vol + ":/volume",
+		"--volume", filepath.Dir(output) + ":/backup",
+		helperImage,
+		"tar", "czf", "/backup/" + filepath.Base(output), "-C", "/volume", ".",
+	}
+	_, err := runner.Output(args...)
+	return err
+}
+
+// importVolume extracts the archive at input (an absolute path) into a volume.
+func importVolume(vol, input, helperImage string) error {
+	args := []string{
+		"run", "--rm",
+		"--volume", vol + ":/volume",
+		"--volume", filepath.Dir(input) + ":/backup:ro",
+		helperImage,
+		"tar", "xzf", "/backup/" + filepath.Base(input), "-C", "/volume",
+	}
+	_, err := runner.Output(args...)
+	return err
+}
+
+// clearVolume deletes everything inside a volume.
+func clearVolume(vol, helperImage string) error {
+	args := []string{
+		"run", "--rm",
+		"--volume", vol + ":/volume",
+		helperImage,
+		"find", "/volume", "-mindepth", "1", "-delete",
+	}
+	_, err := runner.Output(args...)
+	return err
+}
+
+// copyVolume copies everything inside a volume into another.
+func copyVolume(from, to, helperImage string) error {
+	args := []string{
+		"run", "--rm",
+		"--volume", from + ":/from:ro",
+		"--volume", to + ":/to",
+		helperImage,
+		"cp", "-a", "/from/.", "/to/",
+	}
+	_, err := runner.Output(args...)
+	return err
+}
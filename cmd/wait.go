@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/probe"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// waitForService blocks until the service's x-dctl-wait probe succeeds.
+// Services without a wait config are considered ready immediately.
+// Probe hosts that name a project service are resolved to that service's
+// container address, so targets like tcp://db:5432 work from the host.
+func waitForService(ctx context.Context, cc *composeContext, svcName string) error {
+	wc, target, err := waitTarget(cc, svcName)
+	if err != nil || wc == nil {
+		return err
+	}
+
+	timeout, _ := time.ParseDuration(wc.Timeout)
+	interval, _ := time.ParseDuration(wc.Interval)
+
+	report.Infof("Waiting for %s (%s)", svcName, wc.WaitFor)
+	if err := probe.Wait(ctx, target, timeout, interval); err != nil {
+		return fmt.Errorf("service %s not ready: %w", svcName, err)
+	}
+	return nil
+}
+
+// waitTarget returns the service's x-dctl-wait config and its probe target,
+// or a nil config when the service has none.
+func waitTarget(cc *composeContext, svcName string) (*compose.WaitConfig, *probe.Target, error) {
+	cf := cc.composeFile
+	wc, ok := cf.Services[svcName].Wait.(*compose.WaitConfig)
+	if !ok || wc == nil {
+		return nil, nil, nil
+	}
+
+	target, err := probe.Parse(wc.WaitFor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service %s: %w", svcName, err)
+	}
+
+	if _, ok := cf.Services[target.Host]; ok {
+		addr, err := containerAddress(cc.containerName(target.Host))
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: resolving %s: %w", svcName, target.Host, err)
+		}
+		target = target.WithHost(addr)
+	}
+	return wc, target, nil
+}
+
+// waitForDependencies waits on every service_healthy dependency of svc that
+// defines an x-dctl-wait probe.
+func waitForDependencies(ctx context.Context, cc *composeContext, svc compose.Service) error {
+	deps, ok := svc.DependsOn.(map[string]compose.DependsOnCondition)
+	if !ok {
+		return nil
+	}
+	for dep, cond := range deps {
+		if cond.Condition != "service_healthy" {
+			continue
+		}
+		if err := waitForService(ctx, cc, dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectPollInterval is how often waitForProjects checks the projects
+// again.
+var projectPollInterval = time.Second
+
+// waitForProjects blocks until every dctl project the service names in
+// x-dctl-depends-on-project is running, or timeout passes.
+func waitForProjects(ctx context.Context, svcName string, svc compose.Service, timeout time.Duration) error {
+	projects, _ := svc.DependsOnProject.([]string)
+	deadline := time.Now().Add(timeout)
+	for _, name := range projects {
+		waiting := false
+		for {
+			reason := projectNotRunning(name)
+			if reason == "" {
+				break
+			}
+			if !time.Now().Before(deadline) {
+				return fmt.Errorf("service %s depends on project %s, which is not running: %s", svcName, name, reason)
+			}
+			if !waiting {
+				report.Infof("Waiting for project %s (%s)", name, reason)
+				waiting = true
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(projectPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// projectNotRunning returns why a dctl project is not running, or "" when
+// every container it recorded is.
+func projectNotRunning(name string) string {
+	state, err := compose.LoadProject(name)
+	if err != nil || len(state.Containers) == 0 {
+		return "not started"
+	}
+	var names []string
+	for _, svcName := range sortedKeys(state.Containers) {
+		names = append(names, state.Containers[svcName])
+	}
+	infos, err := runner.Inspect(names...)
+	if err != nil {
+		return err.Error()
+	}
+	status := make(map[string]string)
+	for _, info := range infos {
+		status[info.Configuration.ID] = info.Status
+	}
+	for _, cName := range names {
+		if status[cName] != "running" {
+			return cName + " is not running"
+		}
+	}
+	return ""
+}
+
+// containerAddress returns the first IP address of a running container.
+func containerAddress(cName string) (string, error) {
+	containers, err := runner.Inspect(cName)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range containers {
+		if addr := c.Address(); addr != "" {
+			return addr, nil
+		}
+	}
+	return "", fmt.Errorf("container %s has no network address", cName)
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/filesync"
+	"github.com/sonnes/dctl/pkg/report"
+	"github.com/sonnes/dctl/pkg/runner"
+	"github.com/sonnes/dctl/pkg/watch"
+	"github.com/urfave/cli/v3"
+)
+
+func init() { RegisterAlpha(watchCommand) }
+
+// watchCommand returns the compose watch command.
+func watchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "Watch develop.watch paths and sync, restart or rebuild services on change",
+		ArgsUsage: "[SERVICE...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "no-up", Usage: "Do not start the project before watching"},
+		},
+		Action: composeWatchAction,
+	}
+}
+
+func composeWatchAction(ctx context.Context, cmd *cli.Command) error {
+	cc, err := resolveComposeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	services := cmd.Args().Slice()
+	if len(services) == 0 {
+		services = sortedKeys(cc.composeFile.Services)
+	}
+	var watched []string
+	for _, svcName := range services {
+		svc, ok := cc.composeFile.Services[svcName]
+		if !ok {
+			return compose.UnknownServiceError(svcName, sortedKeys(cc.composeFile.Services))
+		}
+		if svc.Develop != nil && len(svc.Develop.Watch) > 0 {
+			watched = append(watched, svcName)
+		}
+	}
+	if len(watched) == 0 {
+		return fmt.Errorf("none of the selected services is configured for watch, add a develop.watch section")
+	}
+
+	if !cmd.Bool("no-up") {
+		argv, err := projectInvocation(cmd, cc)
+		if err != nil {
+			return err
+		}
+		up := exec.Command(argv[0], append(argv[1:], "up", "--detach")...)
+		up.Stdout, up.Stderr = os.Stdout, os.Stderr
+		if err := up.Run(); err != nil {
+			return fmt.Errorf("starting project: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 1)
+	for _, svcName := range watched {
+		// Actions for one service run one at a time.
+		var mu sync.Mutex
+		for _, rule := range cc.composeFile.Services[svcName].Develop.Watch {
+			syncs := rule.Action == compose.WatchSync || rule.Action == compose.WatchSyncRestart
+			if syncs && rule.InitialSync {
+				if err := initialSync(cc.containerName(svcName), rule); err != nil {
+					return fmt.Errorf("initial sync of %s: %w", svcName, err)
+				}
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := watch.Watch(ctx, rule.Path, rule.Ignore, 0, func(changes []watch.Change) error {
+					mu.Lock()
+					defer mu.Unlock()
+					if err := applyWatchRule(cc, svcName, rule, changes); err != nil {
+						report.Warnf("%s: %v", svcName, err)
+					}
+					return nil
+				})
+				if err != nil {
+					select {
+					case errs <- fmt.Errorf("watching %s: %w", rule.Path, err):
+					default:
+					}
+					stop()
+				}
+			}()
+		}
+	}
+
+	report.Infof("Watching %d service(s), press Ctrl-C to stop", len(watched))
+	wg.Wait()
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// applyWatchRule runs a watch rule's action for a batch of changes.
+func applyWatchRule(cc *composeContext, svcName string, rule compose.WatchRule, changes []watch.Change) error {
+	cName := cc.containerName(svcName)
+	switch rule.Action {
+	case compose.WatchSync:
+		return syncChanges(cName, rule.Path, rule.Target, changes)
+	case compose.WatchSyncRestart:
+		if err := syncChanges(cName, rule.Path, rule.Target, changes); err != nil {
+			return err
+		}
+		return restartContainer(cc, svcName)
+	case compose.WatchRestart:
+		return restartContainer(cc, svcName)
+	case compose.WatchRebuild:
+		return rebuildService(cc, svcName)
+	}
+	return nil
+}
+
+// syncChanges copies changed files under root into the container at
+// target and deletes removed ones. Files travel as one tar stream over
+// exec; containers without tar fall back to copying file by file.
+func syncChanges(cName, root, target string, changes []watch.Change) error {
+	copies, removals := filesync.Split(changes)
+	report.Infof("Syncing %d file(s) to %s", len(copies)+len(removals), cName)
+
+	for len(removals) > 0 {
+		batch := removals[:min(len(removals), 200)]
+		removals = removals[len(batch):]
+		args := []string{"exec", cName, "rm", "-rf", "--"}
+		for _, rel := range batch {
+			args = append(args, path.Join(target, rel))
+		}
+		if _, err := runner.Output(args...); err != nil {
+			return fmt.Errorf("removing files: %w", err)
+		}
+	}
+
+	for _, c := range changes {
+		if c.Rel == "." && !c.Removed {
+			// The watched path is a single file and target names it.
+			if err := copyIntoContainer(cName, c.Path, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(copies) == 0 {
+		return nil
+	}
+	err := tarIntoContainer(cName, root, target, copies)
+	if err == nil {
+		return nil
+	}
+	report.Warnf("tar sync failed (%v), copying files one by one", err)
+	for _, rel := range copies {
+		if err := copyIntoContainer(cName, filepath.Join(root, rel), path.Join(target, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tarIntoContainer streams files under root to a tar process extracting
+// into target inside the container.
+func tarIntoContainer(cName, root, target string, rels []string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := filesync.Tar(pw, root, rels)
+		pw.CloseWithError(err)
+	}()
+	err := runner.RunInput(pr, "exec", "--interactive", cName,
+		"sh", "-c", `mkdir -p "$1" && tar -xf - -C "$1"`, "sh", target)
+	pr.Close()
+	return err
+}
+
+// initialSync copies everything under a sync rule's path that is not
+// ignored, so the container starts from the host's files.
+func initialSync(cName string, rule compose.WatchRule) error {
+	files, err := watch.Snapshot(rule.Path, rule.Ignore)
+	if err != nil {
+		return err
+	}
+	return syncChanges(cName, rule.Path, rule.Target, watch.Diff(rule.Path, nil, files))
+}
+
+// copyIntoContainer streams a host file to a path inside the container.
+func copyIntoContainer(cName, src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // removed again before the sync ran
+		}
+		return fmt.Errorf("opening %s: %w", src, err)
+	}
+	defer f.Close()
+
+	err = runner.RunInput(f, "exec", "--interactive", cName,
+		"sh", "-c", `mkdir -p "$(dirname "$1")" && cat > "$1"`, "sh", dest)
+	if err != nil {
+		return fmt.Errorf("copying %s: %w", dest, err)
+	}
+	return nil
+}
+
+func restartContainer(cc *composeContext, svcName string) error {
+	cName := cc.containerName(svcName)
+	report.Infof("Restarting %s", cName)
+	if err := stopService(nil, cc, svcName, cName); err != nil {
+		return fmt.Errorf("stopping %s: %w", cName, err)
+	}
+	if _, err := runner.Output("start", cName); err != nil {
+		return fmt.Errorf("starting %s: %w", cName, err)
+	}
+	return nil
+}
+
+// rebuildService rebuilds a service's image and recreates its container,
+// recording the new configuration in project state.
+func rebuildService(cc *composeContext, svcName string) error {
+	svc := cc.composeFile.Services[svcName]
+	bc, ok := svc.Build.(*compose.BuildConfig)
+	if !ok || bc == nil {
+		return fmt.Errorf("rebuild requires a build section")
+	}
+
+	report.Infof("Rebuilding %s", svcName)
+	buildArgs := composeBuildCLIArgs(bc, serviceImage(cc.projectName, svcName, svc), servicePlatform(svc))
+	if err := runner.RunInput(nil, buildArgs...); err != nil {
+		return fmt.Errorf("building %s: %w", svcName, err)
+	}
+
+	args, err := cc.runArgs(svcName)
+	if err != nil {
+		return err
+	}
+	cName := cc.containerName(svcName)
+	report.Infof("Recreating %s", cName)
+	if err := stopService(nil, cc, svcName, cName); err != nil {
+		report.Warnf("failed to stop %s: %v", svcName, err)
+	}
+	_, _ = runner.Output("delete", cName)
+	if err := startContainer(args); err != nil {
+		return fmt.Errorf("starting %s: %w", cName, err)
+	}
+
+	state, err := compose.LoadProject(cc.projectName)
+	if err != nil {
+		return nil // not started by up; nothing to record
+	}
+	if state.Containers == nil {
+		state.Containers = make(map[string]string)
+	}
+	if state.ConfigHashes == nil {
+		state.ConfigHashes = make(map[string]string)
+	}
+	if state.RunArgs == nil {
+		state.RunArgs = make(map[string][]string)
+	}
+	state.Containers[svcName] = cName
+	state.ConfigHashes[svcName] = compose.ConfigHash(args)
+	state.RunArgs[svcName] = args
+	return compose.SaveProject(state)
+}
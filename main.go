@@ -10,8 +10,11 @@ import (
 
 func main() {
 	app := cmd.NewApp()
-	if err := app.Run(context.Background(), os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if err := app.Run(context.Background(), cmd.PluginArgs(app, os.Args)); err != nil {
+		code, report := cmd.ExitCode(err)
+		if report {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
 	}
 }
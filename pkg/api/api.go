@@ -0,0 +1,226 @@
+// Package api serves dctl project operations over HTTP so that GUIs and
+// editor extensions can drive dctl without shelling out to it.
+//
+// Routes (all under /v1):
+//
+//	GET  /projects               saved projects
+//	GET  /projects/{name}/ps     the project's service containers
+//	POST /projects/{name}/up     start services (?service=NAME, repeatable)
+//	POST /projects/{name}/down   stop and remove the project
+//	GET  /projects/{name}/logs   stream logs (?service=, ?tail=N, ?follow=true)
+//
+// JSON endpoints answer errors with {"error": "..."}. Streaming endpoints
+// send plain text as it is produced and report failures in the Dctl-Error
+// trailer, since the status line has already been written.
+//
+// The API drives the container runtime, so browsers may not reach it:
+// requests carrying an Origin header are refused. When the handler has a
+// token, every request must also present it as a bearer token.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrNotFound is returned by a Backend for projects it does not know.
+var ErrNotFound = errors.New("not found")
+
+// ErrorTrailer reports the failure of a streaming request.
+const ErrorTrailer = "Dctl-Error"
+
+// Project is a saved compose project.
+type Project struct {
+	Name     string            `json:"name"`
+	Dir      string            `json:"dir"`
+	Services []string          `json:"services"`
+	Status   map[string]string `json:"status,omitempty"` // service → status dctl last recorded
+}
+
+// Service is one service container of a project.
+type Service struct {
+	Service   string `json:"service"`
+	Container string `json:"container"`
+	Image     string `json:"image,omitempty"`
+	Status    string `json:"status"`
+}
+
+// LogOptions selects what a logs request streams.
+type LogOptions struct {
+	Services []string
+	Tail     int // lines per service; 0 for all
+	Follow   bool
+}
+
+// Backend performs the operations the server exposes. Operations that
+// produce output write it to w as it happens.
+type Backend interface {
+	Projects() ([]Project, error)
+	Ps(project string) ([]Service, error)
+	Up(ctx context.Context, project string, services []string, w io.Writer) error
+	Down(ctx context.Context, project string, w io.Writer) error
+	Logs(ctx context.Context, project string, opts LogOptions, w io.Writer) error
+}
+
+// NewHandler returns the HTTP handler serving b. A non-empty token is
+// required of every request as "Authorization: Bearer TOKEN".
+func NewHandler(b Backend, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		projects, err := b.Projects()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, projects)
+	})
+	mux.HandleFunc("GET /v1/projects/{name}/ps", func(w http.ResponseWriter, r *http.Request) {
+		services, err := b.Ps(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, services)
+	})
+	mux.HandleFunc("POST /v1/projects/{name}/up", func(w http.ResponseWriter, r *http.Request) {
+		stream(w, func(out io.Writer) error {
+			return b.Up(r.Context(), r.PathValue("name"), r.URL.Query()["service"], out)
+		})
+	})
+	mux.HandleFunc("POST /v1/projects/{name}/down", func(w http.ResponseWriter, r *http.Request) {
+		stream(w, func(out io.Writer) error {
+			return b.Down(r.Context(), r.PathValue("name"), out)
+		})
+	})
+	mux.HandleFunc("GET /v1/projects/{name}/logs", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := LogOptions{Services: q["service"]}
+		if tail := q.Get("tail"); tail != "" {
+			n, err := strconv.Atoi(tail)
+			if err != nil || n < 0 {
+				writeError(w, fmt.Errorf("invalid tail %q", tail))
+				return
+			}
+			opts.Tail = n
+		}
+		if follow := q.Get("follow"); follow != "" {
+			f, err := strconv.ParseBool(follow)
+			if err != nil {
+				writeError(w, fmt.Errorf("invalid follow %q", follow))
+				return
+			}
+			opts.Follow = f
+		}
+		stream(w, func(out io.Writer) error {
+			return b.Logs(r.Context(), r.PathValue("name"), opts, out)
+		})
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeStatus(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeStatus(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Listen opens the listener for an address of the form unix:///path,
+// tcp://host:port or host:port. TCP addresses must be on the loopback
+// interface. A stale unix socket is replaced, and the new one is private
+// to the owner from the moment it exists.
+func Listen(addr string) (net.Listener, error) {
+	network, address := "tcp", addr
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		network, address = "unix", path
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	} else if hostPort, ok := strings.CutPrefix(addr, "tcp://"); ok {
+		address = hostPort
+	} else if strings.Contains(addr, "://") {
+		return nil, fmt.Errorf("unsupported listen address %q (want unix:// or tcp://)", addr)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("empty listen address %q", addr)
+	}
+	if network == "tcp" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("listen address %q is not a loopback address", addr)
+		}
+	}
+
+	if network == "unix" {
+		// Only the owner may drive the container runtime through the socket;
+		// the umask keeps it from ever being reachable by anyone else.
+		defer syscall.Umask(syscall.Umask(0o177))
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	return l, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	writeStatus(w, status, err)
+}
+
+func writeStatus(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// stream runs fn with a writer that flushes every write to the client and
+// reports fn's error in the ErrorTrailer.
+func stream(w http.ResponseWriter, fn func(io.Writer) error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Trailer", ErrorTrailer)
+	w.WriteHeader(http.StatusOK)
+	if err := fn(&flushWriter{w: w}); err != nil {
+		w.Header().Set(ErrorTrailer, err.Error())
+	}
+}
+
+// flushWriter flushes after every write so output streams line by line.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+	return n, err
+}
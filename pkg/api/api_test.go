@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type fakeBackend struct {
+	upServices []string
+	logOpts    LogOptions
+}
+
+func (f *fakeBackend) Projects() ([]Project, error) {
+	return []Project{{Name: "shop", Dir: "/src/shop", Services: []string{"db", "web"}}}, nil
+}
+
+func (f *fakeBackend) Ps(project string) ([]Service, error) {
+	if project != "shop" {
+		return nil, fmt.Errorf("project %q: %w", project, ErrNotFound)
+	}
+	return []Service{{Service: "web", Container: "shop_web", Status: "running"}}, nil
+}
+
+func (f *fakeBackend) Up(ctx context.Context, project string, services []string, w io.Writer) error {
+	f.upServices = services
+	fmt.Fprintln(w, "Starting web")
+	return nil
+}
+
+func (f *fakeBackend) Down(ctx context.Context, project string, w io.Writer) error {
+	fmt.Fprintln(w, "Stopping web")
+	return errors.New("exit status 1")
+}
+
+func (f *fakeBackend) Logs(ctx context.Context, project string, opts LogOptions, w io.Writer) error {
+	f.logOpts = opts
+	fmt.Fprintln(w, "hello")
+	return nil
+}
+
+func TestHandlerJSON(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(&fakeBackend{}, ""))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var projects []Project
+	json.NewDecoder(resp.Body).Decode(&projects)
+	resp.Body.Close()
+	if len(projects) != 1 || projects[0].Name != "shop" {
+		t.Errorf("projects = %+v", projects)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/projects/shop/ps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []Service
+	json.NewDecoder(resp.Body).Decode(&services)
+	resp.Body.Close()
+	if len(services) != 1 || services[0].Status != "running" {
+		t.Errorf("ps = %+v", services)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/projects/blog/ps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown project status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHandlerStream(t *testing.T) {
+	b := &fakeBackend{}
+	srv := httptest.NewServer(NewHandler(b, ""))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v1/projects/shop/up?service=web&service=db", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Starting web\n" || resp.Trailer.Get(ErrorTrailer) != "" {
+		t.Errorf("up = %q, trailer %q", body, resp.Trailer.Get(ErrorTrailer))
+	}
+	if want := []string{"web", "db"}; !reflect.DeepEqual(b.upServices, want) {
+		t.Errorf("up services = %v, want %v", b.upServices, want)
+	}
+
+	resp, err = http.Post(srv.URL+"/v1/projects/shop/down", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := resp.Trailer.Get(ErrorTrailer); got != "exit status 1" {
+		t.Errorf("down trailer = %q, want exit status 1", got)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/projects/shop/logs?service=web&tail=10&follow=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := (LogOptions{Services: []string{"web"}, Tail: 10, Follow: true}); !reflect.DeepEqual(b.logOpts, want) {
+		t.Errorf("log options = %+v, want %+v", b.logOpts, want)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/projects/shop/logs?tail=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid tail status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestHandlerAuth(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(&fakeBackend{}, "secret"))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong token", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized},
+		{"token", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"browser", map[string]string{"Authorization": "Bearer secret", "Origin": "https://example.com"}, http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+"/v1/projects", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "dctl.sock")
+	for range 2 { // the second listen replaces the stale socket
+		l, err := Listen("unix://" + sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Addr().Network() != "unix" {
+			t.Errorf("network = %s, want unix", l.Addr().Network())
+		}
+		if fi, err := os.Stat(sock); err != nil {
+			t.Fatal(err)
+		} else if fi.Mode().Perm() != 0o600 {
+			t.Errorf("socket mode = %v, want 0600", fi.Mode().Perm())
+		}
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+	}
+
+	l, err := Listen("tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	if _, err := Listen("http://localhost"); err == nil {
+		t.Error("Listen(http://) succeeded, want error")
+	}
+	for _, addr := range []string{"tcp://0.0.0.0:0", ":0", "tcp://192.0.2.1:7070"} {
+		if l, err := Listen(addr); err == nil {
+			l.Close()
+			t.Errorf("Listen(%s) succeeded, want a loopback address required", addr)
+		}
+	}
+}
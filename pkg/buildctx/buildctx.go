@@ -0,0 +1,251 @@
+// Package buildctx measures a build context the way the builder will see
+// it, after the .dockerignore patterns have excluded what is not sent.
+package buildctx
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entry is a top-level file or directory of a context, with the size and
+// file count of what it contributes.
+type Entry struct {
+	Path  string
+	Size  int64
+	Files int
+}
+
+// Summary is what a context sends to the builder.
+type Summary struct {
+	Size    int64
+	Files   int
+	Ignore  string  // the ignore file used, "" if none
+	Entries []Entry // largest first
+}
+
+// pattern is a compiled .dockerignore line.
+type pattern struct {
+	re        *regexp.Regexp
+	exclusion bool // a "!" line, which re-includes what it matches
+}
+
+// Matcher decides which context paths .dockerignore patterns exclude.
+type Matcher struct {
+	patterns   []pattern
+	exceptions bool
+}
+
+// NewMatcher compiles .dockerignore patterns. Lines are matched in order
+// and the last one matching a path decides it; "!" re-includes, "**"
+// matches any number of directories and a leading "/" is ignored.
+func NewMatcher(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := pattern{}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.exclusion = true
+			m.exceptions = true
+			line = strings.TrimSpace(rest)
+		}
+		line = strings.TrimPrefix(path.Clean(filepath.ToSlash(line)), "/")
+		if line == "" || line == "." {
+			continue
+		}
+		re, err := compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid .dockerignore pattern %q: %w", line, err)
+		}
+		p.re = re
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// compile turns a pattern into a regexp matching whole slash-separated
+// paths.
+func compile(p string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '*' && strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			class := p[i+1 : i+end]
+			if rest, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + rest
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// Excluded reports whether a slash-separated path relative to the context
+// root is left out. A pattern matching a directory excludes everything
+// under it.
+func (m *Matcher) Excluded(rel string) bool {
+	excluded := false
+	for _, p := range m.patterns {
+		// Only a pattern that would change the result needs checking.
+		if p.exclusion == excluded && matches(p.re, rel) {
+			excluded = !p.exclusion
+		}
+	}
+	return excluded
+}
+
+// matches reports whether re matches rel or one of its parent directories.
+func matches(re *regexp.Regexp, rel string) bool {
+	for {
+		if re.MatchString(rel) {
+			return true
+		}
+		i := strings.LastIndex(rel, "/")
+		if i < 0 {
+			return false
+		}
+		rel = rel[:i]
+	}
+}
+
+// IgnoreFile returns the ignore file that applies to a context: one named
+// after the Dockerfile (Dockerfile.dockerignore) wins over .dockerignore
+// at the context root. It returns "" when there is neither.
+func IgnoreFile(dir, dockerfile string) string {
+	if dockerfile != "" {
+		if !filepath.IsAbs(dockerfile) {
+			dockerfile = filepath.Join(dir, dockerfile)
+		}
+		if f := dockerfile + ".dockerignore"; fileExists(f) {
+			return f
+		}
+	}
+	if f := filepath.Join(dir, ".dockerignore"); fileExists(f) {
+		return f
+	}
+	return ""
+}
+
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
+// readLines returns the lines of a file.
+func readLines(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Measure walks a context directory and sums what its ignore file lets
+// through. dockerfile is the --file path, relative to dir or absolute.
+func Measure(dir, dockerfile string) (*Summary, error) {
+	s := &Summary{Ignore: IgnoreFile(dir, dockerfile)}
+	var lines []string
+	if s.Ignore != "" {
+		var err error
+		if lines, err = readLines(s.Ignore); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", s.Ignore, err)
+		}
+	}
+	m, err := NewMatcher(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Ignore, err)
+	}
+
+	entries := make(map[string]*Entry)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if m.Excluded(rel) {
+			// A "!" pattern may re-include something below an excluded
+			// directory, so only skip it whole when there is none.
+			if d.IsDir() && !m.exceptions {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		top, _, _ := strings.Cut(rel, "/")
+		e := entries[top]
+		if e == nil {
+			e = &Entry{Path: top}
+			entries[top] = e
+		}
+		e.Size += info.Size()
+		e.Files++
+		s.Size += info.Size()
+		s.Files++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("measuring build context %s: %w", dir, err)
+	}
+
+	for _, e := range entries {
+		s.Entries = append(s.Entries, *e)
+	}
+	sort.Slice(s.Entries, func(i, j int) bool {
+		if s.Entries[i].Size != s.Entries[j].Size {
+			return s.Entries[i].Size > s.Entries[j].Size
+		}
+		return s.Entries[i].Path < s.Entries[j].Path
+	})
+	return s, nil
+}
@@ -0,0 +1,108 @@
+package buildctx
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher([]string{
+		"# comment",
+		"node_modules",
+		"/dist",
+		"*.log",
+		"**/*.tmp",
+		"!keep.log",
+		"docs/**",
+		"!docs/README.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"main.go":                 false,
+		"node_modules":            true,
+		"node_modules/react/x.js": true,
+		"web/node_modules/x.js":   false,
+		"dist/app.js":             true,
+		"debug.log":               true,
+		"keep.log":                false,
+		"logs/debug.log":          false,
+		"a/b/c.tmp":               true,
+		"c.tmp":                   true,
+		"docs/guide.md":           true,
+		"docs/README.md":          false,
+		"docs/README.md.orig":     true,
+		"src/docs/guide.md":       false,
+	}
+	for rel, want := range tests {
+		if got := m.Excluded(rel); got != want {
+			t.Errorf("Excluded(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestMatcherInvalid(t *testing.T) {
+	if _, err := NewMatcher([]string{"[abc"}); err == nil {
+		t.Error("expected an error for an unterminated class")
+	}
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Dockerfile"), 10)
+	writeFile(t, filepath.Join(dir, "main.go"), 100)
+	writeFile(t, filepath.Join(dir, "src", "a.go"), 200)
+	writeFile(t, filepath.Join(dir, "src", "b.go"), 300)
+	writeFile(t, filepath.Join(dir, "node_modules", "x", "index.js"), 5000)
+	if err := os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("node_modules\n.dockerignore\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Measure(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != 610 || s.Files != 4 {
+		t.Errorf("size, files = %d, %d, want 610, 4", s.Size, s.Files)
+	}
+	if s.Ignore != filepath.Join(dir, ".dockerignore") {
+		t.Errorf("ignore = %q", s.Ignore)
+	}
+	want := []Entry{
+		{Path: "src", Size: 500, Files: 2},
+		{Path: "main.go", Size: 100, Files: 1},
+		{Path: "Dockerfile", Size: 10, Files: 1},
+	}
+	if !reflect.DeepEqual(s.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", s.Entries, want)
+	}
+
+	// An ignore file named after the Dockerfile wins over .dockerignore.
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile.dockerignore"), []byte("src\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Measure(dir, "Dockerfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Ignore != filepath.Join(dir, "Dockerfile.dockerignore") {
+		t.Errorf("ignore = %q", s.Ignore)
+	}
+	if s.Entries[0].Path != "node_modules" {
+		t.Errorf("largest entry = %q, want node_modules", s.Entries[0].Path)
+	}
+}
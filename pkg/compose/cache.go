@@ -0,0 +1,135 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// modelCache is a cached merged compose model and what it was built from.
+type modelCache struct {
+	Files []fileStamp        `json:"files"`
+	Vars  map[string]*string `json:"vars"`  // interpolated variables; nil when unset
+	Model string             `json:"model"` // merged, interpolated compose YAML
+}
+
+// fileStamp identifies a version of a compose file.
+type fileStamp struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+}
+
+// LoadCached is Load, reusing the merged model of an earlier load from
+// cacheDir while the compose files and the variables they interpolate are
+// unchanged. Services are still resolved on every load, so env_file and
+// environment entries without values are current. Sets that include stdin,
+// remote or OCI files are not cached.
+func LoadCached(files []string, projectDir, cacheDir string) (*ComposeFile, error) {
+	if projectDir == "" {
+		var err error
+		if files, err = AbsFiles(files); err != nil {
+			return nil, err
+		}
+		if projectDir, err = DefaultProjectDir(files); err != nil {
+			return nil, err
+		}
+	}
+	paths, err := ResolveFiles(files, projectDir)
+	if err != nil {
+		return Load(files, projectDir)
+	}
+	cachePath := modelCachePath(cacheDir, projectDir, paths)
+
+	// Stamp before reading so a file changed mid-load invalidates the entry.
+	stamps := make([]fileStamp, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return Load(files, projectDir)
+		}
+		stamps = append(stamps, fileStamp{Path: p, Size: info.Size(), ModTime: info.ModTime().UnixNano()})
+	}
+
+	if merged, ok := readModelCache(cachePath, stamps); ok {
+		return resolveModel(merged, projectDir)
+	}
+
+	vars := make(map[string]*string)
+	merged, err := mergeFiles(paths, projectDir, func(key, value string, ok bool) {
+		if ok {
+			vars[key] = &value
+		} else {
+			vars[key] = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data, err := yaml.Marshal(merged); err == nil {
+		writeModelCache(cachePath, modelCache{Files: stamps, Vars: vars, Model: string(data)})
+	}
+	return resolveModel(merged, projectDir)
+}
+
+// modelCachePath returns where the model of a file set is cached.
+func modelCachePath(cacheDir, projectDir string, paths []string) string {
+	sum := sha256.Sum256([]byte(projectDir + "\x00" + strings.Join(paths, "\x00")))
+	return filepath.Join(cacheDir, "model-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// readModelCache returns the cached merged model when its files and
+// variables still match.
+func readModelCache(path string, stamps []fileStamp) (*yaml.Node, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var c modelCache
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Files) != len(stamps) {
+		return nil, false
+	}
+	for i, s := range stamps {
+		if c.Files[i] != s {
+			return nil, false
+		}
+	}
+	for key, want := range c.Vars {
+		v, ok := os.LookupEnv(key)
+		if ok != (want != nil) || ok && v != *want {
+			return nil, false
+		}
+	}
+	node, err := parseComposeNode([]byte(c.Model))
+	if err != nil {
+		return nil, false
+	}
+	return node, true
+}
+
+// writeModelCache stores a model, ignoring failures: the cache only saves
+// work.
+func writeModelCache(path string, c modelCache) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".model-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err != nil || cerr != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,77 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadCached(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	file := filepath.Join(dir, "compose.yaml")
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	image := func() string {
+		t.Helper()
+		cf, err := LoadCached(nil, dir, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cf.Services["web"].Image
+	}
+
+	t.Setenv("TAG", "1.0")
+	write("services:\n  web:\n    image: nginx:${TAG}\n", time.Unix(1000, 0))
+	if got := image(); got != "nginx:1.0" {
+		t.Fatalf("first load image = %q, want nginx:1.0", got)
+	}
+
+	// Tamper with the cached model to observe cache hits.
+	entries, _ := filepath.Glob(filepath.Join(cacheDir, "model-*.json"))
+	if len(entries) != 1 {
+		t.Fatalf("cache entries = %v, want one", entries)
+	}
+	data, _ := os.ReadFile(entries[0])
+	os.WriteFile(entries[0], []byte(strings.Replace(string(data), "nginx:1.0", "cached:1.0", 1)), 0o644)
+	if got := image(); got != "cached:1.0" {
+		t.Errorf("unchanged load image = %q, want the cached model", got)
+	}
+
+	t.Setenv("TAG", "2.0")
+	if got := image(); got != "nginx:2.0" {
+		t.Errorf("image after variable change = %q, want nginx:2.0", got)
+	}
+
+	write("services:\n  web:\n    image: httpd:${TAG}\n", time.Unix(2000, 0))
+	if got := image(); got != "httpd:2.0" {
+		t.Errorf("image after file change = %q, want httpd:2.0", got)
+	}
+}
+
+func TestLoadCached_UncachedSources(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	stdin = strings.NewReader("services:\n  web:\n    image: nginx\n")
+	defer func() { stdin = os.Stdin }()
+
+	cf, err := LoadCached([]string{"-"}, dir, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Services["web"].Image != "nginx" {
+		t.Errorf("image = %q, want nginx", cf.Services["web"].Image)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("stdin compose file was cached")
+	}
+}
@@ -0,0 +1,75 @@
+package compose
+
+// Clone rescopes a compose model for a copy of its project that runs beside
+// the original, as compose clone creates. The networks and volumes the
+// project creates are renamed with prefix, as are the services' references
+// to them; external ones are shared with the original. Published host ports
+// are left open so the copy is given free ones, and container_name is
+// dropped since two containers cannot share it.
+func Clone(cf *ComposeFile, prefix string) {
+	networks := make(map[string]Network, len(cf.Networks))
+	for key, n := range cf.Networks {
+		if n.External {
+			networks[key] = n
+			continue
+		}
+		if n.Name != "" {
+			n.Name = prefix + n.Name
+		}
+		networks[prefix+key] = n
+	}
+	volumes := make(map[string]VolumeConfig, len(cf.Volumes))
+	for key, v := range cf.Volumes {
+		if v.External {
+			volumes[key] = v
+			continue
+		}
+		if v.Name != "" {
+			v.Name = prefix + v.Name
+		}
+		volumes[prefix+key] = v
+	}
+
+	for svcName, svc := range cf.Services {
+		svc.ContainerName = ""
+		for i, spec := range svc.Volumes {
+			if v, ok := cf.Volumes[ParseMount(spec).Source]; ok && !v.External {
+				svc.Volumes[i] = prefix + spec
+			}
+		}
+
+		if nets, ok := svc.Networks.(map[string]interface{}); ok {
+			renamed := make(map[string]interface{}, len(nets))
+			for key, attach := range nets {
+				if n, ok := cf.Networks[key]; ok && !n.External {
+					key = prefix + key
+				}
+				renamed[key] = attach
+			}
+			svc.Networks = renamed
+		}
+
+		var ports []string
+		for _, spec := range svc.Ports {
+			mappings, err := ParsePort(spec)
+			if err != nil {
+				// Invalid specs are left for up to report.
+				ports = append(ports, spec)
+				continue
+			}
+			for _, m := range mappings {
+				m.Published = 0
+				ports = append(ports, m.String())
+			}
+		}
+		svc.Ports = ports
+		cf.Services[svcName] = svc
+	}
+	cf.Networks, cf.Volumes = networks, volumes
+}
+
+// ClonePrefix returns the prefix compose clone gives the networks and
+// volumes of a copy named project.
+func ClonePrefix(project string) string {
+	return project + "_"
+}
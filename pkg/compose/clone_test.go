@@ -0,0 +1,44 @@
+package compose
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	cf := &ComposeFile{
+		Services: map[string]Service{
+			"db": {
+				Image:         "postgres",
+				ContainerName: "shop-db",
+				Volumes:       []string{"data:/var/lib/postgresql/data", "shared:/shared:ro", "./init:/docker-entrypoint-initdb.d"},
+				Networks:      map[string]interface{}{"back": nil, "outside": nil},
+				Ports:         []string{"127.0.0.1:5432:5432", "9000-9001:9000-9001/udp", "80"},
+			},
+		},
+		Networks: map[string]Network{"back": {}, "outside": {External: true}},
+		Volumes:  map[string]VolumeConfig{"data": {}, "shared": {External: true}, "cache": {Name: "cache-v2"}},
+	}
+	Clone(cf, ClonePrefix("copy"))
+
+	db := cf.Services["db"]
+	if db.ContainerName != "" {
+		t.Errorf("container_name = %q, want it dropped", db.ContainerName)
+	}
+	if want := []string{"copy_data:/var/lib/postgresql/data", "shared:/shared:ro", "./init:/docker-entrypoint-initdb.d"}; !slices.Equal(db.Volumes, want) {
+		t.Errorf("volumes = %q, want %q", db.Volumes, want)
+	}
+	if want := map[string]interface{}{"copy_back": nil, "outside": nil}; !reflect.DeepEqual(db.Networks, want) {
+		t.Errorf("networks = %v, want %v", db.Networks, want)
+	}
+	if want := []string{"127.0.0.1::5432", "9000/udp", "9001/udp", "80"}; !slices.Equal(db.Ports, want) {
+		t.Errorf("ports = %q, want %q", db.Ports, want)
+	}
+	if want := map[string]Network{"copy_back": {}, "outside": {External: true}}; !reflect.DeepEqual(cf.Networks, want) {
+		t.Errorf("top-level networks = %v, want %v", cf.Networks, want)
+	}
+	if want := map[string]VolumeConfig{"copy_data": {}, "shared": {External: true}, "copy_cache": {Name: "copy_cache-v2"}}; !reflect.DeepEqual(cf.Volumes, want) {
+		t.Errorf("top-level volumes = %v, want %v", cf.Volumes, want)
+	}
+}
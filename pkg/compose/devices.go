@@ -0,0 +1,85 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+)
+
+// AllDevices is the resolved count of a device request for every matching
+// device.
+const AllDevices = -1
+
+// DeviceRequests returns the devices svc reserves: its
+// deploy.resources.reservations.devices followed by the request its
+// x-dctl-gpu shortcut stands for.
+func DeviceRequests(svc Service) []DeviceRequest {
+	var reqs []DeviceRequest
+	if svc.Deploy != nil {
+		reqs = slices.Clone(svc.Deploy.Resources.Reservations.Devices)
+	}
+	if n, ok := svc.GPU.(int); ok && n != 0 {
+		reqs = append(reqs, DeviceRequest{Capabilities: []string{"gpu"}, Count: n})
+	}
+	return reqs
+}
+
+// resolveDevices normalizes the counts of device requests: "all" or no
+// count and no device_ids → AllDevices, as in Docker.
+func resolveDevices(deploy *DeployConfig) error {
+	if deploy == nil {
+		return nil
+	}
+	devices := deploy.Resources.Reservations.Devices
+	for i := range devices {
+		d := &devices[i]
+		if len(d.Capabilities) == 0 {
+			return fmt.Errorf("devices[%d]: capabilities is required", i)
+		}
+		switch count := d.Count.(type) {
+		case nil:
+			if len(d.DeviceIDs) == 0 {
+				d.Count = AllDevices
+			} else {
+				d.Count = 0
+			}
+		case string:
+			if count != "all" {
+				return fmt.Errorf("devices[%d]: count %q is not a number or \"all\"", i, count)
+			}
+			d.Count = AllDevices
+		case int:
+			if count < 0 {
+				return fmt.Errorf("devices[%d]: count %d is negative", i, count)
+			}
+		default:
+			return fmt.Errorf("devices[%d]: count has unsupported type %T", i, d.Count)
+		}
+		if d.Count != 0 && len(d.DeviceIDs) > 0 {
+			return fmt.Errorf("devices[%d]: count and device_ids cannot both be set", i)
+		}
+	}
+	return nil
+}
+
+// resolveGPU normalizes x-dctl-gpu: true or "all" → AllDevices, a number →
+// that many GPUs, false → 0.
+func resolveGPU(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if val {
+			return AllDevices, nil
+		}
+		return 0, nil
+	case string:
+		if val == "all" {
+			return AllDevices, nil
+		}
+	case int:
+		if val >= 0 {
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not true, false, \"all\" or a GPU count", v)
+}
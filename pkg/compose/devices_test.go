@@ -0,0 +1,65 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad_DeviceRequests(t *testing.T) {
+	dir := t.TempDir()
+	content := `services:
+  train:
+    image: trainer
+    x-dctl-gpu: 2
+    deploy:
+      resources:
+        reservations:
+          devices:
+            - capabilities: [gpu]
+              driver: nvidia
+            - capabilities: [gpu, compute]
+              device_ids: ["0", "3"]
+            - capabilities: [tpu]
+              count: all
+  web:
+    image: nginx
+    x-dctl-gpu: false
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DeviceRequest{
+		{Capabilities: []string{"gpu"}, Driver: "nvidia", Count: AllDevices},
+		{Capabilities: []string{"gpu", "compute"}, Count: 0, DeviceIDs: []string{"0", "3"}},
+		{Capabilities: []string{"tpu"}, Count: AllDevices},
+		{Capabilities: []string{"gpu"}, Count: 2},
+	}
+	if got := DeviceRequests(cf.Services["train"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("DeviceRequests(train) =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := DeviceRequests(cf.Services["web"]); got != nil {
+		t.Errorf("DeviceRequests(web) = %+v, want none", got)
+	}
+
+	for _, svc := range []string{
+		"x-dctl-gpu: some",
+		"x-dctl-gpu: -1",
+		"deploy: {resources: {reservations: {devices: [{count: 1}]}}}",
+		"deploy: {resources: {reservations: {devices: [{capabilities: [gpu], count: many}]}}}",
+		"deploy: {resources: {reservations: {devices: [{capabilities: [gpu], count: 1, device_ids: [\"0\"]}]}}}",
+	} {
+		content := "services:\n  train:\n    image: trainer\n    " + svc + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(nil, dir); err == nil {
+			t.Errorf("Load() accepted %s", svc)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ServiceEnvironment returns the variables a service's container gets,
+// layered with docker compose's precedence, highest first:
+//
+//  1. overrides such as `run -e`: KEY=VALUE, or KEY for dctl's own value
+//  2. environment entries, whose values may come from the shell through
+//     interpolation or a bare KEY
+//  3. env_file entries, later files overriding earlier ones
+//  4. the project's x-dctl-env defaults
+//  5. the image's ENV, which applies to every variable not set here
+//
+// A bare KEY whose variable is not set in the shell is left out, so the
+// image's value applies.
+func ServiceEnvironment(svc Service, overrides []string) (map[string]string, error) {
+	env := maps.Clone(svc.ProjectEnv)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	if files, ok := svc.EnvFile.([]string); ok {
+		for _, f := range files {
+			vars, err := ReadEnvFile(f)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(env, vars)
+		}
+	}
+	if vars, ok := svc.Environment.(map[string]string); ok {
+		maps.Copy(env, vars)
+	}
+	for _, o := range overrides {
+		if k, v, ok := strings.Cut(o, "="); ok {
+			env[k] = v
+		} else if v, ok := os.LookupEnv(o); ok {
+			env[o] = v
+		}
+	}
+	return env, nil
+}
+
+// RunEnvDir returns the directory holding the env files written for a
+// container's runs.
+func RunEnvDir(container string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "env", container), nil
+}
+
+// WriteRunEnv writes KEY=VALUE entries to an env file readable only by the
+// current user and returns its path, so the values reach the runtime
+// without appearing in its arguments. It is written as the container is
+// created, which reads it then, so the files of the container's earlier
+// incarnations are removed. The file stays until the container is
+// removed; see RemoveRunEnv.
+func WriteRunEnv(container string, env []string) (string, error) {
+	dir, err := RunEnvDir(container)
+	if err != nil {
+		return "", err
+	}
+	data := []byte(strings.Join(env, "\n") + "\n")
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating env directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("writing env file: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("reading env directory: %w", err)
+	}
+	for _, e := range entries {
+		if e.Name() != filepath.Base(path) {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+	return path, nil
+}
+
+// RemoveRunEnv removes the env files written for a container.
+func RemoveRunEnv(container string) error {
+	dir, err := RunEnvDir(container)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing env files of %s: %w", container, err)
+	}
+	return nil
+}
+
+// ReadEnvFile reads and parses an env file such as a service's env_file.
+func ReadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading env file: %w", err)
+	}
+	return parseEnvFile(data), nil
+}
+
+// parseEnvFile parses KEY=VALUE lines. Blank lines and # comments are
+// skipped, an optional "export " prefix is dropped, and values wrapped in
+// matching single or double quotes are unquoted. Unquoted and double-quoted
+// values are expanded like docker compose env files: $VAR and ${VAR}
+// (with the :- and - defaults) take dctl's environment, or else a variable
+// set earlier in the file, and $$ is a literal $. Single-quoted values are
+// kept as written.
+func parseEnvFile(data []byte) map[string]string {
+	env := make(map[string]string)
+	lookup := func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := env[key]
+		return v, ok
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'' {
+			env[key] = val[1 : len(val)-1]
+			continue
+		}
+		if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+			val = val[1 : len(val)-1]
+		}
+		env[key] = expandEnvValue(val, lookup)
+	}
+	return env
+}
+
+// envFileVarPattern matches $$, ${...} and $VAR in env file values.
+var envFileVarPattern = regexp.MustCompile(`\$(\$|\{[^}]+\}|[A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandEnvValue expands the variable references of an env file value.
+func expandEnvValue(val string, lookup func(string) (string, bool)) string {
+	return envFileVarPattern.ReplaceAllStringFunc(val, func(match string) string {
+		switch {
+		case match == "$$":
+			return "$"
+		case match[1] == '{':
+			return interpolateWith(match, lookup)
+		default:
+			v, _ := lookup(match[1:])
+			return v
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	data := []byte(`
+# comment
+TAG=1.0
+export REGION=eu-west-1
+QUOTED="hello world"
+SINGLE='x=y'
+EMPTY=
+NOEQUALS
+`)
+	want := map[string]string{
+		"TAG":    "1.0",
+		"REGION": "eu-west-1",
+		"QUOTED": "hello world",
+		"SINGLE": "x=y",
+		"EMPTY":  "",
+	}
+	if got := parseEnvFile(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEnvFile() = %v, want %v", got, want)
+	}
+}
+
+func TestServiceEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.env")
+	web := filepath.Join(dir, "web.env")
+	os.WriteFile(base, []byte("A=base\nB=base\nC=base\nD=base\n"), 0o644)
+	os.WriteFile(web, []byte("B=web\nC=web\nD=web\n"), 0o644)
+	t.Setenv("SHELL_VAR", "shell")
+
+	// A bare environment entry for an unset variable is dropped at load.
+	environment, err := resolveEnvironment([]interface{}{"C=compose", "D=compose", "SHELL_VAR", "DCTL_TEST_UNSET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := Service{EnvFile: []string{base, web}, Environment: environment,
+		ProjectEnv: map[string]string{"A": "project", "TZ": "UTC"}}
+	got, err := ServiceEnvironment(svc, []string{"D=cli", "SHELL_VAR", "DCTL_TEST_UNSET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"A":         "base",    // env_file beats x-dctl-env
+		"TZ":        "UTC",     // x-dctl-env fills the rest
+		"B":         "web",     // later env_file wins
+		"C":         "compose", // environment beats env_file
+		"D":         "cli",     // -e beats environment
+		"SHELL_VAR": "shell",   // bare entries take the shell's value
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ServiceEnvironment() = %v, want %v", got, want)
+	}
+
+	svc.EnvFile = []string{filepath.Join(dir, "missing.env")}
+	if _, err := ServiceEnvironment(svc, nil); err == nil {
+		t.Error("ServiceEnvironment() succeeded with a missing env_file")
+	}
+}
+
+func TestLoad_ProjectEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DCTL_TEST_PROXY", "http://proxy:3128")
+	content := `x-dctl-env:
+  HTTP_PROXY: ${DCTL_TEST_PROXY}
+  TZ: UTC
+services:
+  web:
+    image: nginx
+    environment:
+      TZ: Europe/Berlin
+  worker:
+    image: worker
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]map[string]string{
+		"web":    {"HTTP_PROXY": "http://proxy:3128", "TZ": "Europe/Berlin"},
+		"worker": {"HTTP_PROXY": "http://proxy:3128", "TZ": "UTC"},
+	}
+	for name, want := range tests {
+		got, err := ServiceEnvironment(cf.Services[name], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ServiceEnvironment(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseEnvFile_Interpolation(t *testing.T) {
+	t.Setenv("DCTL_TEST_REGION", "eu")
+	data := []byte(`
+APP_HOST=localhost
+BASE_URL=http://$APP_HOST:${APP_PORT:-80}
+APP_PORT=8080
+API_URL="${BASE_URL}/api"
+LITERAL='$APP_HOST'
+PRICE=$$5
+ZONE=${DCTL_TEST_REGION}-1
+MISSING=[$DCTL_TEST_UNSET]
+`)
+	want := map[string]string{
+		"APP_HOST": "localhost",
+		"BASE_URL": "http://localhost:80", // APP_PORT is set after this line
+		"APP_PORT": "8080",
+		"API_URL":  "http://localhost:80/api",
+		"LITERAL":  "$APP_HOST",
+		"PRICE":    "$5",
+		"ZONE":     "eu-1",
+		"MISSING":  "[]",
+	}
+	if got := parseEnvFile(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEnvFile() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteRunEnv(t *testing.T) {
+	t.Setenv("DCTL_STATE_DIR", t.TempDir())
+	a, err := WriteRunEnv("shop_web", []string{"A=1", "B=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "A=1\nB=2\n" {
+		t.Errorf("env file = %q, want A=1 and B=2", data)
+	}
+	if fi, err := os.Stat(a); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Errorf("env file mode = %v, want 0600", fi.Mode().Perm())
+	}
+	if again, _ := WriteRunEnv("shop_web", []string{"A=1", "B=2"}); again != a {
+		t.Errorf("same values wrote %s, want %s", again, a)
+	}
+	b, err := WriteRunEnv("shop_web", []string{"A=1", "B=3"})
+	if err != nil || b == a {
+		t.Errorf("changed values wrote %s (%v), want a new file", b, err)
+	}
+	// A recreated container's earlier file is superseded.
+	if _, err := os.Stat(a); !os.IsNotExist(err) {
+		t.Errorf("superseded env file left: %v", err)
+	}
+
+	if err := RemoveRunEnv("shop_web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Errorf("env file left after RemoveRunEnv: %v", err)
+	}
+}
@@ -3,35 +3,15 @@ package compose
 import (
 	"fmt"
 	"sort"
+	"strings"
 )
 
 // ResolveOrder performs a topological sort on services based on depends_on relationships.
 // Returns services in startup order (dependencies first). Detects cycles.
 func ResolveOrder(services map[string]Service) ([]string, error) {
-	// Build adjacency list: service -> list of services it depends on.
-	deps := make(map[string][]string)
-	for name := range services {
-		deps[name] = nil
-	}
-
-	for name, svc := range services {
-		if svc.DependsOn == nil {
-			continue
-		}
-		switch d := svc.DependsOn.(type) {
-		case map[string]DependsOnCondition:
-			for dep := range d {
-				if _, ok := services[dep]; !ok {
-					return nil, fmt.Errorf("service %q depends on undefined service %q", name, dep)
-				}
-				deps[name] = append(deps[name], dep)
-			}
-		}
-	}
-
-	// Sort dependency lists for deterministic output.
-	for name := range deps {
-		sort.Strings(deps[name])
+	deps, err := dependencies(services)
+	if err != nil {
+		return nil, err
 	}
 
 	// Kahn's algorithm for topological sort.
@@ -90,3 +70,113 @@ func ResolveOrder(services map[string]Service) ([]string, error) {
 
 	return order, nil
 }
+
+// ResolveLevels groups services into startup levels: each service depends
+// only on services in earlier levels, so the services of one level can
+// start, or in reverse order stop, concurrently.
+func ResolveLevels(services map[string]Service) ([][]string, error) {
+	order, err := ResolveOrder(services)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := dependencies(services)
+	if err != nil {
+		return nil, err
+	}
+	level := make(map[string]int, len(order))
+	var levels [][]string
+	for _, name := range order {
+		l := 0
+		for _, dep := range deps[name] {
+			l = max(l, level[dep]+1)
+		}
+		level[name] = l
+		if l == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], name)
+	}
+	return levels, nil
+}
+
+// Dependents returns the services that depend on any of names, directly or
+// through other services, sorted. With restartOnly it follows only the
+// depends_on entries marked restart: true, and shared namespaces, which are
+// lost whenever the service owning them restarts.
+func Dependents(services map[string]Service, names []string, restartOnly bool) []string {
+	dependents := make(map[string][]string)
+	for name, svc := range services {
+		for _, ns := range []string{svc.NetworkMode, svc.Pid, svc.Ipc} {
+			if dep, ok := strings.CutPrefix(ns, "service:"); ok {
+				dependents[dep] = append(dependents[dep], name)
+			}
+		}
+		if d, ok := svc.DependsOn.(map[string]DependsOnCondition); ok {
+			for dep, cond := range d {
+				if cond.Restart || !restartOnly {
+					dependents[dep] = append(dependents[dep], name)
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		seen[name] = true
+	}
+	var result []string
+	queue := append([]string(nil), names...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range dependents[current] {
+			if !seen[child] {
+				seen[child] = true
+				result = append(result, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// dependencies maps each service to the services it must start after,
+// sorted: those in its depends_on and those whose namespaces it shares.
+func dependencies(services map[string]Service) (map[string][]string, error) {
+	// Build adjacency list: service -> list of services it depends on.
+	deps := make(map[string][]string)
+	for name := range services {
+		deps[name] = nil
+	}
+
+	for name, svc := range services {
+		// A service sharing another's namespaces starts after it.
+		for _, ns := range [][2]string{{"network", svc.NetworkMode}, {"pid", svc.Pid}, {"ipc", svc.Ipc}} {
+			if dep, ok := strings.CutPrefix(ns[1], "service:"); ok {
+				if _, ok := services[dep]; !ok {
+					return nil, fmt.Errorf("service %q shares the %s namespace of undefined service %q", name, ns[0], dep)
+				}
+				deps[name] = append(deps[name], dep)
+			}
+		}
+		if svc.DependsOn == nil {
+			continue
+		}
+		switch d := svc.DependsOn.(type) {
+		case map[string]DependsOnCondition:
+			for dep := range d {
+				if _, ok := services[dep]; !ok {
+					return nil, fmt.Errorf("service %q depends on undefined service %q", name, dep)
+				}
+				deps[name] = append(deps[name], dep)
+			}
+		}
+	}
+
+	// Sort dependency lists for deterministic output.
+	for name := range deps {
+		sort.Strings(deps[name])
+	}
+	return deps, nil
+}
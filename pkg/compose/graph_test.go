@@ -153,3 +153,77 @@ func TestResolveOrder_Deterministic(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveOrder_SharedNamespaces(t *testing.T) {
+	services := map[string]Service{
+		"app":   {Image: "app"},
+		"debug": {Image: "busybox", NetworkMode: "service:app"},
+		"prof":  {Image: "perf", Pid: "service:debug", Ipc: "service:app"},
+	}
+	order, err := ResolveOrder(services)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"app", "debug", "prof"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got %v, want %v", order, want)
+	}
+
+	services["debug"] = Service{Image: "busybox", NetworkMode: "service:ap"}
+	if _, err := ResolveOrder(services); err == nil || !strings.Contains(err.Error(), `"ap"`) {
+		t.Errorf("error = %v, want undefined service \"ap\"", err)
+	}
+}
+
+func TestResolveLevels(t *testing.T) {
+	services := map[string]Service{
+		"app":     {Image: "alpine", DependsOn: map[string]DependsOnCondition{"db": {}, "cache": {}}},
+		"worker":  {Image: "alpine", DependsOn: map[string]DependsOnCondition{"db": {}}},
+		"proxy":   {Image: "alpine", DependsOn: map[string]DependsOnCondition{"app": {}}},
+		"db":      {Image: "alpine"},
+		"cache":   {Image: "alpine"},
+		"sidecar": {Image: "alpine", NetworkMode: "service:app"},
+	}
+
+	levels, err := ResolveLevels(services)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][]string{{"cache", "db"}, {"app", "worker"}, {"proxy", "sidecar"}}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("got %v, want %v", levels, want)
+	}
+
+	services["db"] = Service{Image: "alpine", DependsOn: map[string]DependsOnCondition{"proxy": {}}}
+	if _, err := ResolveLevels(services); err == nil {
+		t.Error("expected a cycle error")
+	}
+}
+
+func TestDependents(t *testing.T) {
+	services := map[string]Service{
+		"app":     {Image: "alpine", DependsOn: map[string]DependsOnCondition{"db": {Restart: true}, "cache": {}}},
+		"worker":  {Image: "alpine", DependsOn: map[string]DependsOnCondition{"cache": {}}},
+		"proxy":   {Image: "alpine", DependsOn: map[string]DependsOnCondition{"app": {}}},
+		"db":      {Image: "alpine"},
+		"cache":   {Image: "alpine"},
+		"sidecar": {Image: "alpine", NetworkMode: "service:app"},
+	}
+
+	tests := []struct {
+		names       []string
+		restartOnly bool
+		want        []string
+	}{
+		{[]string{"db"}, false, []string{"app", "proxy", "sidecar"}},
+		{[]string{"db"}, true, []string{"app", "sidecar"}},
+		{[]string{"cache"}, true, nil},
+		{[]string{"cache"}, false, []string{"app", "proxy", "sidecar", "worker"}},
+		{[]string{"db", "app"}, false, []string{"proxy", "sidecar"}},
+		{[]string{"proxy"}, false, nil},
+	}
+	for _, tt := range tests {
+		if got := Dependents(services, tt.names, tt.restartOnly); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Dependents(%v, %v) = %v, want %v", tt.names, tt.restartOnly, got, tt.want)
+		}
+	}
+}
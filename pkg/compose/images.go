@@ -0,0 +1,117 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// imagesFilePath returns where the images dctl built or pulled for a
+// project are recorded. The record is kept apart from the project state,
+// which down removes, so images fetched before a down can still be pruned
+// after it.
+func imagesFilePath(project string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "images", project+".json"), nil
+}
+
+// ProjectImages returns the image references recorded for a project,
+// sorted; none when nothing was recorded.
+func ProjectImages(project string) ([]string, error) {
+	path, err := imagesFilePath(project)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading project images: %w", err)
+	}
+	var refs []string
+	if err := json.Unmarshal(data, &refs); err != nil {
+		return nil, fmt.Errorf("parsing project images: %w", err)
+	}
+	return refs, nil
+}
+
+// ImageProjects returns the names of the projects with recorded images,
+// sorted.
+func ImageProjects() ([]string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "images"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading images directory: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// RecordImages adds image references to those recorded for a project.
+func RecordImages(project string, refs ...string) error {
+	recorded, err := ProjectImages(project)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if !slices.Contains(recorded, ref) {
+			recorded = append(recorded, ref)
+		}
+	}
+	return saveProjectImages(project, recorded)
+}
+
+// ForgetImages removes image references from a project's record.
+func ForgetImages(project string, refs ...string) error {
+	recorded, err := ProjectImages(project)
+	if err != nil {
+		return err
+	}
+	recorded = slices.DeleteFunc(recorded, func(ref string) bool { return slices.Contains(refs, ref) })
+	return saveProjectImages(project, recorded)
+}
+
+// saveProjectImages writes a project's image record, removing it when it
+// is empty.
+func saveProjectImages(project string, refs []string) error {
+	path, err := imagesFilePath(project)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing project images: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating images directory: %w", err)
+	}
+	slices.Sort(refs)
+	data, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling project images: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing project images: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package compose
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRecordImages(t *testing.T) {
+	t.Setenv("DCTL_STATE_DIR", t.TempDir())
+
+	if refs, err := ProjectImages("shop"); err != nil || refs != nil {
+		t.Fatalf("ProjectImages() before recording = %v, %v", refs, err)
+	}
+	if err := RecordImages("shop", "nginx", "shop-web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordImages("shop", "postgres", "nginx"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := ProjectImages("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nginx", "postgres", "shop-web"}; !slices.Equal(refs, want) {
+		t.Errorf("ProjectImages() = %v, want %v", refs, want)
+	}
+
+	if err := ForgetImages("shop", "nginx", "postgres", "shop-web"); err != nil {
+		t.Fatal(err)
+	}
+	if refs, err := ProjectImages("shop"); err != nil || refs != nil {
+		t.Errorf("ProjectImages() after forgetting all = %v, %v", refs, err)
+	}
+}
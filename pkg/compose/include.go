@@ -0,0 +1,127 @@
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// includeEntry is one entry of the top-level include list.
+type includeEntry struct {
+	Paths      []string
+	ProjectDir string
+}
+
+// parseInclude reads the include list. Each entry is a path, or a mapping
+// with a path or list of paths and optionally a project_directory.
+func parseInclude(v interface{}) ([]includeEntry, error) {
+	if v == nil {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("include: expected a list")
+	}
+	entries := make([]includeEntry, 0, len(list))
+	for _, item := range list {
+		switch item := item.(type) {
+		case string:
+			entries = append(entries, includeEntry{Paths: []string{item}})
+		case map[string]interface{}:
+			var e includeEntry
+			switch p := item["path"].(type) {
+			case string:
+				e.Paths = []string{p}
+			case []interface{}:
+				for _, v := range p {
+					s, ok := v.(string)
+					if !ok {
+						return nil, fmt.Errorf("include: path entries must be strings")
+					}
+					e.Paths = append(e.Paths, s)
+				}
+			}
+			if len(e.Paths) == 0 {
+				return nil, fmt.Errorf("include: entry has no path")
+			}
+			if dir, ok := item["project_directory"].(string); ok {
+				e.ProjectDir = dir
+			}
+			entries = append(entries, e)
+		default:
+			return nil, fmt.Errorf("include: unsupported entry %v", item)
+		}
+	}
+	return entries, nil
+}
+
+// mergeIncludes loads the files cf includes and adds their services,
+// networks, volumes and secrets, which must not already be defined.
+// Include paths are relative to projectDir; paths inside an included file
+// resolve against its own directory, or the entry's project_directory.
+// including lists the files further up the chain, to catch cycles.
+func mergeIncludes(cf *ComposeFile, projectDir string, including []string) error {
+	entries, err := parseInclude(cf.Include)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		paths := make([]string, len(e.Paths))
+		for i, p := range e.Paths {
+			paths[i] = ResolvePath(projectDir, p)
+			if slices.Contains(including, paths[i]) {
+				return fmt.Errorf("include: %s includes itself", paths[i])
+			}
+		}
+		dir := filepath.Dir(paths[0])
+		if e.ProjectDir != "" {
+			dir = ResolvePath(projectDir, e.ProjectDir)
+		}
+		inc, err := load(paths, dir, append(slices.Clone(including), paths...))
+		if err != nil {
+			return fmt.Errorf("include %s: %w", paths[0], err)
+		}
+
+		if cf.Services, err = addIncluded(cf.Services, inc.Services, "service", paths[0]); err != nil {
+			return err
+		}
+		if cf.Networks, err = addIncluded(cf.Networks, inc.Networks, "network", paths[0]); err != nil {
+			return err
+		}
+		if cf.Volumes, err = addIncluded(cf.Volumes, inc.Volumes, "volume", paths[0]); err != nil {
+			return err
+		}
+		if cf.Secrets, err = addIncluded(cf.Secrets, inc.Secrets, "secret", paths[0]); err != nil {
+			return err
+		}
+		cf.Keys.Services = addKeys(cf.Keys.Services, inc.Keys.Services)
+		cf.Keys.Networks = addKeys(cf.Keys.Networks, inc.Keys.Networks)
+		cf.Keys.Volumes = addKeys(cf.Keys.Volumes, inc.Keys.Volumes)
+	}
+	return nil
+}
+
+// addIncluded adds an included file's definitions of one kind to dst.
+func addIncluded[T any](dst, src map[string]T, kind, from string) (map[string]T, error) {
+	if len(src) > 0 && dst == nil {
+		dst = make(map[string]T, len(src))
+	}
+	for name, def := range src {
+		if _, ok := dst[name]; ok {
+			return nil, fmt.Errorf("include %s: %s %q is already defined", from, kind, name)
+		}
+		dst[name] = def
+	}
+	return dst, nil
+}
+
+// addKeys adds an included file's defined keys to dst.
+func addKeys(dst, src map[string][]string) map[string][]string {
+	if len(src) > 0 && dst == nil {
+		dst = make(map[string][]string, len(src))
+	}
+	for name, keys := range src {
+		dst[name] = keys
+	}
+	return dst
+}
@@ -0,0 +1,319 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merge control tags from the compose specification. A value tagged !reset
+// removes the key from the merged result; a value tagged !override replaces
+// the previous value instead of being merged into it.
+const (
+	resetTag    = "!reset"
+	overrideTag = "!override"
+)
+
+// mergeNode deep-merges src into dst following the compose specification:
+// mappings are merged key by key, sequences are appended (skipping
+// duplicates), and scalars from src override dst. A few service fields have
+// their own rules, chosen by path, the key path of dst from the file root.
+// It returns the merged node, which may be src itself.
+func mergeNode(dst, src *yaml.Node, path []string) *yaml.Node {
+	if src == nil || isNull(src) {
+		return dst
+	}
+	if dst == nil || isNull(dst) {
+		return src
+	}
+
+	switch serviceField(path) {
+	case "command", "entrypoint", "healthcheck.test":
+		return src
+	case "environment", "labels", "depends_on", "networks", "build.args", "build.labels":
+		return mergeMappings(toMapping(dst), toMapping(src), path)
+	case "volumes":
+		if dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode {
+			return mergeByTarget(dst, src)
+		}
+	case "build":
+		return mergeMappings(buildMapping(dst), buildMapping(src), path)
+	}
+
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		return mergeMappings(dst, src, path)
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		return appendUnique(dst, src)
+	default:
+		return src
+	}
+}
+
+// serviceField returns the path below services.<name>, joined with dots,
+// or "" when path is not inside a service.
+func serviceField(path []string) string {
+	if len(path) < 3 || path[0] != "services" {
+		return ""
+	}
+	return strings.Join(path[2:], ".")
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+func mergeMappings(dst, src *yaml.Node, path []string) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i], src.Content[i+1]
+		j := mappingIndex(dst, key.Value)
+		switch {
+		case val.Tag == resetTag:
+			if j >= 0 {
+				dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+			}
+		case j < 0:
+			dst.Content = append(dst.Content, key, val)
+		case val.Tag == overrideTag:
+			dst.Content[j+1] = val
+		default:
+			dst.Content[j+1] = mergeNode(dst.Content[j+1], val, append(path[:len(path):len(path)], key.Value))
+		}
+	}
+	return dst
+}
+
+// stripMergeTags returns a copy of n with !reset entries removed and
+// !override tags cleared, ready to be decoded.
+func stripMergeTags(n *yaml.Node) *yaml.Node {
+	out := *n
+	if out.Tag == overrideTag {
+		out.Tag = ""
+	}
+	out.Content = nil
+	for i := 0; i < len(n.Content); i++ {
+		if n.Kind == yaml.MappingNode && i+1 < len(n.Content) {
+			key, val := n.Content[i], n.Content[i+1]
+			i++
+			if val.Tag == resetTag {
+				continue
+			}
+			out.Content = append(out.Content, key, stripMergeTags(val))
+			continue
+		}
+		out.Content = append(out.Content, stripMergeTags(n.Content[i]))
+	}
+	return &out
+}
+
+// expandMergeKeys returns a copy of n with every alias replaced by a copy of
+// its anchor and every YAML merge key (<<) applied. Unlike plain YAML,
+// where a key given next to << replaces the merged value whole, mappings
+// are merged key by key, so a service adding one variable to an anchored
+// environment keeps the others; see mergeAnchored. path is the key path
+// of n from the file root.
+func expandMergeKeys(n *yaml.Node, path []string) (*yaml.Node, error) {
+	return expand(n, path, make(map[*yaml.Node]bool))
+}
+
+// expand implements expandMergeKeys. active holds the nodes being expanded,
+// so an anchor that contains an alias to itself is an error rather than
+// endless.
+func expand(n *yaml.Node, path []string, active map[*yaml.Node]bool) (*yaml.Node, error) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		if active[n.Alias] {
+			return nil, fmt.Errorf("line %d: anchor %q contains an alias to itself", n.Line, n.Value)
+		}
+		return expand(n.Alias, path, active)
+	}
+	active[n] = true
+	defer delete(active, n)
+
+	out := *n
+	out.Content = nil
+	if n.Kind != yaml.MappingNode {
+		for _, c := range n.Content {
+			e, err := expand(c, path, active)
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, e)
+		}
+		return &out, nil
+	}
+
+	var bases []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i]
+		keyPath := path
+		if !isMergeKey(key) {
+			keyPath = append(path[:len(path):len(path)], key.Value)
+		}
+		val, err := expand(n.Content[i+1], keyPath, active)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !isMergeKey(key):
+			k := *key
+			out.Content = append(out.Content, &k, val)
+		case val.Kind == yaml.SequenceNode:
+			bases = append(bases, val.Content...)
+		default:
+			bases = append(bases, val)
+		}
+	}
+	if len(bases) == 0 {
+		return &out, nil
+	}
+
+	// Of several merged mappings the first wins, and keys given next to
+	// << win over all of them.
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Anchor: out.Anchor, Line: out.Line, Column: out.Column}
+	for i := len(bases) - 1; i >= 0; i-- {
+		if bases[i].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: << must merge a mapping or a list of mappings", bases[i].Line)
+		}
+		merged = mergeAnchored(merged, bases[i], path)
+	}
+	return mergeAnchored(merged, &out, path), nil
+}
+
+// isMergeKey reports whether a mapping key is the YAML merge key <<.
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.Value == "<<" && (key.Tag == "!!merge" || key.Tag == "")
+}
+
+// mergeAnchored merges src over dst for a merge key: mappings, including
+// the list forms of environment, labels and the other fields mergeNode
+// treats as mappings, merge key by key; anything else from src replaces
+// dst. The !reset and !override tags apply as in mergeMappings.
+func mergeAnchored(dst, src *yaml.Node, path []string) *yaml.Node {
+	switch serviceField(path) {
+	case "environment", "labels", "depends_on", "networks", "build.args", "build.labels":
+		dst, src = toMapping(dst), toMapping(src)
+	}
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, val := src.Content[i], src.Content[i+1]
+		j := mappingIndex(dst, key.Value)
+		switch {
+		case j >= 0 && val.Tag == resetTag:
+			dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+		case j < 0:
+			dst.Content = append(dst.Content, key, val)
+		case val.Tag == overrideTag:
+			dst.Content[j+1] = val
+		default:
+			dst.Content[j+1] = mergeAnchored(dst.Content[j+1], val, append(path[:len(path):len(path)], key.Value))
+		}
+	}
+	return dst
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1.
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// toMapping converts the list form of environment, labels, depends_on and
+// networks ("KEY=VALUE" or bare names) to the equivalent mapping.
+func toMapping(n *yaml.Node) *yaml.Node {
+	if n.Kind != yaml.SequenceNode {
+		return n
+	}
+	m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, item := range n.Content {
+		k, v, ok := strings.Cut(item.Value, "=")
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}
+		val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		if ok {
+			val = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+		}
+		if j := mappingIndex(m, k); j >= 0 {
+			m.Content[j+1] = val
+			continue
+		}
+		m.Content = append(m.Content, key, val)
+	}
+	return m
+}
+
+// buildMapping converts the short build form (a context path) to a mapping.
+func buildMapping(n *yaml.Node) *yaml.Node {
+	if n.Kind != yaml.ScalarNode {
+		return n
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "context"},
+		n,
+	}}
+}
+
+// appendUnique appends src items to dst, skipping scalars dst already has.
+func appendUnique(dst, src *yaml.Node) *yaml.Node {
+	seen := make(map[string]bool)
+	for _, item := range dst.Content {
+		if item.Kind == yaml.ScalarNode {
+			seen[item.Value] = true
+		}
+	}
+	for _, item := range src.Content {
+		if item.Kind == yaml.ScalarNode {
+			if seen[item.Value] {
+				continue
+			}
+			seen[item.Value] = true
+		}
+		dst.Content = append(dst.Content, item)
+	}
+	return dst
+}
+
+// mergeByTarget merges volume lists: a src mount replaces the dst mount with
+// the same container path, other mounts are appended.
+func mergeByTarget(dst, src *yaml.Node) *yaml.Node {
+	for _, item := range src.Content {
+		target := mountTarget(item)
+		replaced := false
+		for i, existing := range dst.Content {
+			if target != "" && mountTarget(existing) == target {
+				dst.Content[i] = item
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst.Content = append(dst.Content, item)
+		}
+	}
+	return dst
+}
+
+// mountTarget returns the container path of a short or long volume entry.
+func mountTarget(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(n.Value, ":")
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return parts[1]
+	case yaml.MappingNode:
+		if j := mappingIndex(n, "target"); j >= 0 {
+			return n.Content[j+1].Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,365 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadMerged writes each YAML document to its own file and loads them in order.
+func loadMerged(t *testing.T, docs ...string) *ComposeFile {
+	t.Helper()
+	dir := t.TempDir()
+	var files []string
+	for i, doc := range docs {
+		path := filepath.Join(dir, "compose"+string(rune('a'+i))+".yaml")
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatalf("writing compose file: %v", err)
+		}
+		files = append(files, path)
+	}
+	cf, err := Load(files, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	return cf
+}
+
+func TestLoad_MergeKeepsUnsetFields(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: nginx:1.25
+    ports: ["80:80"]
+    environment:
+      A: "1"
+`, `
+services:
+  web:
+    environment:
+      B: "2"
+`)
+
+	web := cf.Services["web"]
+	if web.Image != "nginx:1.25" {
+		t.Errorf("Image = %q, want base image kept", web.Image)
+	}
+	if !reflect.DeepEqual(web.Ports, []string{"80:80"}) {
+		t.Errorf("Ports = %v, want base ports kept", web.Ports)
+	}
+	want := map[string]string{"A": "1", "B": "2"}
+	if !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("Environment = %v, want %v", web.Environment, want)
+	}
+}
+
+func TestLoad_MergeMixedEnvironmentForms(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: app
+    environment:
+      - A=1
+      - B=2
+`, `
+services:
+  web:
+    environment:
+      B: "3"
+      C: "4"
+`)
+
+	want := map[string]string{"A": "1", "B": "3", "C": "4"}
+	if got := cf.Services["web"].Environment; !reflect.DeepEqual(got, want) {
+		t.Errorf("Environment = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_MergeSequences(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: app
+    command: ["serve", "--port", "80"]
+    ports: ["80:80"]
+    dns: [1.1.1.1]
+    volumes:
+      - ./src:/app
+      - data:/data
+`, `
+services:
+  web:
+    command: ["serve", "--debug"]
+    ports: ["80:80", "443:443"]
+    dns: [8.8.8.8]
+    volumes:
+      - ./other:/app:ro
+      - cache:/cache
+`)
+
+	web := cf.Services["web"]
+	if want := []string{"serve", "--debug"}; !reflect.DeepEqual(web.Command, want) {
+		t.Errorf("Command = %v, want override %v", web.Command, want)
+	}
+	if want := []string{"80:80", "443:443"}; !reflect.DeepEqual(web.Ports, want) {
+		t.Errorf("Ports = %v, want %v", web.Ports, want)
+	}
+	if want := []string{"1.1.1.1", "8.8.8.8"}; !reflect.DeepEqual(web.DNS, want) {
+		t.Errorf("DNS = %v, want %v", web.DNS, want)
+	}
+	if len(web.Volumes) != 3 || !filepath.IsAbs(web.Volumes[0]) || !strings.HasSuffix(web.Volumes[0], "/other:/app:ro") ||
+		!reflect.DeepEqual(web.Volumes[1:], []string{"data:/data", "cache:/cache"}) {
+		t.Errorf("Volumes = %v, want ./other replacing /app, then data and cache", web.Volumes)
+	}
+}
+
+func TestLoad_MergeDependsOnAndBuild(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    build: ./web
+    depends_on: [db]
+  db:
+    image: postgres
+  cache:
+    image: redis
+`, `
+services:
+  web:
+    build:
+      target: dev
+    depends_on:
+      cache:
+        condition: service_healthy
+`)
+
+	web := cf.Services["web"]
+	bc, ok := web.Build.(*BuildConfig)
+	if !ok || filepath.Base(bc.Context) != "web" || bc.Target != "dev" {
+		t.Errorf("Build = %+v, want context web with target dev", web.Build)
+	}
+	deps := web.DependsOn.(map[string]DependsOnCondition)
+	if deps["db"].Condition != "service_started" || deps["cache"].Condition != "service_healthy" {
+		t.Errorf("DependsOn = %v, want db started and cache healthy", deps)
+	}
+}
+
+func TestLoad_MergeTopLevelResources(t *testing.T) {
+	cf := loadMerged(t, `
+services: {}
+networks:
+  backend:
+    driver: bridge
+    labels:
+      a: "1"
+`, `
+networks:
+  backend:
+    labels:
+      b: "2"
+`)
+
+	net := cf.Networks["backend"]
+	if net.Driver != "bridge" {
+		t.Errorf("Driver = %q, want bridge kept", net.Driver)
+	}
+	if want := map[string]string{"a": "1", "b": "2"}; !reflect.DeepEqual(net.Labels, want) {
+		t.Errorf("Labels = %v, want %v", net.Labels, want)
+	}
+}
+
+func TestLoad_MergeResetTag(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: app
+    ports: ["80:80", "443:443"]
+    environment:
+      DEBUG: "1"
+      KEEP: "1"
+    build: .
+`, `
+services:
+  web:
+    ports: !reset []
+    environment:
+      DEBUG: !reset null
+    build: !reset
+`)
+
+	web := cf.Services["web"]
+	if len(web.Ports) != 0 {
+		t.Errorf("Ports = %v, want reset to none", web.Ports)
+	}
+	if want := map[string]string{"KEEP": "1"}; !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("Environment = %v, want %v", web.Environment, want)
+	}
+	if web.Build != nil {
+		t.Errorf("Build = %v, want reset", web.Build)
+	}
+	if web.Image != "app" {
+		t.Errorf("Image = %q, want app", web.Image)
+	}
+}
+
+func TestLoad_MergeOverrideTag(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: app
+    ports: ["80:80"]
+    environment:
+      A: "1"
+`, `
+services:
+  web:
+    ports: !override ["8080:80"]
+    environment: !override
+      B: "2"
+`)
+
+	web := cf.Services["web"]
+	if want := []string{"8080:80"}; !reflect.DeepEqual(web.Ports, want) {
+		t.Errorf("Ports = %v, want %v", web.Ports, want)
+	}
+	if want := map[string]string{"B": "2"}; !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("Environment = %v, want %v", web.Environment, want)
+	}
+}
+
+func TestLoad_MergeTagsInSingleFile(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: app
+    ports: !override ["80:80"]
+    dns: !reset []
+`)
+
+	web := cf.Services["web"]
+	if want := []string{"80:80"}; !reflect.DeepEqual(web.Ports, want) {
+		t.Errorf("Ports = %v, want %v", web.Ports, want)
+	}
+	if web.DNS != nil {
+		t.Errorf("DNS = %v, want unset", web.DNS)
+	}
+}
+
+func TestLoad_MergeKeys(t *testing.T) {
+	cf := loadMerged(t, `
+x-common: &common
+  image: app
+  restart: unless-stopped
+  ports: ["80:80"]
+  environment:
+    LOG_LEVEL: info
+    TZ: UTC
+  labels:
+    team: shop
+x-debug: &debug
+  environment:
+    LOG_LEVEL: debug
+    DEBUG: "1"
+services:
+  web:
+    <<: *common
+    ports: ["8080:80"]
+    environment:
+      PORT: "8080"
+    labels:
+      tier: front
+  worker:
+    <<: [*debug, *common]
+    environment:
+      - QUEUE=jobs
+  admin:
+    <<: *common
+    environment: !override
+      ADMIN: "1"
+`)
+
+	web := cf.Services["web"]
+	if web.Image != "app" || web.Restart != "unless-stopped" {
+		t.Errorf("web = %+v, want image and restart from the anchor", web)
+	}
+	if want := []string{"8080:80"}; !reflect.DeepEqual(web.Ports, want) {
+		t.Errorf("web Ports = %v, want %v replacing the anchor's", web.Ports, want)
+	}
+	if want := map[string]string{"LOG_LEVEL": "info", "TZ": "UTC", "PORT": "8080"}; !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("web Environment = %v, want %v", web.Environment, want)
+	}
+	if want := map[string]string{"team": "shop", "tier": "front"}; !reflect.DeepEqual(web.Labels, want) {
+		t.Errorf("web Labels = %v, want %v", web.Labels, want)
+	}
+
+	// The first of several merged mappings wins.
+	worker := cf.Services["worker"]
+	if want := map[string]string{"LOG_LEVEL": "debug", "DEBUG": "1", "TZ": "UTC", "QUEUE": "jobs"}; !reflect.DeepEqual(worker.Environment, want) {
+		t.Errorf("worker Environment = %v, want %v", worker.Environment, want)
+	}
+
+	if want := map[string]string{"ADMIN": "1"}; !reflect.DeepEqual(cf.Services["admin"].Environment, want) {
+		t.Errorf("admin Environment = %v, want %v", cf.Services["admin"].Environment, want)
+	}
+
+	// Merging into one service leaves the anchor untouched for the others.
+	if _, ok := cf.Services["admin"].Labels["tier"]; ok {
+		t.Errorf("admin Labels = %v, want no tier from web", cf.Services["admin"].Labels)
+	}
+	if slices.Contains(cf.Keys.Services["web"], "<<") {
+		t.Error("<< reported as a service key")
+	}
+}
+
+func TestLoad_MergeKeyErrors(t *testing.T) {
+	for name, doc := range map[string]string{
+		"recursive": "services:\n  web: &web\n    image: app\n    labels:\n      <<: *web\n",
+		"scalar":    "x-image: &image app\nservices:\n  web:\n    <<: *image\n",
+	} {
+		path := filepath.Join(t.TempDir(), "compose.yaml")
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load([]string{path}, filepath.Dir(path)); err == nil {
+			t.Errorf("%s: Load() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestLoad_MultipleDocuments(t *testing.T) {
+	cf := loadMerged(t, `
+services:
+  web:
+    image: nginx
+    environment:
+      A: "1"
+---
+# generated overrides
+---
+services:
+  web:
+    environment:
+      B: "2"
+  db:
+    image: postgres
+---
+`, `
+services:
+  web:
+    image: nginx:1.25
+`)
+
+	web := cf.Services["web"]
+	if web.Image != "nginx:1.25" {
+		t.Errorf("Image = %q, want the second file's", web.Image)
+	}
+	if want := map[string]string{"A": "1", "B": "2"}; !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("Environment = %v, want %v", web.Environment, want)
+	}
+	if _, ok := cf.Services["db"]; !ok {
+		t.Error("db from the second document missing")
+	}
+}
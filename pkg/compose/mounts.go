@@ -0,0 +1,287 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mount is a parsed short-syntax volumes entry.
+type Mount struct {
+	Source   string // host path or volume name; empty for an anonymous volume
+	Target   string
+	ReadOnly bool
+	Options  []string // options other than ro and rw, such as cached or rprivate
+}
+
+// consistencyOptions were macOS file sharing hints that virtiofs does not
+// need. Other options, such as propagation, SELinux relabeling and nocopy,
+// have no counterpart in a VM's shares.
+var consistencyOptions = []string{"cached", "delegated", "consistent"}
+
+// ParseMount parses a short-syntax volumes entry: TARGET, SOURCE:TARGET or
+// SOURCE:TARGET:OPTIONS with comma-separated options.
+func ParseMount(spec string) Mount {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) == 1 {
+		return Mount{Target: parts[0]}
+	}
+	m := Mount{Source: parts[0], Target: parts[1]}
+	if len(parts) == 3 {
+		for _, opt := range strings.Split(parts[2], ",") {
+			switch opt {
+			case "ro":
+				m.ReadOnly = true
+			case "rw", "":
+			default:
+				m.Options = append(m.Options, opt)
+			}
+		}
+	}
+	return m
+}
+
+// RuntimeSpec returns the mount in the form passed to the runtime's
+// --volume. Only read-only is kept; consistency hints are no-ops under
+// virtiofs and the remaining options have no runtime equivalent.
+func (m Mount) RuntimeSpec() string {
+	if m.Source == "" {
+		return m.Target
+	}
+	spec := m.Source + ":" + m.Target
+	if m.ReadOnly {
+		spec += ":ro"
+	}
+	return spec
+}
+
+// IgnoredOptions returns the options of m that have an effect under Docker
+// but none under the runtime. Consistency hints are left out, since Docker
+// ignores them too.
+func (m Mount) IgnoredOptions() []string {
+	var ignored []string
+	for _, opt := range m.Options {
+		if !slices.Contains(consistencyOptions, opt) {
+			ignored = append(ignored, opt)
+		}
+	}
+	return ignored
+}
+
+// longVolume is the long syntax of a volumes entry.
+type longVolume struct {
+	Type        string `yaml:"type"`
+	Source      string `yaml:"source"`
+	Target      string `yaml:"target"`
+	ReadOnly    bool   `yaml:"read_only"`
+	Consistency string `yaml:"consistency"`
+	Bind        struct {
+		Propagation string `yaml:"propagation"`
+		SELinux     string `yaml:"selinux"`
+	} `yaml:"bind"`
+	Volume struct {
+		NoCopy  bool   `yaml:"nocopy"`
+		Subpath string `yaml:"subpath"`
+	} `yaml:"volume"`
+	Tmpfs struct {
+		Size string `yaml:"size"`
+		Mode string `yaml:"mode"`
+	} `yaml:"tmpfs"`
+}
+
+// shortVolumeSyntax rewrites long-syntax entries in every service's volumes
+// list into short syntax, with their options after the target. A tmpfs
+// entry moves to the service's tmpfs list. It modifies root, which must be
+// a decoded-only copy of the file.
+func shortVolumeSyntax(root *yaml.Node) error {
+	i := mappingIndex(root, "services")
+	if i < 0 || root.Content[i+1].Kind != yaml.MappingNode {
+		return nil
+	}
+	services := root.Content[i+1]
+	for j := 0; j+1 < len(services.Content); j += 2 {
+		name, svc := services.Content[j].Value, services.Content[j+1]
+		k := mappingIndex(svc, "volumes")
+		if k < 0 || svc.Content[k+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		list := svc.Content[k+1]
+		entries := list.Content[:0]
+		for _, entry := range list.Content {
+			if entry.Kind != yaml.MappingNode {
+				entries = append(entries, entry)
+				continue
+			}
+			var lv longVolume
+			if err := entry.Decode(&lv); err != nil {
+				return fmt.Errorf("service %s: volumes: %w", name, err)
+			}
+			if lv.Target == "" {
+				return fmt.Errorf("service %s: volumes: target is required", name)
+			}
+			if lv.Type == "tmpfs" {
+				appendTmpfs(svc, lv.tmpfs())
+				continue
+			}
+			spec, err := lv.short()
+			if err != nil {
+				return fmt.Errorf("service %s: volumes: %w", name, err)
+			}
+			entries = append(entries, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: spec})
+		}
+		list.Content = entries
+	}
+	return nil
+}
+
+// short returns the short syntax of a bind or volume mount.
+func (lv longVolume) short() (string, error) {
+	switch lv.Type {
+	case "", "bind", "volume":
+	default:
+		return "", fmt.Errorf("type %q is not supported", lv.Type)
+	}
+	if lv.Volume.Subpath != "" {
+		return "", fmt.Errorf("volume.subpath is not supported")
+	}
+	if lv.Source == "" {
+		return lv.Target, nil
+	}
+	var opts []string
+	if lv.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	for _, opt := range []string{lv.Consistency, lv.Bind.Propagation, lv.Bind.SELinux} {
+		if opt != "" {
+			opts = append(opts, opt)
+		}
+	}
+	if lv.Volume.NoCopy {
+		opts = append(opts, "nocopy")
+	}
+	spec := lv.Source + ":" + lv.Target
+	if len(opts) > 0 {
+		spec += ":" + strings.Join(opts, ",")
+	}
+	return spec, nil
+}
+
+// tmpfs returns the short syntax of a tmpfs mount.
+func (lv longVolume) tmpfs() string {
+	var opts []string
+	if lv.Tmpfs.Size != "" {
+		opts = append(opts, "size="+lv.Tmpfs.Size)
+	}
+	if lv.Tmpfs.Mode != "" {
+		opts = append(opts, "mode="+lv.Tmpfs.Mode)
+	}
+	if len(opts) == 0 {
+		return lv.Target
+	}
+	return lv.Target + ":" + strings.Join(opts, ",")
+}
+
+// appendTmpfs adds an entry to a service's tmpfs key, which may be a single
+// string or a list.
+func appendTmpfs(svc *yaml.Node, spec string) {
+	entry := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: spec}
+	i := mappingIndex(svc, "tmpfs")
+	if i < 0 {
+		svc.Content = append(svc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "tmpfs"},
+			&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{entry}})
+		return
+	}
+	list := svc.Content[i+1]
+	if list.Kind == yaml.ScalarNode {
+		list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{list}}
+		svc.Content[i+1] = list
+	}
+	list.Content = append(list.Content, entry)
+}
+
+// TmpfsMount is a parsed tmpfs entry.
+type TmpfsMount struct {
+	Target  string
+	Size    int64       // bytes; 0 when unset
+	Mode    os.FileMode // permission bits; 0 when unset
+	Options []string    // options other than size and mode, such as noexec
+}
+
+// ParseTmpfs parses a tmpfs entry: PATH or PATH:OPTIONS with comma-separated
+// options. size takes a byte count with an optional k, m or g suffix and
+// mode an octal permission.
+func ParseTmpfs(spec string) (TmpfsMount, error) {
+	target, opts, _ := strings.Cut(spec, ":")
+	if target == "" {
+		return TmpfsMount{}, fmt.Errorf("%q: path is required", spec)
+	}
+	t := TmpfsMount{Target: target}
+	if opts == "" {
+		return t, nil
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "size":
+			size, err := parseByteSize(value)
+			if err != nil {
+				return TmpfsMount{}, fmt.Errorf("%q: size: %w", spec, err)
+			}
+			t.Size = size
+		case "mode":
+			mode, err := strconv.ParseUint(strings.TrimPrefix(value, "0o"), 8, 32)
+			if err != nil || mode > 0o7777 {
+				return TmpfsMount{}, fmt.Errorf("%q: mode %q is not an octal permission", spec, value)
+			}
+			t.Mode = os.FileMode(mode)
+		case "":
+		default:
+			t.Options = append(t.Options, opt)
+		}
+	}
+	return t, nil
+}
+
+// IgnoredOptions returns the options of t the runtime cannot apply: its
+// --tmpfs takes a path only, so the mount gets the runtime's default size
+// and mode.
+func (t TmpfsMount) IgnoredOptions() []string {
+	var ignored []string
+	if t.Size > 0 {
+		ignored = append(ignored, "size")
+	}
+	if t.Mode != 0 {
+		ignored = append(ignored, "mode")
+	}
+	return append(ignored, t.Options...)
+}
+
+// parseByteSize parses a byte count such as 64m or 1g. Suffixes are binary
+// multiples, as in Docker.
+func parseByteSize(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.ToLower(s), "b")
+	shift := 0
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'k':
+			shift = 10
+		case 'm':
+			shift = 20
+		case 'g':
+			shift = 30
+		}
+		if shift > 0 {
+			num = num[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
@@ -0,0 +1,124 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMount(t *testing.T) {
+	tests := []struct {
+		spec    string
+		runtime string
+		ignored []string
+	}{
+		{"/data", "/data", nil},
+		{"data:/data", "data:/data", nil},
+		{"/src:/app:ro", "/src:/app:ro", nil},
+		{"/src:/app:rw", "/src:/app", nil},
+		{"/src:/app:cached", "/src:/app", nil},
+		{"/src:/app:ro,delegated", "/src:/app:ro", nil},
+		{"/src:/app:rslave,z", "/src:/app", []string{"rslave", "z"}},
+		{"data:/data:nocopy", "data:/data", []string{"nocopy"}},
+	}
+	for _, tt := range tests {
+		m := ParseMount(tt.spec)
+		if got := m.RuntimeSpec(); got != tt.runtime {
+			t.Errorf("ParseMount(%q).RuntimeSpec() = %q, want %q", tt.spec, got, tt.runtime)
+		}
+		if got := m.IgnoredOptions(); !reflect.DeepEqual(got, tt.ignored) {
+			t.Errorf("ParseMount(%q).IgnoredOptions() = %v, want %v", tt.spec, got, tt.ignored)
+		}
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    TmpfsMount
+		ignored []string
+	}{
+		{"/run", TmpfsMount{Target: "/run"}, nil},
+		{"/run:size=64m,mode=1777", TmpfsMount{Target: "/run", Size: 64 << 20, Mode: 0o1777}, []string{"size", "mode"}},
+		{"/tmp:size=1024,noexec", TmpfsMount{Target: "/tmp", Size: 1024, Options: []string{"noexec"}}, []string{"size", "noexec"}},
+		{"/tmp:size=2G,mode=0o700", TmpfsMount{Target: "/tmp", Size: 2 << 30, Mode: 0o700}, []string{"size", "mode"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTmpfs(tt.spec)
+		if err != nil {
+			t.Errorf("ParseTmpfs(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTmpfs(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+		if ignored := got.IgnoredOptions(); !reflect.DeepEqual(ignored, tt.ignored) {
+			t.Errorf("ParseTmpfs(%q).IgnoredOptions() = %v, want %v", tt.spec, ignored, tt.ignored)
+		}
+	}
+
+	for _, spec := range []string{":size=1m", "/run:size=big", "/run:size=-1", "/run:mode=999", "/run:mode=17777"} {
+		if _, err := ParseTmpfs(spec); err == nil {
+			t.Errorf("ParseTmpfs(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestLoad_LongVolumeSyntax(t *testing.T) {
+	dir := t.TempDir()
+	content := `services:
+  web:
+    image: nginx
+    tmpfs: /run:size=64m
+    volumes:
+      - ./conf:/etc/nginx:ro
+      - type: bind
+        source: ./src
+        target: /app
+        read_only: true
+        consistency: cached
+        bind:
+          propagation: rprivate
+      - type: volume
+        source: data
+        target: /data
+        volume:
+          nocopy: true
+      - type: volume
+        target: /cache
+      - type: tmpfs
+        target: /tmp
+        tmpfs:
+          size: 1048576
+          mode: 1777
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := cf.Services["web"]
+	wantVolumes := []string{
+		filepath.Join(dir, "conf") + ":/etc/nginx:ro",
+		filepath.Join(dir, "src") + ":/app:ro,cached,rprivate",
+		"data:/data:nocopy",
+		"/cache",
+	}
+	if !reflect.DeepEqual(svc.Volumes, wantVolumes) {
+		t.Errorf("volumes = %v, want %v", svc.Volumes, wantVolumes)
+	}
+	if want := []string{"/run:size=64m", "/tmp:size=1048576,mode=1777"}; !reflect.DeepEqual(svc.Tmpfs, want) {
+		t.Errorf("tmpfs = %v, want %v", svc.Tmpfs, want)
+	}
+
+	content = "services:\n  web:\n    image: nginx\n    volumes:\n      - type: npipe\n        source: x\n        target: /x\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(nil, dir); err == nil {
+		t.Error("Load() accepted an npipe volume")
+	}
+}
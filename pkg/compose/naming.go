@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NamingScheme selects how container names are derived from project and
+// service names.
+type NamingScheme string
+
+const (
+	// NamingDefault names containers project_service.
+	NamingDefault NamingScheme = ""
+	// NamingCompat names containers project-service-1, like docker compose v2.
+	NamingCompat NamingScheme = "compat"
+)
+
+// ContainerName returns the name of a service's container.
+func (n NamingScheme) ContainerName(project, service string) string {
+	if n == NamingCompat {
+		return project + "-" + service + "-1"
+	}
+	return project + "_" + service
+}
+
+// RunContainerName returns the name of a one-off `run` container. The id
+// keeps concurrent runs of a service apart; see NewRunID.
+func (n NamingScheme) RunContainerName(project, service, id string) string {
+	if n == NamingCompat {
+		return project + "-" + service + "-run-" + id
+	}
+	return project + "_" + service + "_run_" + id
+}
+
+// NewRunID returns a random suffix for a run container name.
+func NewRunID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating run container id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RunContainerService returns the service a one-off `run` container belongs
+// to when its name follows either naming scheme for the project.
+func RunContainerService(project, name string) (string, bool) {
+	if rest, ok := strings.CutPrefix(name, project+"_"); ok {
+		if i := strings.LastIndex(rest, "_run"); i > 0 && (rest[i:] == "_run" || strings.HasPrefix(rest[i:], "_run_")) {
+			return rest[:i], true
+		}
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(name, project+"-"); ok {
+		if i := strings.LastIndex(rest, "-run-"); i > 0 && i+len("-run-") < len(rest) {
+			return rest[:i], true
+		}
+	}
+	return "", false
+}
+
+// ServiceFromContainerName returns the service a container belongs to when
+// its name follows either naming scheme for the project.
+func ServiceFromContainerName(project, name string) (string, bool) {
+	if service, ok := RunContainerService(project, name); ok {
+		return service, true
+	}
+	if rest, ok := strings.CutPrefix(name, project+"_"); ok && rest != "" {
+		return rest, true
+	}
+	rest, ok := strings.CutPrefix(name, project+"-")
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndex(rest, "-")
+	if i <= 0 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(rest[i+1:]); err != nil {
+		return "", false
+	}
+	return rest[:i], true
+}
@@ -0,0 +1,81 @@
+package compose
+
+import "testing"
+
+func TestNamingScheme(t *testing.T) {
+	if got := NamingDefault.ContainerName("shop", "web"); got != "shop_web" {
+		t.Errorf("default ContainerName = %q, want shop_web", got)
+	}
+	if got := NamingCompat.ContainerName("shop", "web"); got != "shop-web-1" {
+		t.Errorf("compat ContainerName = %q, want shop-web-1", got)
+	}
+	if got := NamingDefault.RunContainerName("shop", "web", "1a2b"); got != "shop_web_run_1a2b" {
+		t.Errorf("default RunContainerName = %q, want shop_web_run_1a2b", got)
+	}
+	if got := NamingCompat.RunContainerName("shop", "web", "1a2b"); got != "shop-web-run-1a2b" {
+		t.Errorf("compat RunContainerName = %q, want shop-web-run-1a2b", got)
+	}
+	a, err := NewRunID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRunID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || len(a) != 8 {
+		t.Errorf("NewRunID() = %q, %q, want distinct 8-character ids", a, b)
+	}
+}
+
+func TestRunContainerService(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		ok      bool
+	}{
+		{"shop_web_run", "web", true},
+		{"shop_web_run_1a2b", "web", true},
+		{"shop_api_server_run_1a2b", "api_server", true},
+		{"shop-web-run-1", "web", true},
+		{"shop-api-server-run-1a2b", "api-server", true},
+		{"shop_web", "", false},
+		{"shop_runner", "", false},
+		{"shop-web-1", "", false},
+		{"shop-web-run-", "", false},
+		{"blog_web_run_1a2b", "", false},
+	}
+	for _, tt := range tests {
+		service, ok := RunContainerService("shop", tt.name)
+		if ok != tt.ok || service != tt.service {
+			t.Errorf("RunContainerService(%q) = %q, %v, want %q, %v", tt.name, service, ok, tt.service, tt.ok)
+		}
+	}
+}
+
+func TestServiceFromContainerName(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		ok      bool
+	}{
+		{"shop_web", "web", true},
+		{"shop_web_run", "web", true},
+		{"shop_web_run_1a2b", "web", true},
+		{"shop-web-1", "web", true},
+		{"shop-web-3", "web", true},
+		{"shop-api-server-1", "api-server", true},
+		{"shop-web-run-1", "web", true},
+		{"shop-web-run-1a2b", "web", true},
+		{"shop-web", "", false},
+		{"shop-1", "", false},
+		{"blog_web", "", false},
+		{"shop_", "", false},
+	}
+	for _, tt := range tests {
+		service, ok := ServiceFromContainerName("shop", tt.name)
+		if ok != tt.ok || service != tt.service {
+			t.Errorf("ServiceFromContainerName(%q) = %q, %v, want %q, %v", tt.name, service, ok, tt.service, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Obsolete is a key of the legacy compose file formats that the Compose
+// Specification dropped.
+type Obsolete struct {
+	Key         string // "version" or "services.NAME.KEY"
+	Replacement string
+}
+
+func (o Obsolete) String() string {
+	return fmt.Sprintf("%s: %s", o.Key, o.Replacement)
+}
+
+// obsoleteServiceKeys maps obsolete service keys to what replaces them.
+var obsoleteServiceKeys = map[string]string{
+	"links":          "obsolete; services on a shared network reach each other by service name, use networks.NAME.aliases for other names",
+	"external_links": "obsolete; attach the service to an external network shared with those containers",
+}
+
+// ObsoleteKeys lists the obsolete keys cf sets. They load without warnings;
+// the list is for `--strict`.
+func ObsoleteKeys(cf *ComposeFile) []Obsolete {
+	var found []Obsolete
+	if slices.Contains(cf.Keys.Top, "version") {
+		found = append(found, Obsolete{Key: "version", Replacement: "obsolete; the Compose Specification ignores it, remove it"})
+	}
+	names := make([]string, 0, len(cf.Keys.Services))
+	for name := range cf.Keys.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, key := range cf.Keys.Services[name] {
+			if r, ok := obsoleteServiceKeys[key]; ok {
+				found = append(found, Obsolete{Key: "services." + name + "." + key, Replacement: r})
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,40 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestObsoleteKeys(t *testing.T) {
+	dir := t.TempDir()
+	content := `version: "3.8"
+services:
+  web:
+    image: nginx
+    links: [db]
+    external_links: [redis]
+  db:
+    image: postgres
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() with a version key: %v", err)
+	}
+	var keys []string
+	for _, o := range ObsoleteKeys(cf) {
+		keys = append(keys, o.Key)
+	}
+	want := []string{"version", "services.web.links", "services.web.external_links"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ObsoleteKeys() = %v, want %v", keys, want)
+	}
+
+	if got := ObsoleteKeys(&ComposeFile{}); got != nil {
+		t.Errorf("ObsoleteKeys() of a current file = %v, want none", got)
+	}
+}
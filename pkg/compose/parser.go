@@ -1,11 +1,18 @@
 package compose
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,14 +27,29 @@ var defaultComposeFiles = []string{
 
 // Load parses compose files and returns a fully resolved ComposeFile.
 // If files is empty, it searches projectDir for default compose file names.
-// If projectDir is empty, the current working directory is used.
+// Each file may be a local path, "-" for stdin, an https:// URL, or an
+// oci:// compose artifact reference.
+// If projectDir is empty, relative files are taken from the working
+// directory and the project directory is the first file's; see
+// DefaultProjectDir. Relative paths inside the files (build contexts, env
+// files, bind mounts) are resolved against projectDir whichever file they
+// come from, except in files pulled in with include, which resolve against
+// their own directory.
 func Load(files []string, projectDir string) (*ComposeFile, error) {
+	return load(files, projectDir, nil)
+}
+
+// load is Load, with the files already being included further up a chain
+// of include entries.
+func load(files []string, projectDir string, including []string) (*ComposeFile, error) {
 	if projectDir == "" {
-		wd, err := os.Getwd()
-		if err != nil {
-			return nil, fmt.Errorf("getting working directory: %w", err)
+		var err error
+		if files, err = AbsFiles(files); err != nil {
+			return nil, err
+		}
+		if projectDir, err = DefaultProjectDir(files); err != nil {
+			return nil, err
 		}
-		projectDir = wd
 	}
 
 	if len(files) == 0 {
@@ -38,46 +60,219 @@ func Load(files []string, projectDir string) (*ComposeFile, error) {
 		files = []string{found}
 	}
 
-	var merged *ComposeFile
+	merged, err := mergeFiles(files, projectDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIncludingModel(merged, projectDir, including)
+}
+
+// mergeFiles reads, interpolates and merges compose files in order. When
+// observe is set, it is told every variable interpolation looks up.
+func mergeFiles(files []string, projectDir string, observe func(key, value string, ok bool)) (*yaml.Node, error) {
+	var merged *yaml.Node
+	readStdin := false
 	for _, f := range files {
-		path := f
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(projectDir, path)
+		if f == "-" {
+			if readStdin {
+				return nil, fmt.Errorf("stdin (-) can only be given once as a compose file")
+			}
+			readStdin = true
 		}
 
-		data, err := os.ReadFile(path)
+		sources, err := readComposeSource(f, projectDir)
 		if err != nil {
-			return nil, fmt.Errorf("reading %s: %w", path, err)
+			return nil, err
 		}
 
-		data = []byte(interpolateEnv(string(data)))
+		for _, src := range sources {
+			lookup := src.lookup
+			if observe != nil {
+				lookup = func(key string) (string, bool) {
+					v, ok := src.lookup(key)
+					observe(key, v, ok)
+					return v, ok
+				}
+			}
+			data := []byte(interpolateWith(string(src.data), lookup))
 
-		cf, err := parseComposeFile(data)
-		if err != nil {
-			return nil, fmt.Errorf("parsing %s: %w", path, err)
-		}
+			nodes, err := parseComposeNodes(data)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", src.name, err)
+			}
 
-		if merged == nil {
-			merged = cf
-		} else {
-			mergeComposeFiles(merged, cf)
+			for _, node := range nodes {
+				if merged == nil {
+					merged = node
+				} else {
+					merged = mergeNode(merged, node, nil)
+				}
+			}
 		}
 	}
 
 	if merged == nil {
 		return nil, fmt.Errorf("no compose files loaded")
 	}
+	return merged, nil
+}
+
+// resolveModel decodes a merged compose document and resolves its services,
+// secrets and networks against projectDir, adding the files it includes.
+func resolveModel(merged *yaml.Node, projectDir string) (*ComposeFile, error) {
+	return resolveIncludingModel(merged, projectDir, nil)
+}
+
+// resolveIncludingModel is resolveModel for a document reached through
+// the include entries of the files in including.
+func resolveIncludingModel(merged *yaml.Node, projectDir string, including []string) (*ComposeFile, error) {
+	cf, err := decodeComposeFile(merged)
+	if err != nil {
+		return nil, fmt.Errorf("parsing merged compose files: %w", err)
+	}
+
+	cf.Env, err = resolveEnvironment(cf.Env)
+	if err != nil {
+		return nil, fmt.Errorf("x-dctl-env: %w", err)
+	}
+	projectEnv, _ := cf.Env.(map[string]string)
 
 	// Resolve flexible types in all services.
-	for name, svc := range merged.Services {
+	for name, svc := range cf.Services {
 		resolved, err := resolveService(svc)
 		if err != nil {
 			return nil, fmt.Errorf("service %q: %w", name, err)
 		}
-		merged.Services[name] = resolved
+		resolved.ProjectEnv = projectEnv
+		cf.Services[name] = resolveServicePaths(resolved, projectDir)
 	}
 
-	return merged, nil
+	if err := resolveSecrets(cf, projectDir); err != nil {
+		return nil, err
+	}
+
+	if err := mergeIncludes(cf, projectDir, including); err != nil {
+		return nil, err
+	}
+
+	for name, n := range cf.Networks {
+		if err := validateNetwork(n); err != nil {
+			return nil, fmt.Errorf("network %q: %w", name, err)
+		}
+	}
+
+	if err := validateContainerNames(cf.Services); err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+// validateContainerNames checks that no two services set the same
+// container_name.
+func validateContainerNames(services map[string]Service) error {
+	owners := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		cName := services[name].ContainerName
+		if cName == "" {
+			continue
+		}
+		if other, ok := owners[cName]; ok {
+			return fmt.Errorf("services %q and %q both set container_name %q", other, name, cName)
+		}
+		owners[cName] = name
+	}
+	return nil
+}
+
+// ResolveFiles returns the local compose file paths Load would read,
+// resolving relative paths against projectDir and falling back to the
+// default file names when files is empty.
+func ResolveFiles(files []string, projectDir string) ([]string, error) {
+	if len(files) == 0 {
+		found, err := findDefaultFile(projectDir)
+		if err != nil {
+			return nil, err
+		}
+		return []string{found}, nil
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if f == "-" || strings.Contains(f, "://") {
+			return nil, fmt.Errorf("%s is not a local file", f)
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(projectDir, f)
+		}
+		paths = append(paths, f)
+	}
+	return paths, nil
+}
+
+// AbsFiles makes local compose file paths absolute against the working
+// directory, the way -f is given on the command line. Stdin (-) and URLs
+// are left as they are.
+func AbsFiles(files []string) ([]string, error) {
+	abs := make([]string, len(files))
+	for i, f := range files {
+		if f == "-" || strings.Contains(f, "://") || filepath.IsAbs(f) {
+			abs[i] = f
+			continue
+		}
+		p, err := filepath.Abs(f)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", f, err)
+		}
+		abs[i] = p
+	}
+	return abs, nil
+}
+
+// DefaultProjectDir returns the project directory used when none is
+// given: the directory of the first compose file, or the working directory
+// when no files are given or the first is stdin or a URL.
+func DefaultProjectDir(files []string) (string, error) {
+	if len(files) > 0 && files[0] != "-" && !strings.Contains(files[0], "://") {
+		p, err := filepath.Abs(files[0])
+		if err != nil {
+			return "", fmt.Errorf("resolving %s: %w", files[0], err)
+		}
+		return filepath.Dir(p), nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting working directory: %w", err)
+	}
+	return wd, nil
+}
+
+// ProfileFiles returns the files layered over a compose file for the active
+// profiles, in order: for compose.yaml and profile ci, compose.ci.yaml, or
+// else compose.ci.yml. Profiles without such a file are skipped.
+func ProfileFiles(base string, profiles []string) []string {
+	dir, name := filepath.Split(base)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	exts := []string{ext, ".yml"}
+	if ext == ".yml" {
+		exts[1] = ".yaml"
+	}
+
+	var files []string
+	for _, p := range profiles {
+		if p == "" || strings.ContainsAny(p, `/\`) {
+			continue
+		}
+		for _, e := range exts {
+			path := filepath.Join(dir, stem+"."+p+e)
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+				break
+			}
+		}
+	}
+	return files
 }
 
 // findDefaultFile searches for compose files in priority order.
@@ -96,67 +291,162 @@ var envVarPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
 
 // interpolateEnv replaces ${VAR}, ${VAR:-default}, and ${VAR-default} with environment values.
 func interpolateEnv(s string) string {
+	return interpolateWith(s, os.LookupEnv)
+}
+
+// interpolateWith is interpolateEnv with a custom variable lookup.
+func interpolateWith(s string, lookup func(string) (string, bool)) string {
 	return envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
 		// Strip ${ and }
-		inner := match[2 : len(match)-1]
-
-		// Check for ${VAR:-default} (use default if unset or empty)
-		if idx := strings.Index(inner, ":-"); idx >= 0 {
-			varName := inner[:idx]
-			defaultVal := inner[idx+2:]
-			if val, ok := os.LookupEnv(varName); ok && val != "" {
+		name, op, defaultVal := splitReference(match[2 : len(match)-1])
+		val, ok := lookup(name)
+		switch op {
+		case ":-":
+			// Use the default if unset or empty
+			if ok && val != "" {
 				return val
 			}
 			return defaultVal
-		}
-
-		// Check for ${VAR-default} (use default only if unset)
-		if idx := strings.Index(inner, "-"); idx >= 0 {
-			varName := inner[:idx]
-			defaultVal := inner[idx+1:]
-			if val, ok := os.LookupEnv(varName); ok {
+		case "-":
+			// Use the default only if unset
+			if ok {
 				return val
 			}
 			return defaultVal
 		}
-
-		// Plain ${VAR}
-		return os.Getenv(inner)
+		return val
 	})
 }
 
-// parseComposeFile unmarshals YAML data into a ComposeFile.
-func parseComposeFile(data []byte) (*ComposeFile, error) {
-	var cf ComposeFile
-	if err := yaml.Unmarshal(data, &cf); err != nil {
+// splitReference splits the inside of ${...} into the variable name, the
+// default operator (":-", "-" or "" for none) and the default value.
+func splitReference(inner string) (name, op, defaultVal string) {
+	if idx := strings.Index(inner, ":-"); idx >= 0 {
+		return inner[:idx], ":-", inner[idx+2:]
+	}
+	if idx := strings.Index(inner, "-"); idx >= 0 {
+		return inner[:idx], "-", inner[idx+1:]
+	}
+	return inner, "", ""
+}
+
+// parseComposeNode parses YAML data into its root node, which is kept
+// untyped so multiple files can be merged field by field. The file is also
+// decoded once so type errors are reported against the file that has them.
+func parseComposeNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, err
 	}
-	if cf.Services == nil {
-		cf.Services = make(map[string]Service)
+	return composeRoot(&doc)
+}
+
+// parseComposeNodes parses each YAML document in data as parseComposeNode
+// does. Documents separated by --- are merged in order like separate files,
+// as some templating tools emit them; empty documents are skipped.
+func parseComposeNodes(data []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var nodes []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 || isNull(doc.Content[0]) {
+			continue
+		}
+		root, err := composeRoot(&doc)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, root)
 	}
-	return &cf, nil
+	if len(nodes) == 0 {
+		return []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}, nil
+	}
+	return nodes, nil
 }
 
-// mergeComposeFiles merges src into dst. Services in src override those in dst.
-func mergeComposeFiles(dst, src *ComposeFile) {
-	if src.Name != "" {
-		dst.Name = src.Name
+// composeRoot returns a parsed document's root mapping with merge keys
+// expanded, checking that it decodes.
+func composeRoot(doc *yaml.Node) (*yaml.Node, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		var err error
+		if root, err = expandMergeKeys(doc.Content[0], nil); err != nil {
+			return nil, err
+		}
 	}
-	for name, svc := range src.Services {
-		dst.Services[name] = svc
+	if _, err := decodeComposeFile(root); err != nil {
+		return nil, err
 	}
-	if dst.Networks == nil && src.Networks != nil {
-		dst.Networks = make(map[string]Network)
+	return root, nil
+}
+
+// decodeComposeFile decodes a root node into a ComposeFile, ignoring the
+// merge control tags.
+func decodeComposeFile(node *yaml.Node) (*ComposeFile, error) {
+	var cf ComposeFile
+	node = stripMergeTags(node)
+	if err := shortPortSyntax(node); err != nil {
+		return nil, err
 	}
-	for name, net := range src.Networks {
-		dst.Networks[name] = net
+	if err := shortVolumeSyntax(node); err != nil {
+		return nil, err
 	}
-	if dst.Volumes == nil && src.Volumes != nil {
-		dst.Volumes = make(map[string]VolumeConfig)
+	if err := node.Decode(&cf); err != nil {
+		return nil, err
 	}
-	for name, vol := range src.Volumes {
-		dst.Volumes[name] = vol
+	cf.Keys = DefinedKeys{
+		Top:      topKeys(node),
+		Services: definedKeys(node, "services"),
+		Networks: definedKeys(node, "networks"),
+		Volumes:  definedKeys(node, "volumes"),
 	}
+	if cf.Services == nil {
+		cf.Services = make(map[string]Service)
+	}
+	return &cf, nil
+}
+
+// definedKeys returns the keys of each definition in a top-level section.
+func definedKeys(root *yaml.Node, section string) map[string][]string {
+	keys := make(map[string][]string)
+	i := mappingIndex(root, section)
+	if i < 0 || root.Content[i+1].Kind != yaml.MappingNode {
+		return keys
+	}
+	defs := root.Content[i+1]
+	for j := 0; j+1 < len(defs.Content); j += 2 {
+		name, def := defs.Content[j].Value, defs.Content[j+1]
+		keys[name] = []string{}
+		if def.Kind != yaml.MappingNode {
+			continue
+		}
+		for k := 0; k+1 < len(def.Content); k += 2 {
+			key, val := def.Content[k].Value, def.Content[k+1]
+			keys[name] = append(keys[name], key)
+			if (key == "build" || key == "deploy") && val.Kind == yaml.MappingNode {
+				for b := 0; b+1 < len(val.Content); b += 2 {
+					keys[name] = append(keys[name], key+"."+val.Content[b].Value)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// topKeys returns the top-level keys of a compose file in order.
+func topKeys(root *yaml.Node) []string {
+	keys := []string{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keys = append(keys, root.Content[i].Value)
+	}
+	return keys
 }
 
 // resolveService normalizes flexible YAML types in a service definition.
@@ -183,6 +473,16 @@ func resolveService(svc Service) (Service, error) {
 		return svc, fmt.Errorf("env_file: %w", err)
 	}
 
+	if _, err := ParseExpose(svc.Expose); err != nil {
+		return svc, fmt.Errorf("expose: %w", err)
+	}
+
+	if svc.StopGracePeriod != "" {
+		if _, err := time.ParseDuration(svc.StopGracePeriod); err != nil {
+			return svc, fmt.Errorf("stop_grace_period: invalid duration %q", svc.StopGracePeriod)
+		}
+	}
+
 	svc.DependsOn, err = resolveDependsOn(svc.DependsOn)
 	if err != nil {
 		return svc, fmt.Errorf("depends_on: %w", err)
@@ -202,11 +502,33 @@ func resolveService(svc Service) (Service, error) {
 	if err != nil {
 		return svc, fmt.Errorf("tmpfs: %w", err)
 	}
+	if tmpfs, ok := svc.Tmpfs.([]string); ok {
+		for _, t := range tmpfs {
+			if _, err := ParseTmpfs(t); err != nil {
+				return svc, fmt.Errorf("tmpfs: %w", err)
+			}
+		}
+	}
 
 	svc.Networks, err = resolveNetworks(svc.Networks)
 	if err != nil {
 		return svc, fmt.Errorf("networks: %w", err)
 	}
+	if err := validateNetworkMode(svc.NetworkMode); err != nil {
+		return svc, fmt.Errorf("network_mode: %w", err)
+	}
+	if svc.NetworkMode != "" && svc.Networks != nil {
+		return svc, fmt.Errorf("network_mode and networks cannot both be set")
+	}
+	for _, ns := range []struct{ key, mode string }{{"pid", svc.Pid}, {"ipc", svc.Ipc}, {"uts", svc.Uts}} {
+		if err := validateNamespace(ns.key, ns.mode); err != nil {
+			return svc, fmt.Errorf("%s: %w", ns.key, err)
+		}
+	}
+
+	if svc.UsernsMode != "" && svc.UsernsMode != "host" {
+		return svc, fmt.Errorf("userns_mode: invalid value %q", svc.UsernsMode)
+	}
 
 	var resolvedBuild interface{}
 	resolvedBuild, err = resolveBuild(svc.Build)
@@ -215,9 +537,123 @@ func resolveService(svc Service) (Service, error) {
 	}
 	svc.Build = resolvedBuild
 
+	var resolvedWait interface{}
+	resolvedWait, err = resolveWait(svc.Wait)
+	if err != nil {
+		return svc, fmt.Errorf("x-dctl-wait: %w", err)
+	}
+	svc.Wait = resolvedWait
+
+	if err := resolveDevices(svc.Deploy); err != nil {
+		return svc, fmt.Errorf("deploy.resources.reservations: %w", err)
+	}
+	svc.GPU, err = resolveGPU(svc.GPU)
+	if err != nil {
+		return svc, fmt.Errorf("x-dctl-gpu: %w", err)
+	}
+
+	svc.DependsOnProject, err = resolveStringOrList(svc.DependsOnProject)
+	if err != nil {
+		return svc, fmt.Errorf("x-dctl-depends-on-project: %w", err)
+	}
+
+	svc.Secrets, err = resolveServiceSecrets(svc.Secrets)
+	if err != nil {
+		return svc, fmt.Errorf("secrets: %w", err)
+	}
+
+	if err := validateDevelop(svc.Develop); err != nil {
+		return svc, fmt.Errorf("develop: %w", err)
+	}
+	if err := validateHealthcheck(svc.Healthcheck); err != nil {
+		return svc, fmt.Errorf("healthcheck: %w", err)
+	}
+
 	return svc, nil
 }
 
+// validateNetwork checks IPAM subnets. IPv6 subnets require enable_ipv6,
+// as in docker compose.
+func validateNetwork(n Network) error {
+	if n.IPAM == nil {
+		return nil
+	}
+	for _, c := range n.IPAM.Config {
+		if c.Subnet == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(c.Subnet)
+		if err != nil {
+			return fmt.Errorf("ipam: invalid subnet %q", c.Subnet)
+		}
+		if prefix.Addr().Is6() && !n.EnableIPv6 {
+			return fmt.Errorf("ipam: IPv6 subnet %s requires enable_ipv6: true", c.Subnet)
+		}
+	}
+	return nil
+}
+
+// validateHealthcheck checks a healthcheck's test form and durations. As
+// in docker compose, disable: true cannot be combined with a test.
+func validateHealthcheck(h *Healthcheck) error {
+	if h == nil {
+		return nil
+	}
+	none := false
+	switch test := h.Test.(type) {
+	case nil, string:
+	case []interface{}:
+		if len(test) == 0 || test[0] != "CMD" && test[0] != "CMD-SHELL" && test[0] != "NONE" {
+			return fmt.Errorf("test must start with CMD, CMD-SHELL or NONE")
+		}
+		none = test[0] == "NONE"
+	default:
+		return fmt.Errorf("test: unsupported type %T", test)
+	}
+	if h.Disable && h.Test != nil && !none {
+		return fmt.Errorf("disable and test cannot both be set")
+	}
+	for _, d := range []struct{ key, value string }{
+		{"interval", h.Interval},
+		{"timeout", h.Timeout},
+		{"start_period", h.StartPeriod},
+		{"start_interval", h.StartInterval},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			return fmt.Errorf("%s: invalid duration %q", d.key, d.value)
+		}
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("retries: must not be negative")
+	}
+	return nil
+}
+
+// validateDevelop checks develop.watch rules.
+func validateDevelop(d *DevelopConfig) error {
+	if d == nil {
+		return nil
+	}
+	for i, rule := range d.Watch {
+		if rule.Path == "" {
+			return fmt.Errorf("watch[%d]: path is required", i)
+		}
+		switch rule.Action {
+		case WatchSync, WatchSyncRestart:
+			if !strings.HasPrefix(rule.Target, "/") {
+				return fmt.Errorf("watch[%d]: %s requires an absolute target", i, rule.Action)
+			}
+		case WatchRestart, WatchRebuild:
+		default:
+			return fmt.Errorf("watch[%d]: unsupported action %q", i, rule.Action)
+		}
+	}
+	return nil
+}
+
 // resolveCommand normalizes command/entrypoint: string → []string, list passes through.
 func resolveCommand(v interface{}) (interface{}, error) {
 	if v == nil {
@@ -254,7 +690,10 @@ func resolveEnvironment(v interface{}) (interface{}, error) {
 		result := make(map[string]string, len(val))
 		for k, v := range val {
 			if v == nil {
-				result[k] = os.Getenv(k)
+				// No value: inherit from the host env, or leave unset.
+				if hv, ok := os.LookupEnv(k); ok {
+					result[k] = hv
+				}
 			} else {
 				result[k] = fmt.Sprintf("%v", v)
 			}
@@ -269,8 +708,11 @@ func resolveEnvironment(v interface{}) (interface{}, error) {
 			if k, v, ok := strings.Cut(s, "="); ok {
 				result[k] = v
 			} else {
-				// Variable with no value inherits from host env.
-				result[s] = os.Getenv(s)
+				// Variable with no value inherits from host env, or
+				// stays unset so the image's value applies.
+				if hv, ok := os.LookupEnv(s); ok {
+					result[s] = hv
+				}
 			}
 		}
 		return result, nil
@@ -342,6 +784,37 @@ func resolveDependsOn(v interface{}) (interface{}, error) {
 	}
 }
 
+// validateNetworkMode checks the syntax of a network_mode value.
+func validateNetworkMode(mode string) error {
+	switch mode {
+	case "", "bridge", "host", "none":
+		return nil
+	}
+	if kind, name, ok := strings.Cut(mode, ":"); ok && (kind == "service" || kind == "container") && name != "" {
+		return nil
+	}
+	return fmt.Errorf("invalid value %q (want bridge, host, none, service:NAME or container:NAME)", mode)
+}
+
+// namespaceModes lists the plain values of the pid, ipc and uts keys; pid
+// and ipc also take service:NAME and container:NAME.
+var namespaceModes = map[string][]string{
+	"pid": {"host"},
+	"ipc": {"host", "private", "shareable", "none"},
+	"uts": {"host"},
+}
+
+// validateNamespace checks the syntax of a pid, ipc or uts value.
+func validateNamespace(key, mode string) error {
+	if mode == "" || slices.Contains(namespaceModes[key], mode) {
+		return nil
+	}
+	if kind, name, ok := strings.Cut(mode, ":"); ok && key != "uts" && (kind == "service" || kind == "container") && name != "" {
+		return nil
+	}
+	return fmt.Errorf("invalid value %q", mode)
+}
+
 // resolveStringOrList normalizes dns/dns_search/tmpfs: string → []string, list passes through.
 func resolveStringOrList(v interface{}) (interface{}, error) {
 	if v == nil {
@@ -424,3 +897,119 @@ func resolveBuild(v interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("unsupported type %T", v)
 	}
 }
+
+// resolveWait normalizes x-dctl-wait: string (target URL) or map → *WaitConfig.
+func resolveWait(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch val := v.(type) {
+	case *WaitConfig:
+		return val, nil
+	case string:
+		return &WaitConfig{WaitFor: val}, nil
+	case map[string]interface{}:
+		wc := &WaitConfig{}
+		if w, ok := val["wait_for"]; ok {
+			wc.WaitFor = fmt.Sprintf("%v", w)
+		}
+		if t, ok := val["timeout"]; ok {
+			wc.Timeout = fmt.Sprintf("%v", t)
+		}
+		if i, ok := val["interval"]; ok {
+			wc.Interval = fmt.Sprintf("%v", i)
+		}
+		if wc.WaitFor == "" {
+			return nil, fmt.Errorf("wait_for is required")
+		}
+		for _, d := range []string{wc.Timeout, wc.Interval} {
+			if d == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(d); err != nil {
+				return nil, fmt.Errorf("invalid duration %q", d)
+			}
+		}
+		return wc, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// resolveServiceSecrets normalizes a service's secrets: names or long-syntax
+// maps → []ServiceSecret with Target defaulting to the source name.
+func resolveServiceSecrets(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		if secrets, ok := v.([]ServiceSecret); ok {
+			return secrets, nil
+		}
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+	result := make([]ServiceSecret, 0, len(items))
+	for _, item := range items {
+		var s ServiceSecret
+		switch val := item.(type) {
+		case string:
+			s.Source = val
+		case map[string]interface{}:
+			if src, ok := val["source"]; ok {
+				s.Source = fmt.Sprintf("%v", src)
+			}
+			if t, ok := val["target"]; ok {
+				s.Target = fmt.Sprintf("%v", t)
+			}
+			if e, ok := val["x-dctl-env-var"]; ok {
+				s.EnvVar = fmt.Sprintf("%v", e)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported type %T", item)
+		}
+		if s.Source == "" {
+			return nil, fmt.Errorf("source is required")
+		}
+		if s.Target == "" {
+			s.Target = s.Source
+		}
+		if strings.Contains(s.Target, "/") {
+			return nil, fmt.Errorf("%s: target must be a file name", s.Source)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// resolveSecrets validates top-level secrets, makes secret files absolute
+// and checks that every service secret refers to a defined secret.
+func resolveSecrets(cf *ComposeFile, projectDir string) error {
+	for name, sec := range cf.Secrets {
+		sources := 0
+		for _, s := range []string{sec.File, sec.Environment, sec.Keychain, sec.Provider} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("secret %q: exactly one of file, environment, x-dctl-keychain or x-dctl-provider is required", name)
+		}
+		if (sec.Provider == "") != (sec.Ref == "") {
+			return fmt.Errorf("secret %q: x-dctl-provider and x-dctl-ref must be set together", name)
+		}
+		if sec.File != "" {
+			sec.File = ResolvePath(projectDir, sec.File)
+			cf.Secrets[name] = sec
+		}
+	}
+	for svcName, svc := range cf.Services {
+		secrets, _ := svc.Secrets.([]ServiceSecret)
+		for _, s := range secrets {
+			if _, ok := cf.Secrets[s.Source]; !ok {
+				return fmt.Errorf("service %q refers to undefined secret %q", svcName, s.Source)
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package compose
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -329,8 +330,8 @@ services:
 		if !ok {
 			t.Fatalf("build type = %T, want *BuildConfig", cf.Services["app"].Build)
 		}
-		if bc.Context != "./app" {
-			t.Errorf("build.Context = %q, want %q", bc.Context, "./app")
+		if want := filepath.Join(dir, "app"); bc.Context != want {
+			t.Errorf("build.Context = %q, want %q", bc.Context, want)
 		}
 	})
 
@@ -359,8 +360,8 @@ services:
 		if !ok {
 			t.Fatalf("build type = %T, want *BuildConfig", cf.Services["app"].Build)
 		}
-		if bc.Context != "./app" {
-			t.Errorf("build.Context = %q, want %q", bc.Context, "./app")
+		if want := filepath.Join(dir, "app"); bc.Context != want {
+			t.Errorf("build.Context = %q, want %q", bc.Context, want)
 		}
 		if bc.Dockerfile != "Dockerfile.prod" {
 			t.Errorf("build.Dockerfile = %q, want %q", bc.Dockerfile, "Dockerfile.prod")
@@ -614,3 +615,402 @@ volumes:
 	}
 }
 
+func TestLoad_WaitExtension(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+services:
+  db:
+    image: postgres
+    x-dctl-wait: tcp://db:5432
+  web:
+    image: nginx
+    x-dctl-wait:
+      wait_for: http://web:80/health
+      timeout: 30s
+      interval: 2s
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	db, ok := cf.Services["db"].Wait.(*WaitConfig)
+	if !ok {
+		t.Fatalf("db wait type = %T, want *WaitConfig", cf.Services["db"].Wait)
+	}
+	if db.WaitFor != "tcp://db:5432" {
+		t.Errorf("db.WaitFor = %q, want %q", db.WaitFor, "tcp://db:5432")
+	}
+
+	web, ok := cf.Services["web"].Wait.(*WaitConfig)
+	if !ok {
+		t.Fatalf("web wait type = %T, want *WaitConfig", cf.Services["web"].Wait)
+	}
+	if web.WaitFor != "http://web:80/health" || web.Timeout != "30s" || web.Interval != "2s" {
+		t.Errorf("web wait = %+v", web)
+	}
+}
+
+func TestLoad_WaitExtensionInvalid(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+services:
+  web:
+    image: nginx
+    x-dctl-wait:
+      timeout: 30s
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+	if _, err := Load(nil, dir); err == nil || !strings.Contains(err.Error(), "wait_for is required") {
+		t.Errorf("Load() error = %v, want wait_for is required", err)
+	}
+}
+
+func TestLoad_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+secrets:
+  db_password:
+    x-dctl-keychain: shop-db
+  api_key:
+    file: ./secrets/api.key
+services:
+  web:
+    image: nginx
+    secrets:
+      - db_password
+      - source: api_key
+        target: key
+      - source: db_password
+        x-dctl-env-var: DB_PASSWORD
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if got := cf.Secrets["api_key"].File; got != filepath.Join(dir, "secrets", "api.key") {
+		t.Errorf("api_key file = %q, want absolute path", got)
+	}
+	want := []ServiceSecret{
+		{Source: "db_password", Target: "db_password"},
+		{Source: "api_key", Target: "key"},
+		{Source: "db_password", Target: "db_password", EnvVar: "DB_PASSWORD"},
+	}
+	got := cf.Services["web"].Secrets.([]ServiceSecret)
+	if len(got) != len(want) {
+		t.Fatalf("secrets = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("secrets[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoad_SecretsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"undefined secret": `
+services:
+  web:
+    image: nginx
+    secrets: [missing]
+`,
+		"two sources": `
+secrets:
+  s:
+    file: ./s
+    environment: S
+services:
+  web:
+    image: nginx
+`,
+		"target with slash": `
+secrets:
+  s:
+    environment: S
+services:
+  web:
+    image: nginx
+    secrets:
+      - source: s
+        target: a/b
+`,
+	}
+	for name, content := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatalf("writing compose file: %v", err)
+		}
+		if _, err := Load(nil, dir); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoad_LongSyntaxPorts(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80"
+      - target: 443
+        published: 0
+      - target: 53
+        published: "5353"
+        protocol: udp
+      - target: 9000
+        host_ip: 127.0.0.1
+      - target: 81
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := []string{"8080:80", "0:443", "5353:53/udp", "127.0.0.1::9000", "81"}
+	got := cf.Services["web"].Ports
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ports = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_NetworkIPv6(t *testing.T) {
+	write := func(content string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatalf("writing compose file: %v", err)
+		}
+		return dir
+	}
+
+	dir := write(`
+services:
+  web:
+    image: nginx
+networks:
+  front:
+    enable_ipv6: true
+    ipam:
+      config:
+        - subnet: fd00:5::/64
+`)
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !cf.Networks["front"].EnableIPv6 {
+		t.Error("enable_ipv6 not parsed")
+	}
+
+	for _, subnet := range []string{"fd00:5::/64", "not-a-cidr"} {
+		dir := write(`
+services:
+  web:
+    image: nginx
+networks:
+  front:
+    ipam:
+      config:
+        - subnet: ` + subnet + `
+`)
+		if _, err := Load(nil, dir); err == nil {
+			t.Errorf("Load() with subnet %s and no enable_ipv6 succeeded, want error", subnet)
+		}
+	}
+}
+
+func TestLoad_Namespaces(t *testing.T) {
+	write := func(content string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatalf("writing compose file: %v", err)
+		}
+		return dir
+	}
+
+	cf, err := Load(nil, write("services:\n  app:\n    image: app\n  debug:\n    image: busybox\n    network_mode: service:app\n"))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cf.Services["debug"].NetworkMode; got != "service:app" {
+		t.Errorf("network_mode = %q, want service:app", got)
+	}
+
+	cf, err = Load(nil, write("services:\n  app:\n    image: app\n    pid: host\n    ipc: service:app\n    uts: host\n"))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if svc := cf.Services["app"]; svc.Pid != "host" || svc.Ipc != "service:app" || svc.Uts != "host" {
+		t.Errorf("pid, ipc, uts = %q, %q, %q", svc.Pid, svc.Ipc, svc.Uts)
+	}
+
+	for _, svc := range []string{
+		"network_mode: bogus",
+		"network_mode: \"service:\"",
+		"network_mode: host\n    networks: [front]",
+		"pid: private",
+		"ipc: \"service:\"",
+		"uts: service:app",
+	} {
+		if _, err := Load(nil, write("services:\n  web:\n    image: nginx\n    "+svc+"\n")); err == nil {
+			t.Errorf("Load() with %q succeeded, want error", svc)
+		}
+	}
+}
+
+func TestLoad_GroupAddUserns(t *testing.T) {
+	dir := t.TempDir()
+	content := "services:\n  app:\n    image: app\n    group_add: [docker, 999]\n    userns_mode: host\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	svc := cf.Services["app"]
+	if strings.Join(svc.GroupAdd, ",") != "docker,999" || svc.UsernsMode != "host" {
+		t.Errorf("group_add, userns_mode = %v, %q", svc.GroupAdd, svc.UsernsMode)
+	}
+
+	content = "services:\n  app:\n    image: app\n    userns_mode: private\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(nil, dir); err == nil {
+		t.Error("Load() accepted userns_mode: private")
+	}
+}
+
+func TestLoad_StopGracePeriod(t *testing.T) {
+	dir := t.TempDir()
+	for content, ok := range map[string]bool{
+		"services:\n  app:\n    image: app\n    stop_signal: SIGINT\n    stop_grace_period: 1m30s\n": true,
+		"services:\n  app:\n    image: app\n    stop_grace_period: soon\n":                           false,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(nil, dir); (err == nil) != ok {
+			t.Errorf("Load(%q) error = %v, want ok = %v", content, err, ok)
+		}
+	}
+}
+
+func TestProfileFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"compose.yaml", "compose.ci.yaml", "compose.dev.yml", "compose.dev.yaml.bak"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("services: {}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := ProfileFiles(filepath.Join(dir, "compose.yaml"), []string{"dev", "test", "ci", "../compose"})
+	want := []string{filepath.Join(dir, "compose.dev.yml"), filepath.Join(dir, "compose.ci.yaml")}
+	if !slices.Equal(got, want) {
+		t.Errorf("ProfileFiles() = %v, want %v", got, want)
+	}
+	if got := ProfileFiles(filepath.Join(dir, "docker-compose.yml"), []string{"ci"}); len(got) != 0 {
+		t.Errorf("ProfileFiles() for docker-compose.yml = %v, want none", got)
+	}
+}
+
+func TestLoad_DependsOnProject(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compose.yaml")
+	content := "services:\n  web:\n    image: nginx\n    x-dctl-depends-on-project: infra\n  worker:\n    image: app\n    x-dctl-depends-on-project: [infra, auth]\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load([]string{path}, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got, want := cf.Services["web"].DependsOnProject, []string{"infra"}; !slices.Equal(got.([]string), want) {
+		t.Errorf("web DependsOnProject = %v, want %v", got, want)
+	}
+	if got, want := cf.Services["worker"].DependsOnProject, []string{"infra", "auth"}; !slices.Equal(got.([]string), want) {
+		t.Errorf("worker DependsOnProject = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_EmptyEntrypoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compose.yaml")
+	content := "services:\n  web:\n    image: nginx\n    entrypoint: \"\"\n  api:\n    image: app\n    entrypoint: []\n  db:\n    image: postgres\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load([]string{path}, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	for _, name := range []string{"web", "api"} {
+		if ep, ok := cf.Services[name].Entrypoint.([]string); !ok || len(ep) != 0 {
+			t.Errorf("%s Entrypoint = %#v, want an empty list", name, cf.Services[name].Entrypoint)
+		}
+	}
+	if ep := cf.Services["db"].Entrypoint; ep != nil {
+		t.Errorf("db Entrypoint = %#v, want unset", ep)
+	}
+}
+
+func TestLoad_DuplicateContainerName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compose.yaml")
+	content := "services:\n  web:\n    image: nginx\n    container_name: front\n  api:\n    image: app\n    container_name: front\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load([]string{path}, dir)
+	if err == nil || !strings.Contains(err.Error(), `services "api" and "web" both set container_name "front"`) {
+		t.Errorf("Load() error = %v, want a container_name conflict", err)
+	}
+}
+
+func TestLoad_Healthcheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "compose.yaml")
+	content := "services:\n  db:\n    image: postgres\n    healthcheck:\n      test: [\"CMD\", \"pg_isready\"]\n      start_period: 30s\n      start_interval: 1s\n  cache:\n    image: redis\n    healthcheck:\n      disable: true\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load([]string{path}, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if hc := cf.Services["db"].Healthcheck; hc.StartPeriod != "30s" || hc.StartInterval != "1s" {
+		t.Errorf("db healthcheck = %+v, want start_period 30s and start_interval 1s", hc)
+	}
+	if !cf.Services["cache"].Healthcheck.Disabled() {
+		t.Error("cache healthcheck should be disabled")
+	}
+
+	for _, hc := range []string{
+		"test: [\"RUN\", \"true\"]",
+		"disable: true\n      test: [\"CMD\", \"true\"]",
+		"test: [\"CMD\", \"true\"]\n      start_interval: soon",
+	} {
+		content := "services:\n  db:\n    image: postgres\n    healthcheck:\n      " + hc + "\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load([]string{path}, dir); err == nil {
+			t.Errorf("Load() with healthcheck %q succeeded", hc)
+		}
+	}
+}
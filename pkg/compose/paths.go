@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePath makes a path from a compose file absolute. Relative paths are
+// resolved against the project directory and a leading ~ against the
+// user's home directory.
+func ResolvePath(projectDir, p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(projectDir, p)
+}
+
+// resolveServicePaths makes the build context, env files and bind mount
+// sources of a resolved service absolute, so every command sees the same
+// paths regardless of the working directory.
+func resolveServicePaths(svc Service, projectDir string) Service {
+	if bc, ok := svc.Build.(*BuildConfig); ok && !IsRemoteContext(bc.Context) {
+		resolved := *bc
+		if resolved.Context == "" {
+			resolved.Context = "."
+		}
+		resolved.Context = ResolvePath(projectDir, resolved.Context)
+		svc.Build = &resolved
+	}
+
+	if files, ok := svc.EnvFile.([]string); ok {
+		resolved := make([]string, len(files))
+		for i, f := range files {
+			resolved[i] = ResolvePath(projectDir, f)
+		}
+		svc.EnvFile = resolved
+	}
+
+	if len(svc.Volumes) > 0 {
+		resolved := make([]string, len(svc.Volumes))
+		for i, v := range svc.Volumes {
+			resolved[i] = resolveVolumeSource(v, projectDir)
+		}
+		svc.Volumes = resolved
+	}
+
+	if svc.Develop != nil && len(svc.Develop.Watch) > 0 {
+		develop := *svc.Develop
+		develop.Watch = make([]WatchRule, len(svc.Develop.Watch))
+		for i, rule := range svc.Develop.Watch {
+			rule.Path = ResolvePath(projectDir, rule.Path)
+			develop.Watch[i] = rule
+		}
+		svc.Develop = &develop
+	}
+
+	return svc
+}
+
+// resolveVolumeSource makes the host side of a short-syntax bind mount
+// absolute. Named volumes and anonymous volumes are returned unchanged.
+func resolveVolumeSource(spec, projectDir string) string {
+	source, rest, ok := strings.Cut(spec, ":")
+	if !ok || !IsBindSource(source) {
+		return spec
+	}
+	return ResolvePath(projectDir, source) + ":" + rest
+}
+
+// IsBindSource reports whether a volume source is a host path rather than
+// a named volume.
+func IsBindSource(source string) bool {
+	return strings.HasPrefix(source, ".") || strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~")
+}
+
+// IsRemoteContext reports whether a build context is a URL rather than a
+// local directory.
+func IsRemoteContext(context string) bool {
+	return strings.Contains(context, "://") || strings.HasPrefix(context, "git@")
+}
@@ -0,0 +1,201 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"./data", "/proj/data"},
+		{"../shared", "/shared"},
+		{"/abs/path", "/abs/path"},
+		{"~/cache", filepath.Join(home, "cache")},
+		{"~", home},
+	}
+	for _, tt := range tests {
+		if got := ResolvePath("/proj", tt.in); got != tt.want {
+			t.Errorf("ResolvePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_PathsRelativeToProjectDirectory(t *testing.T) {
+	filesDir := t.TempDir()
+
+	content := `
+services:
+  app:
+    build:
+      context: ./app
+    env_file: [.env, /etc/app.env]
+    volumes:
+      - ./src:/src:ro
+      - data:/data
+      - /tmp/cache:/cache
+      - /anonymous
+  remote:
+    build: https://github.com/example/repo.git
+`
+	composePath := filepath.Join(filesDir, "compose.yaml")
+	if err := os.WriteFile(composePath, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+
+	projectDir := t.TempDir()
+	tests := []struct {
+		name       string
+		projectDir string
+		want       string
+	}{
+		// Without a project directory, paths resolve against the first
+		// file's directory, not the working directory.
+		{"default", "", filesDir},
+		{"explicit", projectDir, projectDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			cf, err := Load([]string{composePath}, tt.projectDir)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+
+			app := cf.Services["app"]
+			if got := app.Build.(*BuildConfig).Context; got != filepath.Join(want, "app") {
+				t.Errorf("build.Context = %q, want it under %s", got, want)
+			}
+			wantEnv := []string{filepath.Join(want, ".env"), "/etc/app.env"}
+			if !reflect.DeepEqual(app.EnvFile, wantEnv) {
+				t.Errorf("EnvFile = %v, want %v", app.EnvFile, wantEnv)
+			}
+			wantVolumes := []string{filepath.Join(want, "src") + ":/src:ro", "data:/data", "/tmp/cache:/cache", "/anonymous"}
+			if !reflect.DeepEqual(app.Volumes, wantVolumes) {
+				t.Errorf("Volumes = %v, want %v", app.Volumes, wantVolumes)
+			}
+			if got := cf.Services["remote"].Build.(*BuildConfig).Context; got != "https://github.com/example/repo.git" {
+				t.Errorf("remote build.Context = %q, want URL unchanged", got)
+			}
+		})
+	}
+}
+
+func TestLoad_IncludePathsRelativeToIncludedFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("compose.yaml", `
+include:
+  - db/compose.yaml
+services:
+  web:
+    build: ./web
+`)
+	write("db/compose.yaml", `
+include:
+  - path: cache/compose.yaml
+services:
+  db:
+    image: postgres
+    volumes:
+      - ./data:/var/lib/postgresql/data
+volumes:
+  dbdata: {}
+`)
+	write("db/cache/compose.yaml", `
+services:
+  cache:
+    build: .
+`)
+
+	cf, err := Load([]string{filepath.Join(dir, "compose.yaml")}, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := cf.Services["web"].Build.(*BuildConfig).Context; got != filepath.Join(dir, "web") {
+		t.Errorf("web build.Context = %q, want it under the project directory", got)
+	}
+	want := []string{filepath.Join(dir, "db", "data") + ":/var/lib/postgresql/data"}
+	if got := cf.Services["db"].Volumes; !reflect.DeepEqual(got, want) {
+		t.Errorf("db volumes = %v, want %v", got, want)
+	}
+	if got := cf.Services["cache"].Build.(*BuildConfig).Context; got != filepath.Join(dir, "db", "cache") {
+		t.Errorf("cache build.Context = %q, want the included file's directory", got)
+	}
+	if _, ok := cf.Volumes["dbdata"]; !ok {
+		t.Errorf("volumes = %v, want the included dbdata", cf.Volumes)
+	}
+
+	write("db/cache/compose.yaml", "include: [../compose.yaml]\n")
+	if _, err := Load([]string{filepath.Join(dir, "compose.yaml")}, ""); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("Load() with an include cycle = %v, want an error", err)
+	}
+
+	write("db/cache/compose.yaml", "services:\n  web:\n    image: nginx\n")
+	if _, err := Load([]string{filepath.Join(dir, "compose.yaml")}, ""); err == nil || !strings.Contains(err.Error(), "already defined") {
+		t.Errorf("Load() with a conflicting include = %v, want an error", err)
+	}
+}
+
+func TestLoad_DevelopWatch(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+services:
+  web:
+    image: node
+    develop:
+      watch:
+        - path: ./src
+          action: sync
+          target: /app/src
+          ignore: [node_modules/]
+        - path: package.json
+          action: rebuild
+`
+	path := filepath.Join(dir, "compose.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing compose file: %v", err)
+	}
+	cf, err := Load([]string{path}, dir)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := []WatchRule{
+		{Path: filepath.Join(dir, "src"), Action: WatchSync, Target: "/app/src", Ignore: []string{"node_modules/"}},
+		{Path: filepath.Join(dir, "package.json"), Action: WatchRebuild},
+	}
+	if got := cf.Services["web"].Develop.Watch; !reflect.DeepEqual(got, want) {
+		t.Errorf("watch = %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"{path: ./src, action: sync}",
+		"{path: ./src, action: reload}",
+		"{action: restart}",
+	} {
+		content := "services:\n  web:\n    image: node\n    develop:\n      watch: [" + bad + "]\n"
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("writing compose file: %v", err)
+		}
+		if _, err := Load([]string{path}, dir); err == nil {
+			t.Errorf("Load() with watch rule %s: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Action is what `up` does with a resource.
+type Action string
+
+const (
+	ActionCreate       Action = "create"
+	ActionRecreate     Action = "recreate"
+	ActionStart        Action = "start"
+	ActionUpToDate     Action = "up-to-date"
+	ActionRemoveOrphan Action = "remove-orphan"
+)
+
+// Step is one planned action on a resource.
+type Step struct {
+	Kind   string // service, network, volume or container
+	Name   string
+	Action Action
+	Reason string
+}
+
+// ServiceTarget is the desired state of one service's container.
+type ServiceTarget struct {
+	Service   string
+	Container string
+	Hash      string // ConfigHash of the run arguments
+	Force     bool   // recreate even when the hash matches
+}
+
+// ConfigHash fingerprints a container's run arguments. A container whose
+// recorded hash differs from the current one is out of date.
+func ConfigHash(runArgs []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(runArgs, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// PlanServices decides what to do with each service's container. prev is
+// the saved project state (nil if the project was never started) and
+// observed maps existing container names to their runtime status.
+func PlanServices(targets []ServiceTarget, prev *ProjectState, observed map[string]string) []Step {
+	var prevHashes map[string]string
+	if prev != nil {
+		prevHashes = prev.ConfigHashes
+	}
+
+	steps := make([]Step, 0, len(targets))
+	for _, t := range targets {
+		step := Step{Kind: "service", Name: t.Service}
+		status, exists := observed[t.Container]
+		switch {
+		case !exists:
+			step.Action = ActionCreate
+		case t.Force:
+			step.Action, step.Reason = ActionRecreate, "forced"
+		case prevHashes[t.Service] == "":
+			step.Action, step.Reason = ActionRecreate, "no recorded configuration"
+		case prevHashes[t.Service] != t.Hash:
+			step.Action, step.Reason = ActionRecreate, "configuration changed"
+		case status != "running":
+			step.Action, step.Reason = ActionStart, status
+		default:
+			step.Action = ActionUpToDate
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
@@ -0,0 +1,55 @@
+package compose
+
+import "testing"
+
+func TestConfigHash(t *testing.T) {
+	a := ConfigHash([]string{"run", "--env", "A=1", "nginx"})
+	if a != ConfigHash([]string{"run", "--env", "A=1", "nginx"}) {
+		t.Error("ConfigHash is not stable")
+	}
+	if a == ConfigHash([]string{"run", "--env", "A=2", "nginx"}) {
+		t.Error("ConfigHash ignores argument changes")
+	}
+	if ConfigHash([]string{"a b"}) == ConfigHash([]string{"a", "b"}) {
+		t.Error("ConfigHash ignores argument boundaries")
+	}
+}
+
+func TestPlanServices(t *testing.T) {
+	prev := &ProjectState{ConfigHashes: map[string]string{
+		"same":    "h1",
+		"changed": "old",
+		"stopped": "h1",
+		"forced":  "h1",
+	}}
+	observed := map[string]string{
+		"p_same":     "running",
+		"p_changed":  "running",
+		"p_stopped":  "stopped",
+		"p_forced":   "running",
+		"p_untraced": "running",
+	}
+	targets := []ServiceTarget{
+		{Service: "new", Container: "p_new", Hash: "h1"},
+		{Service: "same", Container: "p_same", Hash: "h1"},
+		{Service: "changed", Container: "p_changed", Hash: "h1"},
+		{Service: "stopped", Container: "p_stopped", Hash: "h1"},
+		{Service: "forced", Container: "p_forced", Hash: "h1", Force: true},
+		{Service: "untraced", Container: "p_untraced", Hash: "h1"},
+	}
+	want := []Action{ActionCreate, ActionUpToDate, ActionRecreate, ActionStart, ActionRecreate, ActionRecreate}
+
+	steps := PlanServices(targets, prev, observed)
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for i, s := range steps {
+		if s.Action != want[i] {
+			t.Errorf("%s: action = %s, want %s", s.Name, s.Action, want[i])
+		}
+	}
+
+	if steps := PlanServices(targets[:1], nil, nil); steps[0].Action != ActionCreate {
+		t.Errorf("no previous state: action = %s, want create", steps[0].Action)
+	}
+}
@@ -0,0 +1,249 @@
+package compose
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PortMapping is a single published or exposed port.
+// Published is 0 when no host port was requested.
+type PortMapping struct {
+	HostIP    string
+	Published int
+	Target    int
+	Protocol  string
+}
+
+// String renders the mapping in short syntax.
+func (p PortMapping) String() string {
+	s := strconv.Itoa(p.Target)
+	if p.Published != 0 {
+		s = strconv.Itoa(p.Published) + ":" + s
+	} else if p.HostIP != "" {
+		// An open host port needs its separator kept after an address.
+		s = ":" + s
+	}
+	if strings.Contains(p.HostIP, ":") {
+		s = "[" + p.HostIP + "]:" + s
+	} else if p.HostIP != "" {
+		s = p.HostIP + ":" + s
+	}
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		s += "/" + p.Protocol
+	}
+	return s
+}
+
+// ParsePort parses a short-syntax port spec such as "80", "8080:80",
+// "127.0.0.1:8080:80/udp" or "8000-8001:9000-9001". Ranges expand to one
+// mapping per port.
+func ParsePort(spec string) ([]PortMapping, error) {
+	protocol := "tcp"
+	rest := spec
+	if base, proto, ok := strings.Cut(spec, "/"); ok {
+		protocol = strings.ToLower(proto)
+		rest = base
+		if protocol != "tcp" && protocol != "udp" {
+			return nil, fmt.Errorf("invalid port %q: unsupported protocol %q", spec, proto)
+		}
+	}
+
+	var hostIP, published, target string
+	if strings.HasPrefix(rest, "[") {
+		// Bracketed IPv6 host address: [::1]:8080:80
+		ip, ports, ok := strings.Cut(rest[1:], "]:")
+		if !ok || !strings.Contains(ports, ":") {
+			return nil, fmt.Errorf("invalid port %q", spec)
+		}
+		hostIP = ip
+		published, target, _ = strings.Cut(ports, ":")
+	} else {
+		parts := strings.Split(rest, ":")
+		switch {
+		case len(parts) == 1:
+			target = parts[0]
+		case len(parts) == 2:
+			published, target = parts[0], parts[1]
+		case len(parts) == 3:
+			hostIP, published, target = parts[0], parts[1], parts[2]
+		default:
+			// Unbracketed IPv6 host address: ::1:8080:80
+			n := len(parts)
+			hostIP, published, target = strings.Join(parts[:n-2], ":"), parts[n-2], parts[n-1]
+		}
+	}
+	if strings.Contains(hostIP, ":") && net.ParseIP(hostIP) == nil {
+		return nil, fmt.Errorf("invalid port %q: invalid host address %q", spec, hostIP)
+	}
+
+	targetStart, targetEnd, err := parsePortRange(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", spec, err)
+	}
+
+	var pubStart, pubEnd int
+	if published != "" {
+		pubStart, pubEnd, err = parsePortRange(published)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", spec, err)
+		}
+		if pubEnd-pubStart != targetEnd-targetStart {
+			return nil, fmt.Errorf("invalid port %q: published and target ranges differ in size", spec)
+		}
+	}
+
+	mappings := make([]PortMapping, 0, targetEnd-targetStart+1)
+	for i := 0; i <= targetEnd-targetStart; i++ {
+		m := PortMapping{HostIP: hostIP, Target: targetStart + i, Protocol: protocol}
+		if pubStart != 0 {
+			m.Published = pubStart + i
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// PublishSpec returns spec in the form passed to the runtime's --publish:
+// unchanged, except that an IPv6 host address is put in brackets.
+func PublishSpec(spec string) string {
+	mappings, err := ParsePort(spec)
+	if err != nil || !strings.Contains(mappings[0].HostIP, ":") || strings.HasPrefix(spec, "[") {
+		return spec
+	}
+	ip := mappings[0].HostIP
+	return "[" + ip + "]" + strings.TrimPrefix(spec, ip)
+}
+
+// ParsePorts parses every spec in a service's ports list.
+func ParsePorts(specs []string) ([]PortMapping, error) {
+	var all []PortMapping
+	for _, spec := range specs {
+		m, err := ParsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, m...)
+	}
+	return all, nil
+}
+
+// ParseExpose parses a service's expose list. Each entry is a port or range
+// with an optional protocol ("3000", "8000-8010", "53/udp"); exposed ports
+// are reachable from the project's networks but never bound on the host.
+func ParseExpose(specs []string) ([]PortMapping, error) {
+	var all []PortMapping
+	for _, spec := range specs {
+		if strings.Contains(spec, ":") {
+			return nil, fmt.Errorf("invalid exposed port %q: expose takes container ports only; use ports to publish", spec)
+		}
+		m, err := ParsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, m...)
+	}
+	return all, nil
+}
+
+// ExposedOnly returns the ports svc exposes without also publishing them.
+func ExposedOnly(svc Service) []PortMapping {
+	exposed, err := ParseExpose(svc.Expose)
+	if err != nil {
+		return nil
+	}
+	published, _ := ParsePorts(svc.Ports)
+	var only []PortMapping
+	for _, e := range exposed {
+		if !slices.ContainsFunc(published, func(p PortMapping) bool {
+			return p.Target == e.Target && p.Protocol == e.Protocol
+		}) {
+			only = append(only, e)
+		}
+	}
+	return only
+}
+
+// parsePortRange parses "80" or "8000-8010".
+func parsePortRange(s string) (int, int, error) {
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	start, err := parsePortNumber(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := parsePortNumber(endStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("range %q ends before it starts", s)
+	}
+	return start, end, nil
+}
+
+// parsePortNumber parses a port number between 0 and 65535.
+func parsePortNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 65535 {
+		return 0, fmt.Errorf("%q is not a valid port number", s)
+	}
+	return n, nil
+}
+
+// longPort is the long syntax of a ports entry. Fields dctl cannot honor
+// (mode, name, app_protocol) are ignored.
+type longPort struct {
+	Target    string `yaml:"target"`
+	Published string `yaml:"published"`
+	HostIP    string `yaml:"host_ip"`
+	Protocol  string `yaml:"protocol"`
+}
+
+// shortPortSyntax rewrites long-syntax entries in every service's ports
+// list into short syntax, so `published: 0` becomes "0:80". It modifies
+// root, which must be a decoded-only copy of the file.
+func shortPortSyntax(root *yaml.Node) error {
+	i := mappingIndex(root, "services")
+	if i < 0 || root.Content[i+1].Kind != yaml.MappingNode {
+		return nil
+	}
+	services := root.Content[i+1]
+	for j := 0; j+1 < len(services.Content); j += 2 {
+		svc := services.Content[j+1]
+		k := mappingIndex(svc, "ports")
+		if k < 0 || svc.Content[k+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, entry := range svc.Content[k+1].Content {
+			if entry.Kind != yaml.MappingNode {
+				continue
+			}
+			var lp longPort
+			if err := entry.Decode(&lp); err != nil {
+				return fmt.Errorf("service %s: ports: %w", services.Content[j].Value, err)
+			}
+			if lp.Target == "" {
+				return fmt.Errorf("service %s: ports: target is required", services.Content[j].Value)
+			}
+			spec := lp.Target
+			if lp.Published != "" || lp.HostIP != "" {
+				spec = lp.Published + ":" + spec
+			}
+			if lp.HostIP != "" {
+				spec = lp.HostIP + ":" + spec
+			}
+			if lp.Protocol != "" {
+				spec += "/" + lp.Protocol
+			}
+			*entry = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: spec}
+		}
+	}
+	return nil
+}
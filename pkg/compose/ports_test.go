@@ -0,0 +1,130 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []PortMapping
+	}{
+		{"80", []PortMapping{{Target: 80, Protocol: "tcp"}}},
+		{"8080:80", []PortMapping{{Published: 8080, Target: 80, Protocol: "tcp"}}},
+		{"127.0.0.1:8080:80", []PortMapping{{HostIP: "127.0.0.1", Published: 8080, Target: 80, Protocol: "tcp"}}},
+		{"53:53/udp", []PortMapping{{Published: 53, Target: 53, Protocol: "udp"}}},
+		{"8000-8001:9000-9001", []PortMapping{
+			{Published: 8000, Target: 9000, Protocol: "tcp"},
+			{Published: 8001, Target: 9001, Protocol: "tcp"},
+		}},
+		{"::1:8080:80", []PortMapping{{HostIP: "::1", Published: 8080, Target: 80, Protocol: "tcp"}}},
+		{"[::1]:8080:80/udp", []PortMapping{{HostIP: "::1", Published: 8080, Target: 80, Protocol: "udp"}}},
+		{"[fd00::2]::80", []PortMapping{{HostIP: "fd00::2", Target: 80, Protocol: "tcp"}}},
+		{":::8080:80", []PortMapping{{HostIP: "::", Published: 8080, Target: 80, Protocol: "tcp"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParsePort(tt.spec)
+			if err != nil {
+				t.Fatalf("ParsePort(%q) error: %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePort(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePort_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "abc", "70000", "80/sctp", "8000-8002:9000-9001", "1:2:3:4", "[::1]:80", "[::1:8080:80"} {
+		if _, err := ParsePort(spec); err == nil {
+			t.Errorf("ParsePort(%q) expected error", spec)
+		}
+	}
+}
+
+func TestPortMapping_String(t *testing.T) {
+	for _, spec := range []string{"80", "8080:80", "127.0.0.1:8080:80", "53:53/udp", "[::1]:8080:80", "127.0.0.1::80", "[::1]::80"} {
+		m, err := ParsePort(spec)
+		if err != nil {
+			t.Fatalf("ParsePort(%q) error: %v", spec, err)
+		}
+		if got := m[0].String(); got != spec {
+			t.Errorf("String() = %q, want %q", got, spec)
+		}
+	}
+	if _, err := ParsePorts([]string{"80", "bad"}); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("ParsePorts() error = %v, want mention of bad spec", err)
+	}
+}
+
+func TestPublishSpec(t *testing.T) {
+	tests := map[string]string{
+		"8080:80":           "8080:80",
+		"127.0.0.1:8080:80": "127.0.0.1:8080:80",
+		"::1:8080:80":       "[::1]:8080:80",
+		"::1:8080:80/udp":   "[::1]:8080:80/udp",
+		"[::1]:8080:80":     "[::1]:8080:80",
+	}
+	for spec, want := range tests {
+		if got := PublishSpec(spec); got != want {
+			t.Errorf("PublishSpec(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestParseExpose(t *testing.T) {
+	got, err := ParseExpose([]string{"3000", "53/udp", "8000-8001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PortMapping{
+		{Target: 3000, Protocol: "tcp"},
+		{Target: 53, Protocol: "udp"},
+		{Target: 8000, Protocol: "tcp"},
+		{Target: 8001, Protocol: "tcp"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseExpose() = %+v, want %+v", got, want)
+	}
+	for _, spec := range []string{"8080:80", "abc"} {
+		if _, err := ParseExpose([]string{spec}); err == nil {
+			t.Errorf("ParseExpose(%q) expected error", spec)
+		}
+	}
+}
+
+func TestExposedOnly(t *testing.T) {
+	svc := Service{Ports: []string{"8080:80"}, Expose: []string{"80", "9000", "80/udp"}}
+	want := []PortMapping{{Target: 9000, Protocol: "tcp"}, {Target: 80, Protocol: "udp"}}
+	if got := ExposedOnly(svc); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExposedOnly() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoad_Expose(t *testing.T) {
+	dir := t.TempDir()
+	content := "services:\n  api:\n    image: app\n    expose:\n      - 3000\n      - \"53/udp\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := Load(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cf.Services["api"].Expose, []string{"3000", "53/udp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expose = %v, want %v", got, want)
+	}
+
+	content = "services:\n  api:\n    image: app\n    expose: [\"8080:80\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(nil, dir); err == nil {
+		t.Error("Load() accepted a published port in expose")
+	}
+}
@@ -5,26 +5,79 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"syscall"
 )
 
 // ProjectState represents the persisted state of a compose project.
 type ProjectState struct {
 	Name        string            `json:"name"`
 	ComposeFile string            `json:"compose_file"`
+	ComposeFiles []string         `json:"compose_files,omitempty"` // absolute paths of every compose file up loaded
+	Profiles    []string          `json:"profiles,omitempty"`    // profiles up enabled
+	EnvFile     string            `json:"env_file,omitempty"`    // absolute path of the --env-file up was given
 	ProjectDir  string            `json:"project_dir"`
 	Containers  map[string]string `json:"containers"`  // service name → container ID
 	Networks    []string          `json:"networks"`     // created network names
 	Volumes     []string          `json:"volumes"`      // created volume names
+	Naming      NamingScheme      `json:"naming,omitempty"` // container naming scheme used by up
+	ClonePrefix string            `json:"clone_prefix,omitempty"` // prefix of the networks and volumes of a copy made by compose clone; see Clone
+	ConfigHashes map[string]string `json:"config_hashes,omitempty"` // service name → ConfigHash of its run arguments
+	RunArgs     map[string][]string `json:"run_args,omitempty"` // service name → container run arguments used by up
+	RunContainers []string        `json:"run_containers,omitempty"` // names of one-off run containers not yet removed
+	Status      map[string]ServiceStatus `json:"status,omitempty"` // service name → container status last set or seen by dctl
 }
 
-// projectsDir returns the path to the projects state directory.
-func projectsDir() (string, error) {
+// Files returns the compose files up last loaded the project from. State
+// recorded before every file was kept yields the first one, resolved
+// against the project directory.
+func (s *ProjectState) Files() []string {
+	if len(s.ComposeFiles) > 0 {
+		return s.ComposeFiles
+	}
+	if s.ComposeFile == "" {
+		return nil
+	}
+	f := s.ComposeFile
+	if !filepath.IsAbs(f) {
+		f = filepath.Join(s.ProjectDir, f)
+	}
+	return []string{f}
+}
+
+// stateDir overrides StateDir when set; see SetStateDir.
+var stateDir string
+
+// SetStateDir makes StateDir return dir, such as the state directory of the
+// active context. An empty dir restores the default.
+func SetStateDir(dir string) {
+	stateDir = dir
+}
+
+// StateDir returns the root of dctl's state: the directory SetStateDir
+// chose, or else ~/.dctl unless overridden by DCTL_STATE_DIR.
+func StateDir() (string, error) {
+	if stateDir != "" {
+		return stateDir, nil
+	}
+	if dir := os.Getenv("DCTL_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("getting home directory: %w", err)
 	}
-	return filepath.Join(home, ".dctl", "projects"), nil
+	return filepath.Join(home, ".dctl"), nil
+}
+
+// projectsDir returns the path to the projects state directory.
+func projectsDir() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "projects"), nil
 }
 
 // projectFilePath returns the path to a project's state file.
@@ -62,6 +115,37 @@ func SaveProject(state *ProjectState) error {
 	return nil
 }
 
+// UpdateProject applies fn to a project's saved state and saves the result,
+// holding a lock on the project for the whole read-modify-write so that
+// concurrent dctl processes do not lose each other's changes.
+func UpdateProject(name string, fn func(*ProjectState) error) error {
+	dir, err := projectsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating projects directory: %w", err)
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, name+".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("locking project state: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("locking project state: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	state, err := LoadProject(name)
+	if err != nil {
+		return err
+	}
+	if err := fn(state); err != nil {
+		return err
+	}
+	return SaveProject(state)
+}
+
 // LoadProject reads project state from disk.
 func LoadProject(name string) (*ProjectState, error) {
 	path, err := projectFilePath(name)
@@ -124,28 +208,45 @@ func ListProjects() ([]string, error) {
 	return names, nil
 }
 
+// projectNamePattern is the project name format docker compose accepts.
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateProjectName reports whether name is a valid project name:
+// lowercase letters, digits, dashes and underscores, starting with a letter
+// or digit.
+func ValidateProjectName(name string) error {
+	if !projectNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid project name %q: must contain only lowercase letters, digits, dashes and underscores, and start with a letter or digit", name)
+	}
+	return nil
+}
+
 // ResolveProjectName determines the project name from flag, compose file, or directory name.
-func ResolveProjectName(flagName string, composeFile *ComposeFile, projectDir string) string {
+// Explicit names from the flag or the compose file must already be valid;
+// a name derived from the directory is normalized instead.
+func ResolveProjectName(flagName string, composeFile *ComposeFile, projectDir string) (string, error) {
 	if flagName != "" {
-		return sanitizeProjectName(flagName)
+		return flagName, ValidateProjectName(flagName)
 	}
 	if composeFile != nil && composeFile.Name != "" {
-		return sanitizeProjectName(composeFile.Name)
+		return composeFile.Name, ValidateProjectName(composeFile.Name)
+	}
+	name := normalizeProjectName(filepath.Base(projectDir))
+	if name == "" {
+		return "", fmt.Errorf("cannot derive a project name from directory %q, use --project-name", projectDir)
 	}
-	return sanitizeProjectName(filepath.Base(projectDir))
+	return name, nil
 }
 
-// sanitizeProjectName normalizes a project name to be safe for file system use.
-func sanitizeProjectName(name string) string {
+// normalizeProjectName lowercases a name and drops characters that are not
+// allowed in project names, the way docker compose does for directory names.
+func normalizeProjectName(name string) string {
 	name = strings.ToLower(name)
-	// Replace non-alphanumeric characters (except hyphens) with hyphens.
 	var b strings.Builder
 	for _, r := range name {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
 			b.WriteRune(r)
-		} else {
-			b.WriteRune('-')
 		}
 	}
-	return strings.Trim(b.String(), "-")
+	return strings.TrimLeft(b.String(), "-_")
 }
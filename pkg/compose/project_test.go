@@ -0,0 +1,79 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResolveProjectName(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       string
+		fileName   string
+		projectDir string
+		want       string
+		wantErr    string
+	}{
+		{name: "flag", flag: "my_app-1", projectDir: "/src/x", want: "my_app-1"},
+		{name: "flag wins over file", flag: "cli", fileName: "file", projectDir: "/src/x", want: "cli"},
+		{name: "compose file name", fileName: "from_file", projectDir: "/src/x", want: "from_file"},
+		{name: "directory is normalized", projectDir: "/src/My.Web_App", want: "myweb_app"},
+		{name: "directory leading separators trimmed", projectDir: "/src/_-App", want: "app"},
+		{name: "uppercase flag rejected", flag: "MyApp", wantErr: "invalid project name"},
+		{name: "flag with dot rejected", flag: "my.app", wantErr: "invalid project name"},
+		{name: "flag with leading dash rejected", flag: "-app", wantErr: "invalid project name"},
+		{name: "invalid compose file name rejected", fileName: "My App", wantErr: "invalid project name"},
+		{name: "underivable directory", projectDir: "/src/___", wantErr: "cannot derive a project name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveProjectName(tt.flag, &ComposeFile{Name: tt.fileName}, tt.projectDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveProjectName() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveProjectName() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveProjectName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateProjectConcurrent(t *testing.T) {
+	t.Setenv("DCTL_STATE_DIR", t.TempDir())
+	if err := SaveProject(&ProjectState{Name: "shop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := UpdateProject("shop", func(s *ProjectState) error {
+				s.RunContainers = append(s.RunContainers, fmt.Sprintf("shop_web_run_%d", i))
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, err := LoadProject("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.RunContainers) != 20 {
+		t.Errorf("run containers = %v, want all 20 updates kept", state.RunContainers)
+	}
+}
@@ -0,0 +1,183 @@
+package compose
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Snapshot describes a saved point-in-time copy of a project.
+// Image archives and volume archives live alongside the manifest in the
+// snapshot directory.
+type Snapshot struct {
+	Name      string            `json:"name"`
+	Project   string            `json:"project"`
+	CreatedAt time.Time         `json:"created_at"`
+	State     *ProjectState     `json:"state"`
+	Images    map[string]string `json:"images"`  // service name → committed image reference
+	Volumes   []string          `json:"volumes"` // volume names with saved archives
+}
+
+// snapshotsDir returns the path to a project's snapshots directory.
+func snapshotsDir(project string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshots", project), nil
+}
+
+// snapshotNamePattern is the snapshot name format: it names a directory
+// and tags the images committed for the snapshot.
+var snapshotNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateSnapshotName reports whether name is a valid snapshot name:
+// letters, digits, dots, dashes and underscores, starting with a letter or
+// digit, so it cannot name a path outside the project's snapshots.
+func ValidateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: must contain only letters, digits, dots, dashes and underscores, and start with a letter or digit", name)
+	}
+	return nil
+}
+
+// SnapshotDir returns the directory holding a snapshot's manifest and archives.
+func SnapshotDir(project, name string) (string, error) {
+	if err := ValidateSnapshotName(name); err != nil {
+		return "", err
+	}
+	dir, err := snapshotsDir(project)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// SnapshotComposeFile returns the path of the resolved compose model saved with a snapshot.
+func SnapshotComposeFile(dir string) string {
+	return filepath.Join(dir, "compose.yaml")
+}
+
+// SnapshotImageArchive returns the path of a service's image archive in a snapshot.
+func SnapshotImageArchive(dir, service string) string {
+	return filepath.Join(dir, "images", service+".tar")
+}
+
+// SnapshotVolumeArchive returns the path of a volume's archive in a snapshot.
+func SnapshotVolumeArchive(dir, volume string) string {
+	return filepath.Join(dir, "volumes", volume+".tar.gz")
+}
+
+// CreateSnapshotDir prepares a snapshot directory, failing if a completed
+// snapshot with the same name already exists. Leftovers from an interrupted
+// snapshot are discarded.
+func CreateSnapshotDir(project, name string) (string, error) {
+	dir, err := SnapshotDir(project, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snapshot.json")); err == nil {
+		return "", fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("removing incomplete snapshot: %w", err)
+	}
+	for _, sub := range []string{"images", "volumes"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return "", fmt.Errorf("creating snapshot directory: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// SaveSnapshot writes a snapshot manifest to disk.
+func SaveSnapshot(snap *Snapshot) error {
+	dir, err := SnapshotDir(snap.Project, snap.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "snapshot.json"), data, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot manifest from disk.
+func LoadSnapshot(project, name string) (*Snapshot, error) {
+	dir, err := SnapshotDir(project, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "snapshot.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("snapshot %q not found for project %q", name, project)
+		}
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// DeleteSnapshot removes a snapshot and its archives from disk.
+func DeleteSnapshot(project, name string) error {
+	dir, err := SnapshotDir(project, name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns a project's snapshots, oldest first.
+func ListSnapshots(project string) ([]*Snapshot, error) {
+	dir, err := snapshotsDir(project)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading snapshots directory: %w", err)
+	}
+
+	var snaps []*Snapshot
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		snap, err := LoadSnapshot(project, e.Name())
+		if err != nil {
+			// Skip partially written snapshots.
+			continue
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].CreatedAt.Before(snaps[j].CreatedAt)
+	})
+	return snaps, nil
+}
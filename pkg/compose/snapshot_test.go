@@ -0,0 +1,81 @@
+package compose
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_SaveLoadList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := CreateSnapshotDir("shop", "before-migration"); err != nil {
+		t.Fatalf("CreateSnapshotDir() error: %v", err)
+	}
+	// An incomplete snapshot (no manifest) can be recreated.
+	if _, err := CreateSnapshotDir("shop", "before-migration"); err != nil {
+		t.Fatalf("CreateSnapshotDir() over incomplete snapshot error: %v", err)
+	}
+
+	older := &Snapshot{
+		Name:      "older",
+		Project:   "shop",
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	newer := &Snapshot{
+		Name:      "before-migration",
+		Project:   "shop",
+		CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		State:     &ProjectState{Name: "shop", Containers: map[string]string{"db": "shop_db"}},
+		Images:    map[string]string{"db": "postgres:16"},
+		Volumes:   []string{"pgdata"},
+	}
+	for _, s := range []*Snapshot{newer, older} {
+		if err := SaveSnapshot(s); err != nil {
+			t.Fatalf("SaveSnapshot(%s) error: %v", s.Name, err)
+		}
+	}
+
+	if _, err := CreateSnapshotDir("shop", "before-migration"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("CreateSnapshotDir() duplicate error = %v, want already exists", err)
+	}
+
+	got, err := LoadSnapshot("shop", "before-migration")
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error: %v", err)
+	}
+	if got.Images["db"] != "postgres:16" || got.State.Containers["db"] != "shop_db" {
+		t.Errorf("LoadSnapshot() = %+v", got)
+	}
+
+	snaps, err := ListSnapshots("shop")
+	if err != nil {
+		t.Fatalf("ListSnapshots() error: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].Name != "older" || snaps[1].Name != "before-migration" {
+		t.Errorf("ListSnapshots() returned wrong order or count: %d", len(snaps))
+	}
+
+	if err := DeleteSnapshot("shop", "older"); err != nil {
+		t.Fatalf("DeleteSnapshot() error: %v", err)
+	}
+	if _, err := LoadSnapshot("shop", "older"); err == nil {
+		t.Error("expected deleted snapshot to be missing")
+	}
+}
+
+func TestSnapshot_InvalidName(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, name := range []string{"", "..", "../../x", "a/b", ".hidden", "-x", "with space"} {
+		if _, err := CreateSnapshotDir("shop", name); err == nil || !strings.Contains(err.Error(), "invalid snapshot name") {
+			t.Errorf("CreateSnapshotDir(%q) error = %v, want an invalid name", name, err)
+		}
+		if err := DeleteSnapshot("shop", name); err == nil {
+			t.Errorf("DeleteSnapshot(%q) succeeded", name)
+		}
+	}
+	if err := ValidateSnapshotName("v1.2_before-migration"); err != nil {
+		t.Errorf("ValidateSnapshotName() error: %v", err)
+	}
+}
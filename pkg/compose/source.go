@@ -0,0 +1,158 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/oci"
+)
+
+// maxRemoteFileSize caps the size of compose files fetched over HTTPS.
+const maxRemoteFileSize = 10 << 20
+
+// httpClient is used to fetch remote compose files.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// stdin is the reader used for the "-" compose file.
+var stdin io.Reader = os.Stdin
+
+// composeSource is a single compose document ready for interpolation.
+type composeSource struct {
+	name   string
+	data   []byte
+	lookup func(string) (string, bool)
+}
+
+// readComposeSource reads a compose file given as a local path, "-" for
+// stdin, an https:// URL, or an oci:// artifact reference. Remote URLs may
+// carry a "#sha256=<hex>" fragment, in which case the downloaded content
+// must match the checksum. Artifacts can hold several compose files, so a
+// slice of sources is returned in merge order.
+func readComposeSource(f, projectDir string) ([]composeSource, error) {
+	switch {
+	case f == "-":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		return []composeSource{{name: "stdin", data: data, lookup: os.LookupEnv}}, nil
+
+	case strings.HasPrefix(f, "https://"), strings.HasPrefix(f, "http://"):
+		data, name, err := readRemoteSource(f)
+		if err != nil {
+			return nil, err
+		}
+		return []composeSource{{name: name, data: data, lookup: os.LookupEnv}}, nil
+
+	case strings.HasPrefix(f, "oci://"):
+		return readOCISource(f)
+
+	default:
+		path := f
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		return []composeSource{{name: path, data: data, lookup: os.LookupEnv}}, nil
+	}
+}
+
+// readOCISource pulls a compose artifact. Env files published with the
+// artifact supply defaults for variables not set in the environment.
+func readOCISource(raw string) ([]composeSource, error) {
+	ref, err := oci.ParseReference(raw)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := oci.Pull(ref)
+	if err != nil {
+		return nil, fmt.Errorf("pulling %s: %w", ref, err)
+	}
+
+	defaults := make(map[string]string)
+	for _, l := range layers {
+		if l.MediaType == oci.EnvFileMediaType {
+			for k, v := range parseEnvFile(l.Data) {
+				defaults[k] = v
+			}
+		}
+	}
+	lookup := func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := defaults[key]
+		return v, ok
+	}
+
+	var sources []composeSource
+	for _, l := range layers {
+		if l.MediaType != oci.ComposeMediaType {
+			continue
+		}
+		sources = append(sources, composeSource{name: ref.String() + "/" + l.Name, data: l.Data, lookup: lookup})
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%s contains no compose files", ref)
+	}
+	return sources, nil
+}
+
+// readRemoteSource downloads a compose file over HTTPS and verifies its
+// checksum when one is given in the URL fragment.
+func readRemoteSource(raw string) ([]byte, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, raw, fmt.Errorf("invalid compose file URL %q: %w", raw, err)
+	}
+	if u.Scheme != "https" {
+		return nil, raw, fmt.Errorf("refusing to fetch %s: remote compose files must use https", raw)
+	}
+
+	var wantSum string
+	if u.Fragment != "" {
+		algo, sum, ok := strings.Cut(u.Fragment, "=")
+		if !ok || algo != "sha256" {
+			return nil, raw, fmt.Errorf("invalid checksum %q in %s: expected #sha256=<hex>", u.Fragment, raw)
+		}
+		wantSum = strings.ToLower(sum)
+		u.Fragment = ""
+	}
+	name := u.String()
+
+	resp, err := httpClient.Get(name)
+	if err != nil {
+		return nil, name, fmt.Errorf("fetching %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, name, fmt.Errorf("fetching %s: unexpected status %s", name, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteFileSize+1))
+	if err != nil {
+		return nil, name, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if len(data) > maxRemoteFileSize {
+		return nil, name, fmt.Errorf("fetching %s: file exceeds %d bytes", name, maxRemoteFileSize)
+	}
+
+	if wantSum != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != wantSum {
+			return nil, name, fmt.Errorf("checksum mismatch for %s: got sha256 %s, want %s", name, got, wantSum)
+		}
+	}
+	return data, name, nil
+}
@@ -0,0 +1,162 @@
+package compose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/oci"
+)
+
+const remoteCompose = `
+services:
+  web:
+    image: nginx:remote
+`
+
+func serveCompose(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/compose.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(remoteCompose))
+	}))
+	t.Cleanup(srv.Close)
+
+	orig := httpClient
+	httpClient = srv.Client()
+	t.Cleanup(func() { httpClient = orig })
+	return srv
+}
+
+func TestLoad_RemoteFile(t *testing.T) {
+	srv := serveCompose(t)
+
+	cf, err := Load([]string{srv.URL + "/compose.yaml"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cf.Services["web"].Image != "nginx:remote" {
+		t.Errorf("image = %q, want %q", cf.Services["web"].Image, "nginx:remote")
+	}
+}
+
+func TestLoad_RemoteFileChecksum(t *testing.T) {
+	srv := serveCompose(t)
+	sum := sha256.Sum256([]byte(remoteCompose))
+	good := hex.EncodeToString(sum[:])
+
+	t.Run("match", func(t *testing.T) {
+		if _, err := Load([]string{srv.URL + "/compose.yaml#sha256=" + good}, t.TempDir()); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := Load([]string{srv.URL + "/compose.yaml#sha256=" + strings.Repeat("0", 64)}, t.TempDir())
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("Load() error = %v, want checksum mismatch", err)
+		}
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := Load([]string{srv.URL + "/compose.yaml#md5=abc"}, t.TempDir())
+		if err == nil || !strings.Contains(err.Error(), "invalid checksum") {
+			t.Errorf("Load() error = %v, want invalid checksum", err)
+		}
+	})
+}
+
+func TestLoad_RemoteFileErrors(t *testing.T) {
+	srv := serveCompose(t)
+
+	if _, err := Load([]string{srv.URL + "/missing.yaml"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Load() missing file error = %v, want 404", err)
+	}
+	if _, err := Load([]string{"http://example.com/compose.yaml"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Errorf("Load() http error = %v, want https requirement", err)
+	}
+}
+
+func TestLoad_Stdin(t *testing.T) {
+	orig := stdin
+	stdin = strings.NewReader(remoteCompose)
+	t.Cleanup(func() { stdin = orig })
+
+	cf, err := Load([]string{"-"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cf.Services["web"].Image != "nginx:remote" {
+		t.Errorf("image = %q, want %q", cf.Services["web"].Image, "nginx:remote")
+	}
+
+	if _, err := Load([]string{"-", "-"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "only be given once") {
+		t.Errorf("Load() double stdin error = %v", err)
+	}
+}
+
+func TestLoad_OCIArtifact(t *testing.T) {
+	composeData := []byte("services:\n  web:\n    image: nginx:${TAG}\n")
+	envData := []byte("TAG=from-artifact\n")
+	digest := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	blobs := map[string][]byte{digest(composeData): composeData, digest(envData): envData}
+	manifest, _ := json.Marshal(oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.ManifestMediaType,
+		ArtifactType:  oci.ArtifactType,
+		Layers: []oci.Descriptor{
+			{MediaType: oci.ComposeMediaType, Digest: digest(composeData), Size: int64(len(composeData)),
+				Annotations: map[string]string{oci.ComposeFileAnnotation: "compose.yaml"}},
+			{MediaType: oci.EnvFileMediaType, Digest: digest(envData), Size: int64(len(envData)),
+				Annotations: map[string]string{oci.EnvFileAnnotation: ".env"}},
+		},
+	})
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/acme/stack/manifests/1.0":
+			w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/acme/stack/blobs/"):
+			w.Write(blobs[strings.TrimPrefix(r.URL.Path, "/v2/acme/stack/blobs/")])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	orig := oci.HTTPClient
+	oci.HTTPClient = srv.Client()
+	defer func() { oci.HTTPClient = orig }()
+
+	ref := "oci://" + strings.TrimPrefix(srv.URL, "https://") + "/acme/stack:1.0"
+
+	t.Run("env defaults from artifact", func(t *testing.T) {
+		cf, err := Load([]string{ref}, t.TempDir())
+		if err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if cf.Services["web"].Image != "nginx:from-artifact" {
+			t.Errorf("image = %q, want %q", cf.Services["web"].Image, "nginx:from-artifact")
+		}
+	})
+
+	t.Run("environment overrides artifact defaults", func(t *testing.T) {
+		t.Setenv("TAG", "from-shell")
+		cf, err := Load([]string{ref}, t.TempDir())
+		if err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if cf.Services["web"].Image != "nginx:from-shell" {
+			t.Errorf("image = %q, want %q", cf.Services["web"].Image, "nginx:from-shell")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package compose
+
+import (
+	"fmt"
+	"time"
+)
+
+// Statuses dctl records for a service's container after changing it. Statuses
+// observed from the runtime, such as Docker's "restarting", are recorded as
+// reported.
+const (
+	StatusCreated = "created"
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+	StatusExited  = "exited"
+	StatusPaused  = "paused"
+)
+
+// ServiceStatus is the status of a service's container as dctl last set or
+// observed it. ExitCode is set only for StatusExited.
+type ServiceStatus struct {
+	State    string    `json:"state"`
+	ExitCode int       `json:"exit_code,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// String returns the state, with the exit code of an exited container, as in
+// "exited (1)".
+func (s ServiceStatus) String() string {
+	if s.State == StatusExited {
+		return fmt.Sprintf("%s (%d)", s.State, s.ExitCode)
+	}
+	return s.State
+}
+
+// SetStatus records the status of a service's container. An empty status,
+// for a container that no longer exists, removes the record.
+func (s *ProjectState) SetStatus(service, status string) {
+	if status == "" {
+		delete(s.Status, service)
+		return
+	}
+	s.setStatus(service, ServiceStatus{State: status})
+}
+
+// SetExited records that a service's container exited with code.
+func (s *ProjectState) SetExited(service string, code int) {
+	s.setStatus(service, ServiceStatus{State: StatusExited, ExitCode: code})
+}
+
+func (s *ProjectState) setStatus(service string, status ServiceStatus) {
+	if s.Status == nil {
+		s.Status = make(map[string]ServiceStatus)
+	}
+	status.Updated = time.Now().UTC()
+	s.Status[service] = status
+}
+
+// StatusOf returns the recorded status of a service's container, or "" when
+// none is recorded.
+func (s *ProjectState) StatusOf(service string) string {
+	return s.Status[service].State
+}
@@ -0,0 +1,44 @@
+package compose
+
+import "testing"
+
+func TestSetStatus(t *testing.T) {
+	t.Setenv("DCTL_STATE_DIR", t.TempDir())
+
+	state := &ProjectState{Name: "shop", Containers: map[string]string{"web": "shop_web", "db": "shop_db"}}
+	state.SetStatus("web", StatusRunning)
+	state.SetStatus("db", StatusCreated)
+	state.SetStatus("db", "")
+	if err := SaveProject(state); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadProject("shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.StatusOf("web"); got != StatusRunning {
+		t.Errorf("StatusOf(web) = %q, want %q", got, StatusRunning)
+	}
+	if loaded.Status["web"].Updated.IsZero() {
+		t.Error("status update time not recorded")
+	}
+	if _, ok := loaded.Status["db"]; ok {
+		t.Errorf("status = %v, want no db entry", loaded.Status)
+	}
+	if got := loaded.StatusOf("cache"); got != "" {
+		t.Errorf("StatusOf(cache) = %q, want empty", got)
+	}
+}
+
+func TestSetExited(t *testing.T) {
+	state := &ProjectState{Name: "shop"}
+	state.SetExited("web", 137)
+	if got := state.Status["web"].String(); got != "exited (137)" {
+		t.Errorf("status = %q, want %q", got, "exited (137)")
+	}
+	state.SetStatus("web", StatusPaused)
+	if got := state.Status["web"]; got.String() != StatusPaused || got.ExitCode != 0 {
+		t.Errorf("status = %+v, want paused without an exit code", got)
+	}
+}
@@ -0,0 +1,87 @@
+package compose
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// UnknownServiceError reports a service name that is not defined, suggesting
+// the closest defined names.
+func UnknownServiceError(name string, services []string) error {
+	matches := Suggest(name, services)
+	if len(matches) == 0 {
+		return fmt.Errorf("no such service: %s", name)
+	}
+	quoted := make([]string, len(matches))
+	for i, m := range matches {
+		quoted[i] = fmt.Sprintf("%q", m)
+	}
+	return fmt.Errorf("no such service: %s (did you mean %s?)", name, strings.Join(quoted, " or "))
+}
+
+// Suggest returns the candidates closest to name: those starting with it,
+// or else those within an edit distance of half the longer name (at least
+// 2), keeping only the nearest. Results are sorted; a name that is itself a
+// candidate has no suggestions.
+func Suggest(name string, candidates []string) []string {
+	if name == "" || slices.Contains(candidates, name) {
+		return nil
+	}
+	var prefixed []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, name) {
+			prefixed = append(prefixed, c)
+		}
+	}
+	if len(prefixed) > 0 {
+		sort.Strings(prefixed)
+		return prefixed
+	}
+
+	best := -1
+	var matches []string
+	for _, c := range candidates {
+		d := editDistance(name, c)
+		if d > max(2, max(len(name), len(c))/2) {
+			continue
+		}
+		switch {
+		case best < 0 || d < best:
+			best, matches = d, []string{c}
+		case d == best:
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions each
+// cost one.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
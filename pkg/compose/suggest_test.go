@@ -0,0 +1,56 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	services := []string{"api", "db", "web", "worker", "workers-cron"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"wrok", []string{"worker"}},
+		{"wokrer", []string{"worker"}},
+		{"work", []string{"worker", "workers-cron"}},
+		{"wbe", []string{"web"}},
+		{"ap", []string{"api"}},
+		{"postgres", nil},
+		{"web", nil},
+	}
+	for _, tt := range tests {
+		if got := Suggest(tt.name, services); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownServiceError(t *testing.T) {
+	err := UnknownServiceError("wrok", []string{"web", "worker"})
+	if want := `no such service: wrok (did you mean "worker"?)`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	err = UnknownServiceError("postgres", []string{"web", "worker"})
+	if want := "no such service: postgres"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"web", "web", 0},
+		{"wbe", "web", 1},
+		{"wrok", "worker", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -1,47 +1,135 @@
 package compose
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ComposeFile represents a parsed docker-compose.yml / compose.yaml file.
 type ComposeFile struct {
 	Name     string                  `yaml:"name,omitempty"`
 	Services map[string]Service      `yaml:"services"`
 	Networks map[string]Network      `yaml:"networks,omitempty"`
 	Volumes  map[string]VolumeConfig `yaml:"volumes,omitempty"`
+	Secrets  map[string]SecretConfig `yaml:"secrets,omitempty"`
+	// Env holds variables every service gets unless it sets them itself;
+	// resolved to map[string]string like a service's environment.
+	Env interface{} `yaml:"x-dctl-env,omitempty"`
+	// Include lists other compose files whose definitions are added to
+	// this one's; see mergeIncludes.
+	Include interface{} `yaml:"include,omitempty"`
+
+	// Keys lists the keys each definition sets, including ones dctl does
+	// not model, so unsupported keys can be reported.
+	Keys DefinedKeys `yaml:"-"`
+}
+
+// DefinedKeys maps service, network and volume names to the keys their
+// definitions set. Keys of a build mapping are recorded as "build.KEY".
+type DefinedKeys struct {
+	Top      []string // top-level keys, such as a legacy version
+	Services map[string][]string
+	Networks map[string][]string
+	Volumes  map[string][]string
 }
 
 // Service represents a single service definition.
 type Service struct {
-	Image       string            `yaml:"image,omitempty"`
-	Build       interface{}       `yaml:"build,omitempty"`
-	Command     interface{}       `yaml:"command,omitempty"`
-	Entrypoint  interface{}       `yaml:"entrypoint,omitempty"`
-	Environment interface{}       `yaml:"environment,omitempty"`
-	EnvFile     interface{}       `yaml:"env_file,omitempty"`
-	Ports       []string          `yaml:"ports,omitempty"`
-	Volumes     []string          `yaml:"volumes,omitempty"`
-	Networks    interface{}       `yaml:"networks,omitempty"`
-	DependsOn   interface{}       `yaml:"depends_on,omitempty"`
-	Restart     string            `yaml:"restart,omitempty"`
-	WorkingDir  string            `yaml:"working_dir,omitempty"`
-	User        string            `yaml:"user,omitempty"`
-	Hostname    string            `yaml:"hostname,omitempty"`
-	DNS         interface{}       `yaml:"dns,omitempty"`
-	DNSSearch   interface{}       `yaml:"dns_search,omitempty"`
-	ExtraHosts  []string          `yaml:"extra_hosts,omitempty"`
-	Labels      map[string]string `yaml:"labels,omitempty"`
-	StdinOpen   bool              `yaml:"stdin_open,omitempty"`
-	Tty         bool              `yaml:"tty,omitempty"`
-	ReadOnly    bool              `yaml:"read_only,omitempty"`
-	Privileged  bool              `yaml:"privileged,omitempty"`
-	Init        bool              `yaml:"init,omitempty"`
-	Platform    string            `yaml:"platform,omitempty"`
-	CPUs        interface{}       `yaml:"cpus,omitempty"`
-	MemLimit    string            `yaml:"mem_limit,omitempty"`
-	Tmpfs       interface{}       `yaml:"tmpfs,omitempty"`
-	Healthcheck *Healthcheck      `yaml:"healthcheck,omitempty"`
-	ContainerName string          `yaml:"container_name,omitempty"`
-	PullPolicy  string            `yaml:"pull_policy,omitempty"`
-	StopSignal  string            `yaml:"stop_signal,omitempty"`
-	StopGracePeriod string        `yaml:"stop_grace_period,omitempty"`
+	Image           string            `yaml:"image,omitempty"`
+	Build           interface{}       `yaml:"build,omitempty"`
+	Command         interface{}       `yaml:"command,omitempty"`
+	Entrypoint      interface{}       `yaml:"entrypoint,omitempty"`
+	Environment     interface{}       `yaml:"environment,omitempty"`
+	EnvFile         interface{}       `yaml:"env_file,omitempty"`
+	Ports           []string          `yaml:"ports,omitempty"`
+	Expose          []string          `yaml:"expose,omitempty"`
+	Volumes         []string          `yaml:"volumes,omitempty"`
+	Networks        interface{}       `yaml:"networks,omitempty"`
+	NetworkMode     string            `yaml:"network_mode,omitempty"`
+	Pid             string            `yaml:"pid,omitempty"`
+	Ipc             string            `yaml:"ipc,omitempty"`
+	Uts             string            `yaml:"uts,omitempty"`
+	DependsOn       interface{}       `yaml:"depends_on,omitempty"`
+	Restart         string            `yaml:"restart,omitempty"`
+	WorkingDir      string            `yaml:"working_dir,omitempty"`
+	User            string            `yaml:"user,omitempty"`
+	Hostname        string            `yaml:"hostname,omitempty"`
+	DNS             interface{}       `yaml:"dns,omitempty"`
+	DNSSearch       interface{}       `yaml:"dns_search,omitempty"`
+	ExtraHosts      []string          `yaml:"extra_hosts,omitempty"`
+	Labels          map[string]string `yaml:"labels,omitempty"`
+	StdinOpen       bool              `yaml:"stdin_open,omitempty"`
+	Tty             bool              `yaml:"tty,omitempty"`
+	Attach          *bool             `yaml:"attach,omitempty"` // nil means attached
+	ReadOnly        bool              `yaml:"read_only,omitempty"`
+	Privileged      bool              `yaml:"privileged,omitempty"`
+	Init            bool              `yaml:"init,omitempty"`
+	GroupAdd        []string          `yaml:"group_add,omitempty"`
+	UsernsMode      string            `yaml:"userns_mode,omitempty"`
+	Platform        string            `yaml:"platform,omitempty"`
+	CPUs            interface{}       `yaml:"cpus,omitempty"`
+	MemLimit        string            `yaml:"mem_limit,omitempty"`
+	Tmpfs           interface{}       `yaml:"tmpfs,omitempty"`
+	Healthcheck     *Healthcheck      `yaml:"healthcheck,omitempty"`
+	ContainerName   string            `yaml:"container_name,omitempty"`
+	PullPolicy      string            `yaml:"pull_policy,omitempty"`
+	StopSignal      string            `yaml:"stop_signal,omitempty"`
+	StopGracePeriod string            `yaml:"stop_grace_period,omitempty"`
+	Secrets         interface{}       `yaml:"secrets,omitempty"`
+	Develop         *DevelopConfig    `yaml:"develop,omitempty"`
+	Deploy          *DeployConfig     `yaml:"deploy,omitempty"`
+	Wait            interface{}       `yaml:"x-dctl-wait,omitempty"`
+	GPU             interface{}       `yaml:"x-dctl-gpu,omitempty"`
+	// DependsOnProject names dctl projects that must be running before the
+	// service starts; resolved to a []string.
+	DependsOnProject interface{} `yaml:"x-dctl-depends-on-project,omitempty"`
+
+	// ProjectEnv is the project's x-dctl-env, copied to every service.
+	ProjectEnv map[string]string `yaml:"-"`
+}
+
+// DeployConfig represents a service's deploy section. Only device
+// reservations are read.
+type DeployConfig struct {
+	Resources struct {
+		Reservations struct {
+			Devices []DeviceRequest `yaml:"devices,omitempty"`
+		} `yaml:"reservations,omitempty"`
+	} `yaml:"resources,omitempty"`
+}
+
+// DeviceRequest is a deploy.resources.reservations.devices entry, such as
+// a request for GPUs.
+type DeviceRequest struct {
+	Capabilities []string          `yaml:"capabilities"`
+	Driver       string            `yaml:"driver,omitempty"`
+	Count        interface{}       `yaml:"count,omitempty"` // resolved to an int; AllDevices for "all"
+	DeviceIDs    []string          `yaml:"device_ids,omitempty"`
+	Options      map[string]string `yaml:"options,omitempty"`
+}
+
+// DevelopConfig represents a service's develop section.
+type DevelopConfig struct {
+	Watch []WatchRule `yaml:"watch,omitempty"`
+}
+
+// Watch actions.
+const (
+	WatchSync        = "sync"
+	WatchRestart     = "restart"
+	WatchSyncRestart = "sync+restart"
+	WatchRebuild     = "rebuild"
+)
+
+// WatchRule is a develop.watch entry: when files under Path change, apply
+// Action. Target is the container path synced files are copied to, and
+// InitialSync copies the whole path when watching starts.
+type WatchRule struct {
+	Path        string   `yaml:"path"`
+	Action      string   `yaml:"action"`
+	Target      string   `yaml:"target,omitempty"`
+	Ignore      []string `yaml:"ignore,omitempty"`
+	InitialSync bool     `yaml:"initial_sync,omitempty"`
 }
 
 // BuildConfig represents the build configuration for a service.
@@ -55,12 +143,13 @@ type BuildConfig struct {
 
 // Network represents a network definition.
 type Network struct {
-	Driver   string            `yaml:"driver,omitempty"`
-	Internal bool              `yaml:"internal,omitempty"`
-	External bool              `yaml:"external,omitempty"`
-	Name     string            `yaml:"name,omitempty"`
-	Labels   map[string]string `yaml:"labels,omitempty"`
-	IPAM     *IPAM             `yaml:"ipam,omitempty"`
+	Driver     string            `yaml:"driver,omitempty"`
+	Internal   bool              `yaml:"internal,omitempty"`
+	External   bool              `yaml:"external,omitempty"`
+	Name       string            `yaml:"name,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	IPAM       *IPAM             `yaml:"ipam,omitempty"`
+	EnableIPv6 bool              `yaml:"enable_ipv6,omitempty"`
 }
 
 // IPAM represents IPAM configuration.
@@ -76,19 +165,118 @@ type IPAMConfig struct {
 
 // VolumeConfig represents a volume definition.
 type VolumeConfig struct {
-	Driver   string            `yaml:"driver,omitempty"`
-	External bool              `yaml:"external,omitempty"`
-	Name     string            `yaml:"name,omitempty"`
-	Labels   map[string]string `yaml:"labels,omitempty"`
+	Driver     string            `yaml:"driver,omitempty"`
+	DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+	External   bool              `yaml:"external,omitempty"`
+	Name       string            `yaml:"name,omitempty"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+}
+
+// SecretConfig represents a top-level secret. Exactly one source is set:
+// a file, a variable from dctl's environment, a macOS Keychain item, or a
+// reference resolved by a secret provider.
+type SecretConfig struct {
+	File        string `yaml:"file,omitempty"`
+	Environment string `yaml:"environment,omitempty"`
+	Keychain    string `yaml:"x-dctl-keychain,omitempty"`
+	Provider    string `yaml:"x-dctl-provider,omitempty"`
+	Ref         string `yaml:"x-dctl-ref,omitempty"`
+}
+
+// ServiceSecret grants a service access to a secret. It is mounted as
+// /run/secrets/<Target>, or injected as the variable EnvVar when set.
+type ServiceSecret struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target,omitempty"`
+	EnvVar string `yaml:"x-dctl-env-var,omitempty"`
 }
 
-// Healthcheck represents a healthcheck configuration.
+// Healthcheck represents a healthcheck configuration. A healthcheck
+// without a test keeps the image's, with its own timings.
 type Healthcheck struct {
-	Test     interface{} `yaml:"test,omitempty"`
-	Interval string      `yaml:"interval,omitempty"`
-	Timeout  string      `yaml:"timeout,omitempty"`
-	Retries  int         `yaml:"retries,omitempty"`
-	Disable  bool        `yaml:"disable,omitempty"`
+	Test        interface{} `yaml:"test,omitempty"`
+	Interval    string      `yaml:"interval,omitempty"`
+	Timeout     string      `yaml:"timeout,omitempty"`
+	Retries     int         `yaml:"retries,omitempty"`
+	StartPeriod string      `yaml:"start_period,omitempty"`
+	// StartInterval is the interval between checks during StartPeriod.
+	StartInterval string `yaml:"start_interval,omitempty"`
+	Disable       bool   `yaml:"disable,omitempty"`
+}
+
+// Disabled reports whether the healthcheck turns checks off, the image's
+// included: disable: true or test: ["NONE"].
+func (h *Healthcheck) Disabled() bool {
+	if h == nil {
+		return false
+	}
+	test, _ := h.Test.([]interface{})
+	return h.Disable || len(test) > 0 && test[0] == "NONE"
+}
+
+// ShellCommand returns the healthcheck's test as a shell command line, the
+// form Docker's --health-cmd takes, or "" when Command returns nil.
+func (h *Healthcheck) ShellCommand() string {
+	args := h.Command()
+	if args == nil {
+		return ""
+	}
+	if test, ok := h.Test.([]interface{}); !ok || test[0] != "CMD" {
+		return args[2] // sh -c COMMAND
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote single-quotes s unless it consists only of shell-safe
+// characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Command returns the command a healthcheck runs in the container, or nil
+// when it is disabled or has no test. CMD-SHELL and string tests run through
+// sh -c.
+func (h *Healthcheck) Command() []string {
+	if h == nil || h.Disable {
+		return nil
+	}
+	switch test := h.Test.(type) {
+	case string:
+		if test == "" {
+			return nil
+		}
+		return []string{"sh", "-c", test}
+	case []interface{}:
+		if len(test) < 2 {
+			return nil
+		}
+		args := make([]string, 0, len(test)-1)
+		for _, a := range test[1:] {
+			args = append(args, fmt.Sprintf("%v", a))
+		}
+		switch test[0] {
+		case "CMD":
+			return args
+		case "CMD-SHELL":
+			return []string{"sh", "-c", strings.Join(args, " ")}
+		}
+	}
+	return nil
+}
+
+// WaitConfig represents an x-dctl-wait readiness probe.
+// WaitFor is a tcp://host:port or http(s):// URL.
+type WaitConfig struct {
+	WaitFor  string `yaml:"wait_for"`
+	Timeout  string `yaml:"timeout,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
 }
 
 // DependsOnCondition represents a depends_on condition.
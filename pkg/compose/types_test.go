@@ -0,0 +1,61 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHealthcheckCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		hc   *Healthcheck
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"string", &Healthcheck{Test: "curl -f localhost"}, []string{"sh", "-c", "curl -f localhost"}},
+		{"CMD", &Healthcheck{Test: []interface{}{"CMD", "pg_isready", "-U", "app"}}, []string{"pg_isready", "-U", "app"}},
+		{"CMD-SHELL", &Healthcheck{Test: []interface{}{"CMD-SHELL", "exit 0"}}, []string{"sh", "-c", "exit 0"}},
+		{"NONE", &Healthcheck{Test: []interface{}{"NONE"}}, nil},
+		{"disabled", &Healthcheck{Test: "true", Disable: true}, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.hc.Command(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Command() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHealthcheckDisabled(t *testing.T) {
+	tests := []struct {
+		hc   *Healthcheck
+		want bool
+	}{
+		{nil, false},
+		{&Healthcheck{Interval: "5s"}, false},
+		{&Healthcheck{Test: []interface{}{"CMD", "true"}}, false},
+		{&Healthcheck{Disable: true}, true},
+		{&Healthcheck{Test: []interface{}{"NONE"}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.hc.Disabled(); got != tt.want {
+			t.Errorf("%+v: Disabled() = %v, want %v", tt.hc, got, tt.want)
+		}
+	}
+}
+
+func TestHealthcheckShellCommand(t *testing.T) {
+	tests := []struct {
+		hc   *Healthcheck
+		want string
+	}{
+		{&Healthcheck{Test: "curl -f localhost || exit 1"}, "curl -f localhost || exit 1"},
+		{&Healthcheck{Test: []interface{}{"CMD-SHELL", "exit 0"}}, "exit 0"},
+		{&Healthcheck{Test: []interface{}{"CMD", "pg_isready", "-U", "app user", "it's"}}, `pg_isready -U 'app user' 'it'\''s'`},
+		{&Healthcheck{Test: []interface{}{"NONE"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.hc.ShellCommand(); got != tt.want {
+			t.Errorf("%+v: ShellCommand() = %q, want %q", tt.hc, got, tt.want)
+		}
+	}
+}
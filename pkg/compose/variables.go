@@ -0,0 +1,51 @@
+package compose
+
+import "sort"
+
+// Variable is a variable the compose files interpolate with ${VAR}.
+type Variable struct {
+	Name    string
+	Default string // from ${VAR:-default} or ${VAR-default}; the first one given
+	Set     bool   // whether the environment sets it
+	Value   string // what the files interpolate it to
+}
+
+// Variables lists the variables the compose files reference, sorted by
+// name, with the values Load would interpolate. files and projectDir are as
+// for Load.
+func Variables(files []string, projectDir string) ([]Variable, error) {
+	if len(files) == 0 {
+		found, err := findDefaultFile(projectDir)
+		if err != nil {
+			return nil, err
+		}
+		files = []string{found}
+	}
+
+	seen := make(map[string]*Variable)
+	for _, f := range files {
+		sources, err := readComposeSource(f, projectDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range sources {
+			for _, m := range envVarPattern.FindAllStringSubmatch(string(src.data), -1) {
+				name, _, defaultVal := splitReference(m[1])
+				if seen[name] != nil {
+					continue
+				}
+				v := &Variable{Name: name, Default: defaultVal}
+				_, v.Set = src.lookup(name)
+				v.Value = interpolateWith(m[0], src.lookup)
+				seen[name] = v
+			}
+		}
+	}
+
+	vars := make([]Variable, 0, len(seen))
+	for _, v := range seen {
+		vars = append(vars, *v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
@@ -0,0 +1,43 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	dir := t.TempDir()
+	base := "services:\n  web:\n    image: nginx:${TAG:-latest}\n    environment:\n      DB: ${DB_URL}\n      MODE: ${MODE-dev}\n"
+	override := "services:\n  web:\n    image: nginx:${TAG:-stable}\n    environment:\n      EMPTY: ${EMPTY:-fallback}\n"
+	for name, data := range map[string]string{"compose.yaml": base, "override.yaml": override} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("TAG", "1.25")
+	t.Setenv("EMPTY", "")
+	for _, name := range []string{"DB_URL", "MODE"} {
+		t.Setenv(name, "") // restored after the test
+		os.Unsetenv(name)
+	}
+
+	vars, err := Variables([]string{"compose.yaml", "override.yaml"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Variable{
+		{Name: "DB_URL"},
+		{Name: "EMPTY", Default: "fallback", Set: true, Value: "fallback"},
+		{Name: "MODE", Default: "dev", Value: "dev"},
+		{Name: "TAG", Default: "latest", Set: true, Value: "1.25"},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("Variables() =\n%+v\nwant\n%+v", vars, want)
+	}
+
+	if vars, err := Variables(nil, dir); err != nil || len(vars) != 3 {
+		t.Errorf("Variables() of the default file = %+v, %v, want 3 variables", vars, err)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user defaults from ~/.dctl/config.yaml. Every setting maps to
+// an environment variable, so flags and the environment still take
+// precedence over the file.
+type Config struct {
+	Backend  string     `yaml:"backend,omitempty"`   // container or docker
+	Parallel int        `yaml:"parallel,omitempty"`  // max concurrent operations
+	ANSI     string     `yaml:"ansi,omitempty"`      // auto, never or always
+	Platform string     `yaml:"platform,omitempty"`  // default platform for run and build
+	StateDir string     `yaml:"state_dir,omitempty"` // dctl state root
+	Logs     LogOptions `yaml:"logs,omitempty"`
+	// DetachKeys is the key sequence for leaving attached exec and run
+	// sessions, such as ctrl-p,ctrl-q.
+	DetachKeys string `yaml:"detach_keys,omitempty"`
+}
+
+// LogOptions are defaults for `compose logs`.
+type LogOptions struct {
+	Tail string `yaml:"tail,omitempty"`
+}
+
+// Path returns the config file path: DCTL_CONFIG if set, otherwise
+// ~/.dctl/config.yaml. It does not follow DCTL_STATE_DIR because the file
+// can set the state directory.
+func Path() (string, error) {
+	if p := os.Getenv("DCTL_CONFIG"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, ".dctl", "config.yaml"), nil
+}
+
+// Load reads and validates the config file. A missing file yields an
+// empty config.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks enumerated settings.
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case "", "container", "docker":
+	default:
+		return fmt.Errorf("invalid backend %q (want container or docker)", c.Backend)
+	}
+	switch c.ANSI {
+	case "", "auto", "never", "always":
+	default:
+		return fmt.Errorf("invalid ansi %q (want auto, never or always)", c.ANSI)
+	}
+	if c.Logs.Tail != "" && c.Logs.Tail != "all" {
+		if _, err := strconv.Atoi(c.Logs.Tail); err != nil {
+			return fmt.Errorf("invalid logs.tail %q (want a number or all)", c.Logs.Tail)
+		}
+	}
+	return nil
+}
+
+// Env returns the environment variables the config provides defaults for.
+func (c *Config) Env() map[string]string {
+	env := make(map[string]string)
+	if c.Backend != "" {
+		env["DCTL_BACKEND"] = c.Backend
+	}
+	if c.Parallel != 0 {
+		env["COMPOSE_PARALLEL_LIMIT"] = strconv.Itoa(c.Parallel)
+	}
+	if c.ANSI != "" {
+		env["COMPOSE_ANSI"] = c.ANSI
+	}
+	if c.Platform != "" {
+		env["DOCKER_DEFAULT_PLATFORM"] = c.Platform
+	}
+	if c.StateDir != "" {
+		env["DCTL_STATE_DIR"] = expandHome(c.StateDir)
+	}
+	if c.Logs.Tail != "" {
+		env["DCTL_LOGS_TAIL"] = c.Logs.Tail
+	}
+	if c.DetachKeys != "" {
+		env["DCTL_DETACH_KEYS"] = c.DetachKeys
+	}
+	return env
+}
+
+// ApplyDefaults loads the config file and sets each variable it provides
+// that is not already set in the environment.
+func ApplyDefaults() error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	for k, v := range cfg.Env() {
+		if _, set := os.LookupEnv(k); !set {
+			os.Setenv(k, v)
+		}
+	}
+	return nil
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
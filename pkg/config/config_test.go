@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	t.Setenv("DCTL_CONFIG", path)
+}
+
+func TestLoad_Missing(t *testing.T) {
+	t.Setenv("DCTL_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(cfg.Env()) != 0 {
+		t.Errorf("Env() = %v, want empty", cfg.Env())
+	}
+}
+
+func TestLoad_Env(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeConfig(t, `
+backend: docker
+parallel: 4
+ansi: never
+platform: linux/arm64
+state_dir: ~/state
+logs:
+  tail: "100"
+detach_keys: ctrl-p,ctrl-q
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := map[string]string{
+		"DCTL_BACKEND":            "docker",
+		"COMPOSE_PARALLEL_LIMIT":  "4",
+		"COMPOSE_ANSI":            "never",
+		"DOCKER_DEFAULT_PLATFORM": "linux/arm64",
+		"DCTL_STATE_DIR":          filepath.Join(home, "state"),
+		"DCTL_LOGS_TAIL":          "100",
+		"DCTL_DETACH_KEYS":        "ctrl-p,ctrl-q",
+	}
+	if got := cfg.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, content := range []string{"backend: podman", "ansi: sometimes", "logs: {tail: lots}", "parallel: [1]"} {
+		writeConfig(t, content)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load(%q) expected error", content)
+		}
+	}
+}
+
+func TestApplyDefaults_EnvWins(t *testing.T) {
+	writeConfig(t, "parallel: 4\nansi: never\n")
+	t.Setenv("COMPOSE_PARALLEL_LIMIT", "2")
+	t.Setenv("COMPOSE_ANSI", "")
+	os.Unsetenv("COMPOSE_ANSI")
+
+	if err := ApplyDefaults(); err != nil {
+		t.Fatalf("ApplyDefaults() error: %v", err)
+	}
+	if got := os.Getenv("COMPOSE_PARALLEL_LIMIT"); got != "2" {
+		t.Errorf("COMPOSE_PARALLEL_LIMIT = %q, want environment value 2", got)
+	}
+	if got := os.Getenv("COMPOSE_ANSI"); got != "never" {
+		t.Errorf("COMPOSE_ANSI = %q, want config value never", got)
+	}
+}
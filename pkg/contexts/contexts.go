@@ -0,0 +1,181 @@
+package contexts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultName is the built-in context that targets Apple container with
+// auto-detected settings, or Docker when DCTL_BACKEND=docker. It always
+// exists and cannot be removed.
+const DefaultName = "default"
+
+// Supported backends.
+const (
+	BackendContainer = "container" // Apple container, via command translation
+	BackendDocker    = "docker"    // Docker CLI, commands passed through unchanged
+)
+
+// Context is a named backend configuration.
+type Context struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Backend     string            `json:"backend"`
+	Bin         string            `json:"bin,omitempty"`       // backend binary; auto-detected when empty
+	StateDir    string            `json:"state_dir,omitempty"` // dctl state root; ~/.dctl when empty
+	Env         map[string]string `json:"env,omitempty"`       // extra environment for backend commands
+}
+
+// Store is the persisted set of contexts and the current selection.
+type Store struct {
+	Current  string             `json:"current,omitempty"`
+	Contexts map[string]Context `json:"contexts"`
+}
+
+// Path returns the contexts file, ~/.dctl/contexts.json. It lives outside
+// the state directory because contexts choose the state directory.
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, ".dctl", "contexts.json"), nil
+}
+
+// Load reads the contexts file. A missing file yields an empty store.
+func Load() (*Store, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{Contexts: make(map[string]Context)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading contexts: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if s.Contexts == nil {
+		s.Contexts = make(map[string]Context)
+	}
+	return s, nil
+}
+
+// Save writes the store to the contexts file.
+func (s *Store) Save() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating contexts directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling contexts: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing contexts: %w", err)
+	}
+	return nil
+}
+
+// Get returns the named context.
+func (s *Store) Get(name string) (Context, error) {
+	if name == DefaultName {
+		if os.Getenv("DCTL_BACKEND") == BackendDocker {
+			return Context{Name: DefaultName, Description: "Docker (from DCTL_BACKEND)", Backend: BackendDocker}, nil
+		}
+		return Context{Name: DefaultName, Description: "Apple container (auto-detected)", Backend: BackendContainer}, nil
+	}
+	c, ok := s.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("context %q not found", name)
+	}
+	return c, nil
+}
+
+// Active resolves the context to use: override (the --context flag) first,
+// then DCTL_CONTEXT, then the stored current context, then the default.
+func (s *Store) Active(override string) (Context, error) {
+	name := override
+	if name == "" {
+		name = os.Getenv("DCTL_CONTEXT")
+	}
+	if name == "" {
+		name = s.Current
+	}
+	if name == "" {
+		name = DefaultName
+	}
+	return s.Get(name)
+}
+
+// Create adds a new context after validating it.
+func (s *Store) Create(c Context) error {
+	if c.Name == "" || c.Name == DefaultName {
+		return fmt.Errorf("invalid context name %q", c.Name)
+	}
+	if _, exists := s.Contexts[c.Name]; exists {
+		return fmt.Errorf("context %q already exists", c.Name)
+	}
+	if c.Backend == "" {
+		c.Backend = BackendContainer
+	}
+	if c.Backend != BackendContainer && c.Backend != BackendDocker {
+		return fmt.Errorf("unsupported backend %q (want %s or %s)", c.Backend, BackendContainer, BackendDocker)
+	}
+	s.Contexts[c.Name] = c
+	return nil
+}
+
+// Use makes the named context current.
+func (s *Store) Use(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+	if name == DefaultName {
+		name = ""
+	}
+	s.Current = name
+	return nil
+}
+
+// Remove deletes a context. The current context falls back to the default.
+func (s *Store) Remove(name string) error {
+	if name == DefaultName {
+		return fmt.Errorf("cannot remove the %s context", DefaultName)
+	}
+	if _, ok := s.Contexts[name]; !ok {
+		return fmt.Errorf("context %q not found", name)
+	}
+	delete(s.Contexts, name)
+	if s.Current == name {
+		s.Current = ""
+	}
+	return nil
+}
+
+// List returns all contexts, the default first and the rest sorted by name.
+func (s *Store) List() []Context {
+	def, _ := s.Get(DefaultName)
+	list := []Context{def}
+	names := make([]string, 0, len(s.Contexts))
+	for name := range s.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list = append(list, s.Contexts[name])
+	}
+	return list
+}
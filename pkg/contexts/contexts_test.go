@@ -0,0 +1,93 @@
+package contexts
+
+import (
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DCTL_CONTEXT", "")
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if c, _ := s.Active(""); c.Name != DefaultName || c.Backend != BackendContainer {
+		t.Errorf("Active() on empty store = %+v, want default container context", c)
+	}
+
+	err = s.Create(Context{Name: "desktop", Backend: BackendDocker, Bin: "/usr/local/bin/docker", Env: map[string]string{"DOCKER_HOST": "unix:///tmp/docker.sock"}})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if err := s.Use("desktop"); err != nil {
+		t.Fatalf("Use() error: %v", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	c, err := loaded.Active("")
+	if err != nil {
+		t.Fatalf("Active() error: %v", err)
+	}
+	if c.Name != "desktop" || c.Backend != BackendDocker || c.Env["DOCKER_HOST"] == "" {
+		t.Errorf("Active() = %+v, want desktop docker context", c)
+	}
+
+	if list := loaded.List(); len(list) != 2 || list[0].Name != DefaultName {
+		t.Errorf("List() = %+v, want default then desktop", list)
+	}
+}
+
+func TestStore_ActivePrecedence(t *testing.T) {
+	s := &Store{Current: "a", Contexts: map[string]Context{
+		"a": {Name: "a", Backend: BackendContainer},
+		"b": {Name: "b", Backend: BackendContainer},
+		"c": {Name: "c", Backend: BackendDocker},
+	}}
+
+	t.Setenv("DCTL_CONTEXT", "b")
+	if c, _ := s.Active(""); c.Name != "b" {
+		t.Errorf("Active() = %s, want b from DCTL_CONTEXT", c.Name)
+	}
+	if c, _ := s.Active("c"); c.Name != "c" {
+		t.Errorf("Active(c) = %s, want flag override", c.Name)
+	}
+	if _, err := s.Active("missing"); err == nil {
+		t.Error("Active(missing) expected error")
+	}
+}
+
+func TestStore_Validation(t *testing.T) {
+	s := &Store{Contexts: map[string]Context{}}
+	if err := s.Create(Context{Name: DefaultName}); err == nil {
+		t.Error("Create(default) expected error")
+	}
+	if err := s.Create(Context{Name: "x", Backend: "podman"}); err == nil {
+		t.Error("Create with unknown backend expected error")
+	}
+	if err := s.Create(Context{Name: "x"}); err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if s.Contexts["x"].Backend != BackendContainer {
+		t.Errorf("Backend = %q, want default %q", s.Contexts["x"].Backend, BackendContainer)
+	}
+	if err := s.Create(Context{Name: "x"}); err == nil {
+		t.Error("Create duplicate expected error")
+	}
+	if err := s.Remove(DefaultName); err == nil {
+		t.Error("Remove(default) expected error")
+	}
+	_ = s.Use("x")
+	if err := s.Remove("x"); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if s.Current != "" {
+		t.Errorf("Current = %q after removing it, want empty", s.Current)
+	}
+}
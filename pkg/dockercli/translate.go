@@ -0,0 +1,266 @@
+package dockercli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// flagSpec describes how a docker flag maps onto the container CLI.
+type flagSpec struct {
+	name  string // container CLI flag; empty keeps the docker name
+	value bool   // flag consumes a value
+	drop  bool   // flag has no container equivalent and is removed with a warning
+	// format marks --format, whose table and json values the container CLI
+	// shares; it has no Go templates.
+	format bool
+}
+
+// commandSpec describes how a docker command maps onto the container CLI.
+type commandSpec struct {
+	target []string
+	flags  map[string]flagSpec
+	// interspersed is false for commands whose arguments after the first
+	// positional belong to the containerized process (run, exec).
+	interspersed bool
+}
+
+var runFlags = map[string]flagSpec{
+	"-d": {name: "--detach"}, "--detach": {},
+	"-i": {name: "--interactive"}, "--interactive": {},
+	"-t": {name: "--tty"}, "--tty": {},
+	"--rm":   {},
+	"--init": {},
+	"--name": {value: true},
+	"-e":     {name: "--env", value: true}, "--env": {value: true},
+	"--env-file": {value: true},
+	"-p":         {name: "--publish", value: true}, "--publish": {value: true},
+	"-v": {name: "--volume", value: true}, "--volume": {value: true},
+	"--mount": {value: true},
+	"-w":      {name: "--workdir", value: true}, "--workdir": {value: true},
+	"-u": {name: "--user", value: true}, "--user": {value: true},
+	"--entrypoint": {value: true},
+	"--network":    {value: true}, "--net": {name: "--network", value: true},
+	"--platform": {value: true},
+	"--cpus":     {value: true},
+	"-m":         {name: "--memory", value: true}, "--memory": {value: true},
+	"-l": {name: "--label", value: true}, "--label": {value: true},
+	"--dns":        {value: true},
+	"--dns-search": {value: true},
+	"--tmpfs":      {value: true},
+	"--read-only":  {},
+	"--restart":    {value: true, drop: true},
+	"--add-host":   {value: true, drop: true},
+	"--cap-add":    {value: true, drop: true},
+	"--cap-drop":   {value: true, drop: true},
+	"--privileged": {drop: true},
+	"--hostname":   {value: true, drop: true},
+	"-h":           {value: true, drop: true},
+	"--pull":       {value: true, drop: true},
+	"--log-driver": {value: true, drop: true},
+}
+
+var execFlags = map[string]flagSpec{
+	"-d": {name: "--detach"}, "--detach": {},
+	"-i": {name: "--interactive"}, "--interactive": {},
+	"-t": {name: "--tty"}, "--tty": {},
+	"-e": {name: "--env", value: true}, "--env": {value: true},
+	"-w": {name: "--workdir", value: true}, "--workdir": {value: true},
+	"-u": {name: "--user", value: true}, "--user": {value: true},
+	"--privileged":  {drop: true},
+	"--detach-keys": {value: true, drop: true},
+}
+
+var commands = map[string]commandSpec{
+	"run":  {target: []string{"run"}, flags: runFlags},
+	"exec": {target: []string{"exec"}, flags: execFlags},
+	"ps": {target: []string{"list"}, interspersed: true, flags: map[string]flagSpec{
+		"-a": {name: "--all"}, "--all": {},
+		"-q": {name: "--quiet"}, "--quiet": {},
+		"--format":   {value: true, format: true},
+		"--no-trunc": {drop: true},
+		"-f":         {value: true, drop: true},
+		"--filter":   {value: true, drop: true},
+	}},
+	"logs": {target: []string{"logs"}, interspersed: true, flags: map[string]flagSpec{
+		"-f": {name: "--follow"}, "--follow": {},
+		"-n": {value: true}, "--tail": {name: "-n", value: true},
+		"-t": {drop: true}, "--timestamps": {drop: true},
+		"--since": {value: true, drop: true},
+		"--until": {value: true, drop: true},
+	}},
+	"images": {target: []string{"image", "list"}, interspersed: true, flags: map[string]flagSpec{
+		"-q": {name: "--quiet"}, "--quiet": {},
+		"--format": {value: true, format: true},
+		"-a":       {drop: true}, "--all": {drop: true},
+	}},
+	"pull": {target: []string{"image", "pull"}, interspersed: true, flags: map[string]flagSpec{
+		"--platform": {value: true},
+		"-q":         {drop: true}, "--quiet": {drop: true},
+	}},
+	"push": {target: []string{"image", "push"}, interspersed: true, flags: map[string]flagSpec{
+		"--platform": {value: true},
+	}},
+	"rmi": {target: []string{"image", "delete"}, interspersed: true, flags: map[string]flagSpec{
+		"-f": {drop: true}, "--force": {drop: true},
+	}},
+	"tag": {target: []string{"image", "tag"}, interspersed: true},
+	"save": {target: []string{"image", "save"}, interspersed: true, flags: map[string]flagSpec{
+		"-o": {name: "--output", value: true}, "--output": {value: true},
+	}},
+	"load": {target: []string{"image", "load"}, interspersed: true, flags: map[string]flagSpec{
+		"-i": {name: "--input", value: true}, "--input": {value: true},
+	}},
+	"build": {target: []string{"build"}, interspersed: true, flags: map[string]flagSpec{
+		"-t": {name: "--tag", value: true}, "--tag": {value: true},
+		"-f": {name: "--file", value: true}, "--file": {value: true},
+		"--build-arg": {value: true},
+		"--target":    {value: true},
+		"--label":     {value: true},
+		"--no-cache":  {},
+		"--platform":  {value: true},
+		"-q":          {name: "--quiet"}, "--quiet": {},
+		"--pull":     {drop: true},
+		"--progress": {value: true, drop: true},
+	}},
+	"rm": {target: []string{"delete"}, interspersed: true, flags: map[string]flagSpec{
+		"-f": {name: "--force"}, "--force": {},
+		"-v": {drop: true}, "--volumes": {drop: true},
+	}},
+	"start": {target: []string{"start"}, interspersed: true, flags: map[string]flagSpec{
+		"-a": {drop: true}, "--attach": {drop: true},
+		"-i": {drop: true}, "--interactive": {drop: true},
+	}},
+	"stop": {target: []string{"stop"}, interspersed: true, flags: map[string]flagSpec{
+		"-t": {name: "--time", value: true}, "--time": {value: true},
+		"-s": {name: "--signal", value: true}, "--signal": {value: true},
+	}},
+	"kill": {target: []string{"kill"}, interspersed: true, flags: map[string]flagSpec{
+		"-s": {name: "--signal", value: true}, "--signal": {value: true},
+	}},
+	"inspect": {target: []string{"inspect"}, interspersed: true, flags: map[string]flagSpec{
+		"-f": {value: true, drop: true}, "--format": {value: true, drop: true},
+	}},
+}
+
+// groupCommands maps docker "network"/"volume" subcommands.
+var groupCommands = map[string]map[string]string{
+	"network": {"ls": "list", "list": "list", "rm": "delete", "remove": "delete", "create": "create", "inspect": "inspect", "prune": "prune"},
+	"volume":  {"ls": "list", "list": "list", "rm": "delete", "remove": "delete", "create": "create", "inspect": "inspect", "prune": "prune"},
+}
+
+// Commands returns the docker commands Translate understands, sorted.
+func Commands() []string {
+	names := make([]string, 0, len(commands)+len(groupCommands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	for name := range groupCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Translate converts a docker CLI invocation (without the leading "docker")
+// into container CLI arguments. Flags without an equivalent are removed and
+// reported as warnings. args is not modified.
+func Translate(command string, args []string) ([]string, []string, error) {
+	if subs, ok := groupCommands[command]; ok {
+		if len(args) == 0 {
+			return nil, nil, fmt.Errorf("%s requires a subcommand", command)
+		}
+		sub, ok := subs[args[0]]
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported command: %s %s", command, args[0])
+		}
+		return append([]string{command, sub}, args[1:]...), nil, nil
+	}
+
+	spec, ok := commands[command]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported command: %s", command)
+	}
+
+	out := append([]string{}, spec.target...)
+	var warnings []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			out = append(out, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if !spec.interspersed {
+				out = append(out, args[i:]...)
+				break
+			}
+			out = append(out, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+
+		fs, known := spec.flags[name]
+		if !known && !hasValue && !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// Combined short flags such as -it or -dit.
+			if expanded, ok := expandShort(arg, spec.flags); ok {
+				args = slices.Concat(args[:i], expanded, args[i+1:])
+				i--
+				continue
+			}
+		}
+		if !known {
+			out = append(out, arg)
+			continue
+		}
+
+		if fs.value && !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			value = args[i]
+			hasValue = true
+		}
+
+		if fs.format && value != "table" && value != "json" {
+			return nil, nil, fmt.Errorf("%s --format %q is not supported by the container runtime (want table or json)", command, value)
+		}
+
+		if fs.drop {
+			warnings = append(warnings, fmt.Sprintf("%s %s is not supported by the container runtime, ignoring", command, name))
+			continue
+		}
+
+		target := fs.name
+		if target == "" {
+			target = name
+		}
+		out = append(out, target)
+		if hasValue {
+			out = append(out, value)
+		}
+	}
+
+	return out, warnings, nil
+}
+
+// expandShort splits "-it" into "-i", "-t" when every letter is a known
+// boolean short flag, or when only the last letter takes a value.
+func expandShort(arg string, flags map[string]flagSpec) ([]string, bool) {
+	letters := arg[1:]
+	expanded := make([]string, 0, len(letters))
+	for i, r := range letters {
+		f := "-" + string(r)
+		fs, ok := flags[f]
+		if !ok || (fs.value && i != len(letters)-1) {
+			return nil, false
+		}
+		expanded = append(expanded, f)
+	}
+	return expanded, true
+}
@@ -0,0 +1,144 @@
+package dockercli
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		want     []string
+		warnings int
+	}{
+		{
+			name:    "run with combined short flags",
+			command: "run",
+			args:    []string{"-it", "--rm", "-p", "8080:80", "-e=A=1", "nginx", "sh", "-c", "echo -t"},
+			want:    []string{"run", "--interactive", "--tty", "--rm", "--publish", "8080:80", "--env", "A=1", "nginx", "sh", "-c", "echo -t"},
+		},
+		{
+			name:     "run drops unsupported flags",
+			command:  "run",
+			args:     []string{"-d", "--restart", "always", "--name", "web", "nginx"},
+			want:     []string{"run", "--detach", "--name", "web", "nginx"},
+			warnings: 1,
+		},
+		{
+			name:    "run combined flag ending in value flag",
+			command: "run",
+			args:    []string{"-dp", "80:80", "nginx"},
+			want:    []string{"run", "--detach", "--publish", "80:80", "nginx"},
+		},
+		{
+			name:    "exec keeps process args",
+			command: "exec",
+			args:    []string{"-it", "web", "ls", "-la"},
+			want:    []string{"exec", "--interactive", "--tty", "web", "ls", "-la"},
+		},
+		{
+			name:    "ps",
+			command: "ps",
+			args:    []string{"-aq"},
+			want:    []string{"list", "--all", "--quiet"},
+		},
+		{
+			name:    "ps format",
+			command: "ps",
+			args:    []string{"--format", "json"},
+			want:    []string{"list", "--format", "json"},
+		},
+		{
+			name:    "images format",
+			command: "images",
+			args:    []string{"--format=table"},
+			want:    []string{"image", "list", "--format", "table"},
+		},
+		{
+			name:    "logs tail",
+			command: "logs",
+			args:    []string{"web", "--tail", "50", "-f"},
+			want:    []string{"logs", "web", "-n", "50", "--follow"},
+		},
+		{
+			name:    "images",
+			command: "images",
+			want:    []string{"image", "list"},
+		},
+		{
+			name:    "build",
+			command: "build",
+			args:    []string{"-t", "app:dev", "-f", "Dockerfile.dev", "."},
+			want:    []string{"build", "--tag", "app:dev", "--file", "Dockerfile.dev", "."},
+		},
+		{
+			name:    "rm force",
+			command: "rm",
+			args:    []string{"-f", "web", "db"},
+			want:    []string{"delete", "--force", "web", "db"},
+		},
+		{
+			name:    "network ls",
+			command: "network",
+			args:    []string{"ls"},
+			want:    []string{"network", "list"},
+		},
+		{
+			name:    "volume rm",
+			command: "volume",
+			args:    []string{"rm", "data"},
+			want:    []string{"volume", "delete", "data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := Translate(tt.command, tt.args)
+			if err != nil {
+				t.Fatalf("Translate() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Translate() = %v, want %v", got, tt.want)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{"swarm", nil, "unsupported command"},
+		{"network", nil, "requires a subcommand"},
+		{"network", []string{"connect"}, "unsupported command"},
+		{"run", []string{"--name"}, "requires a value"},
+		{"ps", []string{"--format", "{{.Names}}"}, "not supported"},
+		{"images", []string{"--format", "table {{.Repository}}"}, "not supported"},
+	}
+	for _, tt := range tests {
+		if _, _, err := Translate(tt.command, tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Translate(%s %v) error = %v, want %q", tt.command, tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestTranslate_KeepsArgs(t *testing.T) {
+	// Spare capacity lets an in-place rewrite go unnoticed by append.
+	args := append(make([]string, 0, 8), "-it", "--rm", "nginx", "sh")
+	want := slices.Clone(args)
+	if _, _, err := Translate("run", args); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("args = %v after Translate, want %v", args, want)
+	}
+}
@@ -0,0 +1,185 @@
+// Package drift compares live containers with their compose service
+// definitions.
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// Change is one difference between the compose file and a container.
+// Want and Got are empty when the value is absent on that side.
+type Change struct {
+	Field string
+	Want  string
+	Got   string
+}
+
+// String renders the change as "field: want X, got Y".
+func (c Change) String() string {
+	return fmt.Sprintf("%s: want %s, got %s", c.Field, orUnset(c.Want), orUnset(c.Got))
+}
+
+func orUnset(s string) string {
+	if s == "" {
+		return "(unset)"
+	}
+	return s
+}
+
+// Compare reports how a container differs from its service definition
+// in image, environment, mounts and published ports. env is the desired
+// environment, including values from env_file.
+func Compare(svc compose.Service, env map[string]string, c runner.ContainerInfo) []Change {
+	cfg := c.Configuration
+	var changes []Change
+
+	if want, got := NormalizeImage(svc.Image), NormalizeImage(cfg.Image.Reference); want != got {
+		changes = append(changes, Change{Field: "image", Want: want, Got: got})
+	}
+
+	for _, ch := range CompareEnv(env, cfg.InitProcess.Environment) {
+		ch.Field = "env " + ch.Field
+		changes = append(changes, ch)
+	}
+
+	changes = append(changes, compareMounts(svc.Volumes, cfg.Mounts)...)
+	changes = append(changes, comparePorts(svc.Ports, cfg.PublishedPorts)...)
+	return changes
+}
+
+// CompareEnv reports the desired variables a container's KEY=VALUE
+// environment lacks or sets differently, each Change naming its variable.
+// Only desired variables are compared: the container also carries
+// variables set by the image and the runtime.
+func CompareEnv(env map[string]string, environment []string) []Change {
+	live := make(map[string]string)
+	for _, kv := range environment {
+		k, v, _ := strings.Cut(kv, "=")
+		live[k] = v
+	}
+	var changes []Change
+	for _, k := range sortedKeys(env) {
+		if got, ok := live[k]; !ok || got != env[k] {
+			changes = append(changes, Change{Field: k, Want: env[k], Got: got})
+		}
+	}
+	return changes
+}
+
+// compareMounts matches bind mounts and named volumes by container path.
+func compareMounts(volumes []string, mounts []runner.Mount) []Change {
+	want := make(map[string]string)
+	for _, v := range volumes {
+		parts := strings.Split(v, ":")
+		if len(parts) < 2 {
+			continue // anonymous volume
+		}
+		want[parts[1]] = parts[0]
+	}
+
+	got := make(map[string]string)
+	for _, m := range mounts {
+		switch m.Kind() {
+		case "tmpfs":
+			continue
+		case "volume":
+			got[m.Destination] = m.VolumeName()
+		default:
+			got[m.Destination] = m.Source
+		}
+	}
+
+	var changes []Change
+	for _, target := range unionKeys(want, got) {
+		if want[target] != got[target] {
+			changes = append(changes, Change{Field: "mount " + target, Want: want[target], Got: got[target]})
+		}
+	}
+	return changes
+}
+
+// comparePorts compares published ports. Ports without a requested host
+// port match any host port for the same container port.
+func comparePorts(specs []string, published []runner.PublishedPort) []Change {
+	want := make(map[string]string)
+	for _, spec := range specs {
+		mappings, err := compose.ParsePort(spec)
+		if err != nil {
+			continue
+		}
+		for _, m := range mappings {
+			want[portKey(m.Target, m.Protocol)] = m.String()
+		}
+	}
+
+	got := make(map[string]string)
+	for _, p := range published {
+		m := compose.PortMapping{Published: p.HostPort, Target: p.ContainerPort, Protocol: p.Proto}
+		if p.HostAddress != "0.0.0.0" {
+			m.HostIP = p.HostAddress
+		}
+		got[portKey(p.ContainerPort, p.Proto)] = m.String()
+	}
+
+	var changes []Change
+	for _, key := range unionKeys(want, got) {
+		w, g := want[key], got[key]
+		if w != "" && g != "" && !strings.Contains(w, ":") {
+			continue // any host port is fine
+		}
+		if w != g {
+			changes = append(changes, Change{Field: "port " + key, Want: w, Got: g})
+		}
+	}
+	return changes
+}
+
+func portKey(target int, protocol string) string {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	return strconv.Itoa(target) + "/" + strings.ToLower(protocol)
+}
+
+// NormalizeImage expands an image reference to name:tag form without the
+// default registry, so "nginx" and "docker.io/library/nginx:latest" match.
+func NormalizeImage(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	ref = strings.TrimPrefix(ref, "docker.io/")
+	ref = strings.TrimPrefix(ref, "library/")
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	if i := strings.LastIndex(ref, ":"); i <= strings.LastIndex(ref, "/") {
+		ref += ":latest"
+	}
+	return ref
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := make(map[string]string, len(a)+len(b))
+	for k := range a {
+		seen[k] = ""
+	}
+	for k := range b {
+		seen[k] = ""
+	}
+	return sortedKeys(seen)
+}
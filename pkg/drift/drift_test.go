@@ -0,0 +1,81 @@
+package drift
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func TestNormalizeImage(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                          "nginx:latest",
+		"docker.io/library/nginx:latest": "nginx:latest",
+		"docker.io/bitnami/redis:7":      "bitnami/redis:7",
+		"localhost:5000/app":             "localhost:5000/app:latest",
+		"ghcr.io/o/app:1.2":              "ghcr.io/o/app:1.2",
+		"alpine@sha256:abc":              "alpine@sha256:abc",
+		"":                               "",
+	}
+	for in, want := range tests {
+		if got := NormalizeImage(in); got != want {
+			t.Errorf("NormalizeImage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c := runner.ContainerInfo{Configuration: runner.ContainerConfig{
+		Image: runner.ImageRef{Reference: "docker.io/library/postgres:15"},
+		InitProcess: runner.Process{Environment: []string{
+			"PATH=/usr/bin", "PG_VERSION=15", "POSTGRES_USER=app", "POSTGRES_DB=old",
+		}},
+		Mounts: []runner.Mount{
+			{Type: map[string]json.RawMessage{"volume": json.RawMessage(`{"name":"pgdata"}`)}, Destination: "/var/lib/postgresql/data"},
+			{Type: map[string]json.RawMessage{"virtiofs": nil}, Source: "/src/old", Destination: "/init"},
+			{Type: map[string]json.RawMessage{"tmpfs": nil}, Destination: "/tmp"},
+		},
+		PublishedPorts: []runner.PublishedPort{
+			{HostAddress: "0.0.0.0", HostPort: 5433, ContainerPort: 5432, Proto: "tcp"},
+			{HostAddress: "0.0.0.0", HostPort: 49152, ContainerPort: 9187, Proto: "tcp"},
+		},
+	}}
+	svc := compose.Service{
+		Image:   "postgres:16",
+		Volumes: []string{"pgdata:/var/lib/postgresql/data", "/src/new:/init:ro"},
+		Ports:   []string{"5432:5432", "9187"},
+	}
+	env := map[string]string{"POSTGRES_USER": "app", "POSTGRES_DB": "shop", "TZ": "UTC"}
+
+	want := []Change{
+		{Field: "image", Want: "postgres:16", Got: "postgres:15"},
+		{Field: "env POSTGRES_DB", Want: "shop", Got: "old"},
+		{Field: "env TZ", Want: "UTC"},
+		{Field: "mount /init", Want: "/src/new", Got: "/src/old"},
+		{Field: "port 5432/tcp", Want: "5432:5432", Got: "5433:5432"},
+	}
+	if got := Compare(svc, env, c); !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%v\nwant\n%v", got, want)
+	}
+
+	svc.Image = "postgres:15"
+	svc.Volumes = []string{"pgdata:/var/lib/postgresql/data", "/src/old:/init"}
+	svc.Ports = []string{"5433:5432", "9187"}
+	env = map[string]string{"POSTGRES_USER": "app"}
+	if got := Compare(svc, env, c); len(got) != 0 {
+		t.Errorf("Compare() on matching container = %v, want no changes", got)
+	}
+}
+
+func TestCompareEnv(t *testing.T) {
+	env := map[string]string{"DEBUG": "1", "TZ": "UTC", "EMPTY": ""}
+	want := []Change{
+		{Field: "DEBUG", Want: "1", Got: "0"},
+		{Field: "EMPTY"},
+	}
+	if got := CompareEnv(env, []string{"PATH=/usr/bin", "DEBUG=0", "TZ=UTC"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareEnv() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Package events derives the container events of a project by comparing
+// snapshots of its containers, since the runtime publishes no event
+// stream.
+package events
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions, as events name what happened to a container.
+const (
+	ActionCreate  = "create"
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionDestroy = "destroy"
+)
+
+// Container is what a snapshot records about a project container.
+type Container struct {
+	Service string
+	Image   string
+	Status  string // runtime status, e.g. running or stopped
+}
+
+// Event is a change to a project container.
+type Event struct {
+	Time       time.Time         `json:"time"`
+	Type       string            `json:"type"` // always "container"
+	Action     string            `json:"action"`
+	ID         string            `json:"id"`
+	Service    string            `json:"service"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// String renders an event the way docker compose events prints it, e.g.
+// "2024-05-01 10:00:00.000000 container start shop_web (image=nginx, name=shop_web, service=web)".
+func (e Event) String() string {
+	attrs := []string{"service=" + e.Service}
+	for k, v := range e.Attributes {
+		attrs = append(attrs, k+"="+v)
+	}
+	sort.Strings(attrs)
+	return fmt.Sprintf("%s %s %s %s (%s)", e.Time.Format("2006-01-02 15:04:05.000000"), e.Type, e.Action, e.ID, strings.Join(attrs, ", "))
+}
+
+// Diff returns the events that lead from one snapshot to the next, keyed by
+// container name, in name order: create for a new container, followed by
+// start if it runs; start and stop when it starts or stops running; and
+// destroy when it is gone. A container found with another image was
+// replaced between the snapshots, and is destroyed and created again.
+func Diff(before, after map[string]Container, now time.Time) []Event {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var events []Event
+	add := func(action, name string, c Container) {
+		events = append(events, Event{
+			Time:       now,
+			Type:       "container",
+			Action:     action,
+			ID:         name,
+			Service:    c.Service,
+			Attributes: map[string]string{"name": name, "image": c.Image},
+		})
+	}
+	for _, name := range sorted {
+		old, existed := before[name]
+		cur, exists := after[name]
+		if existed && exists && old.Image != cur.Image {
+			add(ActionDestroy, name, old)
+			existed = false
+		}
+		switch {
+		case !exists:
+			add(ActionDestroy, name, old)
+		case !existed:
+			add(ActionCreate, name, cur)
+			if cur.Status == "running" {
+				add(ActionStart, name, cur)
+			}
+		case old.Status != "running" && cur.Status == "running":
+			add(ActionStart, name, cur)
+		case old.Status == "running" && cur.Status != "running":
+			add(ActionStop, name, cur)
+		}
+	}
+	return events
+}
+
+// Filter selects events by service and by action. Values for the same key
+// are alternatives; an empty key matches everything.
+type Filter struct {
+	Services []string
+	Actions  []string
+}
+
+// ParseFilter parses --filter values: service=NAME and type=ACTION (or
+// event=ACTION, as docker names it).
+func ParseFilter(specs []string) (Filter, error) {
+	actions := []string{ActionCreate, ActionStart, ActionStop, ActionDestroy}
+	var f Filter
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		switch {
+		case ok && key == "service" && value != "":
+			f.Services = append(f.Services, value)
+			continue
+		case ok && (key == "type" || key == "event") && slices.Contains(actions, value):
+			f.Actions = append(f.Actions, value)
+			continue
+		}
+		return Filter{}, fmt.Errorf("unsupported filter %q (supported: service=NAME, type=%s)", spec, strings.Join(actions, "|"))
+	}
+	return f, nil
+}
+
+// Match reports whether the filter selects e.
+func (f Filter) Match(e Event) bool {
+	return (len(f.Services) == 0 || slices.Contains(f.Services, e.Service)) &&
+		(len(f.Actions) == 0 || slices.Contains(f.Actions, e.Action))
+}
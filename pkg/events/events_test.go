@@ -0,0 +1,79 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	before := map[string]Container{
+		"shop_web":   {Service: "web", Image: "nginx:1.0", Status: "running"},
+		"shop_db":    {Service: "db", Image: "postgres", Status: "running"},
+		"shop_cache": {Service: "cache", Image: "redis", Status: "stopped"},
+		"shop_old":   {Service: "old", Image: "busybox", Status: "stopped"},
+	}
+	after := map[string]Container{
+		"shop_web":    {Service: "web", Image: "nginx:1.1", Status: "running"},
+		"shop_db":     {Service: "db", Image: "postgres", Status: "stopped"},
+		"shop_cache":  {Service: "cache", Image: "redis", Status: "running"},
+		"shop_worker": {Service: "worker", Image: "app", Status: "created"},
+	}
+	var got []string
+	for _, e := range Diff(before, after, now) {
+		got = append(got, e.Action+" "+e.ID)
+	}
+	want := []string{
+		"start shop_cache",
+		"stop shop_db",
+		"destroy shop_old",
+		"destroy shop_web",
+		"create shop_web",
+		"start shop_web",
+		"create shop_worker",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	if events := Diff(after, after, now); len(events) != 0 {
+		t.Errorf("unchanged snapshot gave %v", events)
+	}
+}
+
+func TestEventString(t *testing.T) {
+	e := Diff(nil, map[string]Container{"shop_web": {Service: "web", Image: "nginx", Status: "created"}}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))[0]
+	want := "2024-05-01 10:00:00.000000 container create shop_web (image=nginx, name=shop_web, service=web)"
+	if e.String() != want {
+		t.Errorf("String() = %q, want %q", e.String(), want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f, err := ParseFilter([]string{"service=web", "service=db", "type=stop", "event=destroy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		e    Event
+		want bool
+	}{
+		{Event{Service: "web", Action: ActionStop}, true},
+		{Event{Service: "db", Action: ActionDestroy}, true},
+		{Event{Service: "web", Action: ActionStart}, false},
+		{Event{Service: "cache", Action: ActionStop}, false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.e); got != tt.want {
+			t.Errorf("Match(%+v) = %v, want %v", tt.e, got, tt.want)
+		}
+	}
+	if !(Filter{}).Match(Event{Service: "web", Action: ActionStart}) {
+		t.Error("an empty filter should match everything")
+	}
+	for _, spec := range []string{"service", "type=restart", "label=x"} {
+		if _, err := ParseFilter([]string{spec}); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", spec)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package features detects the container runtime's version and gates dctl
+// features on it, so unsupported options fail with a clear message instead
+// of an opaque CLI error.
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is a runtime version. The zero value means unknown.
+type Version struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Known reports whether the version was detected.
+func (v Version) Known() bool {
+	return v != Version{}
+}
+
+// Less reports whether v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion extracts the first version number from CLI output such as
+// "container CLI version 0.5.0 (build: release, commit: 1a2b3c4)".
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version number in %q", strings.TrimSpace(s))
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// Feature is a dctl capability that needs a minimum runtime version.
+type Feature struct {
+	Name string
+	Min  Version
+}
+
+// Features gated on the Apple container version. Each minimum is the
+// first release whose CLI takes what dctl passes for the feature, noted
+// beside it.
+var (
+	// container run taking --network more than once.
+	MultiNetwork = Feature{Name: "attaching a container to multiple networks", Min: Version{0, 4, 0}}
+	// container network create --subnet.
+	Subnet = Feature{Name: "an ipam subnet", Min: Version{0, 5, 0}}
+	// container network create --subnet-v6.
+	SubnetV6 = Feature{Name: "an IPv6 ipam subnet", Min: Version{0, 6, 0}}
+	// container exec exiting with the command's status, which is how dctl
+	// reads a healthcheck test's result; earlier releases exit 0.
+	Healthcheck = Feature{Name: "a healthcheck", Min: Version{0, 2, 0}}
+	// container run --volume SOURCE:TARGET:ro, sharing the directory
+	// read-only over virtiofs.
+	ReadOnlyMount = Feature{Name: "a read-only bind mount", Min: Version{0, 3, 0}}
+)
+
+// Require returns an error when runtime version v is known to be older than
+// f needs. An unknown version is given the benefit of the doubt.
+func Require(v Version, f Feature) error {
+	if v.Known() && v.Less(f.Min) {
+		return fmt.Errorf("%s requires container >= %s (found %s)", f.Name, f.Min, v)
+	}
+	return nil
+}
+
+// cacheEntry records the version of one runtime binary. The binary's
+// modification time invalidates it when the runtime is upgraded.
+type cacheEntry struct {
+	Bin     string    `json:"bin"`
+	ModTime time.Time `json:"mod_time"`
+	Version Version   `json:"version"`
+}
+
+// versionOutput runs `bin --version`. Tests replace it.
+var versionOutput = func(bin string) (string, error) {
+	out, err := exec.Command(bin, "--version").Output()
+	return string(out), err
+}
+
+// Detect returns the version of the runtime binary bin, caching it in
+// cacheDir/runtime.json until the binary changes.
+func Detect(bin, cacheDir string) (Version, error) {
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return Version{}, fmt.Errorf("finding %s: %w", bin, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Version{}, fmt.Errorf("finding %s: %w", bin, err)
+	}
+
+	cachePath := filepath.Join(cacheDir, "runtime.json")
+	var cached cacheEntry
+	if data, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(data, &cached) == nil {
+		if cached.Bin == path && cached.ModTime.Equal(info.ModTime()) && cached.Version.Known() {
+			return cached.Version, nil
+		}
+	}
+
+	out, err := versionOutput(path)
+	if err != nil {
+		return Version{}, fmt.Errorf("querying %s version: %w", bin, err)
+	}
+	v, err := ParseVersion(out)
+	if err != nil {
+		return Version{}, err
+	}
+
+	data, err := json.MarshalIndent(cacheEntry{Bin: path, ModTime: info.ModTime(), Version: v}, "", "  ")
+	if err == nil && os.MkdirAll(cacheDir, 0o755) == nil {
+		os.WriteFile(cachePath, data, 0o644) // best effort; detection is repeated next time
+	}
+	return v, nil
+}
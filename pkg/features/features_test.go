@@ -0,0 +1,75 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"container CLI version 0.5.0 (build: release, commit: 1a2b3c4)": {0, 5, 0},
+		"container version 1.2":                {1, 2, 0},
+		"Docker version 27.3.1, build ce12230": {27, 3, 1},
+	}
+	for out, want := range tests {
+		got, err := ParseVersion(out)
+		if err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %v, %v, want %v", out, got, err, want)
+		}
+	}
+	if _, err := ParseVersion("unknown"); err == nil {
+		t.Error("ParseVersion(unknown) succeeded, want error")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	f := Feature{Name: "frobbing", Min: Version{0, 4, 0}}
+	if err := Require(Version{0, 4, 0}, f); err != nil {
+		t.Errorf("Require(0.4.0) = %v", err)
+	}
+	if err := Require(Version{1, 0, 0}, f); err != nil {
+		t.Errorf("Require(1.0.0) = %v", err)
+	}
+	if err := Require(Version{}, f); err != nil {
+		t.Errorf("Require(unknown) = %v, want nil", err)
+	}
+	err := Require(Version{0, 3, 9}, f)
+	if err == nil || err.Error() != "frobbing requires container >= 0.4.0 (found 0.3.9)" {
+		t.Errorf("Require(0.3.9) = %v", err)
+	}
+}
+
+func TestDetectCaches(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "container")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	orig := versionOutput
+	versionOutput = func(string) (string, error) {
+		calls++
+		return "container CLI version 0.6.1", nil
+	}
+	defer func() { versionOutput = orig }()
+
+	for range 2 {
+		v, err := Detect(bin, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != (Version{0, 6, 1}) {
+			t.Errorf("Detect() = %v, want 0.6.1", v)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("version queried %d times, want 1", calls)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "runtime.json"))
+	if !strings.Contains(string(data), bin) {
+		t.Errorf("cache = %s, want entry for %s", data, bin)
+	}
+}
@@ -0,0 +1,331 @@
+package features
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+// Issue is a key in a compose file that dctl or the runtime will not honor.
+type Issue struct {
+	Kind   string // service, network or volume
+	Name   string
+	Key    string
+	Reason string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s: %s", i.Kind, i.Name, i.Key, i.Reason)
+}
+
+// ignored is the reason for keys dctl reads past without acting on.
+const ignored = "ignored"
+
+// dockerOnly is the reason for keys only the Docker backend honors.
+const dockerOnly = "ignored by the container runtime; passed to the Docker backend"
+
+// serviceKeys lists every service key dctl knows. An empty reason means the
+// key is honored; keys missing from the table are not supported.
+var serviceKeys = map[string]string{
+	"image":                     "",
+	"build":                     "",
+	"build.context":             "",
+	"build.dockerfile":          "",
+	"build.args":                "",
+	"build.target":              "",
+	"build.labels":              "",
+	"command":                   "",
+	"entrypoint":                "",
+	"environment":               "",
+	"env_file":                  "",
+	"ports":                     "",
+	"expose":                    "",
+	"volumes":                   "",
+	"networks":                  "",
+	"network_mode":              "",
+	"pid":                       "",
+	"ipc":                       "",
+	"uts":                       "",
+	"depends_on":                "",
+	"working_dir":               "",
+	"user":                      "",
+	"stop_signal":               "",
+	"stop_grace_period":         "",
+	"labels":                    "",
+	"stdin_open":                "",
+	"tty":                       "",
+	"attach":                    "",
+	"read_only":                 "",
+	"platform":                  "",
+	"cpus":                      "",
+	"mem_limit":                 "",
+	"tmpfs":                     "",
+	"dns":                       "",
+	"secrets":                   "",
+	"develop":                   "",
+	"x-dctl-wait":               "",
+	"x-dctl-gpu":                "",
+	"x-dctl-depends-on-project": "",
+	"deploy":                    "",
+	"deploy.resources":          "only reservations.devices is read",
+	"restart":                   "ignored; containers are not restarted by the runtime (see compose autostart)",
+	"healthcheck":               "not polled by the container runtime; compose ps runs the test on demand, use x-dctl-wait for readiness checks",
+	"profiles":                  "ignored; every service is started",
+	"hostname":                  ignored,
+	"dns_search":                ignored,
+	"extra_hosts":               dockerOnly,
+	"init":                      "",
+	"privileged":                dockerOnly,
+	"group_add":                 dockerOnly,
+	"userns_mode":               dockerOnly,
+	"container_name":            "",
+	"pull_policy":               "honored by compose pull; up pulls only missing images",
+}
+
+// resourceKeys lists the honored network and volume keys.
+var resourceKeys = map[string]map[string]string{
+	"network": {
+		"name":        "",
+		"external":    "",
+		"ipam":        "",
+		"enable_ipv6": "",
+		"driver":      ignored,
+		"internal":    ignored,
+		"labels":      ignored,
+	},
+	"volume": {
+		"name":        "",
+		"external":    "",
+		"driver":      "",
+		"driver_opts": "",
+		"labels":      "",
+	},
+}
+
+// CheckSupport lists the keys in cf that dctl ignores or does not support,
+// and those that need a newer runtime than v.
+func CheckSupport(cf *compose.ComposeFile, v Version) []Issue {
+	var issues []Issue
+	for _, name := range sortedNames(cf.Services) {
+		for _, key := range cf.Keys.Services[name] {
+			if reason := keyReason(serviceKeys, key); reason != "" {
+				issues = append(issues, Issue{Kind: "service", Name: name, Key: key, Reason: reason})
+			}
+		}
+
+		svc := cf.Services[name]
+		if ep, ok := svc.Entrypoint.([]string); ok && len(ep) > 1 {
+			issues = append(issues, Issue{Kind: "service", Name: name, Key: "entrypoint",
+				Reason: fmt.Sprintf("only %q is used; the remaining elements are dropped", ep[0])})
+		}
+		if err := NetworkMode(svc.NetworkMode); err != nil {
+			issues = append(issues, Issue{Kind: "service", Name: name, Key: "network_mode", Reason: err.Error() + "; passed to the Docker backend"})
+		}
+		for _, v := range svc.Volumes {
+			if opts := compose.ParseMount(v).IgnoredOptions(); len(opts) > 0 {
+				issues = append(issues, Issue{Kind: "service", Name: name, Key: "volumes",
+					Reason: fmt.Sprintf("%s: options %s ignored; only ro is supported", v, strings.Join(opts, ","))})
+			}
+		}
+		if tmpfs, ok := svc.Tmpfs.([]string); ok {
+			for _, t := range tmpfs {
+				m, err := compose.ParseTmpfs(t)
+				if err != nil {
+					continue
+				}
+				if opts := m.IgnoredOptions(); len(opts) > 0 {
+					issues = append(issues, Issue{Kind: "service", Name: name, Key: "tmpfs",
+						Reason: fmt.Sprintf("%s: options %s ignored; the runtime mounts tmpfs with its default size and mode", t, strings.Join(opts, ","))})
+				}
+			}
+		}
+		for _, key := range []string{"pid", "ipc", "uts"} {
+			if err := Namespace(key, ServiceNamespace(svc, key)); err != nil {
+				issues = append(issues, Issue{Kind: "service", Name: name, Key: key, Reason: err.Error() + "; ignored, but passed to the Docker backend"})
+			}
+		}
+		if err := Devices(compose.DeviceRequests(svc)); err != nil {
+			issues = append(issues, Issue{Kind: "service", Name: name, Key: "devices", Reason: err.Error()})
+		}
+		if nets, ok := svc.Networks.(map[string]interface{}); ok && len(nets) > 1 {
+			if err := Require(v, MultiNetwork); err != nil {
+				issues = append(issues, Issue{Kind: "service", Name: name, Key: "networks", Reason: err.Error()})
+			}
+		}
+		gates := ServiceGates(svc)
+		for _, key := range sortedNames(gates) {
+			if err := Require(v, gates[key]); err != nil {
+				issues = append(issues, Issue{Kind: "service", Name: name, Key: key, Reason: err.Error()})
+			}
+		}
+	}
+
+	for _, name := range sortedNames(cf.Networks) {
+		for _, key := range cf.Keys.Networks[name] {
+			if reason := keyReason(resourceKeys["network"], key); reason != "" {
+				issues = append(issues, Issue{Kind: "network", Name: name, Key: key, Reason: reason})
+			}
+		}
+		if n := cf.Networks[name]; n.IPAM != nil {
+			for _, c := range n.IPAM.Config {
+				prefix, err := netip.ParsePrefix(c.Subnet)
+				if err != nil {
+					continue
+				}
+				f := Subnet
+				if prefix.Addr().Is6() {
+					f = SubnetV6
+				}
+				if err := Require(v, f); err != nil {
+					issues = append(issues, Issue{Kind: "network", Name: name, Key: "ipam", Reason: err.Error()})
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedNames(cf.Volumes) {
+		for _, key := range cf.Keys.Volumes[name] {
+			if reason := keyReason(resourceKeys["volume"], key); reason != "" {
+				issues = append(issues, Issue{Kind: "volume", Name: name, Key: key, Reason: reason})
+			}
+		}
+		if d := cf.Volumes[name].Driver; d != "" && d != "local" {
+			issues = append(issues, Issue{Kind: "volume", Name: name, Key: "driver",
+				Reason: fmt.Sprintf("only the local driver is available; %s falls back to it", d)})
+		}
+	}
+	return issues
+}
+
+// NetworkMode returns an error for a network_mode the container runtime
+// cannot honor. Each container is a VM with its own network stack, so only
+// bridge, the runtime's default network, has an equivalent. The Docker
+// backend is given every mode as --network.
+func NetworkMode(mode string) error {
+	kind, name, _ := strings.Cut(mode, ":")
+	switch kind {
+	case "", "bridge":
+		return nil
+	case "host":
+		return fmt.Errorf(`network_mode "host" is not supported: containers run in their own VM and cannot share the host's network; publish ports instead`)
+	case "none":
+		return fmt.Errorf(`network_mode "none" is not supported: the runtime always attaches a network`)
+	case "service", "container":
+		return fmt.Errorf("network_mode %q is not supported: containers run in their own VM and cannot share a network namespace; attach the service to the networks of %s and reach it by name instead", mode, name)
+	}
+	return fmt.Errorf("unknown network_mode %q", mode)
+}
+
+// ServiceNamespace returns svc's pid, ipc or uts setting.
+func ServiceNamespace(svc compose.Service, key string) string {
+	switch key {
+	case "pid":
+		return svc.Pid
+	case "ipc":
+		return svc.Ipc
+	case "uts":
+		return svc.Uts
+	}
+	return ""
+}
+
+// Namespace returns an error for a pid, ipc or uts setting the container
+// runtime cannot honor. A VM always has its own namespaces, which is what
+// ipc private and shareable ask for; nothing can be shared with the host or
+// with other containers. The Docker backend is given every setting; see
+// DockerOnly.
+func Namespace(key, mode string) error {
+	kind, name, _ := strings.Cut(mode, ":")
+	switch kind {
+	case "", "private", "shareable":
+		return nil
+	case "host":
+		return fmt.Errorf(`%s "host" is not supported: containers run in their own VM and cannot share the host's %s namespace`, key, key)
+	case "service", "container":
+		return fmt.Errorf("%s %q is not supported: containers run in their own VM and cannot share the %s namespace of %s", key, mode, key, name)
+	}
+	return fmt.Errorf("%s %q is not supported by the runtime", key, mode)
+}
+
+// Devices returns an error for device requests the runtime cannot honor.
+// A container's VM is given no GPU or other host device, so any request,
+// including x-dctl-gpu, fails.
+func Devices(reqs []compose.DeviceRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	var caps []string
+	for _, r := range reqs {
+		for _, c := range r.Capabilities {
+			if !slices.Contains(caps, c) {
+				caps = append(caps, c)
+			}
+		}
+	}
+	return fmt.Errorf("device requests (%s) are not supported: containers run in their own VM, which the runtime gives no access to the host's GPU or other devices", strings.Join(caps, ", "))
+}
+
+// ServiceGates returns the gated features svc uses besides multiple
+// networks, with the key that uses each.
+func ServiceGates(svc compose.Service) map[string]Feature {
+	gates := make(map[string]Feature)
+	if svc.Healthcheck != nil && !svc.Healthcheck.Disabled() {
+		gates["healthcheck"] = Healthcheck
+	}
+	for _, v := range svc.Volumes {
+		if m := compose.ParseMount(v); m.ReadOnly && compose.IsBindSource(m.Source) {
+			gates["volumes"] = ReadOnlyMount
+		}
+	}
+	return gates
+}
+
+// DockerOnly returns the keys svc sets that only the Docker backend honors;
+// the container runtime has no equivalent and they are dropped.
+func DockerOnly(svc compose.Service) []string {
+	var keys []string
+	if svc.Privileged {
+		keys = append(keys, "privileged")
+	}
+	if len(svc.GroupAdd) > 0 {
+		keys = append(keys, "group_add")
+	}
+	if svc.UsernsMode != "" {
+		keys = append(keys, "userns_mode")
+	}
+	if len(svc.ExtraHosts) > 0 {
+		keys = append(keys, "extra_hosts")
+	}
+	for _, key := range []string{"pid", "ipc", "uts"} {
+		if Namespace(key, ServiceNamespace(svc, key)) != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyReason returns why key is not honored, or "" if it is. Extension
+// keys (x-*) other than dctl's own are left to other tools.
+func keyReason(table map[string]string, key string) string {
+	reason, known := table[key]
+	if known {
+		return reason
+	}
+	if strings.HasPrefix(key, "x-") && !strings.HasPrefix(key, "x-dctl-") {
+		return ""
+	}
+	return "not supported; ignored"
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
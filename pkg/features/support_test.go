@@ -0,0 +1,148 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+func TestCheckSupport(t *testing.T) {
+	dir := t.TempDir()
+	content := `
+services:
+  web:
+    image: nginx
+    restart: always
+    cap_add: [NET_ADMIN]
+    x-team: web
+    entrypoint: ["/bin/sh", "-c"]
+    networks: [front, back]
+    tmpfs: /run:size=64m
+    build:
+      context: .
+      cache_from: [nginx]
+networks:
+  front:
+    driver: bridge
+    ipam:
+      config:
+        - subnet: 10.1.0.0/24
+  back:
+volumes:
+  data:
+    name: app-data
+    driver: nfs
+    driver_opts:
+      size: 10G
+    labels:
+      tier: db
+`
+	if err := os.WriteFile(filepath.Join(dir, "compose.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := compose.Load(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, issue := range CheckSupport(cf, Version{0, 3, 0}) {
+		got = append(got, issue.Kind+" "+issue.Name+" "+issue.Key)
+	}
+	want := []string{
+		"service web restart",
+		"service web cap_add",
+		"service web build.cache_from",
+		"service web entrypoint",
+		"service web tmpfs",
+		"service web networks",
+		"network front driver",
+		"network front ipam",
+		"volume data driver",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckSupport() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("issue %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// A recent runtime only leaves the keys dctl itself ignores.
+	if n := len(CheckSupport(cf, Version{1, 0, 0})); n != 7 {
+		t.Errorf("CheckSupport(1.0.0) returned %d issues, want 7", n)
+	}
+}
+
+func TestNetworkMode(t *testing.T) {
+	for _, mode := range []string{"", "bridge"} {
+		if err := NetworkMode(mode); err != nil {
+			t.Errorf("NetworkMode(%q) = %v, want nil", mode, err)
+		}
+	}
+	for _, mode := range []string{"host", "none", "service:app", "container:abc"} {
+		if err := NetworkMode(mode); err == nil {
+			t.Errorf("NetworkMode(%q) = nil, want an unsupported error", mode)
+		}
+	}
+	if err := NetworkMode("service:app"); !strings.Contains(err.Error(), "networks of app") {
+		t.Errorf("NetworkMode(service:app) = %v, want a hint naming app", err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	for _, tt := range []struct{ key, mode string }{{"pid", ""}, {"ipc", "private"}, {"ipc", "shareable"}} {
+		if err := Namespace(tt.key, tt.mode); err != nil {
+			t.Errorf("Namespace(%s, %q) = %v, want nil", tt.key, tt.mode, err)
+		}
+	}
+	for _, tt := range []struct{ key, mode string }{{"pid", "host"}, {"ipc", "service:app"}, {"ipc", "none"}, {"uts", "host"}} {
+		if err := Namespace(tt.key, tt.mode); err == nil {
+			t.Errorf("Namespace(%s, %q) = nil, want an unsupported error", tt.key, tt.mode)
+		}
+	}
+}
+
+func TestDevices(t *testing.T) {
+	if err := Devices(nil); err != nil {
+		t.Errorf("Devices(nil) = %v", err)
+	}
+	reqs := []compose.DeviceRequest{
+		{Capabilities: []string{"gpu"}, Count: compose.AllDevices},
+		{Capabilities: []string{"gpu", "compute"}, Count: 1},
+	}
+	err := Devices(reqs)
+	if err == nil || !strings.Contains(err.Error(), "(gpu, compute)") {
+		t.Errorf("Devices() = %v, want an error naming gpu, compute", err)
+	}
+}
+
+func TestDockerOnly(t *testing.T) {
+	if keys := DockerOnly(compose.Service{Init: true}); keys != nil {
+		t.Errorf("DockerOnly(init) = %v, want none", keys)
+	}
+	svc := compose.Service{Privileged: true, GroupAdd: []string{"docker"}, UsernsMode: "host", ExtraHosts: []string{"db:10.0.0.5"}, Pid: "host", Ipc: "private"}
+	if keys, want := DockerOnly(svc), []string{"privileged", "group_add", "userns_mode", "extra_hosts", "pid"}; !slices.Equal(keys, want) {
+		t.Errorf("DockerOnly() = %v, want %v", keys, want)
+	}
+}
+
+func TestServiceGates(t *testing.T) {
+	svc := compose.Service{
+		Healthcheck: &compose.Healthcheck{Test: []interface{}{"CMD", "true"}},
+		Volumes:     []string{"data:/data:ro", "./src:/src"},
+	}
+	if gates := ServiceGates(svc); len(gates) != 1 || gates["healthcheck"] != Healthcheck {
+		t.Errorf("ServiceGates() = %v, want the healthcheck only", gates)
+	}
+	svc.Healthcheck = &compose.Healthcheck{Disable: true}
+	svc.Volumes = append(svc.Volumes, "./conf:/etc/app:ro")
+	if gates := ServiceGates(svc); len(gates) != 1 || gates["volumes"] != ReadOnlyMount {
+		t.Errorf("ServiceGates() = %v, want the read-only bind mount only", gates)
+	}
+}
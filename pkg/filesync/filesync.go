@@ -0,0 +1,86 @@
+// Package filesync packs changed files into tar streams so they can be
+// copied into a container in one exec, like a one-way rsync.
+package filesync
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sonnes/dctl/pkg/watch"
+)
+
+// Split separates changes into files to copy and paths to remove, both
+// relative to the watched root. A change to the root itself (a watched
+// file) has no relative path and is left to the caller.
+func Split(changes []watch.Change) (copies, removals []string) {
+	for _, c := range changes {
+		if c.Rel == "." {
+			continue
+		}
+		if c.Removed {
+			removals = append(removals, c.Rel)
+		} else {
+			copies = append(copies, c.Rel)
+		}
+	}
+	return copies, removals
+}
+
+// Tar writes the files named by rels, relative to root, to w as a tar
+// archive and returns how many were written. Files that vanished since
+// they were listed are skipped.
+func Tar(w io.Writer, root string, rels []string) (int, error) {
+	tw := tar.NewWriter(w)
+	n := 0
+	for _, rel := range rels {
+		ok, err := addFile(tw, root, rel)
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			n++
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return n, fmt.Errorf("writing archive: %w", err)
+	}
+	return n, nil
+}
+
+func addFile(tw *tar.Writer, root, rel string) (bool, error) {
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return false, fmt.Errorf("archiving %s: %w", path, err)
+	}
+	hdr.Name = filepath.ToSlash(rel)
+	// Owners on the host mean nothing in the container.
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if err := tw.WriteHeader(hdr); err != nil {
+		return false, fmt.Errorf("archiving %s: %w", path, err)
+	}
+	// Copy exactly the size in the header even if the file grew since.
+	if _, err := io.CopyN(tw, f, hdr.Size); err != nil {
+		return false, fmt.Errorf("archiving %s: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package filesync
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/watch"
+)
+
+func TestSplit(t *testing.T) {
+	changes := []watch.Change{
+		{Rel: "a.js"},
+		{Rel: "old.js", Removed: true},
+		{Rel: "lib/b.js"},
+		{Rel: "."},
+	}
+	copies, removals := Split(changes)
+	if want := []string{"a.js", "lib/b.js"}; !reflect.DeepEqual(copies, want) {
+		t.Errorf("copies = %v, want %v", copies, want)
+	}
+	if want := []string{"old.js"}; !reflect.DeepEqual(removals, want) {
+		t.Errorf("removals = %v, want %v", removals, want)
+	}
+}
+
+func TestTar(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"a.js": "alpha", "lib/b.js": "beta"}
+	for rel, data := range files {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := Tar(&buf, root, []string{"a.js", "gone.js", "lib/b.js"})
+	if err != nil {
+		t.Fatalf("Tar() error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Tar() wrote %d files, want 2", n)
+	}
+
+	got := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		got[hdr.Name] = string(data)
+		if hdr.Uid != 0 || hdr.Uname != "" {
+			t.Errorf("%s: owner %d/%q, want root", hdr.Name, hdr.Uid, hdr.Uname)
+		}
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("archive = %v, want %v", got, files)
+	}
+}
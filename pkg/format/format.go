@@ -0,0 +1,119 @@
+// Package format renders command output with Go templates, following the
+// docker CLI's --format flag.
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"unicode"
+)
+
+// funcs are the template functions the docker CLI offers.
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n"), err
+	},
+	"join":  strings.Join,
+	"split": strings.Split,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"truncate": func(s string, n int) string {
+		if len(s) > n {
+			return s[:n]
+		}
+		return s
+	},
+}
+
+// JSON is the format that prints each row as a JSON object on its own line.
+const JSON = "json"
+
+// Write renders rows with a Go template, once per row. A format of "table"
+// uses defaultTable, and "table TEMPLATE" prints TEMPLATE's fields in
+// aligned columns under a header naming them; tabs (written \t) separate
+// columns. A format of JSON prints the rows as JSON instead.
+func Write[T any](w io.Writer, format, defaultTable string, rows []T) error {
+	if format == JSON {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return fmt.Errorf("encoding JSON: %w", err)
+			}
+		}
+		return nil
+	}
+	body, table := strings.CutPrefix(format, "table")
+	if !table {
+		body = format
+	} else if body = strings.TrimSpace(body); body == "" {
+		body = defaultTable
+	}
+	body = strings.ReplaceAll(body, `\t`, "\t")
+	tmpl, err := template.New("format").Funcs(funcs).Parse(body + "\n")
+	if err != nil {
+		return fmt.Errorf("parsing format: %w", err)
+	}
+
+	out := w
+	var tw *tabwriter.Writer
+	if table {
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		out = tw
+		var zero T
+		if err := tmpl.Execute(out, Headers(zero)); err != nil {
+			return fmt.Errorf("executing format: %w", err)
+		}
+	}
+	for _, row := range rows {
+		if err := tmpl.Execute(out, row); err != nil {
+			return fmt.Errorf("executing format: %w", err)
+		}
+	}
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
+
+// Headers returns the table headers for a struct's fields, keyed by field
+// name: ExposedPorts is headed EXPOSED PORTS.
+func Headers(v interface{}) map[string]string {
+	headers := make(map[string]string)
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return headers
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.IsExported() {
+			headers[f.Name] = header(f.Name)
+		}
+	}
+	return headers
+}
+
+// header upper-cases a field name, splitting its words with spaces; runs
+// of capitals such as ID stay one word.
+func header(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
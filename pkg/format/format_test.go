@@ -0,0 +1,73 @@
+package format
+
+import (
+	"strings"
+	"testing"
+)
+
+type row struct {
+	Name         string
+	ID           string
+	ExposedPorts []string
+}
+
+func TestWrite(t *testing.T) {
+	rows := []row{
+		{Name: "shop_web", ID: "abc", ExposedPorts: []string{"80/tcp", "443/tcp"}},
+		{Name: "shop_db", ID: "def"},
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "template", format: "{{.Name}} {{join .ExposedPorts \",\"}}", want: "shop_web 80/tcp,443/tcp\nshop_db \n"},
+		{name: "json function", format: "{{json .ExposedPorts}}", want: "[\"80/tcp\",\"443/tcp\"]\nnull\n"},
+		{name: "default table", format: "table", want: "NAME       ID\nshop_web   abc\nshop_db    def\n"},
+		{name: "table template", format: `table {{.ID}}\t{{upper .Name}}`, want: "ID    NAME\nabc   SHOP_WEB\ndef   SHOP_DB\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Write(&b, tt.format, `{{.Name}}\t{{.ID}}`, rows); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Write(%q) = %q, want %q", tt.format, b.String(), tt.want)
+			}
+		})
+	}
+
+	var b strings.Builder
+	if err := Write(&b, "{{.Missing}}", "", rows); err == nil || !strings.Contains(err.Error(), "executing format") {
+		t.Errorf("Write() error = %v, want an execution error", err)
+	}
+	if err := Write(&b, "{{.Name", "", rows); err == nil || !strings.Contains(err.Error(), "parsing format") {
+		t.Errorf("Write() error = %v, want a parse error", err)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	got := Headers(&row{})
+	want := map[string]string{"Name": "NAME", "ID": "ID", "ExposedPorts": "EXPOSED PORTS"}
+	if len(got) != len(want) {
+		t.Fatalf("Headers() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Headers()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	rows := []row{{Name: "shop_web", ExposedPorts: []string{"8080->80/tcp"}}, {Name: "shop_db"}}
+	if err := Write(&b, JSON, "", rows); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Name":"shop_web","ID":"","ExposedPorts":["8080->80/tcp"]}` + "\n" + `{"Name":"shop_db","ID":"","ExposedPorts":null}` + "\n"
+	if b.String() != want {
+		t.Errorf("Write(json) = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,347 @@
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultStorage is the size requested for PersistentVolumeClaims.
+const defaultStorage = "1Gi"
+
+// Manifest is a generic Kubernetes object.
+type Manifest struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   Metadata    `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+// Metadata is Kubernetes object metadata.
+type Metadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// DeploymentSpec is the subset of apps/v1 DeploymentSpec dctl emits.
+type DeploymentSpec struct {
+	Replicas int             `yaml:"replicas"`
+	Selector LabelSelector   `yaml:"selector"`
+	Template PodTemplateSpec `yaml:"template"`
+}
+
+// LabelSelector selects pods by labels.
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+// PodTemplateSpec describes the pods a Deployment creates.
+type PodTemplateSpec struct {
+	Metadata Metadata `yaml:"metadata"`
+	Spec     PodSpec  `yaml:"spec"`
+}
+
+// PodSpec is the subset of core/v1 PodSpec dctl emits.
+type PodSpec struct {
+	Hostname   string      `yaml:"hostname,omitempty"`
+	Containers []Container `yaml:"containers"`
+	Volumes    []Volume    `yaml:"volumes,omitempty"`
+}
+
+// Container is the subset of core/v1 Container dctl emits.
+type Container struct {
+	Name         string          `yaml:"name"`
+	Image        string          `yaml:"image"`
+	Command      []string        `yaml:"command,omitempty"`
+	Args         []string        `yaml:"args,omitempty"`
+	WorkingDir   string          `yaml:"workingDir,omitempty"`
+	Env          []EnvVar        `yaml:"env,omitempty"`
+	Ports        []ContainerPort `yaml:"ports,omitempty"`
+	VolumeMounts []VolumeMount   `yaml:"volumeMounts,omitempty"`
+	TTY          bool            `yaml:"tty,omitempty"`
+	Stdin        bool            `yaml:"stdin,omitempty"`
+}
+
+// EnvVar is a container environment variable.
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// ContainerPort is a port exposed by a container.
+type ContainerPort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+// VolumeMount mounts a pod volume into a container.
+type VolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+// Volume is a pod volume source.
+type Volume struct {
+	Name                  string          `yaml:"name"`
+	PersistentVolumeClaim *ClaimSource    `yaml:"persistentVolumeClaim,omitempty"`
+	HostPath              *HostPathSource `yaml:"hostPath,omitempty"`
+	EmptyDir              *EmptyDirSource `yaml:"emptyDir,omitempty"`
+}
+
+// ClaimSource references a PersistentVolumeClaim.
+type ClaimSource struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// HostPathSource mounts a host directory.
+type HostPathSource struct {
+	Path string `yaml:"path"`
+}
+
+// EmptyDirSource is a scratch volume.
+type EmptyDirSource struct {
+	Medium    string `yaml:"medium,omitempty"`
+	SizeLimit string `yaml:"sizeLimit,omitempty"`
+}
+
+// ServiceSpec is the subset of core/v1 ServiceSpec dctl emits.
+type ServiceSpec struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []ServicePort     `yaml:"ports"`
+}
+
+// ServicePort is a port exposed by a Service.
+type ServicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+	Protocol   string `yaml:"protocol,omitempty"`
+}
+
+// ClaimSpec is the subset of core/v1 PersistentVolumeClaimSpec dctl emits.
+type ClaimSpec struct {
+	AccessModes []string             `yaml:"accessModes"`
+	Resources   ResourceRequirements `yaml:"resources"`
+}
+
+// ResourceRequirements holds storage requests.
+type ResourceRequirements struct {
+	Requests map[string]string `yaml:"requests"`
+}
+
+// Convert translates a compose model into Deployments, Services and
+// PersistentVolumeClaims. Bind mounts become hostPath volumes resolved
+// against projectDir, and tmpfs mounts become memory-backed emptyDirs.
+// Objects are returned in a stable order: claims, then per service a
+// Deployment followed by its Service.
+func Convert(cf *compose.ComposeFile, project, projectDir string) ([]Manifest, error) {
+	var manifests []Manifest
+
+	volNames := make([]string, 0, len(cf.Volumes))
+	for name := range cf.Volumes {
+		volNames = append(volNames, name)
+	}
+	sort.Strings(volNames)
+	for _, name := range volNames {
+		manifests = append(manifests, Manifest{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+			Metadata:   Metadata{Name: objectName(name), Labels: projectLabels(project, "")},
+			Spec: ClaimSpec{
+				AccessModes: []string{"ReadWriteOnce"},
+				Resources:   ResourceRequirements{Requests: map[string]string{"storage": defaultStorage}},
+			},
+		})
+	}
+
+	svcNames := make([]string, 0, len(cf.Services))
+	for name := range cf.Services {
+		svcNames = append(svcNames, name)
+	}
+	sort.Strings(svcNames)
+
+	for _, name := range svcNames {
+		svc := cf.Services[name]
+		deployment, ports, err := convertService(cf, project, projectDir, name, svc)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		manifests = append(manifests, deployment)
+
+		if len(ports) > 0 {
+			manifests = append(manifests, Manifest{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Metadata:   Metadata{Name: objectName(name), Labels: projectLabels(project, name)},
+				Spec:       ServiceSpec{Selector: selectorLabels(name), Ports: ports},
+			})
+		}
+	}
+
+	return manifests, nil
+}
+
+// convertService builds a Deployment and the Service ports for one compose service.
+func convertService(cf *compose.ComposeFile, project, projectDir, name string, svc compose.Service) (Manifest, []ServicePort, error) {
+	image := svc.Image
+	if image == "" {
+		image = project + "-" + name
+	}
+
+	c := Container{
+		Name:       objectName(name),
+		Image:      image,
+		WorkingDir: svc.WorkingDir,
+		TTY:        svc.Tty,
+		Stdin:      svc.StdinOpen,
+	}
+	if ep, ok := svc.Entrypoint.([]string); ok {
+		c.Command = ep
+	}
+	if cmd, ok := svc.Command.([]string); ok {
+		c.Args = cmd
+	}
+
+	env := maps.Clone(svc.ProjectEnv)
+	if vars, ok := svc.Environment.(map[string]string); ok {
+		if env == nil {
+			env = make(map[string]string)
+		}
+		maps.Copy(env, vars)
+	}
+	if len(env) > 0 {
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			c.Env = append(c.Env, EnvVar{Name: k, Value: env[k]})
+		}
+	}
+
+	mappings, err := compose.ParsePorts(svc.Ports)
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	var svcPorts []ServicePort
+	for _, m := range mappings {
+		proto := strings.ToUpper(m.Protocol)
+		c.Ports = append(c.Ports, ContainerPort{ContainerPort: m.Target, Protocol: proto})
+		port := m.Published
+		if port == 0 {
+			port = m.Target
+		}
+		svcPorts = append(svcPorts, ServicePort{
+			Name:       fmt.Sprintf("%s-%d", strings.ToLower(proto), port),
+			Port:       port,
+			TargetPort: m.Target,
+			Protocol:   proto,
+		})
+	}
+
+	var volumes []Volume
+	for i, spec := range svc.Volumes {
+		source, target, readOnly := splitVolume(spec)
+		volName := fmt.Sprintf("%s-vol-%d", objectName(name), i)
+		switch {
+		case source == "":
+			volumes = append(volumes, Volume{Name: volName, EmptyDir: &EmptyDirSource{}})
+		case compose.IsBindSource(source):
+			source = compose.ResolvePath(projectDir, source)
+			volumes = append(volumes, Volume{Name: volName, HostPath: &HostPathSource{Path: source}})
+		default:
+			if _, ok := cf.Volumes[source]; !ok {
+				return Manifest{}, nil, fmt.Errorf("volume %q is not defined", source)
+			}
+			volumes = append(volumes, Volume{Name: volName, PersistentVolumeClaim: &ClaimSource{ClaimName: objectName(source)}})
+		}
+		c.VolumeMounts = append(c.VolumeMounts, VolumeMount{Name: volName, MountPath: target, ReadOnly: readOnly})
+	}
+
+	if tmpfs, ok := svc.Tmpfs.([]string); ok {
+		for i, t := range tmpfs {
+			m, err := compose.ParseTmpfs(t)
+			if err != nil {
+				return Manifest{}, nil, err
+			}
+			dir := &EmptyDirSource{Medium: "Memory"}
+			if m.Size > 0 {
+				dir.SizeLimit = strconv.FormatInt(m.Size, 10)
+			}
+			volName := fmt.Sprintf("%s-tmpfs-%d", objectName(name), i)
+			volumes = append(volumes, Volume{Name: volName, EmptyDir: dir})
+			c.VolumeMounts = append(c.VolumeMounts, VolumeMount{Name: volName, MountPath: m.Target})
+		}
+	}
+
+	selector := selectorLabels(name)
+	podLabels := projectLabels(project, name)
+
+	return Manifest{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   Metadata{Name: objectName(name), Labels: podLabels},
+		Spec: DeploymentSpec{
+			Replicas: 1,
+			Selector: LabelSelector{MatchLabels: selector},
+			Template: PodTemplateSpec{
+				Metadata: Metadata{Name: objectName(name), Labels: podLabels},
+				Spec: PodSpec{
+					Hostname:   svc.Hostname,
+					Containers: []Container{c},
+					Volumes:    volumes,
+				},
+			},
+		},
+	}, svcPorts, nil
+}
+
+// Marshal renders manifests as a multi-document YAML stream.
+func Marshal(manifests []Manifest) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, m := range manifests {
+		if err := enc.Encode(m); err != nil {
+			return nil, fmt.Errorf("encoding %s %s: %w", m.Kind, m.Metadata.Name, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// splitVolume splits a short-syntax volume spec into source, target and read-only flag.
+// Anonymous volumes have an empty source.
+func splitVolume(spec string) (string, string, bool) {
+	m := compose.ParseMount(spec)
+	return m.Source, m.Target, m.ReadOnly
+}
+
+// objectName converts a compose name into a DNS-1123 compatible object name.
+func objectName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// selectorLabels returns the labels used to select a service's pods.
+func selectorLabels(service string) map[string]string {
+	return map[string]string{"app.kubernetes.io/name": objectName(service)}
+}
+
+// projectLabels returns the labels applied to generated objects.
+func projectLabels(project, service string) map[string]string {
+	labels := map[string]string{"app.kubernetes.io/part-of": project}
+	if service != "" {
+		labels["app.kubernetes.io/name"] = objectName(service)
+	}
+	return labels
+}
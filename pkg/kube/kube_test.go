@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConvert(t *testing.T) {
+	cf := &compose.ComposeFile{
+		Services: map[string]compose.Service{
+			"web": {
+				Image:       "nginx:1.25",
+				Command:     []string{"nginx", "-g", "daemon off;"},
+				Environment: map[string]string{"B": "2", "A": "1"},
+				Ports:       []string{"8080:80"},
+				Volumes:     []string{"./site:/usr/share/nginx/html:ro", "cache:/var/cache/nginx"},
+				Tmpfs:       []string{"/run:size=64m,mode=1777"},
+			},
+			"worker_1": {
+				Build: &compose.BuildConfig{Context: "."},
+			},
+		},
+		Volumes: map[string]compose.VolumeConfig{"cache": {}},
+	}
+
+	manifests, err := Convert(cf, "shop", "/src/shop")
+	if err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+
+	var kinds []string
+	for _, m := range manifests {
+		kinds = append(kinds, m.Kind+"/"+m.Metadata.Name)
+	}
+	want := "PersistentVolumeClaim/cache Deployment/web Service/web Deployment/worker-1"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Fatalf("objects = %q, want %q", got, want)
+	}
+
+	web := manifests[1].Spec.(DeploymentSpec).Template.Spec
+	c := web.Containers[0]
+	if c.Image != "nginx:1.25" || strings.Join(c.Args, " ") != "nginx -g daemon off;" {
+		t.Errorf("container = %+v", c)
+	}
+	if len(c.Env) != 2 || c.Env[0].Name != "A" {
+		t.Errorf("env not sorted: %+v", c.Env)
+	}
+	if len(c.Ports) != 1 || c.Ports[0].ContainerPort != 80 {
+		t.Errorf("ports = %+v", c.Ports)
+	}
+	if web.Volumes[0].HostPath == nil || web.Volumes[0].HostPath.Path != "/src/shop/site" || !c.VolumeMounts[0].ReadOnly {
+		t.Errorf("bind mount not converted to read-only hostPath: %+v / %+v", web.Volumes[0], c.VolumeMounts[0])
+	}
+	if web.Volumes[1].PersistentVolumeClaim == nil || web.Volumes[1].PersistentVolumeClaim.ClaimName != "cache" {
+		t.Errorf("named volume not converted to claim: %+v", web.Volumes[1])
+	}
+	if web.Volumes[2].EmptyDir == nil || web.Volumes[2].EmptyDir.Medium != "Memory" || web.Volumes[2].EmptyDir.SizeLimit != "67108864" || c.VolumeMounts[2].MountPath != "/run" {
+		t.Errorf("tmpfs not converted to memory emptyDir: %+v", web.Volumes[2])
+	}
+
+	svcSpec := manifests[2].Spec.(ServiceSpec)
+	if svcSpec.Ports[0].Port != 8080 || svcSpec.Ports[0].TargetPort != 80 {
+		t.Errorf("service ports = %+v", svcSpec.Ports)
+	}
+
+	worker := manifests[3].Spec.(DeploymentSpec).Template.Spec.Containers[0]
+	if worker.Image != "shop-worker_1" {
+		t.Errorf("worker image = %q, want %q", worker.Image, "shop-worker_1")
+	}
+}
+
+func TestConvert_UndefinedVolume(t *testing.T) {
+	cf := &compose.ComposeFile{
+		Services: map[string]compose.Service{
+			"db": {Image: "postgres", Volumes: []string{"data:/var/lib/postgresql/data"}},
+		},
+	}
+	if _, err := Convert(cf, "shop", "/src"); err == nil || !strings.Contains(err.Error(), "not defined") {
+		t.Errorf("Convert() error = %v, want undefined volume", err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	cf := &compose.ComposeFile{
+		Services: map[string]compose.Service{"web": {Image: "nginx", Ports: []string{"80"}}},
+	}
+	manifests, err := Convert(cf, "shop", "/src")
+	if err != nil {
+		t.Fatalf("Convert() error: %v", err)
+	}
+	out, err := Marshal(manifests)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+
+	dec := yaml.NewDecoder(strings.NewReader(string(out)))
+	var docs int
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		docs++
+	}
+	if docs != 2 {
+		t.Errorf("decoded %d documents, want 2:\n%s", docs, out)
+	}
+	if !strings.Contains(string(out), "containerPort: 80") {
+		t.Errorf("output missing containerPort:\n%s", out)
+	}
+}
@@ -0,0 +1,157 @@
+package launchd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxIntervals bounds how many calendar entries a cron expression may
+// expand to; launchd needs one entry per combination of listed values.
+const maxIntervals = 500
+
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cronField describes one of the five cron fields.
+type cronField struct {
+	name     string
+	min, max int
+	sunday7  bool // 7 is an alias for 0
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59, false},
+	{"hour", 0, 23, false},
+	{"day of month", 1, 31, false},
+	{"month", 1, 12, false},
+	{"day of week", 0, 7, true},
+}
+
+// ParseCron converts a five-field cron expression (minute hour day month
+// weekday) into launchd calendar intervals. Fields accept *, numbers,
+// lists, ranges and steps; the @daily style aliases are also accepted.
+// Sunday is 0 or 7.
+func ParseCron(expr string) ([]CalendarInterval, error) {
+	expr = strings.TrimSpace(expr)
+	if alias, ok := cronAliases[expr]; ok {
+		expr = alias
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: want 5 fields", expr)
+	}
+
+	values := make([][]int, len(parts)) // nil means any value
+	total := 1
+	for i, part := range parts {
+		vals, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		values[i] = vals
+		if vals != nil {
+			total *= len(vals)
+		}
+		if total > maxIntervals {
+			return nil, fmt.Errorf("cron expression %q expands to more than %d launchd intervals", expr, maxIntervals)
+		}
+	}
+
+	intervals := []CalendarInterval{{}}
+	for i, vals := range values {
+		if vals == nil {
+			continue
+		}
+		var next []CalendarInterval
+		for _, ci := range intervals {
+			for _, v := range vals {
+				c := ci
+				switch i {
+				case 0:
+					c.Minute = &v
+				case 1:
+					c.Hour = &v
+				case 2:
+					c.Day = &v
+				case 3:
+					c.Month = &v
+				case 4:
+					c.Weekday = &v
+				}
+				next = append(next, c)
+			}
+		}
+		intervals = next
+	}
+	return intervals, nil
+}
+
+// parseCronField returns the sorted values a field matches, or nil for *.
+func parseCronField(s string, f cronField) ([]int, error) {
+	if s == "*" {
+		return nil, nil
+	}
+	seen := make(map[int]bool)
+	for _, item := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%s: invalid step %q", f.name, stepStr)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(loStr, f); err != nil {
+				return nil, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(hiStr, f); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("%s: invalid range %q", f.name, rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			if f.sunday7 && v == 7 {
+				seen[0] = true
+				continue
+			}
+			seen[v] = true
+		}
+	}
+
+	vals := make([]int, 0, len(seen))
+	for v := f.min; v <= f.max; v++ {
+		if seen[v] {
+			vals = append(vals, v)
+		}
+	}
+	return vals, nil
+}
+
+func cronValue(s string, f cronField) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
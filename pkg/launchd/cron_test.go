@@ -0,0 +1,66 @@
+package launchd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render formats intervals as "m:h:d:M:w" with * for unset fields.
+func render(intervals []CalendarInterval) string {
+	f := func(p *int) string {
+		if p == nil {
+			return "*"
+		}
+		return fmt.Sprint(*p)
+	}
+	var out []string
+	for _, c := range intervals {
+		out = append(out, strings.Join([]string{f(c.Minute), f(c.Hour), f(c.Day), f(c.Month), f(c.Weekday)}, ":"))
+	}
+	return strings.Join(out, " ")
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"0 9 * * *", "0:9:*:*:*"},
+		{"@daily", "0:0:*:*:*"},
+		{"*/20 * * * *", "0:*:*:*:* 20:*:*:*:* 40:*:*:*:*"},
+		{"30 8 * * 1-5", "30:8:*:*:1 30:8:*:*:2 30:8:*:*:3 30:8:*:*:4 30:8:*:*:5"},
+		{"0 0,12 1 * *", "0:0:1:*:* 0:12:1:*:*"},
+		{"15 3 * * 7", "15:3:*:*:0"},
+		{"0 6 * * 5-7", "0:6:*:*:0 0:6:*:*:5 0:6:*:*:6"},
+		{"10-30/10 * * * *", "10:*:*:*:* 20:*:*:*:* 30:*:*:*:*"},
+	}
+	for _, tt := range tests {
+		got, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if s := render(got); s != tt.want {
+			t.Errorf("ParseCron(%q) = %s, want %s", tt.expr, s, tt.want)
+		}
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 9 * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"* * 0 * *",
+		"0 9 * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q): expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package launchd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// LabelPrefix namespaces every agent dctl installs.
+const LabelPrefix = "com.github.sonnes.dctl."
+
+// CalendarInterval is a launchd StartCalendarInterval entry.
+// Nil fields match any value.
+type CalendarInterval struct {
+	Minute  *int
+	Hour    *int
+	Day     *int
+	Weekday *int
+	Month   *int
+}
+
+// Agent describes a per-user launchd agent.
+type Agent struct {
+	Label            string
+	ProgramArguments []string
+	WorkingDirectory string
+	Environment      map[string]string
+	RunAtLoad        bool
+	Calendar         []CalendarInterval
+	LogPath          string
+}
+
+// Render returns the agent as a property list document.
+func (a *Agent) Render() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+
+	writeKey(&b, "Label")
+	writeString(&b, a.Label)
+
+	writeKey(&b, "ProgramArguments")
+	b.WriteString("\t<array>\n")
+	for _, arg := range a.ProgramArguments {
+		b.WriteString("\t")
+		writeString(&b, arg)
+	}
+	b.WriteString("\t</array>\n")
+
+	if a.WorkingDirectory != "" {
+		writeKey(&b, "WorkingDirectory")
+		writeString(&b, a.WorkingDirectory)
+	}
+
+	if len(a.Environment) > 0 {
+		keys := make([]string, 0, len(a.Environment))
+		for k := range a.Environment {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeKey(&b, "EnvironmentVariables")
+		b.WriteString("\t<dict>\n")
+		for _, k := range keys {
+			b.WriteString("\t")
+			writeKey(&b, k)
+			b.WriteString("\t")
+			writeString(&b, a.Environment[k])
+		}
+		b.WriteString("\t</dict>\n")
+	}
+
+	writeKey(&b, "RunAtLoad")
+	if a.RunAtLoad {
+		b.WriteString("\t<true/>\n")
+	} else {
+		b.WriteString("\t<false/>\n")
+	}
+
+	if len(a.Calendar) > 0 {
+		writeKey(&b, "StartCalendarInterval")
+		b.WriteString("\t<array>\n")
+		for _, c := range a.Calendar {
+			b.WriteString("\t\t<dict>\n")
+			for _, f := range []struct {
+				key string
+				val *int
+			}{
+				{"Minute", c.Minute}, {"Hour", c.Hour}, {"Day", c.Day}, {"Weekday", c.Weekday}, {"Month", c.Month},
+			} {
+				if f.val == nil {
+					continue
+				}
+				b.WriteString("\t\t\t<key>" + f.key + "</key>\n")
+				b.WriteString("\t\t\t<integer>" + strconv.Itoa(*f.val) + "</integer>\n")
+			}
+			b.WriteString("\t\t</dict>\n")
+		}
+		b.WriteString("\t</array>\n")
+	}
+
+	if a.LogPath != "" {
+		writeKey(&b, "StandardOutPath")
+		writeString(&b, a.LogPath)
+		writeKey(&b, "StandardErrorPath")
+		writeString(&b, a.LogPath)
+	}
+
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
+
+func writeKey(b *bytes.Buffer, key string) {
+	b.WriteString("\t<key>")
+	xml.EscapeText(b, []byte(key))
+	b.WriteString("</key>\n")
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	b.WriteString("\t<string>")
+	xml.EscapeText(b, []byte(s))
+	b.WriteString("</string>\n")
+}
+
+// AgentsDir returns ~/Library/LaunchAgents.
+func AgentsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents"), nil
+}
+
+// Path returns the plist path for a label.
+func Path(label string) (string, error) {
+	dir, err := AgentsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, label+".plist"), nil
+}
+
+// Install writes the agent's plist and (re)loads it into the user's launchd domain.
+func Install(a *Agent) (string, error) {
+	path, err := Path(a.Label)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("creating LaunchAgents directory: %w", err)
+	}
+	if a.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(a.LogPath), 0o755); err != nil {
+			return "", fmt.Errorf("creating log directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, a.Render(), 0o644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	// Ignore errors: the agent is usually not loaded yet.
+	_ = launchctl("bootout", domain()+"/"+a.Label)
+	if err := launchctl("bootstrap", domain(), path); err != nil {
+		return path, fmt.Errorf("loading %s: %w", a.Label, err)
+	}
+	return path, nil
+}
+
+// Uninstall unloads an agent and removes its plist.
+func Uninstall(label string) error {
+	path, err := Path(label)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("agent %s is not installed", label)
+	}
+	_ = launchctl("bootout", domain()+"/"+label)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("removing %s: %w", path, err)
+	}
+	return nil
+}
+
+// Installed returns the labels of installed agents whose label starts with prefix.
+func Installed(prefix string) ([]string, error) {
+	dir, err := AgentsDir()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*.plist"))
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, 0, len(matches))
+	for _, m := range matches {
+		base := filepath.Base(m)
+		labels = append(labels, base[:len(base)-len(".plist")])
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+// domain returns the launchd GUI domain for the current user.
+func domain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+func launchctl(args ...string) error {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("launchctl %s: %w: %s", args[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package launchd
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAgent_Render(t *testing.T) {
+	nine := 9
+	zero := 0
+	a := &Agent{
+		Label:            LabelPrefix + "shop.autostart",
+		ProgramArguments: []string{"/usr/local/bin/dctl", "compose", "-p", "shop&co", "up", "-d"},
+		WorkingDirectory: "/src/shop",
+		Environment:      map[string]string{"PATH": "/usr/bin", "A": "1"},
+		RunAtLoad:        true,
+		Calendar:         []CalendarInterval{{Minute: &zero, Hour: &nine}},
+		LogPath:          "/tmp/shop.log",
+	}
+	out := string(a.Render())
+
+	for _, want := range []string{
+		"<string>com.github.sonnes.dctl.shop.autostart</string>",
+		"<string>shop&amp;co</string>",
+		"<key>RunAtLoad</key>\n\t<true/>",
+		"<key>Hour</key>\n\t\t\t<integer>9</integer>",
+		"<key>StandardErrorPath</key>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plist missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "<key>A</key>") > strings.Index(out, "<key>PATH</key>") {
+		t.Error("environment keys not sorted")
+	}
+
+	// The document must be well-formed XML.
+	dec := xml.NewDecoder(strings.NewReader(out))
+	dec.Strict = false
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("invalid XML: %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestInstalled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	labels, err := Installed(LabelPrefix)
+	if err != nil {
+		t.Fatalf("Installed() error: %v", err)
+	}
+	if len(labels) != 0 {
+		t.Errorf("Installed() = %v, want none", labels)
+	}
+}
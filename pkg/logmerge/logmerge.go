@@ -0,0 +1,155 @@
+// Package logmerge interleaves the log streams of several services in
+// timestamp order rather than in the order their lines arrive.
+package logmerge
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Line is a log line of a service.
+type Line struct {
+	Service string
+	Text    string
+	// Time is the line's timestamp, or for a line without one the last
+	// timestamp of its stream, or its arrival.
+	Time time.Time
+}
+
+// layouts are the timestamp forms recognized at the start of a line: the
+// runtime's and most loggers' RFC 3339, and the space-separated form
+// common in application logs, read as local time. Fractional seconds are
+// optional in both.
+var layouts = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+// ParseTime returns the timestamp a log line starts with, if any. The
+// timestamp may be in square brackets.
+func ParseTime(text string) (time.Time, bool) {
+	text = strings.TrimPrefix(text, "[")
+	field := func(s string) string {
+		if i := strings.IndexAny(s, " ]"); i >= 0 {
+			return s[:i]
+		}
+		return s
+	}
+	first := field(text)
+	candidates := []string{first}
+	if rest, ok := strings.CutPrefix(text[len(first):], " "); ok {
+		candidates = append(candidates, first+" "+field(rest))
+	}
+	for _, c := range candidates {
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, c, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// pending is a line waiting to be emitted.
+type pending struct {
+	Line
+	arrived time.Time
+	seq     int
+}
+
+// Merger orders the lines written to its streams by timestamp. Each line
+// is held for a window after it arrives, so a line a slower stream
+// delivers late can still be placed before newer lines of the others;
+// Release emits the lines whose window has passed and Flush all of them.
+// It is safe for concurrent use.
+type Merger struct {
+	window time.Duration
+	emit   func(Line)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending []pending
+	last    map[string]time.Time
+	seq     int
+}
+
+// New returns a Merger that holds lines for window and passes them to emit
+// in order.
+func New(window time.Duration, emit func(Line)) *Merger {
+	return &Merger{window: window, emit: emit, now: time.Now, last: make(map[string]time.Time)}
+}
+
+// Add queues a line of a service.
+func (m *Merger) Add(service, text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	arrived := m.now()
+	t, ok := ParseTime(text)
+	if !ok {
+		// Continuation lines, such as a stack trace's, stay with the
+		// line before them.
+		t = m.last[service]
+		if t.IsZero() {
+			t = arrived
+		}
+	}
+	m.last[service] = t
+	m.seq++
+	m.pending = append(m.pending, pending{Line: Line{Service: service, Text: text, Time: t}, arrived: arrived, seq: m.seq})
+}
+
+// Release emits, in order, the lines held for the window by now. A line
+// still in its window holds back the lines ordered after it.
+func (m *Merger) Release(now time.Time) {
+	m.release(func(p pending) bool { return !p.arrived.After(now.Add(-m.window)) })
+}
+
+// Flush emits every queued line in order.
+func (m *Merger) Flush() {
+	m.release(func(pending) bool { return true })
+}
+
+func (m *Merger) release(due func(pending) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sort.SliceStable(m.pending, func(i, j int) bool {
+		a, b := m.pending[i], m.pending[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return a.seq < b.seq
+	})
+	n := 0
+	for n < len(m.pending) && due(m.pending[n]) {
+		m.emit(m.pending[n].Line)
+		n++
+	}
+	m.pending = m.pending[n:]
+}
+
+// Writer returns a writer whose lines are added as the service's.
+func (m *Merger) Writer(service string) io.Writer {
+	return &streamWriter{m: m, service: service}
+}
+
+// streamWriter splits a service's output into lines for its Merger. A
+// last line without a newline is added when the next write ends it, or
+// never; callers end their streams with a newline.
+type streamWriter struct {
+	m       *Merger
+	service string
+	buf     []byte
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		w.m.Add(w.service, strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+}
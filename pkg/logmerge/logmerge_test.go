@@ -0,0 +1,68 @@
+package logmerge
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := map[string]string{
+		"2024-05-01T10:00:00.123456789Z GET /":   "2024-05-01T10:00:00.123456789Z",
+		"2024-05-01T10:00:00+02:00 started":      "2024-05-01T08:00:00Z",
+		"[2024-05-01T10:00:01Z] INFO ready":      "2024-05-01T10:00:01Z",
+		"2024-05-01 10:00:02.5 LOG:  checkpoint": "2024-05-01T10:00:02.5Z",
+		"[2024-05-01 10:00:03,250] WARN slow":    "2024-05-01T10:00:03.25Z",
+		"2024-05-01 10:00:04":                    "2024-05-01T10:00:04Z",
+	}
+	for text, want := range tests {
+		got, ok := ParseTime(text)
+		if !ok {
+			t.Errorf("ParseTime(%q) found no timestamp", text)
+			continue
+		}
+		// Space-separated stamps are local; compare them as if UTC.
+		if got.Location() == time.Local {
+			got = time.Date(got.Year(), got.Month(), got.Day(), got.Hour(), got.Minute(), got.Second(), got.Nanosecond(), time.UTC)
+		}
+		if w, _ := time.Parse(time.RFC3339Nano, want); !got.Equal(w) {
+			t.Errorf("ParseTime(%q) = %v, want %v", text, got, w)
+		}
+	}
+	for _, text := range []string{"GET / 200", "", "  at main.go:12", "2024-05-01"} {
+		if got, ok := ParseTime(text); ok {
+			t.Errorf("ParseTime(%q) = %v, want none", text, got)
+		}
+	}
+}
+
+func TestMerger(t *testing.T) {
+	var got []string
+	m := New(100*time.Millisecond, func(l Line) { got = append(got, l.Service+": "+l.Text) })
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+
+	fmt.Fprint(m.Writer("web"), "2024-05-01T10:00:01Z GET /\n2024-05-01T10:00:03Z GET /x\npanic: boom\n")
+	clock = clock.Add(50 * time.Millisecond)
+	fmt.Fprint(m.Writer("db"), "2024-05-01T10:00:02Z LOG: query\r\n2024-05-01T10:00:04Z LOG: done\n")
+
+	// web's lines are due, but db's earlier line arrived later and still
+	// holds back everything ordered after it.
+	m.Release(clock.Add(60 * time.Millisecond))
+	if want := []string{"web: 2024-05-01T10:00:01Z GET /"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("released %q, want %q", got, want)
+	}
+
+	m.Flush()
+	want := []string{
+		"web: 2024-05-01T10:00:01Z GET /",
+		"db: 2024-05-01T10:00:02Z LOG: query",
+		"web: 2024-05-01T10:00:03Z GET /x",
+		"web: panic: boom",
+		"db: 2024-05-01T10:00:04Z LOG: done",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged\n%q\nwant\n%q", got, want)
+	}
+}
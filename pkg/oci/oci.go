@@ -0,0 +1,462 @@
+package oci
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Media types used by docker compose's OCI artifact format.
+const (
+	ArtifactType      = "application/vnd.docker.compose.project"
+	ComposeMediaType  = "application/vnd.docker.compose.file+yaml"
+	EnvFileMediaType  = "application/vnd.docker.compose.envfile"
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	emptyMediaType    = "application/vnd.oci.empty.v1+json"
+)
+
+// Annotation keys naming the files carried by each layer.
+const (
+	ComposeFileAnnotation = "com.docker.compose.file"
+	EnvFileAnnotation     = "com.docker.compose.envfile"
+)
+
+// imageManifestTypes are the manifest media types container images are
+// published with.
+var imageManifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	ManifestMediaType,
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// emptyConfig is the OCI empty descriptor payload used as the artifact config.
+var emptyConfig = []byte("{}")
+
+// HTTPClient is used for all registry requests.
+var HTTPClient = http.DefaultClient
+
+// Reference is a parsed registry/repository:tag or @digest reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses a reference such as ghcr.io/acme/stack:1.0,
+// with or without an oci:// prefix. The tag defaults to "latest".
+func ParseReference(s string) (*Reference, error) {
+	s = strings.TrimPrefix(s, "oci://")
+	registry, rest, ok := strings.Cut(s, "/")
+	if !ok || registry == "" || rest == "" {
+		return nil, fmt.Errorf("invalid reference %q: expected REGISTRY/REPOSITORY[:TAG]", s)
+	}
+
+	ref := &Reference{Registry: registry}
+	if repo, digest, ok := strings.Cut(rest, "@"); ok {
+		ref.Repository = repo
+		ref.Digest = digest
+	} else if i := strings.LastIndex(rest, ":"); i >= 0 {
+		ref.Repository = rest[:i]
+		ref.Tag = rest[i+1:]
+	} else {
+		ref.Repository = rest
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	if ref.Repository == "" {
+		return nil, fmt.Errorf("invalid reference %q: missing repository", s)
+	}
+	return ref, nil
+}
+
+// String returns the reference without a scheme.
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Registry + "/" + r.Repository + "@" + r.Digest
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// version returns the tag or digest used to address the manifest.
+func (r *Reference) version() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Descriptor is an OCI content descriptor.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest carrying an artifact.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	ArtifactType  string       `json:"artifactType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Layer is a file stored in a compose artifact.
+type Layer struct {
+	MediaType string
+	Name      string // value of the file annotation
+	Data      []byte
+}
+
+// digestOf returns the sha256 digest of data in OCI form.
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Push uploads layers as a compose artifact and tags the manifest.
+// It returns the manifest digest.
+func Push(ref *Reference, layers []Layer) (string, error) {
+	c := newClient(ref, "push,pull")
+
+	if err := c.pushBlob(emptyConfig); err != nil {
+		return "", err
+	}
+
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config: Descriptor{
+			MediaType: emptyMediaType,
+			Digest:    digestOf(emptyConfig),
+			Size:      int64(len(emptyConfig)),
+		},
+	}
+	for _, l := range layers {
+		if err := c.pushBlob(l.Data); err != nil {
+			return "", err
+		}
+		key := ComposeFileAnnotation
+		if l.MediaType == EnvFileMediaType {
+			key = EnvFileAnnotation
+		}
+		manifest.Layers = append(manifest.Layers, Descriptor{
+			MediaType:   l.MediaType,
+			Digest:      digestOf(l.Data),
+			Size:        int64(len(l.Data)),
+			Annotations: map[string]string{key: l.Name},
+		})
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("marshaling manifest: %w", err)
+	}
+	resp, err := c.do(http.MethodPut, "/manifests/"+ref.version(), ManifestMediaType, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("pushing manifest: unexpected status %s", resp.Status)
+	}
+	return digestOf(data), nil
+}
+
+// Pull downloads a compose artifact and returns its layers in manifest order.
+func Pull(ref *Reference) ([]Layer, error) {
+	c := newClient(ref, "pull")
+
+	resp, err := c.do(http.MethodGet, "/manifests/"+ref.version(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching manifest for %s: unexpected status %s", ref, resp.Status)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("parsing manifest for %s: %w", ref, err)
+	}
+	if manifest.ArtifactType != "" && manifest.ArtifactType != ArtifactType {
+		return nil, fmt.Errorf("%s is not a compose artifact (artifactType %s)", ref, manifest.ArtifactType)
+	}
+
+	var layers []Layer
+	for _, d := range manifest.Layers {
+		var name string
+		switch d.MediaType {
+		case ComposeMediaType:
+			name = d.Annotations[ComposeFileAnnotation]
+		case EnvFileMediaType:
+			name = d.Annotations[EnvFileAnnotation]
+		default:
+			continue
+		}
+		data, err := c.fetchBlob(d)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, Layer{MediaType: d.MediaType, Name: name, Data: data})
+	}
+	return layers, nil
+}
+
+// ManifestDigest returns the digest of the manifest ref currently points
+// to, for images the index covering every platform, without downloading
+// it. It is compared with the digest of a local image to tell whether a
+// pull would fetch anything new.
+func ManifestDigest(ref *Reference) (string, error) {
+	c := newClient(ref, "pull")
+	c.accept = strings.Join(imageManifestTypes, ", ")
+
+	resp, err := c.do(http.MethodHead, "/manifests/"+ref.version(), "", nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("checking manifest for %s: unexpected status %s", ref, resp.Status)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Registries need not report the digest; the manifest's bytes give it.
+	resp, err = c.do(http.MethodGet, "/manifests/"+ref.version(), "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching manifest for %s: unexpected status %s", ref, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading manifest for %s: %w", ref, err)
+	}
+	return digestOf(data), nil
+}
+
+// client issues authenticated requests against one repository.
+type client struct {
+	ref    *Reference
+	scope  string
+	auth   string
+	accept string // manifest media types requested; ManifestMediaType when empty
+}
+
+func newClient(ref *Reference, actions string) *client {
+	return &client{ref: ref, scope: "repository:" + ref.Repository + ":" + actions}
+}
+
+// baseURL returns the repository's API root. DCTL_REGISTRY_INSECURE=1 selects plain HTTP.
+// Docker Hub's API is served from registry-1.docker.io.
+func (c *client) baseURL() string {
+	scheme := "https"
+	if os.Getenv("DCTL_REGISTRY_INSECURE") == "1" {
+		scheme = "http"
+	}
+	host := c.ref.Registry
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	return scheme + "://" + host + "/v2/" + c.ref.Repository
+}
+
+// do sends a request, performing the bearer/basic auth handshake on a 401.
+func (c *client) do(method, path, contentType string, body []byte) (*http.Response, error) {
+	target := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		target = c.baseURL() + path
+	}
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", cmp.Or(c.accept, ManifestMediaType))
+		if c.auth != "" {
+			req.Header.Set("Authorization", c.auth)
+		}
+		return HTTPClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, target, err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || c.auth != "" {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	if err := c.authenticate(challenge); err != nil {
+		return nil, err
+	}
+	resp, err = send()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, target, err)
+	}
+	return resp, nil
+}
+
+// authenticate answers a WWW-Authenticate challenge using
+// DCTL_REGISTRY_USERNAME/DCTL_REGISTRY_PASSWORD when set.
+func (c *client) authenticate(challenge string) error {
+	user := os.Getenv("DCTL_REGISTRY_USERNAME")
+	pass := os.Getenv("DCTL_REGISTRY_PASSWORD")
+
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if user == "" {
+			return fmt.Errorf("registry %s requires credentials (set DCTL_REGISTRY_USERNAME and DCTL_REGISTRY_PASSWORD)", c.ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, pass)
+		c.auth = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("registry %s: unsupported auth challenge %q", c.ref.Registry, challenge)
+	}
+
+	attrs := parseChallenge(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || attrs["realm"] == "" {
+		return fmt.Errorf("registry %s: invalid auth realm %q", c.ref.Registry, attrs["realm"])
+	}
+	q := realm.Query()
+	if svc := attrs["service"]; svc != "" {
+		q.Set("service", svc)
+	}
+	q.Set("scope", c.scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching registry token: unexpected status %s", resp.Status)
+	}
+
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("parsing registry token: %w", err)
+	}
+	if tok.Token == "" {
+		tok.Token = tok.AccessToken
+	}
+	c.auth = "Bearer " + tok.Token
+	return nil
+}
+
+// parseChallenge parses key="value" pairs from a WWW-Authenticate header.
+func parseChallenge(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			attrs[k] = strings.Trim(v, `"`)
+		}
+	}
+	return attrs
+}
+
+// pushBlob uploads data unless the registry already has it.
+func (c *client) pushBlob(data []byte) error {
+	digest := digestOf(data)
+
+	resp, err := c.do(http.MethodHead, "/blobs/"+digest, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = c.do(http.MethodPost, "/blobs/uploads/", "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("starting blob upload: unexpected status %s", resp.Status)
+	}
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location: %w", err)
+	}
+	if !loc.IsAbs() {
+		base, _ := url.Parse(c.baseURL())
+		loc = base.ResolveReference(loc)
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	resp, err = c.do(http.MethodPut, loc.String(), "application/octet-stream", data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("uploading blob %s: unexpected status %s", digest, resp.Status)
+	}
+	return nil
+}
+
+// fetchBlob downloads a blob and verifies its digest.
+func (c *client) fetchBlob(d Descriptor) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, "/blobs/"+d.Digest, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching blob %s: unexpected status %s", d.Digest, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob %s: %w", d.Digest, err)
+	}
+	if got := digestOf(data); got != d.Digest {
+		return nil, fmt.Errorf("blob digest mismatch: got %s, want %s", got, d.Digest)
+	}
+	return data, nil
+}
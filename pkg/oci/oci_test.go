@@ -0,0 +1,166 @@
+package oci
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry is a minimal in-memory OCI distribution server.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	token     string // when set, requests require "Bearer <token>"
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		w.Write([]byte(`{"token":"` + r.token + `"}`))
+		return
+	}
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+req.Host+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/acme/stack")
+	switch {
+	case strings.HasPrefix(path, "/blobs/uploads/"):
+		if req.Method == http.MethodPost {
+			w.Header().Set("Location", "/v2/acme/stack/blobs/uploads/1")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := io.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/blobs/"):
+		data, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case strings.HasPrefix(path, "/manifests/"):
+		tag := strings.TrimPrefix(path, "/manifests/")
+		if req.Method == http.MethodPut {
+			data, _ := io.ReadAll(req.Body)
+			r.manifests[tag] = data
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		data, ok := r.manifests[tag]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func startRegistry(t *testing.T, token string) string {
+	t.Helper()
+	reg := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}, token: token}
+	srv := httptest.NewTLSServer(reg)
+	t.Cleanup(srv.Close)
+
+	orig := HTTPClient
+	HTTPClient = srv.Client()
+	t.Cleanup(func() { HTTPClient = orig })
+	return strings.TrimPrefix(srv.URL, "https://")
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Reference
+	}{
+		{"oci://ghcr.io/acme/stack:1.0", Reference{Registry: "ghcr.io", Repository: "acme/stack", Tag: "1.0"}},
+		{"localhost:5000/stack", Reference{Registry: "localhost:5000", Repository: "stack", Tag: "latest"}},
+		{"ghcr.io/acme/stack@sha256:abc", Reference{Registry: "ghcr.io", Repository: "acme/stack", Digest: "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseReference(tt.in)
+		if err != nil {
+			t.Fatalf("ParseReference(%q) error: %v", tt.in, err)
+		}
+		if *got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+
+	if _, err := ParseReference("stack"); err == nil {
+		t.Error("expected error for reference without registry")
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	for _, token := range []string{"", "s3cret"} {
+		name := "anonymous"
+		if token != "" {
+			name = "bearer"
+		}
+		t.Run(name, func(t *testing.T) {
+			host := startRegistry(t, token)
+			ref, err := ParseReference(host + "/acme/stack:1.0")
+			if err != nil {
+				t.Fatalf("ParseReference() error: %v", err)
+			}
+
+			layers := []Layer{
+				{MediaType: ComposeMediaType, Name: "compose.yaml", Data: []byte("services: {}\n")},
+				{MediaType: EnvFileMediaType, Name: ".env", Data: []byte("TAG=1.0\n")},
+			}
+			digest, err := Push(ref, layers)
+			if err != nil {
+				t.Fatalf("Push() error: %v", err)
+			}
+			if !strings.HasPrefix(digest, "sha256:") {
+				t.Errorf("Push() digest = %q", digest)
+			}
+
+			got, err := Pull(ref)
+			if err != nil {
+				t.Fatalf("Pull() error: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("Pull() returned %d layers, want 2", len(got))
+			}
+			for i := range layers {
+				if got[i].Name != layers[i].Name || string(got[i].Data) != string(layers[i].Data) || got[i].MediaType != layers[i].MediaType {
+					t.Errorf("layer %d = %+v, want %+v", i, got[i], layers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestManifestDigest(t *testing.T) {
+	host := startRegistry(t, "s3cret")
+	ref, err := ParseReference(host + "/acme/stack:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ManifestDigest(ref); err == nil {
+		t.Error("ManifestDigest() of a missing tag succeeded")
+	}
+	digest, err := Push(ref, []Layer{{MediaType: ComposeMediaType, Name: "compose.yaml", Data: []byte("services: {}\n")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fake registry sends no Docker-Content-Digest, so the manifest is
+	// fetched and hashed.
+	if got, err := ManifestDigest(ref); err != nil || got != digest {
+		t.Errorf("ManifestDigest() = %q, %v, want %q", got, err, digest)
+	}
+}
@@ -0,0 +1,213 @@
+// Package portcheck finds requested host ports that are already taken,
+// so up can fail before starting anything instead of mid-way with a
+// runtime error.
+package portcheck
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// Request is a host port a service asks to publish.
+type Request struct {
+	Service string
+	Port    compose.PortMapping
+}
+
+// Conflict is a requested host port that is already taken.
+type Conflict struct {
+	Request
+	Holder string // "container NAME" or "service NAME"; empty for a host process
+}
+
+func (c Conflict) String() string {
+	holder := c.Holder
+	if holder == "" {
+		holder = "another process"
+	}
+	return fmt.Sprintf("service %s: host port %s is already in use by %s", c.Service, hostPort(c.Port), holder)
+}
+
+// available reports whether a host port can be bound. Tests replace it.
+var available = func(protocol, hostIP string, port int) bool {
+	addr := net.JoinHostPort(hostIP, strconv.Itoa(port))
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// freePort asks the kernel for an unused host port. Tests replace it.
+var freePort = func(protocol, hostIP string) (int, error) {
+	addr := net.JoinHostPort(hostIP, "0")
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return 0, fmt.Errorf("finding a free udp port: %w", err)
+		}
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return 0, fmt.Errorf("finding a free tcp port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Assign picks a free host port for every mapping in specs that does not
+// request one ("80" or "0:80"), returning one spec per mapping. Specs that
+// already name their host ports are returned unchanged.
+func Assign(specs []string) ([]string, error) {
+	var out []string
+	taken := make(map[string]bool) // port/protocol, regardless of host IP
+	key := func(m compose.PortMapping) string {
+		return strconv.Itoa(m.Published) + "/" + protocol(m.Protocol)
+	}
+	for _, spec := range specs {
+		mappings, err := compose.ParsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(mappings, func(m compose.PortMapping) bool { return m.Published == 0 }) {
+			for _, m := range mappings {
+				taken[key(m)] = true
+			}
+			out = append(out, spec)
+			continue
+		}
+		for _, m := range mappings {
+			for m.Published == 0 || taken[key(m)] {
+				if m.Published, err = freePort(protocol(m.Protocol), m.HostIP); err != nil {
+					return nil, err
+				}
+			}
+			taken[key(m)] = true
+			out = append(out, m.String())
+		}
+	}
+	return out, nil
+}
+
+// Check returns the requests whose host port is requested twice, published
+// by a running container, or bound by a host process. Containers in own
+// belong to the project being started; their ports are expected to be
+// released when they are recreated.
+func Check(reqs []Request, containers []runner.ContainerInfo, own map[string]bool) []Conflict {
+	var conflicts []Conflict
+	for i, req := range reqs {
+		if req.Port.Published == 0 {
+			continue
+		}
+		if holder, ok := heldByService(req, reqs[:i]); ok {
+			conflicts = append(conflicts, Conflict{Request: req, Holder: "service " + holder})
+			continue
+		}
+		holder, ok := heldByContainer(req.Port, containers)
+		if ok && own[holder] {
+			continue
+		}
+		if ok {
+			conflicts = append(conflicts, Conflict{Request: req, Holder: "container " + holder})
+			continue
+		}
+		if !available(protocol(req.Port.Protocol), req.Port.HostIP, req.Port.Published) {
+			conflicts = append(conflicts, Conflict{Request: req})
+		}
+	}
+	return conflicts
+}
+
+// waitInterval is how often WaitFree checks the ports again.
+var waitInterval = 100 * time.Millisecond
+
+// WaitFree waits up to timeout for a container's published host ports to
+// become bindable, as they may still be releasing right after the container
+// stopped. It returns the ports still taken.
+func WaitFree(ports []runner.PublishedPort, timeout time.Duration) []runner.PublishedPort {
+	deadline := time.Now().Add(timeout)
+	for {
+		var taken []runner.PublishedPort
+		for _, p := range ports {
+			if !available(protocol(p.Proto), p.HostAddress, p.HostPort) {
+				taken = append(taken, p)
+			}
+		}
+		if len(taken) == 0 || !time.Now().Before(deadline) {
+			return taken
+		}
+		time.Sleep(waitInterval)
+		ports = taken
+	}
+}
+
+// heldByService returns the earlier request for the same host port.
+func heldByService(req Request, earlier []Request) (string, bool) {
+	for _, e := range earlier {
+		if e.Port.Published == req.Port.Published &&
+			protocol(e.Port.Protocol) == protocol(req.Port.Protocol) &&
+			overlaps(e.Port.HostIP, req.Port.HostIP) {
+			return e.Service, true
+		}
+	}
+	return "", false
+}
+
+// heldByContainer returns the running container publishing the port.
+func heldByContainer(p compose.PortMapping, containers []runner.ContainerInfo) (string, bool) {
+	for _, c := range containers {
+		if c.Status != "running" {
+			continue
+		}
+		for _, pp := range c.Configuration.PublishedPorts {
+			if pp.HostPort == p.Published &&
+				protocol(pp.Proto) == protocol(p.Protocol) &&
+				overlaps(pp.HostAddress, p.HostIP) {
+				return c.Configuration.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// overlaps reports whether two host addresses can collide: they are equal
+// or either binds every address.
+func overlaps(a, b string) bool {
+	return a == b || wildcard(a) || wildcard(b)
+}
+
+func wildcard(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::" || ip == "[::]"
+}
+
+func protocol(p string) string {
+	if p == "" {
+		return "tcp"
+	}
+	return p
+}
+
+func hostPort(p compose.PortMapping) string {
+	s := strconv.Itoa(p.Published) + "/" + protocol(p.Protocol)
+	if !wildcard(p.HostIP) {
+		s = net.JoinHostPort(p.HostIP, s)
+	}
+	return s
+}
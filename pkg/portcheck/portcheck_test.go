@@ -0,0 +1,118 @@
+package portcheck
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func container(name, status string, ports ...runner.PublishedPort) runner.ContainerInfo {
+	return runner.ContainerInfo{
+		Status:        status,
+		Configuration: runner.ContainerConfig{ID: name, PublishedPorts: ports},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	busy := map[int]bool{9000: true}
+	orig := available
+	available = func(protocol, hostIP string, port int) bool { return !busy[port] }
+	defer func() { available = orig }()
+
+	tcp := func(service string, published int) Request {
+		return Request{Service: service, Port: compose.PortMapping{Published: published, Target: 80, Protocol: "tcp"}}
+	}
+	reqs := []Request{
+		tcp("web", 8080),
+		tcp("api", 8080),   // duplicate of web
+		tcp("db", 5432),    // held by another project's container
+		tcp("cache", 6379), // held by this project's own container
+		tcp("admin", 9000), // bound by a host process
+		tcp("free", 3000),
+		{Service: "dns", Port: compose.PortMapping{Published: 5432, Target: 53, Protocol: "udp"}}, // different protocol
+		{Service: "side", Port: compose.PortMapping{Target: 80}},                                  // no host port
+		{Service: "local", Port: compose.PortMapping{HostIP: "127.0.0.1", Published: 4000, Target: 80}},
+	}
+	containers := []runner.ContainerInfo{
+		container("blog_db", "running", runner.PublishedPort{HostPort: 5432, ContainerPort: 5432, Proto: "tcp"}),
+		container("shop_cache", "running", runner.PublishedPort{HostPort: 6379, ContainerPort: 6379}),
+		container("old_web", "stopped", runner.PublishedPort{HostPort: 3000, ContainerPort: 80}),
+		container("other", "running", runner.PublishedPort{HostAddress: "10.0.0.1", HostPort: 4000, ContainerPort: 80}),
+	}
+
+	got := Check(reqs, containers, map[string]bool{"shop_cache": true})
+	want := []string{
+		"service api: host port 8080/tcp is already in use by service web",
+		"service db: host port 5432/tcp is already in use by container blog_db",
+		"service admin: host port 9000/tcp is already in use by another process",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Check() = %v, want %d conflicts", got, len(want))
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("conflict %d = %q, want %q", i, got[i].String(), want[i])
+		}
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	if available("tcp", "127.0.0.1", port) {
+		t.Errorf("available(%d) = true while listening", port)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	next := []int{40000, 40000, 40001, 40002}
+	orig := freePort
+	freePort = func(protocol, hostIP string) (int, error) {
+		port := next[0]
+		next = next[1:]
+		return port, nil
+	}
+	defer func() { freePort = orig }()
+
+	got, err := Assign([]string{"8080:80", "0:443", "127.0.0.1::9000", "53/udp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"8080:80", "40000:443", "127.0.0.1:40001:9000", "40002:53/udp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Assign() = %v, want %v", got, want)
+	}
+}
+
+func TestWaitFree(t *testing.T) {
+	checks := make(map[int]int)
+	orig, origInterval := available, waitInterval
+	available = func(protocol, hostIP string, port int) bool {
+		checks[port]++
+		return port != 9000 && checks[port] > 2 // 8080 frees up on the third check
+	}
+	waitInterval = time.Millisecond
+	defer func() { available, waitInterval = orig, origInterval }()
+
+	ports := []runner.PublishedPort{{HostPort: 8080, ContainerPort: 80}, {HostPort: 9000, ContainerPort: 90}}
+	taken := WaitFree(ports, 50*time.Millisecond)
+	if len(taken) != 1 || taken[0].HostPort != 9000 {
+		t.Errorf("WaitFree() = %v, want only 9000 taken", taken)
+	}
+	if checks[8080] != 3 {
+		t.Errorf("8080 checked %d times, want 3", checks[8080])
+	}
+
+	checks = make(map[int]int)
+	if taken := WaitFree(ports, 0); len(taken) != 2 {
+		t.Errorf("WaitFree() with no timeout = %v, want both taken", taken)
+	}
+}
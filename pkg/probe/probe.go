@@ -0,0 +1,128 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout is how long Wait polls a target when no timeout is given.
+const DefaultTimeout = 60 * time.Second
+
+// DefaultInterval is the delay between probe attempts when no interval is given.
+const DefaultInterval = time.Second
+
+// Target is a parsed readiness probe destination.
+type Target struct {
+	Scheme string // tcp, http or https
+	Host   string
+	Port   string
+	URL    *url.URL
+}
+
+// Parse parses a probe target such as tcp://db:5432 or http://web:8080/health.
+func Parse(raw string) (*Target, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid probe target %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("invalid probe target %q: tcp targets require a port", raw)
+		}
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("invalid probe target %q: scheme must be tcp, http or https", raw)
+	}
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid probe target %q: missing host", raw)
+	}
+
+	return &Target{
+		Scheme: u.Scheme,
+		Host:   u.Hostname(),
+		Port:   u.Port(),
+		URL:    u,
+	}, nil
+}
+
+// WithHost returns a copy of the target pointing at a different host.
+func (t *Target) WithHost(host string) *Target {
+	u := *t.URL
+	if t.Port != "" {
+		u.Host = net.JoinHostPort(host, t.Port)
+	} else {
+		u.Host = host
+	}
+	return &Target{Scheme: t.Scheme, Host: host, Port: t.Port, URL: &u}
+}
+
+// String returns the target as a URL.
+func (t *Target) String() string {
+	return t.URL.String()
+}
+
+// Check performs a single probe attempt.
+// TCP targets succeed when a connection can be opened; HTTP targets succeed
+// on any 2xx or 3xx response.
+func Check(ctx context.Context, t *Target) error {
+	switch t.Scheme {
+	case "tcp":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(t.Host, t.Port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL.String(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Wait polls the target until a probe succeeds or the timeout expires.
+// Zero values for timeout and interval select the defaults.
+func Wait(ctx context.Context, t *Target, timeout, interval time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		attemptCtx, attemptCancel := context.WithTimeout(ctx, interval)
+		lastErr = Check(attemptCtx, t)
+		attemptCancel()
+		if lastErr == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s: %w", timeout, t, lastErr)
+		case <-time.After(interval):
+		}
+	}
+}
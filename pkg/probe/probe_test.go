@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw     string
+		scheme  string
+		host    string
+		port    string
+		wantErr string
+	}{
+		{raw: "tcp://db:5432", scheme: "tcp", host: "db", port: "5432"},
+		{raw: "http://web:8080/health", scheme: "http", host: "web", port: "8080"},
+		{raw: "https://api/ready", scheme: "https", host: "api"},
+		{raw: "tcp://db", wantErr: "require a port"},
+		{raw: "udp://db:53", wantErr: "scheme must be"},
+		{raw: "http:///health", wantErr: "missing host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			target, err := Parse(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want containing %q", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.raw, err)
+			}
+			if target.Scheme != tt.scheme || target.Host != tt.host || target.Port != tt.port {
+				t.Errorf("Parse(%q) = %s/%s/%s, want %s/%s/%s",
+					tt.raw, target.Scheme, target.Host, target.Port, tt.scheme, tt.host, tt.port)
+			}
+		})
+	}
+}
+
+func TestTarget_WithHost(t *testing.T) {
+	target, err := Parse("http://web:8080/health")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	got := target.WithHost("192.168.64.3").String()
+	if got != "http://192.168.64.3:8080/health" {
+		t.Errorf("WithHost() = %q, want %q", got, "http://192.168.64.3:8080/health")
+	}
+	if target.Host != "web" {
+		t.Errorf("original target host changed to %q", target.Host)
+	}
+}
+
+func TestWait_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	target, err := Parse("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if err := Wait(context.Background(), target, time.Second, 50*time.Millisecond); err != nil {
+		t.Errorf("Wait() error: %v", err)
+	}
+}
+
+func TestWait_HTTP(t *testing.T) {
+	ready := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-ready:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	target, err := Parse(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() { close(ready) })
+	if err := Wait(context.Background(), target, 2*time.Second, 20*time.Millisecond); err != nil {
+		t.Errorf("Wait() error: %v", err)
+	}
+}
+
+func TestWait_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	target, err := Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	err = Wait(context.Background(), target, 100*time.Millisecond, 20*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Wait() error = %v, want timeout", err)
+	}
+}
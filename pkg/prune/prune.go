@@ -0,0 +1,208 @@
+package prune
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+// Filter matches container labels: label=KEY, label=KEY=VALUE, or the
+// negated label!=... forms.
+type Filter struct {
+	Key    string
+	Value  string
+	HasVal bool
+	Negate bool
+}
+
+// ParseFilters parses --filter arguments.
+func ParseFilters(specs []string) ([]Filter, error) {
+	var filters []Filter
+	for _, spec := range specs {
+		var f Filter
+		var rest string
+		switch {
+		case strings.HasPrefix(spec, "label!="):
+			f.Negate = true
+			rest = strings.TrimPrefix(spec, "label!=")
+		case strings.HasPrefix(spec, "label="):
+			rest = strings.TrimPrefix(spec, "label=")
+		default:
+			return nil, fmt.Errorf("unsupported filter %q (supported: label=KEY[=VALUE], label!=KEY[=VALUE])", spec)
+		}
+		f.Key, f.Value, f.HasVal = strings.Cut(rest, "=")
+		if f.Key == "" {
+			return nil, fmt.Errorf("invalid filter %q: missing label key", spec)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// matches reports whether labels satisfy the filter.
+func (f Filter) matches(labels map[string]string) bool {
+	v, ok := labels[f.Key]
+	hit := ok && (!f.HasVal || v == f.Value)
+	return hit != f.Negate
+}
+
+// Options controls what is pruned.
+type Options struct {
+	AllImages bool // remove every unused image, not only dangling ones
+	Volumes   bool // also remove unused volumes
+	Filters   []Filter
+	// Owned holds the networks and volumes of every dctl project. Filters
+	// only select containers, and other tools' networks and volumes carry
+	// nothing dctl could match, so a global prune removes only these.
+	Owned *Scope
+}
+
+// Scope limits pruning to one project's resources. A nil scope is global.
+type Scope struct {
+	Containers map[string]bool
+	Networks   map[string]bool
+	Volumes    map[string]bool
+}
+
+// Resources is a snapshot of everything the runtime knows about.
+type Resources struct {
+	Containers []runner.ContainerInfo
+	Images     []runner.ImageInfo
+	Networks   []runner.NetworkInfo
+	Volumes    []runner.VolumeInfo
+}
+
+// Plan lists the resources a prune will remove.
+type Plan struct {
+	Containers []string
+	Images     []runner.ImageInfo
+	Networks   []string
+	Volumes    []string
+}
+
+// Empty reports whether the plan removes nothing.
+func (p Plan) Empty() bool {
+	return len(p.Containers) == 0 && len(p.Images) == 0 && len(p.Networks) == 0 && len(p.Volumes) == 0
+}
+
+// ImageBytes is the total size of the images the plan removes.
+func (p Plan) ImageBytes() int64 {
+	var n int64
+	for _, img := range p.Images {
+		n += img.Descriptor.Size
+	}
+	return n
+}
+
+// Compute decides what to remove. Stopped containers matching the scope and
+// filters go first; networks, volumes and images are removable only when no
+// remaining container uses them, and networks and volumes only when the
+// scope, or for a global prune opts.Owned, holds them. The default network
+// is never removed.
+func Compute(res Resources, scope *Scope, opts Options) Plan {
+	var plan Plan
+	owned := scope
+	if owned == nil {
+		owned = opts.Owned
+	}
+
+	usedNetworks := make(map[string]bool)
+	usedVolumes := make(map[string]bool)
+	usedImages := make(map[string]bool)
+	removedImages := make(map[string]bool)
+
+	for _, c := range res.Containers {
+		cfg := c.Configuration
+		if removableContainer(c, scope, opts.Filters) {
+			plan.Containers = append(plan.Containers, cfg.ID)
+			removedImages[cfg.Image.Reference] = true
+			continue
+		}
+		usedImages[cfg.Image.Reference] = true
+		for _, n := range cfg.NetworkNames() {
+			usedNetworks[n] = true
+		}
+		for _, m := range cfg.Mounts {
+			if m.Kind() == "volume" {
+				usedVolumes[m.VolumeName()] = true
+			}
+		}
+	}
+
+	for _, n := range res.Networks {
+		if n.ID == "default" || usedNetworks[n.ID] {
+			continue
+		}
+		if owned == nil || !owned.Networks[n.ID] {
+			continue
+		}
+		plan.Networks = append(plan.Networks, n.ID)
+	}
+
+	if opts.Volumes {
+		for _, v := range res.Volumes {
+			if usedVolumes[v.Name] {
+				continue
+			}
+			if owned == nil || !owned.Volumes[v.Name] {
+				continue
+			}
+			plan.Volumes = append(plan.Volumes, v.Name)
+		}
+	}
+
+	for _, img := range res.Images {
+		if usedImages[img.Reference] {
+			continue
+		}
+		if scope != nil {
+			// Dangling images cannot be attributed to a project, so a
+			// project prune only removes images its own containers used.
+			if opts.AllImages && removedImages[img.Reference] {
+				plan.Images = append(plan.Images, img)
+			}
+			continue
+		}
+		if img.Dangling() || opts.AllImages {
+			plan.Images = append(plan.Images, img)
+		}
+	}
+
+	sort.Strings(plan.Containers)
+	sort.Strings(plan.Networks)
+	sort.Strings(plan.Volumes)
+	return plan
+}
+
+func removableContainer(c runner.ContainerInfo, scope *Scope, filters []Filter) bool {
+	if c.Status == "running" {
+		return false
+	}
+	if scope != nil && !scope.Containers[c.Configuration.ID] {
+		return false
+	}
+	for _, f := range filters {
+		if !f.matches(c.Configuration.Labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// HumanSize formats a byte count using decimal units, like docker does.
+func HumanSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	units := []string{"kB", "MB", "GB", "TB"}
+	f := float64(n) / unit
+	i := 0
+	for f >= unit && i < len(units)-1 {
+		f /= unit
+		i++
+	}
+	return fmt.Sprintf("%.3g%s", f, units[i])
+}
@@ -0,0 +1,144 @@
+package prune
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/runner"
+)
+
+func container(id, status, image string, labels map[string]string, networks []string, volumes ...string) runner.ContainerInfo {
+	raw, _ := json.Marshal(networks)
+	c := runner.ContainerInfo{Status: status}
+	c.Configuration.ID = id
+	c.Configuration.Image.Reference = image
+	c.Configuration.Labels = labels
+	c.Configuration.Networks = raw
+	for _, v := range volumes {
+		c.Configuration.Mounts = append(c.Configuration.Mounts, runner.Mount{
+			Type:        map[string]json.RawMessage{"volume": json.RawMessage(`{"name":"` + v + `"}`)},
+			Destination: "/data",
+		})
+	}
+	return c
+}
+
+func testResources() Resources {
+	return Resources{
+		Containers: []runner.ContainerInfo{
+			container("shop_web", "running", "nginx:1", nil, []string{"shop_default"}),
+			container("shop_db", "stopped", "postgres:16", map[string]string{"tier": "data"}, []string{"shop_default"}, "shop_pgdata"),
+			container("blog_app", "stopped", "blog:latest", nil, []string{"blog_default"}, "blog_data"),
+		},
+		Images: []runner.ImageInfo{
+			{Reference: "nginx:1", Descriptor: runner.Descriptor{Size: 1000}},
+			{Reference: "postgres:16", Descriptor: runner.Descriptor{Size: 2000}},
+			{Reference: "blog:latest", Descriptor: runner.Descriptor{Size: 3000}},
+			{Reference: "<none>:<none>", Descriptor: runner.Descriptor{Size: 500}},
+		},
+		Networks: []runner.NetworkInfo{{ID: "default"}, {ID: "shop_default"}, {ID: "blog_default"}, {ID: "orphan"}},
+		Volumes:  []runner.VolumeInfo{{Name: "shop_pgdata"}, {Name: "blog_data"}},
+	}
+}
+
+func TestCompute_Global(t *testing.T) {
+	owned := &Scope{
+		Networks: map[string]bool{"default": true, "shop_default": true, "blog_default": true},
+		Volumes:  map[string]bool{"shop_pgdata": true},
+	}
+	plan := Compute(testResources(), nil, Options{Volumes: true, Owned: owned})
+
+	if want := []string{"blog_app", "shop_db"}; !reflect.DeepEqual(plan.Containers, want) {
+		t.Errorf("Containers = %v, want %v", plan.Containers, want)
+	}
+	if want := []string{"blog_default"}; !reflect.DeepEqual(plan.Networks, want) {
+		t.Errorf("Networks = %v, want %v (in-use, default and unrecorded kept)", plan.Networks, want)
+	}
+	if want := []string{"shop_pgdata"}; !reflect.DeepEqual(plan.Volumes, want) {
+		t.Errorf("Volumes = %v, want %v (unrecorded kept)", plan.Volumes, want)
+	}
+	if len(plan.Images) != 1 || !plan.Images[0].Dangling() {
+		t.Errorf("Images = %v, want only the dangling image", plan.Images)
+	}
+	if plan.ImageBytes() != 500 {
+		t.Errorf("ImageBytes() = %d, want 500", plan.ImageBytes())
+	}
+}
+
+func TestCompute_AllImages(t *testing.T) {
+	plan := Compute(testResources(), nil, Options{AllImages: true})
+
+	var refs []string
+	for _, img := range plan.Images {
+		refs = append(refs, img.Reference)
+	}
+	if want := []string{"postgres:16", "blog:latest", "<none>:<none>"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("Images = %v, want %v", refs, want)
+	}
+	if len(plan.Volumes) != 0 {
+		t.Errorf("Volumes = %v, want none without Volumes option", plan.Volumes)
+	}
+}
+
+func TestCompute_ProjectScope(t *testing.T) {
+	scope := &Scope{
+		Containers: map[string]bool{"shop_web": true, "shop_db": true},
+		Networks:   map[string]bool{"shop_default": true},
+		Volumes:    map[string]bool{"shop_pgdata": true},
+	}
+	plan := Compute(testResources(), scope, Options{Volumes: true, AllImages: true})
+
+	if want := []string{"shop_db"}; !reflect.DeepEqual(plan.Containers, want) {
+		t.Errorf("Containers = %v, want %v", plan.Containers, want)
+	}
+	if len(plan.Networks) != 0 {
+		t.Errorf("Networks = %v, want none (shop_web still attached)", plan.Networks)
+	}
+	if want := []string{"shop_pgdata"}; !reflect.DeepEqual(plan.Volumes, want) {
+		t.Errorf("Volumes = %v, want %v", plan.Volumes, want)
+	}
+	if len(plan.Images) != 1 || plan.Images[0].Reference != "postgres:16" {
+		t.Errorf("Images = %v, want only postgres:16", plan.Images)
+	}
+}
+
+func TestCompute_Filters(t *testing.T) {
+	filters, err := ParseFilters([]string{"label=tier=data"})
+	if err != nil {
+		t.Fatalf("ParseFilters() error: %v", err)
+	}
+	plan := Compute(testResources(), nil, Options{Filters: filters})
+	if want := []string{"shop_db"}; !reflect.DeepEqual(plan.Containers, want) {
+		t.Errorf("Containers = %v, want %v", plan.Containers, want)
+	}
+
+	filters, _ = ParseFilters([]string{"label!=tier"})
+	plan = Compute(testResources(), nil, Options{Filters: filters})
+	if want := []string{"blog_app"}; !reflect.DeepEqual(plan.Containers, want) {
+		t.Errorf("Containers = %v, want %v", plan.Containers, want)
+	}
+}
+
+func TestParseFilters_Invalid(t *testing.T) {
+	for _, spec := range []string{"until=24h", "label=", "dangling=true"} {
+		if _, err := ParseFilters([]string{spec}); err == nil {
+			t.Errorf("ParseFilters(%q) expected error", spec)
+		}
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int64]string{
+		0:             "0B",
+		999:           "999B",
+		1500:          "1.5kB",
+		250_000_000:   "250MB",
+		3_200_000_000: "3.2GB",
+	}
+	for n, want := range tests {
+		if got := HumanSize(n); got != want {
+			t.Errorf("HumanSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package report prints dctl's own messages to the user: progress, warnings
+// and errors. Warnings are also collected so a command can end with a
+// summary of them.
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a message.
+type Level int
+
+const (
+	// LevelInfo is progress, such as "Starting shop_web".
+	LevelInfo Level = iota
+	// LevelWarn is something the user should know about that did not stop
+	// the command.
+	LevelWarn
+	// LevelError is a failure. Errors are never suppressed.
+	LevelError
+)
+
+// Reporter writes messages to an output, normally stderr.
+type Reporter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	quiet    bool
+	warnings []string
+}
+
+// New returns a Reporter writing to w.
+func New(w io.Writer) *Reporter {
+	return &Reporter{w: w}
+}
+
+// SetQuiet suppresses everything but errors. Warnings are still collected.
+func (r *Reporter) SetQuiet(quiet bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quiet = quiet
+}
+
+// Quiet reports whether only errors are printed.
+func (r *Reporter) Quiet() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quiet
+}
+
+// Printf writes a message at level, ending it with a newline. Warnings are
+// prefixed with "Warning: " and errors with "Error: ".
+func (r *Reporter) Printf(level Level, format string, args ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch level {
+	case LevelWarn:
+		r.warnings = append(r.warnings, msg)
+		msg = "Warning: " + msg
+	case LevelError:
+		msg = "Error: " + msg
+	}
+	if r.quiet && level != LevelError {
+		return
+	}
+	fmt.Fprintln(r.w, msg)
+}
+
+// Warnings returns the warnings reported so far.
+func (r *Reporter) Warnings() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.warnings...)
+}
+
+// Summary lists the warnings reported so far when there was more than one,
+// so none is lost among the progress output, and forgets them.
+func (r *Reporter) Summary() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	warnings := r.warnings
+	r.warnings = nil
+	if r.quiet || len(warnings) < 2 {
+		return
+	}
+	fmt.Fprintf(r.w, "%d warnings:\n", len(warnings))
+	for _, w := range warnings {
+		fmt.Fprintf(r.w, "  - %s\n", w)
+	}
+}
+
+// std is the reporter the package functions use.
+var std = New(os.Stderr)
+
+// Default returns the reporter the package functions use.
+func Default() *Reporter { return std }
+
+// SetDefault replaces the reporter the package functions use, e.g. to
+// capture messages in tests.
+func SetDefault(r *Reporter) { std = r }
+
+// SetQuiet suppresses everything but errors from the default reporter.
+func SetQuiet(quiet bool) { std.SetQuiet(quiet) }
+
+// Quiet reports whether the default reporter prints only errors.
+func Quiet() bool { return std.Quiet() }
+
+// Infof reports progress.
+func Infof(format string, args ...interface{}) { std.Printf(LevelInfo, format, args...) }
+
+// Warnf reports a warning.
+func Warnf(format string, args ...interface{}) { std.Printf(LevelWarn, format, args...) }
+
+// Errorf reports an error that does not end the command.
+func Errorf(format string, args ...interface{}) { std.Printf(LevelError, format, args...) }
+
+// Summary lists the warnings reported so far; see Reporter.Summary.
+func Summary() { std.Summary() }
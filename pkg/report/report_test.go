@@ -0,0 +1,50 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReporter(t *testing.T) {
+	var b strings.Builder
+	r := New(&b)
+	r.Printf(LevelInfo, "Starting %s\n", "shop_web")
+	r.Printf(LevelWarn, "failed to stop %s", "db")
+	r.Printf(LevelError, "failed to start %s", "web")
+	want := "Starting shop_web\nWarning: failed to stop db\nError: failed to start web\n"
+	if b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+
+	// A single warning needs no summary.
+	b.Reset()
+	r.Summary()
+	if b.String() != "" {
+		t.Errorf("summary = %q, want none", b.String())
+	}
+	if got := r.Warnings(); len(got) != 0 {
+		t.Errorf("warnings after summary = %v", got)
+	}
+
+	r.Printf(LevelWarn, "one")
+	r.Printf(LevelWarn, "two")
+	b.Reset()
+	r.Summary()
+	if want := "2 warnings:\n  - one\n  - two\n"; b.String() != want {
+		t.Errorf("summary = %q, want %q", b.String(), want)
+	}
+}
+
+func TestReporterQuiet(t *testing.T) {
+	var b strings.Builder
+	r := New(&b)
+	r.SetQuiet(true)
+	r.Printf(LevelInfo, "Starting shop_web")
+	r.Printf(LevelWarn, "one")
+	r.Printf(LevelWarn, "two")
+	r.Printf(LevelError, "failed")
+	r.Summary()
+	if want := "Error: failed\n"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Backend executes container CLI commands. The default runs ContainerBin;
+// embedders and tests install another with SetBackend, such as a Recorder.
+type Backend interface {
+	// Run executes the command with the given stdio and returns when it
+	// exits. The error of a command that exited non-zero should implement
+	// ExitCode() int, as *exec.ExitError and *ExitError do.
+	Run(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// execBackend runs ContainerBin.
+type execBackend struct{}
+
+func (execBackend) Run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := command(args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// command returns the command running ContainerBin with args, with Env
+// added to its environment.
+func command(args ...string) *exec.Cmd {
+	return commandContext(context.Background(), args...)
+}
+
+// commandContext is command, killed when ctx is done.
+func commandContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, ContainerBin, args...)
+	if len(Env) > 0 {
+		cmd.Env = append(os.Environ(), Env...)
+	}
+	return cmd
+}
+
+var backend Backend = execBackend{}
+
+// SetBackend makes every container CLI command go through b. A nil b
+// restores the default, which runs ContainerBin.
+func SetBackend(b Backend) {
+	if b == nil {
+		b = execBackend{}
+	}
+	backend = b
+}
+
+// CurrentBackend returns the Backend commands go through, so a nested app
+// can be given the same one.
+func CurrentBackend() Backend {
+	return backend
+}
+
+// UsesBinary reports whether commands run ContainerBin, rather than a
+// Backend installed with SetBackend.
+func UsesBinary() bool {
+	_, ok := backend.(execBackend)
+	return ok
+}
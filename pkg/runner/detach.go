@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ParseDetachKeys parses a detach sequence in Docker's format:
+// comma-separated keys, each a single character or ctrl-<key> with <key> a
+// lowercase letter or one of @ [ \ ] ^ _.
+func ParseDetachKeys(s string) ([]byte, error) {
+	var keys []byte
+	for _, key := range strings.Split(s, ",") {
+		name, ctrl := strings.CutPrefix(key, "ctrl-")
+		switch {
+		case len(key) == 1:
+			keys = append(keys, key[0])
+		case ctrl && len(name) == 1 && name[0] >= 'a' && name[0] <= 'z':
+			keys = append(keys, name[0]-'a'+1)
+		case ctrl && name == "@":
+			keys = append(keys, 0)
+		case ctrl && len(name) == 1 && strings.Contains(`[\]^_`, name):
+			keys = append(keys, 27+byte(strings.Index(`[\]^_`, name)))
+		default:
+			return nil, fmt.Errorf("invalid detach key %q", key)
+		}
+	}
+	return keys, nil
+}
+
+// AttachedDetachable is Attached for a command with a TTY that the user can
+// detach from by typing keys, leaving the process running. It reports
+// whether the user detached.
+//
+// The runtime's CLI owns the container's TTY, so the command is started in
+// its own session on a pseudo-terminal that follows the size of dctl's
+// terminal: dctl forwards input through it, and the command holds its
+// master end too, so its input never ends. The command writes its output
+// to the file at logPath, which dctl follows. On detach the command keeps
+// running and logging there; otherwise the file is removed when the
+// command exits. Without keys, a TTY or a terminal on stdin, it runs as
+// Attached does; detaching is refused where no pseudo-terminal can be
+// opened, since the session could not follow the terminal's size.
+func AttachedDetachable(tty bool, keys []byte, logPath string, args ...string) (bool, error) {
+	if !tty || len(keys) == 0 || !UsesBinary() || !IsTerminal(os.Stdin) {
+		return false, Attached(tty, args...)
+	}
+	ptm, pts, err := openPTY()
+	if err != nil {
+		return false, fmt.Errorf("detach keys need a pseudo-terminal: %w", err)
+	}
+	defer ptm.Close()
+	defer pts.Close()
+	if _, err := makeRaw(pts); err != nil {
+		return false, fmt.Errorf("detach keys need a pseudo-terminal: %w", err)
+	}
+	if err := copySize(ptm, os.Stdin); err != nil {
+		return false, fmt.Errorf("sizing pseudo-terminal: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0o755); err != nil {
+		return false, fmt.Errorf("creating session log: %w", err)
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return false, fmt.Errorf("creating session log: %w", err)
+	}
+	defer logFile.Close()
+	output, err := os.Open(logPath)
+	if err != nil {
+		return false, fmt.Errorf("opening session log: %w", err)
+	}
+	defer output.Close()
+
+	old, err := makeRaw(os.Stdin)
+	if err != nil {
+		return false, Attached(tty, args...)
+	}
+	defer setTermios(os.Stdin, old)
+
+	cmd := command(args...)
+	cmd.Stdin = pts
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.ExtraFiles = []*os.File{ptm}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	detach := make(chan struct{})
+	go func() {
+		if ok, _ := detachCopy(ptm, os.Stdin, keys); ok {
+			close(detach)
+		}
+	}()
+	stop := make(chan struct{})
+	followed := make(chan struct{})
+	go func() {
+		follow(os.Stdout, output, stop)
+		close(followed)
+	}()
+
+	for {
+		select {
+		case <-winch:
+			copySize(ptm, os.Stdin)
+		case err := <-exited:
+			close(stop)
+			<-followed
+			os.Remove(logPath)
+			return false, err
+		case <-detach:
+			close(stop)
+			<-followed
+			return true, nil
+		}
+	}
+}
+
+// detachCopy copies src to dst until src ends or the keys are read in
+// sequence, reporting which. Bytes that start the sequence are held back
+// until it completes, and forwarded if it breaks off; the sequence itself
+// is never forwarded.
+func detachCopy(dst io.Writer, src io.Reader, keys []byte) (bool, error) {
+	buf := make([]byte, 1024)
+	matched := 0
+	for {
+		n, err := src.Read(buf)
+		var out bytes.Buffer
+		for _, b := range buf[:n] {
+			if b == keys[matched] {
+				matched++
+				if matched == len(keys) {
+					_, werr := dst.Write(out.Bytes())
+					return true, werr
+				}
+				continue
+			}
+			out.Write(keys[:matched])
+			matched = 0
+			if b == keys[0] {
+				matched = 1
+				continue
+			}
+			out.WriteByte(b)
+		}
+		if _, werr := dst.Write(out.Bytes()); werr != nil {
+			return false, werr
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// followInterval is how often follow checks a file for new output.
+const followInterval = 10 * time.Millisecond
+
+// follow copies f to dst as it grows, until stop is closed and everything
+// written before then has been copied.
+func follow(dst io.Writer, f *os.File, stop <-chan struct{}) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, _ := f.Read(buf)
+		if n > 0 {
+			dst.Write(buf[:n])
+			continue
+		}
+		select {
+		case <-stop:
+			// Copy what was written while waiting.
+			io.Copy(dst, f)
+			return
+		case <-time.After(followInterval):
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []byte
+	}{
+		{"ctrl-p,ctrl-q", []byte{16, 17}},
+		{"ctrl-@,ctrl-[,ctrl-_", []byte{0, 27, 31}},
+		{"x,ctrl-a", []byte{'x', 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDetachKeys(tt.in)
+		if err != nil {
+			t.Errorf("ParseDetachKeys(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("ParseDetachKeys(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "ctrl-", "ctrl-P", "ctrl-1", "alt-x", "ab"} {
+		if _, err := ParseDetachKeys(in); err == nil {
+			t.Errorf("ParseDetachKeys(%q) succeeded", in)
+		}
+	}
+}
+
+func TestDetachCopy(t *testing.T) {
+	keys := []byte{16, 17} // ctrl-p,ctrl-q
+	tests := []struct {
+		name     string
+		in       string
+		want     string
+		detached bool
+	}{
+		{"no keys", "ls\r", "ls\r", false},
+		{"detach", "ls\r\x10\x11echo", "ls\r", true},
+		{"broken sequence is forwarded", "a\x10b\x10\x10\x11", "a\x10b\x10", true},
+		{"held prefix at end", "a\x10", "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			detached, err := detachCopy(&out, strings.NewReader(tt.in), keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detached != tt.detached || out.String() != tt.want {
+				t.Errorf("detachCopy() = %v, %q; want %v, %q", detached, out.String(), tt.detached, tt.want)
+			}
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	w, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var out bytes.Buffer
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		follow(&out, r, stop)
+		close(done)
+	}()
+	// Output written just before the session ends is still copied.
+	time.Sleep(followInterval / 2)
+	w.WriteString("bye\n")
+	close(stop)
+	<-done
+	if out.String() != "bye\n" {
+		t.Errorf("followed %q, want %q", out.String(), "bye\n")
+	}
+}
@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ContainerInfo is the subset of `container inspect` / `container list
+// --format json` output that dctl relies on.
+type ContainerInfo struct {
+	Status        string          `json:"status"`
+	Configuration ContainerConfig `json:"configuration"`
+	Networks      []NetworkStatus `json:"networks"`
+}
+
+// ContainerConfig is a container's creation-time configuration.
+type ContainerConfig struct {
+	ID             string            `json:"id"`
+	Image          ImageRef          `json:"image"`
+	InitProcess    Process           `json:"initProcess"`
+	Mounts         []Mount           `json:"mounts"`
+	PublishedPorts []PublishedPort   `json:"publishedPorts"`
+	Labels         map[string]string `json:"labels"`
+	Hostname       string            `json:"hostname"`
+	Networks       json.RawMessage   `json:"networks"`
+}
+
+// ImageRef identifies the image a container was created from.
+type ImageRef struct {
+	Reference  string     `json:"reference"`
+	Descriptor Descriptor `json:"descriptor"`
+}
+
+// Process is a container's init process.
+type Process struct {
+	Executable       string   `json:"executable"`
+	Arguments        []string `json:"arguments"`
+	Environment      []string `json:"environment"`
+	WorkingDirectory string   `json:"workingDirectory"`
+	Terminal         bool     `json:"terminal"`
+}
+
+// Mount is a filesystem attached to a container. Type holds a single key
+// naming the mount kind (e.g. "virtiofs", "volume", "tmpfs").
+type Mount struct {
+	Type        map[string]json.RawMessage `json:"type"`
+	Source      string                     `json:"source"`
+	Destination string                     `json:"destination"`
+	Options     []string                   `json:"options"`
+}
+
+// Kind returns the mount kind: "volume", "tmpfs" or "bind".
+func (m Mount) Kind() string {
+	if _, ok := m.Type["volume"]; ok {
+		return "volume"
+	}
+	if _, ok := m.Type["tmpfs"]; ok {
+		return "tmpfs"
+	}
+	return "bind"
+}
+
+// VolumeName returns the name of a named-volume mount.
+func (m Mount) VolumeName() string {
+	var v struct {
+		Name string `json:"name"`
+	}
+	if raw, ok := m.Type["volume"]; ok {
+		_ = json.Unmarshal(raw, &v)
+	}
+	if v.Name != "" {
+		return v.Name
+	}
+	return m.Source
+}
+
+// PublishedPort is a host port forwarded to the container.
+type PublishedPort struct {
+	HostAddress   string `json:"hostAddress"`
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Proto         string `json:"proto"`
+}
+
+// NetworkStatus is a container's attachment to a network.
+type NetworkStatus struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+}
+
+// NetworkNames returns the networks named in the container configuration.
+// Older runtimes store plain names, newer ones store attachment objects.
+func (c ContainerConfig) NetworkNames() []string {
+	var plain []string
+	if err := json.Unmarshal(c.Networks, &plain); err == nil {
+		return plain
+	}
+	var names []string
+	var attachments []struct {
+		Network string `json:"network"`
+	}
+	if err := json.Unmarshal(c.Networks, &attachments); err == nil {
+		for _, a := range attachments {
+			names = append(names, a.Network)
+		}
+	}
+	return names
+}
+
+// Address returns the container's first IP address without the prefix length.
+func (c ContainerInfo) Address() string {
+	for _, n := range c.Networks {
+		if n.Address != "" {
+			addr, _, _ := strings.Cut(n.Address, "/")
+			return addr
+		}
+	}
+	return ""
+}
+
+// ParseContainers parses a JSON array or newline-delimited JSON objects
+// describing containers.
+func ParseContainers(out string) ([]ContainerInfo, error) {
+	return parseJSONList[ContainerInfo](out, "container")
+}
+
+// parseJSONList parses container CLI output that is either a JSON array or
+// newline-delimited JSON objects.
+func parseJSONList[T any](out, kind string) ([]T, error) {
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+
+	var items []T
+	if err := json.Unmarshal([]byte(out), &items); err == nil {
+		return items, nil
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var item T
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			return nil, fmt.Errorf("parsing %s JSON: %w", kind, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Inspect returns the parsed configuration of the named containers.
+func Inspect(names ...string) ([]ContainerInfo, error) {
+	out, err := Output(append([]string{"inspect"}, names...)...)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting %s: %w", strings.Join(names, ", "), err)
+	}
+	return ParseContainers(out)
+}
+
+// List returns all containers, including stopped ones when all is set.
+func List(all bool) ([]ContainerInfo, error) {
+	args := []string{"list", "--format", "json"}
+	if all {
+		args = append(args, "--all")
+	}
+	out, err := Output(args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	return ParseContainers(out)
+}
@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+const inspectJSON = `[{
+  "status": "running",
+  "configuration": {
+    "id": "shop_web",
+    "image": {"reference": "docker.io/library/nginx:1.25"},
+    "initProcess": {
+      "executable": "/docker-entrypoint.sh",
+      "arguments": ["nginx", "-g", "daemon off;"],
+      "environment": ["PATH=/usr/bin", "APP_ENV=prod"],
+      "workingDirectory": "/"
+    },
+    "mounts": [
+      {"type": {"virtiofs": {}}, "source": "/src/site", "destination": "/usr/share/nginx/html", "options": ["ro"]},
+      {"type": {"volume": {"name": "cache", "format": "ext4"}}, "source": "/var/lib/volumes/cache", "destination": "/var/cache/nginx"},
+      {"type": {"tmpfs": {}}, "source": "", "destination": "/run"}
+    ],
+    "publishedPorts": [{"hostAddress": "0.0.0.0", "hostPort": 8080, "containerPort": 80, "proto": "tcp"}],
+    "labels": {"com.example": "yes"},
+    "networks": [{"network": "frontend"}]
+  },
+  "networks": [{"network": "frontend", "address": "192.168.64.3/24"}]
+}]`
+
+func TestParseContainers(t *testing.T) {
+	containers, err := ParseContainers(inspectJSON)
+	if err != nil {
+		t.Fatalf("ParseContainers() error: %v", err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("got %d containers, want 1", len(containers))
+	}
+	c := containers[0]
+
+	if c.Configuration.ID != "shop_web" || c.Status != "running" {
+		t.Errorf("id/status = %q/%q", c.Configuration.ID, c.Status)
+	}
+	if c.Address() != "192.168.64.3" {
+		t.Errorf("Address() = %q, want %q", c.Address(), "192.168.64.3")
+	}
+	if got := c.Configuration.NetworkNames(); !reflect.DeepEqual(got, []string{"frontend"}) {
+		t.Errorf("NetworkNames() = %v", got)
+	}
+
+	kinds := []string{}
+	for _, m := range c.Configuration.Mounts {
+		kinds = append(kinds, m.Kind())
+	}
+	if !reflect.DeepEqual(kinds, []string{"bind", "volume", "tmpfs"}) {
+		t.Errorf("mount kinds = %v", kinds)
+	}
+	if name := c.Configuration.Mounts[1].VolumeName(); name != "cache" {
+		t.Errorf("VolumeName() = %q, want %q", name, "cache")
+	}
+}
+
+func TestParseContainers_NDJSONAndStringNetworks(t *testing.T) {
+	out := `{"status":"stopped","configuration":{"id":"a","networks":["default"]}}
+{"status":"running","configuration":{"id":"b"}}`
+	containers, err := ParseContainers(out)
+	if err != nil {
+		t.Fatalf("ParseContainers() error: %v", err)
+	}
+	if len(containers) != 2 || containers[1].Configuration.ID != "b" {
+		t.Fatalf("ParseContainers() = %+v", containers)
+	}
+	if got := containers[0].Configuration.NetworkNames(); !reflect.DeepEqual(got, []string{"default"}) {
+		t.Errorf("NetworkNames() = %v", got)
+	}
+
+	if c, err := ParseContainers("  "); err != nil || c != nil {
+		t.Errorf("ParseContainers(empty) = %v, %v", c, err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build freebsd || netbsd || openbsd
+
+package runner
+
+import (
+	"errors"
+	"os"
+)
+
+// openPTY opens a pseudo-terminal, returning its master and slave ends.
+// Only Linux and macOS are supported.
+func openPTY() (ptm, pts *os.File, err error) {
+	return nil, nil, errors.New("pseudo-terminals are not supported on this system")
+}
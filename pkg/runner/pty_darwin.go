@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// openPTY opens a pseudo-terminal, returning its master and slave ends.
+func openPTY() (ptm, pts *os.File, err error) {
+	ptm, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var name [128]byte
+	for _, req := range []uintptr{syscall.TIOCPTYGRANT, syscall.TIOCPTYUNLK} {
+		if err := ioctl(ptm, req, nil); err != nil {
+			ptm.Close()
+			return nil, nil, err
+		}
+	}
+	if err := ioctl(ptm, syscall.TIOCPTYGNAME, unsafe.Pointer(&name[0])); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	path, _, _ := bytes.Cut(name[:], []byte{0})
+	pts, err = os.OpenFile(string(path), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	return ptm, pts, nil
+}
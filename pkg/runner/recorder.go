@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ExitError is the error of a command that exited with a non-zero status.
+// Recorder scripts return it to simulate failing commands.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// ExitCode returns the exit status.
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// Recorder is a Backend that records commands instead of running them and
+// answers with scripted output, so compose actions can be exercised without
+// a container runtime. Unscripted commands succeed with no output. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	calls   [][]string
+	scripts []script
+}
+
+type script struct {
+	prefix []string
+	stdout string
+	err    error
+}
+
+// Respond makes commands whose arguments start with prefix write stdout and
+// return err. Later scripts take precedence over earlier ones.
+func (r *Recorder) Respond(prefix []string, stdout string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts = append(r.scripts, script{prefix: prefix, stdout: stdout, err: err})
+}
+
+// Calls returns the arguments of every command run so far, in order.
+func (r *Recorder) Calls() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := make([][]string, len(r.calls))
+	for i, c := range r.calls {
+		calls[i] = slices.Clone(c)
+	}
+	return calls
+}
+
+// Run implements Backend.
+func (r *Recorder) Run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	r.mu.Lock()
+	r.calls = append(r.calls, slices.Clone(args))
+	var match *script
+	for i := len(r.scripts) - 1; i >= 0; i-- {
+		s := r.scripts[i]
+		if len(args) >= len(s.prefix) && slices.Equal(args[:len(s.prefix)], s.prefix) {
+			match = &s
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if match == nil {
+		return nil
+	}
+	if _, err := io.WriteString(stdout, match.stdout); err != nil {
+		return err
+	}
+	return match.err
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := &Recorder{}
+	SetBackend(rec)
+	defer SetBackend(nil)
+	if UsesBinary() {
+		t.Fatal("UsesBinary() = true with a Recorder installed")
+	}
+
+	rec.Respond([]string{"list"}, `[{"status":"running","configuration":{"id":"shop_web"}}]`, nil)
+	rec.Respond([]string{"inspect", "shop_db"}, "", &ExitError{Code: 1})
+
+	containers, err := List(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0].Configuration.ID != "shop_web" {
+		t.Errorf("List() = %+v, want shop_web", containers)
+	}
+	if _, err := Inspect("shop_db"); err == nil {
+		t.Error("Inspect(shop_db) succeeded, want scripted failure")
+	}
+	if err := RunInput(nil, "stop", "shop_web"); err != nil {
+		t.Errorf("unscripted command failed: %v", err)
+	}
+
+	want := [][]string{
+		{"list", "--format", "json"},
+		{"inspect", "shop_db"},
+		{"stop", "shop_web"},
+	}
+	if got := rec.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,214 @@
+package runner
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ImageInfo is the subset of `container image list --format json` output
+// that dctl relies on.
+type ImageInfo struct {
+	Reference  string     `json:"reference"`
+	Descriptor Descriptor `json:"descriptor"`
+}
+
+// Descriptor is an OCI content descriptor.
+type Descriptor struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// Dangling reports whether the image has lost its tag.
+func (i ImageInfo) Dangling() bool {
+	return i.Reference == "" || strings.Contains(i.Reference, "<none>")
+}
+
+// HasReference reports whether the image is ref, comparing references in
+// their fully qualified form so "nginx" matches
+// "docker.io/library/nginx:latest".
+func (i ImageInfo) HasReference(ref string) bool {
+	return i.Reference != "" && QualifyReference(i.Reference) == QualifyReference(ref)
+}
+
+// QualifyReference expands an image reference the way registries resolve
+// it: a missing registry is docker.io, single-name repositories there are
+// under library/, and a missing tag or digest is latest.
+func QualifyReference(ref string) string {
+	name, digest, hasDigest := strings.Cut(ref, "@")
+	domain, rest, ok := strings.Cut(name, "/")
+	if !ok || !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		domain, rest = "docker.io", name
+	}
+	if domain == "docker.io" && !strings.Contains(rest, "/") {
+		rest = "library/" + rest
+	}
+	if hasDigest {
+		return domain + "/" + rest + "@" + digest
+	}
+	if !strings.Contains(rest[strings.LastIndex(rest, "/")+1:], ":") {
+		rest += ":latest"
+	}
+	return domain + "/" + rest
+}
+
+// ImageConfig is the run configuration an image was built with: what its
+// containers run when the service does not say otherwise.
+type ImageConfig struct {
+	Entrypoint  []string          `json:"Entrypoint"`
+	Cmd         []string          `json:"Cmd"`
+	Env         []string          `json:"Env"`
+	WorkingDir  string            `json:"WorkingDir"`
+	User        string            `json:"User"`
+	Healthcheck *ImageHealthcheck `json:"Healthcheck"`
+}
+
+// ImageHealthcheck is an image's HEALTHCHECK. Test is in Docker's form:
+// ["CMD", args...], ["CMD-SHELL", command] or ["NONE"].
+type ImageHealthcheck struct {
+	Test []string `json:"Test"`
+}
+
+// Command returns the command the image runs by default: its entrypoint
+// followed by its arguments.
+func (c ImageConfig) Command() []string {
+	return slices.Concat(c.Entrypoint, c.Cmd)
+}
+
+// imageDetail is an image from `image inspect`. The container CLI lists a
+// config per platform variant; the Docker CLI has a single Config.
+type imageDetail struct {
+	Config       *ImageConfig `json:"Config"`
+	OS           string       `json:"Os"`
+	Architecture string       `json:"Architecture"`
+	Variants     []struct {
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+		Config struct {
+			Config *ImageConfig `json:"config"`
+		} `json:"config"`
+	} `json:"variants"`
+}
+
+// ParseImageConfig parses `image inspect` output, picking the variant for
+// this machine's architecture when the image has several.
+func ParseImageConfig(out string) (ImageConfig, error) {
+	details, err := parseJSONList[imageDetail](out, "image")
+	if err != nil {
+		return ImageConfig{}, err
+	}
+	if len(details) == 0 {
+		return ImageConfig{}, fmt.Errorf("no image in inspect output")
+	}
+	d := details[0]
+	if d.Config != nil {
+		return *d.Config, nil
+	}
+	var found *ImageConfig
+	for _, v := range d.Variants {
+		if v.Config.Config == nil || v.Platform.OS != "linux" {
+			continue
+		}
+		if v.Platform.Architecture == runtime.GOARCH {
+			return *v.Config.Config, nil
+		}
+		if found == nil {
+			found = v.Config.Config
+		}
+	}
+	if found == nil {
+		return ImageConfig{}, fmt.Errorf("image has no linux configuration")
+	}
+	return *found, nil
+}
+
+// ParseImagePlatforms parses `image inspect` output into the platforms the
+// image has, as os/arch. Attestation entries of unknown platform are left
+// out.
+func ParseImagePlatforms(out string) ([]string, error) {
+	details, err := parseJSONList[imageDetail](out, "image")
+	if err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		return nil, fmt.Errorf("no image in inspect output")
+	}
+	d := details[0]
+	if d.OS != "" {
+		return []string{d.OS + "/" + d.Architecture}, nil
+	}
+	var platforms []string
+	for _, v := range d.Variants {
+		if v.Platform.OS == "" || v.Platform.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, v.Platform.OS+"/"+v.Platform.Architecture)
+	}
+	return platforms, nil
+}
+
+// InspectImagePlatforms returns the platforms of a local image.
+func InspectImagePlatforms(ref string) ([]string, error) {
+	out, err := Output("image", "inspect", ref)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting image %s: %w", ref, err)
+	}
+	platforms, err := ParseImagePlatforms(out)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting image %s: %w", ref, err)
+	}
+	return platforms, nil
+}
+
+// InspectImage returns the run configuration of a local image.
+func InspectImage(ref string) (ImageConfig, error) {
+	out, err := Output("image", "inspect", ref)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("inspecting image %s: %w", ref, err)
+	}
+	c, err := ParseImageConfig(out)
+	if err != nil {
+		return ImageConfig{}, fmt.Errorf("inspecting image %s: %w", ref, err)
+	}
+	return c, nil
+}
+
+// VolumeInfo is a volume from `container volume list --format json`.
+type VolumeInfo struct {
+	Name string `json:"name"`
+}
+
+// NetworkInfo is a network from `container network list --format json`.
+type NetworkInfo struct {
+	ID string `json:"id"`
+}
+
+// ListImages returns all local images.
+func ListImages() ([]ImageInfo, error) {
+	out, err := Output("image", "list", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing images: %w", err)
+	}
+	return parseJSONList[ImageInfo](out, "image")
+}
+
+// ListVolumes returns all volumes.
+func ListVolumes() ([]VolumeInfo, error) {
+	out, err := Output("volume", "list", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing volumes: %w", err)
+	}
+	return parseJSONList[VolumeInfo](out, "volume")
+}
+
+// ListNetworks returns all networks.
+func ListNetworks() ([]NetworkInfo, error) {
+	out, err := Output("network", "list", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	return parseJSONList[NetworkInfo](out, "network")
+}
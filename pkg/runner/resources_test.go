@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestQualifyReference(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                       "docker.io/library/nginx:latest",
+		"nginx:1.27":                  "docker.io/library/nginx:1.27",
+		"shop-web":                    "docker.io/library/shop-web:latest",
+		"bitnami/redis":               "docker.io/bitnami/redis:latest",
+		"ghcr.io/acme/app:v1":         "ghcr.io/acme/app:v1",
+		"localhost:5000/app":          "localhost:5000/app:latest",
+		"nginx@sha256:abc":            "docker.io/library/nginx@sha256:abc",
+		"docker.io/library/nginx:1.0": "docker.io/library/nginx:1.0",
+	}
+	for ref, want := range tests {
+		if got := QualifyReference(ref); got != want {
+			t.Errorf("QualifyReference(%q) = %q, want %q", ref, got, want)
+		}
+	}
+	img := ImageInfo{Reference: "docker.io/library/nginx:latest"}
+	if !img.HasReference("nginx") || img.HasReference("nginx:1.27") {
+		t.Error("HasReference() does not compare qualified references")
+	}
+}
+
+func TestParseImageConfig(t *testing.T) {
+	container := `[{"name":"docker.io/library/nginx:latest","variants":[
+		{"platform":{"os":"unknown","architecture":"unknown"},"config":{}},
+		{"platform":{"os":"linux","architecture":"other"},"config":{"config":{"Cmd":["other"]}}},
+		{"platform":{"os":"linux","architecture":"` + runtime.GOARCH + `"},"config":{"config":{"Entrypoint":["/docker-entrypoint.sh"],"Cmd":["nginx","-g","daemon off;"],"WorkingDir":"/"}}}]}]`
+	c, err := ParseImageConfig(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Command(), []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"}; !slices.Equal(got, want) {
+		t.Errorf("Command() = %q, want %q", got, want)
+	}
+
+	docker := `[{"Id":"sha256:abc","Config":{"Cmd":["redis-server"],"Healthcheck":{"Test":["CMD-SHELL","redis-cli ping"]}}}]`
+	if c, err = ParseImageConfig(docker); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Cmd, []string{"redis-server"}) || c.Healthcheck == nil || len(c.Healthcheck.Test) != 2 {
+		t.Errorf("ParseImageConfig(docker) = %+v", c)
+	}
+
+	if _, err := ParseImageConfig(`[{"name":"x","variants":[{"platform":{"os":"unknown"},"config":{}}]}]`); err == nil {
+		t.Error("ParseImageConfig() of an image without a linux variant succeeded")
+	}
+}
+
+func TestParseImagePlatforms(t *testing.T) {
+	tests := map[string][]string{
+		`[{"name":"nginx","variants":[{"platform":{"os":"linux","architecture":"amd64"}},{"platform":{"os":"linux","architecture":"arm64","variant":"v8"}},{"platform":{"os":"unknown","architecture":"unknown"}}]}]`: {"linux/amd64", "linux/arm64"},
+		`[{"Id":"sha256:abc","Os":"linux","Architecture":"amd64","Config":{}}]`: {"linux/amd64"},
+	}
+	for out, want := range tests {
+		got, err := ParseImagePlatforms(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("ParseImagePlatforms() = %v, want %v", got, want)
+		}
+	}
+}
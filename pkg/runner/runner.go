@@ -1,7 +1,9 @@
 package runner
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -11,6 +13,11 @@ import (
 // ContainerBin is the path to the container CLI binary.
 var ContainerBin = findContainerBin()
 
+// Env holds KEY=VALUE variables added to the environment of container CLI
+// commands, such as a context's DOCKER_HOST. dctl's own environment, which
+// compose files are interpolated from, is left alone.
+var Env []string
+
 func findContainerBin() string {
 	if bin := os.Getenv("DCTL_CONTAINER_BIN"); bin != "" {
 		return bin
@@ -26,37 +33,104 @@ func findContainerBin() string {
 	return "container"
 }
 
-// Run executes a container CLI command, streaming stdin/stdout/stderr.
+// Run executes a container CLI command, streaming stdin/stdout/stderr. The
+// error of a command that exited non-zero implements ExitCode() int, so
+// dctl can exit with the command's status.
 func Run(args ...string) error {
-	cmd := exec.Command(ContainerBin, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
-		}
-		return err
-	}
-	return nil
+	return backend.Run(args, os.Stdin, os.Stdout, os.Stderr)
 }
 
 // Output executes a container CLI command and captures stdout.
 func Output(args ...string) (string, error) {
-	cmd := exec.Command(ContainerBin, args...)
-	cmd.Stderr = os.Stderr
-	out, err := cmd.Output()
-	return strings.TrimSpace(string(out)), err
+	var out strings.Builder
+	err := backend.Run(args, nil, &out, os.Stderr)
+	return strings.TrimSpace(out.String()), err
+}
+
+// OutputContext is Output, giving up with ctx's error once ctx is done.
+// The container binary is killed then; a command run through a Backend
+// installed with SetBackend is left to finish in the background.
+func OutputContext(ctx context.Context, args ...string) (string, error) {
+	if UsesBinary() {
+		var out strings.Builder
+		cmd := commandContext(ctx, args...)
+		cmd.Stdout = &out
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return strings.TrimSpace(out.String()), err
+	}
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := Output(args...)
+		done <- result{out, err}
+	}()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
 }
 
-// Exec replaces the current process with the container CLI.
+// RunInput executes a container CLI command with the given stdin.
+func RunInput(stdin io.Reader, args ...string) error {
+	return backend.Run(args, stdin, os.Stdout, os.Stderr)
+}
+
+// Stream executes a container CLI command with both its stdout and stderr
+// going to w. Like RunInput it returns a failed command's error.
+func Stream(w io.Writer, args ...string) error {
+	return backend.Run(args, nil, w, w)
+}
+
+// Exec replaces the current process with the container CLI. With a
+// Backend installed by SetBackend the command runs through it instead, as
+// Run runs it, and Exec returns once it exits.
 func Exec(args ...string) error {
+	if !UsesBinary() {
+		return Run(args...)
+	}
 	binary, err := exec.LookPath(ContainerBin)
 	if err != nil {
 		return fmt.Errorf("container binary not found: %w", err)
 	}
 	argv := append([]string{"container"}, args...)
-	return syscall.Exec(binary, argv, os.Environ())
+	return syscall.Exec(binary, argv, append(os.Environ(), Env...))
+}
+
+// CommandLine renders a container CLI invocation as a shell command line,
+// quoting arguments where needed so it can be copied and run as is.
+func CommandLine(args ...string) string {
+	parts := []string{quote(ContainerBin)}
+	for _, a := range args {
+		parts = append(parts, quote(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quote single-quotes s unless it consists only of shell-safe characters.
+func quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@%+", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // BuildArgs constructs a container CLI argument list from flag mappings.
@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestCommandLine(t *testing.T) {
+	orig := ContainerBin
+	ContainerBin = "container"
+	defer func() { ContainerBin = orig }()
+
+	got := CommandLine("run", "--env", "GREETING=hello world", "--label", "a=b", "alpine", "sh", "-c", "echo 'hi'", "")
+	want := `container run --env 'GREETING=hello world' --label a=b alpine sh -c 'echo '\''hi'\''' ''`
+	if got != want {
+		t.Errorf("CommandLine = %s\nwant %s", got, want)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if IsTerminal(r) || IsTerminal(w) {
+		t.Error("IsTerminal(pipe) = true, want false")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f) {
+		t.Error("IsTerminal(regular file) = true, want false")
+	}
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	if IsTerminal(null) {
+		t.Error("IsTerminal(/dev/null) = true, want false")
+	}
+}
+
+func TestCopySize(t *testing.T) {
+	src, srcSlave, err := openPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer src.Close()
+	defer srcSlave.Close()
+	ptm, pts, err := openPTY()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	defer ptm.Close()
+	defer pts.Close()
+	if !IsTerminal(pts) {
+		t.Fatal("IsTerminal(pseudo-terminal) = false, want true")
+	}
+
+	want := winsize{Row: 40, Col: 120}
+	if err := ioctl(src, syscall.TIOCSWINSZ, unsafe.Pointer(&want)); err != nil {
+		t.Fatal(err)
+	}
+	if err := copySize(ptm, srcSlave); err != nil {
+		t.Fatal(err)
+	}
+	var got winsize
+	if err := ioctl(pts, syscall.TIOCGWINSZ, unsafe.Pointer(&got)); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("size = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal reports whether f is a terminal. Pipes, regular files and
+// other character devices such as /dev/null are not.
+func IsTerminal(f *os.File) bool {
+	_, err := getTermios(f)
+	return err == nil
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func getTermios(f *os.File) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if err := ioctl(f, ioctlGetTermios, unsafe.Pointer(&t)); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func setTermios(f *os.File, t *syscall.Termios) error {
+	return ioctl(f, ioctlSetTermios, unsafe.Pointer(t))
+}
+
+// winsize is struct winsize of tty_ioctl(4).
+type winsize struct {
+	Row, Col, X, Y uint16
+}
+
+// copySize gives the terminal dst the window size of the terminal src.
+// The kernel signals SIGWINCH to the processes dst controls when it
+// changes.
+func copySize(dst, src *os.File) error {
+	var ws winsize
+	if err := ioctl(src, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return err
+	}
+	return ioctl(dst, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+}
+
+// makeRaw puts the terminal f into raw mode, as cfmakeraw(3) does, and
+// returns the previous settings for restoring it.
+func makeRaw(f *os.File) (*syscall.Termios, error) {
+	old, err := getTermios(f)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(f, &raw); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
+
+// RunTerminal executes a container CLI command that allocates a TTY, as
+// Attached does. Like Run, it returns a non-zero exit as an error with the
+// command's status.
+func RunTerminal(args ...string) error {
+	return Attached(true, args...)
+}
+
+// Attached executes a container CLI command on dctl's stdin, stdout and
+// stderr and returns its error, including a non-zero exit. With tty set and
+// stdin a terminal, the terminal is switched to raw mode so keystrokes,
+// including Ctrl-C, reach the container process unmodified, window size
+// changes are forwarded to the command, and the terminal is restored when
+// it exits.
+func Attached(tty bool, args ...string) error {
+	if !tty || !UsesBinary() {
+		return backend.Run(args, os.Stdin, os.Stdout, os.Stderr)
+	}
+	old, err := makeRaw(os.Stdin)
+	if err != nil {
+		return backend.Run(args, os.Stdin, os.Stdout, os.Stderr)
+	}
+	defer setTermios(os.Stdin, old)
+
+	cmd := command(args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Forward window size changes so the container's TTY follows the
+	// local terminal.
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for sig := range winch {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	err = cmd.Wait()
+	signal.Stop(winch)
+	close(winch)
+	return err
+}
@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+// Mask replaces redacted values.
+const Mask = "****"
+
+// sensitiveWords mark a variable name as holding a credential wherever
+// they appear in it, in any case.
+var sensitiveWords = []string{"PASS", "SECRET", "TOKEN", "API_KEY", "APIKEY", "PRIVATE_KEY", "ACCESS_KEY", "CREDENTIAL", "AUTH"}
+
+// minMaskLen is the length below which a secret value is not masked
+// wherever it appears: replacing every "1" or "on" would garble the output
+// without hiding anything worth hiding.
+const minMaskLen = 4
+
+var (
+	knownMu sync.Mutex
+	known   = make(map[string]bool)
+)
+
+// Sensitive reports whether a variable name looks like it holds a
+// credential, such as DB_PASSWORD or GITHUB_TOKEN.
+func Sensitive(name string) bool {
+	name = strings.ToUpper(name)
+	for _, w := range sensitiveWords {
+		if strings.Contains(name, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// remember makes Redact mask a secret value.
+func remember(v []byte) {
+	s := strings.TrimSpace(string(v))
+	if len(s) < minMaskLen {
+		return
+	}
+	knownMu.Lock()
+	defer knownMu.Unlock()
+	known[s] = true
+}
+
+// RememberLocal makes Redact mask the values of the secrets read from files
+// and environment variables, without resolving those held by the keychain
+// or a provider, which may prompt. Unreadable secrets are skipped.
+func RememberLocal(defs map[string]compose.SecretConfig) {
+	for name, cfg := range defs {
+		if cfg.File != "" || cfg.Environment != "" {
+			_, _ = Value(name, cfg)
+		}
+	}
+}
+
+// Redact returns a copy of container CLI arguments that is safe to print:
+// the value of a KEY=VALUE argument, or of a --flag=KEY=VALUE one, is
+// masked when KEY is Sensitive, and every secret value resolved so far is
+// masked wherever it appears.
+func Redact(args []string) []string {
+	knownMu.Lock()
+	values := make([]string, 0, len(known))
+	for v := range known {
+		values = append(values, v)
+	}
+	knownMu.Unlock()
+	// Longer values first, so one containing another is masked whole.
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	redacted := make([]string, len(args))
+	for i, a := range args {
+		prefix, body := "", a
+		if strings.HasPrefix(a, "-") {
+			flag, rest, ok := strings.Cut(a, "=")
+			if !ok {
+				redacted[i] = a
+				continue
+			}
+			prefix, body = flag+"=", rest
+		}
+		if k, v, ok := strings.Cut(body, "="); ok && v != "" && Sensitive(k) {
+			body = k + "=" + Mask
+		}
+		for _, v := range values {
+			body = strings.ReplaceAll(body, v, Mask)
+		}
+		redacted[i] = prefix + body
+	}
+	return redacted
+}
@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+func TestSensitive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"DB_PASSWORD":    true,
+		"github_token":   true,
+		"AWS_ACCESS_KEY": true,
+		"ClientSecret":   true,
+		"TZ":             false,
+		"DATABASE_URL":   false,
+	} {
+		if got := Sensitive(name); got != want {
+			t.Errorf("Sensitive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dsn")
+	if err := os.WriteFile(file, []byte("postgres://app:hunter2@db\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHORT", "on")
+	RememberLocal(map[string]compose.SecretConfig{
+		"dsn":   {File: file},
+		"short": {Environment: "SHORT"},
+		"vault": {Provider: "missing", Ref: "kv/app"},
+	})
+
+	args := []string{
+		"run", "--env", "DB_PASSWORD=hunter2", "--env=API_TOKEN=abc", "--env", "TZ=UTC",
+		"--env", "DATABASE_URL=postgres://app:hunter2@db", "--env", "DEBUG=on", "--env", "EMPTY_SECRET=",
+		"alpine",
+	}
+	want := []string{
+		"run", "--env", "DB_PASSWORD=****", "--env=API_TOKEN=****", "--env", "TZ=UTC",
+		"--env", "DATABASE_URL=****", "--env", "DEBUG=on", "--env", "EMPTY_SECRET=",
+		"alpine",
+	}
+	if got := Redact(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact() =\n%v\nwant\n%v", got, want)
+	}
+	if args[2] != "DB_PASSWORD=hunter2" {
+		t.Error("Redact() modified its argument")
+	}
+}
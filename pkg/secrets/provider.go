@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Provider resolves secret references in an external store.
+type Provider interface {
+	// Resolve returns the secret value a reference points to.
+	Resolve(ref string) ([]byte, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ref string) ([]byte, error)
+
+// Resolve calls f(ref).
+func (f ProviderFunc) Resolve(ref string) ([]byte, error) { return f(ref) }
+
+// PluginPrefix names exec-based provider plugins: a provider "acme" is
+// served by a dctl-secret-acme binary on PATH.
+const PluginPrefix = "dctl-secret-"
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{
+		"keychain":  ProviderFunc(Keychain),
+		"1password": command("op", "read", "{ref}"),
+		"vault":     ProviderFunc(vaultKV),
+		"aws":       command("aws", "secretsmanager", "get-secret-value", "--secret-id", "{ref}", "--query", "SecretString", "--output", "text"),
+	}
+)
+
+// Register makes a provider available under name, replacing any provider
+// already registered with that name.
+func Register(name string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[name] = p
+}
+
+// Providers returns the names of the registered providers.
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the named provider: a registered one, or else the
+// dctl-secret-<name> plugin on PATH.
+func Lookup(name string) (Provider, error) {
+	mu.RLock()
+	p, ok := providers[name]
+	mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+	bin, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown secret provider %q (no %s%s on PATH)", name, PluginPrefix, name)
+	}
+	return plugin(bin, name), nil
+}
+
+// plugin returns a provider that runs `BIN get REF` and reads the value
+// from stdout. DCTL_SECRET_PROVIDER tells the plugin which name it was
+// invoked as.
+func plugin(bin, name string) Provider {
+	return ProviderFunc(func(ref string) ([]byte, error) {
+		cmd := exec.Command(bin, "get", ref)
+		cmd.Env = append(os.Environ(), "DCTL_SECRET_PROVIDER="+name)
+		return output(cmd)
+	})
+}
+
+// command returns a provider that runs a CLI, substituting {ref} in its
+// arguments, and reads the value from stdout.
+func command(bin string, args ...string) Provider {
+	return ProviderFunc(func(ref string) ([]byte, error) {
+		argv := make([]string, len(args))
+		for i, a := range args {
+			argv[i] = strings.ReplaceAll(a, "{ref}", ref)
+		}
+		return output(exec.Command(bin, argv...))
+	})
+}
+
+// vaultKV reads a field of a KV secret; ref is PATH#FIELD, and the field
+// defaults to "value".
+func vaultKV(ref string) ([]byte, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		field = "value"
+	}
+	return output(exec.Command("vault", "kv", "get", "-field="+field, path))
+}
+
+// output runs cmd and returns its stdout without the trailing newline.
+func output(cmd *exec.Cmd) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimSuffix(out, []byte("\n")), nil
+}
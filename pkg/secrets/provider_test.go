@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+// writeScript creates an executable shell script named name in dir.
+func writeScript(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-static", ProviderFunc(func(ref string) ([]byte, error) {
+		return []byte("value-of-" + ref), nil
+	}))
+	got, err := Value("s", compose.SecretConfig{Provider: "test-static", Ref: "x"})
+	if err != nil || string(got) != "value-of-x" {
+		t.Errorf("Value() = %q, %v; want value-of-x", got, err)
+	}
+}
+
+func TestLookup_Plugin(t *testing.T) {
+	bin := t.TempDir()
+	writeScript(t, bin, "dctl-secret-acme", `[ "$1" = get ] || exit 2
+echo "$DCTL_SECRET_PROVIDER:$2"
+`)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	got, err := Value("s", compose.SecretConfig{Provider: "acme", Ref: "team/db"})
+	if err != nil || string(got) != "acme:team/db" {
+		t.Errorf("Value() = %q, %v; want acme:team/db", got, err)
+	}
+
+	if _, err := Lookup("no-such-provider"); err == nil {
+		t.Error("Lookup() of a missing plugin: expected error")
+	}
+}
+
+func TestBuiltinProviders(t *testing.T) {
+	bin := t.TempDir()
+	writeScript(t, bin, "vault", `echo "$*"`)
+	writeScript(t, bin, "op", `echo "$*"`)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		provider, ref, want string
+	}{
+		{"vault", "secret/shop#password", "kv get -field=password secret/shop"},
+		{"vault", "secret/shop", "kv get -field=value secret/shop"},
+		{"1password", "op://dev/db/password", "read op://dev/db/password"},
+	}
+	for _, tt := range tests {
+		got, err := Value("s", compose.SecretConfig{Provider: tt.provider, Ref: tt.ref})
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s %s = %q, %v; want %q", tt.provider, tt.ref, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package secrets resolves compose secrets and materializes them for
+// containers as files under /run/secrets or as environment variables.
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+// MountPath is where secret files appear inside containers.
+const MountPath = "/run/secrets"
+
+// securityBin is the macOS keychain CLI.
+var securityBin = "security"
+
+// Value returns a secret's value from its source. The value is also
+// remembered so Redact masks it.
+func Value(name string, cfg compose.SecretConfig) ([]byte, error) {
+	v, err := read(name, cfg)
+	if err == nil {
+		remember(v)
+	}
+	return v, err
+}
+
+func read(name string, cfg compose.SecretConfig) ([]byte, error) {
+	switch {
+	case cfg.File != "":
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		return data, nil
+	case cfg.Environment != "":
+		v, ok := os.LookupEnv(cfg.Environment)
+		if !ok {
+			return nil, fmt.Errorf("secret %s: environment variable %s is not set", name, cfg.Environment)
+		}
+		return []byte(v), nil
+	case cfg.Keychain != "":
+		return resolve(name, "keychain", cfg.Keychain)
+	case cfg.Provider != "":
+		return resolve(name, cfg.Provider, cfg.Ref)
+	}
+	return nil, fmt.Errorf("secret %s has no source", name)
+}
+
+func resolve(name, provider, ref string) ([]byte, error) {
+	p, err := Lookup(provider)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", name, err)
+	}
+	v, err := p.Resolve(ref)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", name, err)
+	}
+	return v, nil
+}
+
+// Keychain reads the password of a generic keychain item by service name.
+func Keychain(item string) ([]byte, error) {
+	v, err := output(exec.Command(securityBin, "find-generic-password", "-s", item, "-w"))
+	if err != nil {
+		return nil, fmt.Errorf("reading keychain item %q: %w", item, err)
+	}
+	return v, nil
+}
+
+// Dir returns the host directory holding a container's secrets.
+func Dir(container string) string {
+	state, err := compose.StateDir()
+	if err != nil {
+		state = os.TempDir()
+	}
+	return filepath.Join(state, "secrets", container)
+}
+
+func filesDir(container string) string { return filepath.Join(Dir(container), "files") }
+func envFile(container string) string  { return filepath.Join(Dir(container), "env") }
+
+// Exposure returns how a service's secrets reach its container: a volume
+// spec mounting the secret files read-only and an env file for secrets
+// injected as variables, each empty when no secret needs it.
+func Exposure(container string, secrets []compose.ServiceSecret) (volume, env string) {
+	for _, s := range secrets {
+		if s.EnvVar != "" {
+			env = envFile(container)
+		} else {
+			volume = filesDir(container) + ":" + MountPath + ":ro"
+		}
+	}
+	return volume, env
+}
+
+// RunArgs returns the container run flags that expose a service's secrets:
+// a read-only mount of the secret files and an env file for secrets
+// injected as variables. Values stay out of the arguments.
+func RunArgs(container string, secrets []compose.ServiceSecret) []string {
+	volume, env := Exposure(container, secrets)
+	var args []string
+	if volume != "" {
+		args = append(args, "--volume", volume)
+	}
+	if env != "" {
+		args = append(args, "--env-file", env)
+	}
+	return args
+}
+
+// Write resolves a service's secrets and writes them where RunArgs
+// expects them, readable only by the current user.
+func Write(container string, secrets []compose.ServiceSecret, defs map[string]compose.SecretConfig) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	if err := os.RemoveAll(Dir(container)); err != nil {
+		return fmt.Errorf("clearing secrets: %w", err)
+	}
+	if err := os.MkdirAll(filesDir(container), 0o700); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+
+	var env strings.Builder
+	for _, s := range secrets {
+		value, err := Value(s.Source, defs[s.Source])
+		if err != nil {
+			return err
+		}
+		if s.EnvVar != "" {
+			value = bytes.TrimSuffix(value, []byte("\n"))
+			if bytes.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("secret %s: multi-line values cannot be injected as %s", s.Source, s.EnvVar)
+			}
+			fmt.Fprintf(&env, "%s=%s\n", s.EnvVar, value)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(filesDir(container), s.Target), value, 0o600); err != nil {
+			return fmt.Errorf("writing secret %s: %w", s.Source, err)
+		}
+	}
+	if env.Len() > 0 {
+		if err := os.WriteFile(envFile(container), []byte(env.String()), 0o600); err != nil {
+			return fmt.Errorf("writing secret environment: %w", err)
+		}
+	}
+	return nil
+}
+
+// Remove deletes a container's materialized secrets.
+func Remove(container string) error {
+	if err := os.RemoveAll(Dir(container)); err != nil {
+		return fmt.Errorf("removing secrets: %w", err)
+	}
+	return nil
+}
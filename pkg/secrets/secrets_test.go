@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+// fakeSecurity installs a security stub that prints "s3cret" for the
+// item "db-pass" and fails for anything else.
+func fakeSecurity(t *testing.T) {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "security")
+	script := "#!/bin/sh\n[ \"$3\" = db-pass ] && echo s3cret && exit 0\necho 'item not found' >&2\nexit 44\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	orig := securityBin
+	securityBin = bin
+	t.Cleanup(func() { securityBin = orig })
+}
+
+func TestValue(t *testing.T) {
+	fakeSecurity(t)
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("API_TOKEN", "from-env")
+
+	tests := []struct {
+		cfg  compose.SecretConfig
+		want string
+	}{
+		{compose.SecretConfig{File: file}, "from-file\n"},
+		{compose.SecretConfig{Environment: "API_TOKEN"}, "from-env"},
+		{compose.SecretConfig{Keychain: "db-pass"}, "s3cret"},
+	}
+	for _, tt := range tests {
+		got, err := Value("x", tt.cfg)
+		if err != nil {
+			t.Errorf("Value(%+v) error: %v", tt.cfg, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Value(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
+
+	for _, cfg := range []compose.SecretConfig{
+		{Keychain: "missing"},
+		{Environment: "DCTL_TEST_UNSET_SECRET"},
+		{File: filepath.Join(t.TempDir(), "nope")},
+	} {
+		if _, err := Value("x", cfg); err == nil {
+			t.Errorf("Value(%+v): expected error", cfg)
+		}
+	}
+}
+
+func TestWriteAndRunArgs(t *testing.T) {
+	fakeSecurity(t)
+	state := t.TempDir()
+	t.Setenv("DCTL_STATE_DIR", state)
+	t.Setenv("API_TOKEN", "tok\n")
+
+	defs := map[string]compose.SecretConfig{
+		"db":  {Keychain: "db-pass"},
+		"api": {Environment: "API_TOKEN"},
+	}
+	svcSecrets := []compose.ServiceSecret{
+		{Source: "db", Target: "db_password"},
+		{Source: "api", Target: "api", EnvVar: "API_TOKEN"},
+	}
+	if err := Write("shop_web", svcSecrets, defs); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	dir := filepath.Join(state, "secrets", "shop_web")
+	data, err := os.ReadFile(filepath.Join(dir, "files", "db_password"))
+	if err != nil || string(data) != "s3cret" {
+		t.Errorf("secret file = %q, %v; want s3cret", data, err)
+	}
+	info, _ := os.Stat(filepath.Join(dir, "files", "db_password"))
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("secret file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "env")); string(data) != "API_TOKEN=tok\n" {
+		t.Errorf("env file = %q, want API_TOKEN=tok", data)
+	}
+
+	want := []string{
+		"--volume", filepath.Join(dir, "files") + ":/run/secrets:ro",
+		"--env-file", filepath.Join(dir, "env"),
+	}
+	if got := RunArgs("shop_web", svcSecrets); !reflect.DeepEqual(got, want) {
+		t.Errorf("RunArgs() = %v, want %v", got, want)
+	}
+
+	if err := Remove("shop_web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("secrets directory still exists after Remove")
+	}
+}
@@ -0,0 +1,247 @@
+// Package selfupdate checks GitHub releases for newer dctl versions and
+// replaces the running binary with the release built for this platform.
+//
+// Releases carry one binary per platform named dctl-<os>-<arch> and a
+// checksums.txt in sha256sum format, as produced by `make release`.
+package selfupdate
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repo is the GitHub repository releases are published to.
+const Repo = "sonnes/dctl"
+
+// ChecksumsAsset is the release asset listing sha256 sums of the binaries.
+const ChecksumsAsset = "checksums.txt"
+
+// apiBase is the GitHub API root. Tests point it at a local server.
+var apiBase = "https://api.github.com"
+
+// Release is a published GitHub release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// AssetName returns the release binary for a platform.
+func AssetName(goos, goarch string) string {
+	return fmt.Sprintf("dctl-%s-%s", goos, goarch)
+}
+
+// Asset returns the named asset.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Latest fetches the latest release.
+func Latest(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiBase+"/repos/"+Repo+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching latest release: %s", resp.Status)
+	}
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("parsing latest release: %w", err)
+	}
+	return &rel, nil
+}
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// parse returns the numeric parts of a version such as "v1.2.3" or the
+// `git describe` form "v1.2.3-4-gabcdef".
+func parse(v string) ([3]int, bool) {
+	m := semverPattern.FindStringSubmatch(v)
+	if m == nil {
+		return [3]int{}, false
+	}
+	var parts [3]int
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(m[i+1])
+	}
+	return parts, true
+}
+
+// Newer reports whether latest is a newer release than current. Versions
+// that are not semantic versions, such as "dev" builds, are never behind.
+func Newer(current, latest string) bool {
+	c, ok := parse(current)
+	if !ok {
+		return false
+	}
+	l, ok := parse(latest)
+	if !ok {
+		return false
+	}
+	for i := range c {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// Install downloads the release binary for goos/goarch, verifies it
+// against the release checksums, and atomically replaces the executable at
+// path. A release without checksums is refused unless insecure is set, in
+// which case the binary is installed unverified.
+func Install(ctx context.Context, rel *Release, goos, goarch, path string, insecure bool) error {
+	name := AssetName(goos, goarch)
+	asset, ok := rel.Asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no %s binary", rel.Tag, name)
+	}
+
+	var want string
+	if sums, ok := rel.Asset(ChecksumsAsset); ok {
+		data, err := download(ctx, sums.URL)
+		if err != nil {
+			return err
+		}
+		if want = checksum(data, name); want == "" {
+			return fmt.Errorf("%s has no entry for %s", ChecksumsAsset, name)
+		}
+	} else if !insecure {
+		return fmt.Errorf("release %s has no %s to verify %s against", rel.Tag, ChecksumsAsset, name)
+	}
+
+	data, err := download(ctx, asset.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if want != "" && hex.EncodeToString(sum[:]) != want {
+		return fmt.Errorf("checksum mismatch for %s", name)
+	}
+
+	// Write next to the target so the rename stays on one filesystem.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dctl-update-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// checksum returns name's hex sha256 from sha256sum-format data.
+func checksum(data []byte, name string) string {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// CheckInterval is how long a recorded version check stays fresh.
+const CheckInterval = 24 * time.Hour
+
+// Check is the result of the last version check.
+type Check struct {
+	Latest    string    `json:"latest"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Stale reports whether the check should be repeated.
+func (c Check) Stale(now time.Time) bool {
+	return now.Sub(c.CheckedAt) > CheckInterval
+}
+
+// checkFile is where the last check is recorded.
+func checkFile(dir string) string {
+	return filepath.Join(dir, "update-check.json")
+}
+
+// LoadCheck reads the last check from dir. A missing or unreadable record
+// yields the zero Check, which is stale.
+func LoadCheck(dir string) Check {
+	var c Check
+	if data, err := os.ReadFile(checkFile(dir)); err == nil {
+		json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// SaveCheck records a check in dir.
+func SaveCheck(dir string, c Check) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling version check: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := os.WriteFile(checkFile(dir), data, 0o644); err != nil {
+		return fmt.Errorf("writing version check: %w", err)
+	}
+	return nil
+}
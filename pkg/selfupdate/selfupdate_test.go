@@ -0,0 +1,151 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"v1.2.3", "v1.2.4", true},
+		{"v1.2.3", "v1.10.0", true},
+		{"1.2.3", "v2.0.0", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2.3-4-gabcdef", "v1.2.3", false},
+		{"v1.3.0", "v1.2.9", false},
+		{"dev", "v9.9.9", false},
+		{"v1.2.3", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+// releaseServer serves a latest release with one binary and its checksum,
+// or no checksums when sum is empty.
+func releaseServer(t *testing.T, binary []byte, sum string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + Repo + "/releases/latest":
+			rel := Release{Tag: "v1.1.0", Assets: []Asset{{Name: "dctl-darwin-arm64", URL: srv.URL + "/bin"}}}
+			if sum != "" {
+				rel.Assets = append(rel.Assets, Asset{Name: ChecksumsAsset, URL: srv.URL + "/sums"})
+			}
+			json.NewEncoder(w).Encode(rel)
+		case "/bin":
+			w.Write(binary)
+		case "/sums":
+			fmt.Fprintf(w, "%s  dctl-darwin-arm64\n", sum)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	orig := apiBase
+	apiBase = srv.URL
+	t.Cleanup(func() {
+		apiBase = orig
+		srv.Close()
+	})
+	return srv
+}
+
+func TestInstall(t *testing.T) {
+	binary := []byte("new dctl")
+	sum := sha256.Sum256(binary)
+	releaseServer(t, binary, hex.EncodeToString(sum[:]))
+
+	rel, err := Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Tag != "v1.1.0" {
+		t.Errorf("Tag = %q, want v1.1.0", rel.Tag)
+	}
+
+	path := filepath.Join(t.TempDir(), "dctl")
+	if err := os.WriteFile(path, []byte("old dctl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(context.Background(), rel, "darwin", "arm64", path, false); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new dctl" {
+		t.Errorf("binary = %q, want new dctl", data)
+	}
+
+	if err := Install(context.Background(), rel, "linux", "amd64", path, false); err == nil {
+		t.Error("Install() for a missing platform succeeded, want error")
+	}
+}
+
+func TestInstall_ChecksumMismatch(t *testing.T) {
+	releaseServer(t, []byte("tampered"), "0000")
+	rel, err := Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dctl")
+	os.WriteFile(path, []byte("old dctl"), 0o755)
+	if err := Install(context.Background(), rel, "darwin", "arm64", path, false); err == nil {
+		t.Fatal("Install() succeeded, want checksum error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old dctl" {
+		t.Errorf("binary replaced despite checksum mismatch")
+	}
+}
+
+func TestInstall_MissingChecksums(t *testing.T) {
+	releaseServer(t, []byte("new dctl"), "")
+	rel, err := Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "dctl")
+	os.WriteFile(path, []byte("old dctl"), 0o755)
+	if err := Install(context.Background(), rel, "darwin", "arm64", path, false); err == nil {
+		t.Fatal("Install() without checksums succeeded, want error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old dctl" {
+		t.Errorf("binary replaced without checksums")
+	}
+	if err := Install(context.Background(), rel, "darwin", "arm64", path, true); err != nil {
+		t.Fatalf("Install() insecure error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new dctl" {
+		t.Errorf("binary = %q, want new dctl", data)
+	}
+}
+
+func TestCheckRecord(t *testing.T) {
+	dir := t.TempDir()
+	if !LoadCheck(dir).Stale(time.Now()) {
+		t.Error("missing check is not stale")
+	}
+	now := time.Now()
+	if err := SaveCheck(dir, Check{Latest: "v1.1.0", CheckedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	c := LoadCheck(dir)
+	if c.Latest != "v1.1.0" || c.Stale(now.Add(time.Hour)) {
+		t.Errorf("LoadCheck() = %+v, want fresh v1.1.0", c)
+	}
+	if !c.Stale(now.Add(CheckInterval + time.Minute)) {
+		t.Error("check older than CheckInterval is not stale")
+	}
+}
@@ -0,0 +1,147 @@
+// Package stack reads stack manifests, which group several compose
+// projects, possibly from different directories, so they can be brought up
+// and down together in dependency order.
+package stack
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles are the manifest names looked for in the working directory.
+var DefaultFiles = []string{"dctl-stack.yaml", "dctl-stack.yml"}
+
+// Manifest lists the projects of a stack, keyed by project name.
+type Manifest struct {
+	Projects map[string]Project `yaml:"projects"`
+}
+
+// Project is a compose project in a stack.
+type Project struct {
+	// Path is the project directory, relative to the manifest. It defaults
+	// to the project name.
+	Path string `yaml:"path,omitempty"`
+	// Files are compose files relative to Path; empty for the default file.
+	Files    []string `yaml:"files,omitempty"`
+	Profiles []string `yaml:"profiles,omitempty"`
+	// EnvFile is an alternate environment file relative to Path.
+	EnvFile   string   `yaml:"env_file,omitempty"`
+	DependsOn []string `yaml:"depends_on,omitempty"`
+}
+
+// Find returns the manifest in dir.
+func Find(dir string) (string, error) {
+	for _, name := range DefaultFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no stack manifest found in %s (tried: %v)", dir, DefaultFiles)
+}
+
+// Load reads a manifest, making project paths absolute and checking that
+// dependencies name projects of the stack and do not form a cycle.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading stack manifest: %w", err)
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(m.Projects) == 0 {
+		return nil, fmt.Errorf("%s lists no projects", path)
+	}
+
+	dir := filepath.Dir(path)
+	for name, p := range m.Projects {
+		p.Path = compose.ResolvePath(dir, cmp.Or(p.Path, name))
+		for _, dep := range p.DependsOn {
+			if _, ok := m.Projects[dep]; !ok {
+				return nil, fmt.Errorf("project %q depends on %q, which is not in the stack", name, dep)
+			}
+		}
+		m.Projects[name] = p
+	}
+	if _, err := m.Order(nil, false); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Order returns the named projects, or all when names is empty, in
+// dependency order. With withDeps the projects they depend on are included.
+func (m *Manifest) Order(names []string, withDeps bool) ([]string, error) {
+	for _, name := range names {
+		if _, ok := m.Projects[name]; !ok {
+			return nil, fmt.Errorf("no such project in the stack: %s", name)
+		}
+	}
+
+	// Projects are ordered like services, by the same depends_on graph.
+	services := make(map[string]compose.Service, len(m.Projects))
+	for name, p := range m.Projects {
+		deps := make(map[string]compose.DependsOnCondition, len(p.DependsOn))
+		for _, dep := range p.DependsOn {
+			deps[dep] = compose.DependsOnCondition{}
+		}
+		services[name] = compose.Service{DependsOn: deps}
+	}
+	order, err := compose.ResolveOrder(services)
+	if err != nil {
+		return nil, fmt.Errorf("stack: %w", err)
+	}
+	if len(names) == 0 {
+		return order, nil
+	}
+
+	selected := make(map[string]bool)
+	var add func(string)
+	add = func(name string) {
+		selected[name] = true
+		if withDeps {
+			for _, dep := range m.Projects[name].DependsOn {
+				add(dep)
+			}
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+	var result []string
+	for _, name := range order {
+		if selected[name] {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// Dependents returns names with every project that depends on one of
+// them, directly or not, added, so none is left running without a project
+// it depends on.
+func (m *Manifest) Dependents(names []string) []string {
+	selected := slices.Clone(names)
+	for changed := true; changed; {
+		changed = false
+		for _, name := range slices.Sorted(maps.Keys(m.Projects)) {
+			if slices.Contains(selected, name) {
+				continue
+			}
+			if slices.ContainsFunc(m.Projects[name].DependsOn, func(dep string) bool { return slices.Contains(selected, dep) }) {
+				selected = append(selected, name)
+				changed = true
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,103 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dctl-stack.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeManifest(t, `
+projects:
+  infra:
+    profiles: [dev]
+  api:
+    path: services/api
+    files: [compose.yaml, compose.local.yaml]
+    depends_on: [infra]
+  web:
+    path: /srv/web
+    depends_on: [api]
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(path)
+	if got := m.Projects["infra"].Path; got != filepath.Join(dir, "infra") {
+		t.Errorf("infra Path = %q, want the project name under the manifest directory", got)
+	}
+	if got := m.Projects["api"].Path; got != filepath.Join(dir, "services/api") {
+		t.Errorf("api Path = %q", got)
+	}
+	if got := m.Projects["web"].Path; got != "/srv/web" {
+		t.Errorf("web Path = %q", got)
+	}
+
+	tests := []struct {
+		names    []string
+		withDeps bool
+		want     []string
+	}{
+		{nil, false, []string{"infra", "api", "web"}},
+		{[]string{"web"}, false, []string{"web"}},
+		{[]string{"web"}, true, []string{"infra", "api", "web"}},
+		{[]string{"web", "infra"}, false, []string{"infra", "web"}},
+	}
+	for _, tt := range tests {
+		got, err := m.Order(tt.names, tt.withDeps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Order(%v, %v) = %v, want %v", tt.names, tt.withDeps, got, tt.want)
+		}
+	}
+	if _, err := m.Order([]string{"db"}, false); err == nil {
+		t.Error("Order() of an unknown project succeeded")
+	}
+
+	if got, want := m.Dependents([]string{"infra"}), []string{"infra", "api", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(infra) = %v, want %v", got, want)
+	}
+	if got, want := m.Dependents([]string{"web"}), []string{"web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(web) = %v, want %v", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for content, want := range map[string]string{
+		"projects: {}\n": "lists no projects",
+		"projects:\n  api:\n    depends_on: [db]\n":                         `depends on "db", which is not in the stack`,
+		"projects:\n  a:\n    depends_on: [b]\n  b:\n    depends_on: [a]\n": "cycle",
+	} {
+		if _, err := Load(writeManifest(t, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Find(dir); err == nil {
+		t.Error("Find() in an empty directory succeeded")
+	}
+	path := filepath.Join(dir, "dctl-stack.yml")
+	if err := os.WriteFile(path, []byte("projects: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Find(dir); err != nil || got != path {
+		t.Errorf("Find() = %q, %v, want %q", got, err, path)
+	}
+}
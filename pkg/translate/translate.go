@@ -0,0 +1,300 @@
+// Package translate converts resolved compose services into container runs.
+// A service and the options of one invocation become a typed RunSpec, which
+// renders as container CLI arguments.
+package translate
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sonnes/dctl/pkg/compose"
+	"github.com/sonnes/dctl/pkg/secrets"
+)
+
+// Stdio selects how a run is attached to dctl's stdin and terminal.
+type Stdio struct {
+	Interactive bool
+	TTY         bool
+}
+
+// Options are the per-invocation settings of a run that do not come from
+// the service definition. Zero values keep the service's own settings.
+type Options struct {
+	Name       string
+	Detach     bool
+	Remove     bool     // remove the container when it exits
+	Ports      []string // replaces the service's ports when non-nil
+	Volumes    []string // added after the service's volumes
+	Env        []string // overrides the service's environment; see compose.ServiceEnvironment
+	User       string
+	Workdir    string
+	Entrypoint string
+	// ClearEntrypoint runs Command without the image's entrypoint, as
+	// --entrypoint "" does.
+	ClearEntrypoint bool
+	Command         []string // replaces the service's command when non-empty
+	Stdio           *Stdio   // replaces the service's tty and stdin_open when set
+	// DefaultPlatform is used when the service sets no platform.
+	DefaultPlatform string
+}
+
+// RunSpec is a container run in the runtime's terms.
+type RunSpec struct {
+	Name          string
+	Detach        bool
+	Remove        bool
+	Publish       []string // --publish values
+	Volumes       []string
+	Env           []string // KEY=VALUE, sorted by key
+	EnvFile       string   // env file holding variables passed in place of Env
+	SecretVolume  string   // read-only mount of the secret files
+	SecretEnvFile string   // env file of secrets injected as variables
+	Workdir       string
+	User          string
+	GroupAdd      []string
+	Userns        string
+	Pid           string // pid, ipc and uts namespaces, with service: resolved to container:
+	Ipc           string
+	Uts           string
+	AddHosts      []string // --add-host values, HOST:IP
+	TTY           bool
+	Interactive   bool
+	ReadOnly      bool
+	Init          bool
+	Privileged    bool
+	Health        *Health // Docker's healthcheck flags; nil keeps the image's
+	CPUs          string
+	Memory        string
+	DNS           []string
+	Labels        []string // KEY=VALUE
+	Tmpfs         []string
+	Entrypoint    string
+	NoEntrypoint  bool // clears the image's entrypoint
+	Platform      string
+	Networks      []string
+	Image         string
+	Command       []string
+}
+
+// Health is a service's healthcheck in docker run's terms: Disable turns
+// the image's off, while an empty Cmd keeps its test with the other
+// settings applied.
+type Health struct {
+	Disable       bool
+	Cmd           string
+	Interval      string
+	Timeout       string
+	Retries       int
+	StartPeriod   string
+	StartInterval string
+}
+
+// FromService returns the run of svc with opts applied. The service's image
+// must already be resolved, and network and namespaces shared with a
+// service given as container:NAME.
+func FromService(svc compose.Service, opts Options) (RunSpec, error) {
+	env, err := compose.ServiceEnvironment(svc, opts.Env)
+	if err != nil {
+		return RunSpec{}, err
+	}
+	spec := RunSpec{
+		Name:       opts.Name,
+		Detach:     opts.Detach,
+		Remove:     opts.Remove,
+		Workdir:    cmp.Or(opts.Workdir, svc.WorkingDir),
+		User:       cmp.Or(opts.User, svc.User),
+		GroupAdd:   svc.GroupAdd,
+		Userns:     svc.UsernsMode,
+		Pid:        svc.Pid,
+		Ipc:        svc.Ipc,
+		Uts:        svc.Uts,
+		ReadOnly:   svc.ReadOnly,
+		Init:       svc.Init,
+		Privileged: svc.Privileged,
+		Memory:     svc.MemLimit,
+		Entrypoint: opts.Entrypoint,
+		Platform:   cmp.Or(svc.Platform, opts.DefaultPlatform),
+		Image:      svc.Image,
+		Command:    opts.Command,
+	}
+
+	// Mount options the runtime lacks are dropped; see compose.Mount.
+	for _, v := range slices.Concat(svc.Volumes, opts.Volumes) {
+		spec.Volumes = append(spec.Volumes, compose.ParseMount(v).RuntimeSpec())
+	}
+
+	ports := svc.Ports
+	if opts.Ports != nil {
+		ports = opts.Ports
+	}
+	for _, p := range ports {
+		spec.Publish = append(spec.Publish, compose.PublishSpec(p))
+	}
+
+	// Variables are passed resolved, so the runtime never applies its own
+	// precedence between env files and --env.
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		spec.Env = append(spec.Env, k+"="+env[k])
+	}
+
+	// Secrets are written by secrets.Write before the container starts.
+	if secs, ok := svc.Secrets.([]compose.ServiceSecret); ok {
+		spec.SecretVolume, spec.SecretEnvFile = secrets.Exposure(opts.Name, secs)
+	}
+
+	if opts.Stdio != nil {
+		spec.TTY, spec.Interactive = opts.Stdio.TTY, opts.Stdio.Interactive
+	} else {
+		spec.TTY, spec.Interactive = svc.Tty, svc.StdinOpen
+	}
+
+	if hc := svc.Healthcheck; hc.Disabled() {
+		spec.Health = &Health{Disable: true}
+	} else if hc != nil {
+		spec.Health = &Health{
+			Cmd:           hc.ShellCommand(),
+			Interval:      hc.Interval,
+			Timeout:       hc.Timeout,
+			Retries:       hc.Retries,
+			StartPeriod:   hc.StartPeriod,
+			StartInterval: hc.StartInterval,
+		}
+	}
+
+	if svc.CPUs != nil {
+		spec.CPUs = fmt.Sprintf("%v", svc.CPUs)
+	}
+	// extra_hosts may be written HOST=IP; --add-host takes HOST:IP.
+	for _, h := range svc.ExtraHosts {
+		if host, ip, ok := strings.Cut(h, "="); ok {
+			h = host + ":" + ip
+		}
+		spec.AddHosts = append(spec.AddHosts, h)
+	}
+	if dns, ok := svc.DNS.([]string); ok {
+		spec.DNS = dns
+	}
+	for _, k := range slices.Sorted(maps.Keys(svc.Labels)) {
+		spec.Labels = append(spec.Labels, k+"="+svc.Labels[k])
+	}
+	// The runtime's --tmpfs takes a path only; size and mode are dropped.
+	if tmpfs, ok := svc.Tmpfs.([]string); ok {
+		for _, t := range tmpfs {
+			target, _, _ := strings.Cut(t, ":")
+			spec.Tmpfs = append(spec.Tmpfs, target)
+		}
+	}
+	// An empty entrypoint ("" or []) clears the image's, unlike an unset one.
+	if ep, ok := svc.Entrypoint.([]string); ok && spec.Entrypoint == "" && !opts.ClearEntrypoint {
+		if len(ep) > 0 && ep[0] != "" {
+			spec.Entrypoint = ep[0]
+		} else {
+			spec.NoEntrypoint = true
+		}
+	}
+	if opts.ClearEntrypoint {
+		spec.NoEntrypoint = true
+	}
+	if nets, ok := svc.Networks.(map[string]interface{}); ok {
+		spec.Networks = slices.Sorted(maps.Keys(nets))
+	}
+	// network_mode, which excludes networks, is passed as the one network;
+	// bridge is the runtime's default.
+	if mode := svc.NetworkMode; mode != "" && mode != "bridge" {
+		spec.Networks = []string{mode}
+	}
+	if cmdSlice, ok := svc.Command.([]string); ok && len(spec.Command) == 0 {
+		spec.Command = cmdSlice
+	}
+	return spec, nil
+}
+
+// Args returns the container CLI arguments for the run. Their order is
+// stable, since config hashes of recorded runs are computed over them.
+func (s RunSpec) Args() []string {
+	args := []string{"run"}
+	if s.Detach {
+		args = append(args, "--detach")
+	}
+	if s.Remove {
+		args = append(args, "--rm")
+	}
+	args = append(args, "--name", s.Name)
+
+	args = repeat(args, "--publish", s.Publish)
+	args = repeat(args, "--volume", s.Volumes)
+	args = repeat(args, "--env", s.Env)
+	args = optional(args, "--env-file", s.EnvFile)
+	args = optional(args, "--volume", s.SecretVolume)
+	args = optional(args, "--env-file", s.SecretEnvFile)
+	args = optional(args, "--workdir", s.Workdir)
+	args = optional(args, "--user", s.User)
+	args = repeat(args, "--group-add", s.GroupAdd)
+	args = optional(args, "--userns", s.Userns)
+	args = optional(args, "--pid", s.Pid)
+	args = optional(args, "--ipc", s.Ipc)
+	args = optional(args, "--uts", s.Uts)
+	args = repeat(args, "--add-host", s.AddHosts)
+	if s.TTY {
+		args = append(args, "--tty")
+	}
+	if s.Interactive {
+		args = append(args, "--interactive")
+	}
+	if s.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	if s.Init {
+		args = append(args, "--init")
+	}
+	if s.Privileged {
+		args = append(args, "--privileged")
+	}
+	if h := s.Health; h != nil && h.Disable {
+		args = append(args, "--no-healthcheck")
+	} else if h != nil {
+		args = optional(args, "--health-cmd", h.Cmd)
+		args = optional(args, "--health-interval", h.Interval)
+		args = optional(args, "--health-timeout", h.Timeout)
+		if h.Retries > 0 {
+			args = append(args, "--health-retries", strconv.Itoa(h.Retries))
+		}
+		args = optional(args, "--health-start-period", h.StartPeriod)
+		args = optional(args, "--health-start-interval", h.StartInterval)
+	}
+	args = optional(args, "--cpus", s.CPUs)
+	args = optional(args, "--memory", s.Memory)
+	args = repeat(args, "--dns", s.DNS)
+	args = repeat(args, "--label", s.Labels)
+	args = repeat(args, "--tmpfs", s.Tmpfs)
+	if s.NoEntrypoint {
+		args = append(args, "--entrypoint", "")
+	} else {
+		args = optional(args, "--entrypoint", s.Entrypoint)
+	}
+	args = optional(args, "--platform", s.Platform)
+	args = repeat(args, "--network", s.Networks)
+
+	args = append(args, s.Image)
+	return append(args, s.Command...)
+}
+
+// repeat appends flag once per value.
+func repeat(args []string, flag string, values []string) []string {
+	for _, v := range values {
+		args = append(args, flag, v)
+	}
+	return args
+}
+
+// optional appends flag with value unless value is empty.
+func optional(args []string, flag, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, flag, value)
+}
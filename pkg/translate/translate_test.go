@@ -0,0 +1,176 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sonnes/dctl/pkg/compose"
+)
+
+func TestFromService(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("DCTL_STATE_DIR", state)
+	secretsDir := filepath.Join(state, "secrets", "shop_web")
+	envFile := filepath.Join(t.TempDir(), "web.env")
+	if err := os.WriteFile(envFile, []byte("B=file\nC=3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := []string{"run", "--detach", "--name", "shop_web"}
+	tests := []struct {
+		field string
+		svc   compose.Service
+		opts  Options
+		want  []string // arguments between --name and the image
+	}{
+		{"none", compose.Service{}, Options{}, nil},
+		{"ports", compose.Service{Ports: []string{"8080:80", "::1:9090:90"}}, Options{},
+			[]string{"--publish", "8080:80", "--publish", "[::1]:9090:90"}},
+		{"volumes", compose.Service{Volumes: []string{"data:/data", "/src:/app:ro"}}, Options{},
+			[]string{"--volume", "data:/data", "--volume", "/src:/app:ro"}},
+		{"volume options", compose.Service{Volumes: []string{"/src:/app:cached", "/conf:/etc/app:ro,rslave"}}, Options{},
+			[]string{"--volume", "/src:/app", "--volume", "/conf:/etc/app:ro"}},
+		{"env_file", compose.Service{EnvFile: []string{envFile}, Environment: map[string]string{"A": "1", "B": "2"}}, Options{},
+			[]string{"--env", "A=1", "--env", "B=2", "--env", "C=3"}},
+		{"environment", compose.Service{Environment: map[string]string{"B": "2", "A": "1"}}, Options{},
+			[]string{"--env", "A=1", "--env", "B=2"}},
+		{"secrets", compose.Service{Secrets: []compose.ServiceSecret{{Source: "db", Target: "db"}, {Source: "key", EnvVar: "API_KEY"}}}, Options{},
+			[]string{"--volume", filepath.Join(secretsDir, "files") + ":/run/secrets:ro", "--env-file", filepath.Join(secretsDir, "env")}},
+		{"working_dir", compose.Service{WorkingDir: "/app"}, Options{}, []string{"--workdir", "/app"}},
+		{"user", compose.Service{User: "1000:1000"}, Options{}, []string{"--user", "1000:1000"}},
+		{"group_add", compose.Service{GroupAdd: []string{"docker", "999"}}, Options{},
+			[]string{"--group-add", "docker", "--group-add", "999"}},
+		{"userns_mode", compose.Service{UsernsMode: "host"}, Options{}, []string{"--userns", "host"}},
+		{"namespaces", compose.Service{Pid: "host", Ipc: "container:shop_db", Uts: "host"}, Options{},
+			[]string{"--pid", "host", "--ipc", "container:shop_db", "--uts", "host"}},
+		{"extra_hosts", compose.Service{ExtraHosts: []string{"db:10.0.0.5", "api=10.0.0.6"}}, Options{},
+			[]string{"--add-host", "db:10.0.0.5", "--add-host", "api:10.0.0.6"}},
+		{"tty", compose.Service{Tty: true}, Options{}, []string{"--tty"}},
+		{"stdin_open", compose.Service{StdinOpen: true}, Options{}, []string{"--interactive"}},
+		{"read_only", compose.Service{ReadOnly: true}, Options{}, []string{"--read-only"}},
+		{"init", compose.Service{Init: true}, Options{}, []string{"--init"}},
+		{"privileged", compose.Service{Privileged: true}, Options{}, []string{"--privileged"}},
+		{"healthcheck", compose.Service{Healthcheck: &compose.Healthcheck{Test: []interface{}{"CMD", "pg_isready"}, Interval: "10s", Retries: 3, StartPeriod: "30s", StartInterval: "1s"}}, Options{},
+			[]string{"--health-cmd", "pg_isready", "--health-interval", "10s", "--health-retries", "3", "--health-start-period", "30s", "--health-start-interval", "1s"}},
+		{"healthcheck timings", compose.Service{Healthcheck: &compose.Healthcheck{StartInterval: "2s"}}, Options{},
+			[]string{"--health-start-interval", "2s"}},
+		{"healthcheck disabled", compose.Service{Healthcheck: &compose.Healthcheck{Test: []interface{}{"NONE"}}}, Options{}, []string{"--no-healthcheck"}},
+		{"cpus", compose.Service{CPUs: 1.5}, Options{}, []string{"--cpus", "1.5"}},
+		{"mem_limit", compose.Service{MemLimit: "512m"}, Options{}, []string{"--memory", "512m"}},
+		{"dns", compose.Service{DNS: []string{"1.1.1.1", "8.8.8.8"}}, Options{},
+			[]string{"--dns", "1.1.1.1", "--dns", "8.8.8.8"}},
+		{"labels", compose.Service{Labels: map[string]string{"tier": "web", "app": "shop"}}, Options{},
+			[]string{"--label", "app=shop", "--label", "tier=web"}},
+		{"tmpfs", compose.Service{Tmpfs: []string{"/tmp", "/run:size=64m,mode=1777"}}, Options{},
+			[]string{"--tmpfs", "/tmp", "--tmpfs", "/run"}},
+		{"entrypoint", compose.Service{Entrypoint: []string{"/docker-entrypoint.sh"}}, Options{},
+			[]string{"--entrypoint", "/docker-entrypoint.sh"}},
+		{"empty entrypoint", compose.Service{Entrypoint: []string{}}, Options{}, []string{"--entrypoint", ""}},
+		{"platform", compose.Service{Platform: "linux/amd64"}, Options{DefaultPlatform: "linux/arm64"},
+			[]string{"--platform", "linux/amd64"}},
+		{"default platform", compose.Service{}, Options{DefaultPlatform: "linux/arm64"},
+			[]string{"--platform", "linux/arm64"}},
+		{"networks", compose.Service{Networks: map[string]interface{}{"front": nil, "back": nil}}, Options{},
+			[]string{"--network", "back", "--network", "front"}},
+		{"network_mode", compose.Service{NetworkMode: "host"}, Options{}, []string{"--network", "host"}},
+		{"bridge network_mode", compose.Service{NetworkMode: "bridge"}, Options{}, nil},
+		{"order", compose.Service{
+			Ports:       []string{"80"},
+			Volumes:     []string{"data:/data"},
+			Environment: map[string]string{"A": "1"},
+			User:        "app",
+			WorkingDir:  "/app",
+			Labels:      map[string]string{"a": "b"},
+			Networks:    map[string]interface{}{"front": nil},
+		}, Options{}, []string{
+			"--publish", "80", "--volume", "data:/data", "--env", "A=1",
+			"--workdir", "/app", "--user", "app", "--label", "a=b", "--network", "front",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			tt.svc.Image = "nginx"
+			tt.opts.Name, tt.opts.Detach = "shop_web", true
+			want := append(append(append([]string{}, base...), tt.want...), "nginx")
+			spec, err := FromService(tt.svc, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.Args(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Args() =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestFromService_Options(t *testing.T) {
+	svc := compose.Service{
+		Image:       "app",
+		Ports:       []string{"80"},
+		Volumes:     []string{"data:/data"},
+		Environment: map[string]string{"MODE": "prod"},
+		User:        "app",
+		WorkingDir:  "/app",
+		Tty:         true,
+		Entrypoint:  []string{"/entry"},
+		Command:     []string{"serve"},
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"service defaults", Options{Name: "shop_web_run_1"}, []string{
+			"run", "--name", "shop_web_run_1", "--publish", "80", "--volume", "data:/data",
+			"--env", "MODE=prod", "--workdir", "/app", "--user", "app", "--tty",
+			"--entrypoint", "/entry", "app", "serve",
+		}},
+		{"overrides", Options{
+			Name:       "one-off",
+			Remove:     true,
+			Ports:      []string{"8080:80"},
+			Volumes:    []string{"/tmp:/tmp"},
+			Env:        []string{"MODE=dev"},
+			User:       "root",
+			Workdir:    "/",
+			Entrypoint: "sh",
+			Command:    []string{"-c", "env"},
+			Stdio:      &Stdio{Interactive: true},
+		}, []string{
+			"run", "--rm", "--name", "one-off", "--publish", "8080:80",
+			"--volume", "data:/data", "--volume", "/tmp:/tmp", "--env", "MODE=dev",
+			"--workdir", "/", "--user", "root", "--interactive",
+			"--entrypoint", "sh", "app", "-c", "env",
+		}},
+		{"cleared entrypoint", Options{Name: "n", ClearEntrypoint: true}, []string{
+			"run", "--name", "n", "--publish", "80", "--volume", "data:/data",
+			"--env", "MODE=prod", "--workdir", "/app", "--user", "app", "--tty",
+			"--entrypoint", "", "app", "serve",
+		}},
+		{"no ports", Options{Name: "n", Ports: []string{}}, []string{
+			"run", "--name", "n", "--volume", "data:/data",
+			"--env", "MODE=prod", "--workdir", "/app", "--user", "app", "--tty",
+			"--entrypoint", "/entry", "app", "serve",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := FromService(svc, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromService_MissingEnvFile(t *testing.T) {
+	svc := compose.Service{Image: "app", EnvFile: []string{filepath.Join(t.TempDir(), "missing.env")}}
+	if _, err := FromService(svc, Options{Name: "n"}); err == nil {
+		t.Error("FromService() succeeded with a missing env_file")
+	}
+}
@@ -0,0 +1,151 @@
+// Package watch detects file changes under a path by polling.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInterval is how often Watch polls when no interval is given.
+const DefaultInterval = 500 * time.Millisecond
+
+// Change is a file that was created, modified or removed. Rel is the path
+// relative to the watched root, "." when the root is a file.
+type Change struct {
+	Path    string
+	Rel     string
+	Removed bool
+}
+
+// FileState is what Snapshot records about a file.
+type FileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Snapshot records the files under root with their modification time and
+// size. Ignored paths are skipped, and ignored directories are not read.
+func Snapshot(root string, ignore []string) (map[string]FileState, error) {
+	files := make(map[string]FileState)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && Ignored(filepath.ToSlash(rel), ignore) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		files[path] = FileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+// Diff returns the changes between two snapshots of root, sorted by path.
+func Diff(root string, before, after map[string]FileState) []Change {
+	var changes []Change
+	for path, st := range after {
+		if prev, ok := before[path]; !ok || prev != st {
+			changes = append(changes, newChange(root, path, false))
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, newChange(root, path, true))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func newChange(root, path string, removed bool) Change {
+	rel, _ := filepath.Rel(root, path)
+	return Change{Path: path, Rel: filepath.ToSlash(rel), Removed: removed}
+}
+
+// Ignored reports whether a slash-separated relative path matches one of
+// the patterns. A pattern matches the whole path, any leading directory
+// of it, or its base name; a trailing slash is ignored, so "node_modules/"
+// skips the directory and everything in it. .git is always ignored.
+func Ignored(rel string, patterns []string) bool {
+	parts := strings.Split(rel, "/")
+	for _, part := range parts {
+		if part == ".git" {
+			return true
+		}
+	}
+	for _, p := range patterns {
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "./"), "/")
+		if p == "" {
+			continue
+		}
+		for i := range parts {
+			if ok, _ := filepath.Match(p, strings.Join(parts[:i+1], "/")); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(p, parts[i]); ok && !strings.Contains(p, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Watch polls root every interval and calls fn with each batch of changes.
+// A batch is delivered once a poll finds no further changes, so a burst of
+// writes (a save, a git checkout) arrives together. Watch returns when ctx
+// is done or fn returns an error.
+func Watch(ctx context.Context, root string, ignore []string, interval time.Duration, fn func([]Change) error) error {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	base, err := Snapshot(root, ignore)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := base
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := Snapshot(root, ignore)
+		if err != nil {
+			return err
+		}
+		settled := len(Diff(root, last, current)) == 0
+		last = current
+		if !settled {
+			continue
+		}
+		if changes := Diff(root, base, current); len(changes) > 0 {
+			base = current
+			if err := fn(changes); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{"node_modules/", "*.log", "build/tmp"}
+	tests := map[string]bool{
+		"src/app.js":                  false,
+		"node_modules":                true,
+		"node_modules/react/index.js": true,
+		"pkg/node_modules/x.js":       true,
+		"debug.log":                   true,
+		"logs/debug.log":              true,
+		"build/tmp/a.o":               true,
+		"build/out/a.o":               false,
+		".git/HEAD":                   true,
+	}
+	for rel, want := range tests {
+		if got := Ignored(rel, patterns); got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, data string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "a")
+	write("gone.txt", "x")
+	write("node_modules/dep.js", "x")
+
+	before, err := Snapshot(root, []string{"node_modules/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("a.txt", "changed")
+	write("sub/new.txt", "n")
+	write("node_modules/dep.js", "changed")
+	if err := os.Remove(filepath.Join(root, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	after, err := Snapshot(root, []string{"node_modules/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Change
+	for _, c := range Diff(root, before, after) {
+		got = append(got, Change{Rel: c.Rel, Removed: c.Removed})
+	}
+	want := []Change{{Rel: "a.txt"}, {Rel: "gone.txt", Removed: true}, {Rel: "sub/new.txt"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	batches := make(chan []Change, 1)
+	go func() {
+		_ = Watch(ctx, root, nil, 10*time.Millisecond, func(c []Change) error {
+			batches <- c
+			cancel()
+			return nil
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(root, "f.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-batches:
+		if len(c) != 1 || c[0].Rel != "f.txt" || c[0].Removed {
+			t.Errorf("batch = %+v, want f.txt created", c)
+		}
+	case <-ctx.Done():
+		t.Fatal("no change delivered")
+	}
+}